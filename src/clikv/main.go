@@ -1,30 +1,71 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"math"
 	"os"
+	"reflect"
+	"time"
 
+	"router/client"
 	"storage"
 )
 
 const (
-	get = "get"
-	put = "put"
-	del = "del"
+	get  = "get"
+	put  = "put"
+	del  = "del"
+	scan = "scan"
+
+	versions = "versions"
+	update   = "update"
+	cas      = "cas"
+	delIf    = "del-if"
+	upsert   = "upsert"
+
+	// absent is the -x value expecting a record not to exist.
+	absent = "absent"
+
+	addNode    = "add-node"
+	removeNode = "remove-node"
+	drainNode  = "drain-node"
+
+	watch     = "watch"
+	suspicion = "suspicion"
+	status    = "status"
 )
 
 func usage() {
 	fmt.Println("Usage:")
 	fmt.Println("  clikv [-h]")
-	fmt.Println("  clikv <command> -s=<addr> -k=<key> [-v=<val>]")
+	fmt.Println("  clikv <command> -s=<addr> -k=<key>|-key=<key> [-v=<val>] [-ttl=<duration>] [-c=<level>] [-r=<reads>] [-w=<writes>]")
+	fmt.Println("  clikv cas|del-if -s=<addr> -k=<key>|-key=<key> -x=<clock> [-v=<val>]")
+	fmt.Println("  clikv scan -s=<addr> [-k=<start key>|-key=<start key>] [-e=<end key>|-end=<end key>] [-l=<limit>] [-t=<token>]")
+	fmt.Println("  clikv <node command> -s=<router addr> -n=<node addr>")
+	fmt.Println("  clikv watch|suspicion|status -s=<router addr>")
 
 	fmt.Println()
 	fmt.Println("List of available commands:")
 	fmt.Printf("  %s\n", get)
 	fmt.Printf("  %s\n", put)
 	fmt.Printf("  %s\n", del)
+	fmt.Printf("  %s\n", scan)
+	fmt.Printf("  %s (prints all concurrent versions of a record)\n", versions)
+	fmt.Printf("  %s (replaces all versions of a record with -v)\n", update)
+	fmt.Printf("  %s (replaces a record having the single version -x with -v)\n", cas)
+	fmt.Printf("  %s (deletes a record having the single version -x)\n", delIf)
+	fmt.Printf("  %s (replaces a record with -v whether it exists or not)\n", upsert)
+
+	fmt.Println()
+	fmt.Println("List of available node commands:")
+	fmt.Printf("  %s\n", addNode)
+	fmt.Printf("  %s\n", removeNode)
+	fmt.Printf("  %s\n", drainNode)
+	fmt.Printf("  %s (prints the nodes served by a router and their liveness on every change)\n", watch)
+	fmt.Printf("  %s (prints suspicion levels of the failure detector of a router)\n", suspicion)
+	fmt.Printf("  %s (prints the state and load of the nodes served by a router)\n", status)
 
 	fmt.Println()
 	fmt.Println("List of available options:")
@@ -32,10 +73,21 @@ func usage() {
 }
 
 var (
-	addr = flag.String("s", "", "address to send request to (e.g. localhost:7319) (REQUIRED)")
-	key  = flag.Int64("k", -1, "key (REQUIRED)")
-	val  = flag.String("v", "", "value")
-	help = flag.Bool("h", false, "show this help message")
+	addr   = flag.String("s", "", "address to send request to (e.g. localhost:7319) (REQUIRED)")
+	key    = flag.Int64("k", -1, "numeric key (REQUIRED unless -key is set)")
+	bkey   = flag.String("key", "", "byte string key, overrides -k")
+	val    = flag.String("v", "", "value")
+	node   = flag.String("n", "", "node address for node commands")
+	end    = flag.Int64("e", 0, "numeric key following the last one to scan, 0 means no bound")
+	bend   = flag.String("end", "", "byte string key following the last one to scan, overrides -e")
+	limit  = flag.Int("l", 0, "maximum number of records to scan, 0 means no limit")
+	token  = flag.String("t", "", "token resuming a scan")
+	clock  = flag.String("x", "", "expected version clock printed by the versions command (e.g. fe1:2,fe2:1), or \"absent\"")
+	level  = flag.String("c", "", "consistency level: one, quorum or all (default quorum)")
+	reads  = flag.Int("r", 0, "number of replicas a read waits for, overrides -c")
+	writes = flag.Int("w", 0, "number of replicas a write waits for, overrides -c")
+	ttl    = flag.Duration("ttl", 0, "time after which a written record expires, 0 means never (e.g. 30m)")
+	help   = flag.Bool("h", false, "show this help message")
 )
 
 func main() {
@@ -48,42 +100,253 @@ func main() {
 		fmt.Fprintln(os.Stderr, "-s cannot be empty")
 		os.Exit(2)
 	}
-	if *key < 0 || *key > math.MaxUint32 {
-		fmt.Fprintln(os.Stderr, "-k should be set to a uint32 value")
-		os.Exit(2)
-	}
 	if flag.NArg() != 1 {
 		fmt.Fprintln(os.Stderr, "exactly one command should be provided")
 		os.Exit(2)
 
 	}
 
+	switch flag.Arg(0) {
+	case addNode, removeNode, drainNode:
+		nodeCommand(flag.Arg(0))
+		return
+	case scan:
+		scanCommand()
+		return
+	case watch:
+		watchCommand()
+		return
+	case suspicion:
+		suspicionCommand()
+		return
+	case status:
+		statusCommand()
+		return
+	}
+
+	if *bkey == "" && (*key < 0 || *key > math.MaxUint32) {
+		fmt.Fprintln(os.Stderr, "-k should be set to a uint32 value or -key to a non-empty string")
+		os.Exit(2)
+	}
+
+	l, err := storage.ParseLevel(*level)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	ctx := storage.WithConsistency(context.Background(), storage.Consistency{Level: l, R: *reads, W: *writes})
+	if *ttl > 0 {
+		ctx = storage.WithTTL(ctx, *ttl)
+	}
+
 	client := storage.NewClient()
+	kc := storage.WithKeys(client)
 	node := storage.ServiceAddr(*addr)
 
-	k := storage.RecordID(*key)
+	k := storage.Key(*bkey)
+	if k == "" {
+		k = storage.RecordID(*key).Key()
+	}
 	data := []byte(*val)
 
 	switch flag.Arg(0) {
 	case put:
-		if err := client.Put(node, k, data); err != nil {
+		if err := kc.PutKeyContext(ctx, node, k, data); err != nil {
 			fmt.Fprintf(os.Stderr, "Error putting record: %v\n", err)
 			os.Exit(1)
 		}
 	case get:
-		b, err := client.Get(node, k)
+		b, err := kc.GetKeyContext(ctx, node, k)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error getting record: %v\n", err)
 			os.Exit(1)
 		}
 		fmt.Printf("Got record %q\n", b)
 	case del:
-		if err := client.Del(node, k); err != nil {
+		if err := kc.DelKeyContext(ctx, node, k); err != nil {
 			fmt.Fprintf(os.Stderr, "Error deleting record: %v\n", err)
 			os.Exit(1)
 		}
+	case versions:
+		vs, err := client.(storage.VersionedClient).GetVersionsContext(ctx, node, k)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting record versions: %v\n", err)
+			os.Exit(1)
+		}
+		for _, v := range vs {
+			fmt.Printf("Got version %v: %q\n", v.Clock, v.Data)
+		}
+	case update:
+		vs, err := client.(storage.VersionedClient).GetVersionsContext(ctx, node, k)
+		if err != nil && err != storage.ErrRecordNotFound {
+			fmt.Fprintf(os.Stderr, "Error getting record versions: %v\n", err)
+			os.Exit(1)
+		}
+		v := storage.Version{Clock: storage.MergedClock(vs), Data: data, Expires: storage.ExpiryFromContext(ctx)}
+		if err := client.(storage.VersionedClient).UpdateContext(ctx, node, k, v); err != nil {
+			fmt.Fprintf(os.Stderr, "Error updating record: %v\n", err)
+			os.Exit(1)
+		}
+	case cas:
+		cond := expectedCondition()
+		v := storage.Version{Clock: cond.Clock, Data: data, Expires: storage.ExpiryFromContext(ctx)}
+		if err := client.(storage.ConditionalClient).PutIfVersionContext(ctx, node, k, cond, v); err != nil {
+			fmt.Fprintf(os.Stderr, "Error swapping record: %v\n", err)
+			os.Exit(1)
+		}
+	case delIf:
+		if err := client.(storage.ConditionalClient).DeleteIfVersionContext(ctx, node, k, expectedCondition()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error deleting record: %v\n", err)
+			os.Exit(1)
+		}
+	case upsert:
+		if err := client.(storage.ConditionalClient).UpsertContext(ctx, node, k, storage.Version{Data: data, Expires: storage.ExpiryFromContext(ctx)}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error upserting record: %v\n", err)
+			os.Exit(1)
+		}
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command %q", flag.Arg(0))
 		os.Exit(2)
 	}
 }
+
+func expectedCondition() storage.Condition {
+	if *clock == absent {
+		return storage.Condition{Absent: true}
+	}
+	c, err := storage.ParseVectorClock(*clock)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	return storage.Condition{Clock: c}
+}
+
+func nodeCommand(cmd string) {
+	if *node == "" {
+		fmt.Fprintln(os.Stderr, "-n cannot be empty")
+		os.Exit(2)
+	}
+
+	c := client.NewPooled(storage.DefaultIdleTimeout)
+	defer c.Close()
+	r := storage.ServiceAddr(*addr)
+	n := storage.ServiceAddr(*node)
+
+	var (
+		epoch uint64
+		err   error
+	)
+	switch cmd {
+	case addNode:
+		epoch, err = c.AddNode(r, n)
+	case removeNode:
+		epoch, err = c.RemoveNode(r, n)
+	case drainNode:
+		epoch, err = c.DrainNode(r, n)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running %s: %v\n", cmd, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Topology epoch %d\n", epoch)
+}
+
+func watchCommand() {
+	c := client.NewPooled(storage.DefaultIdleTimeout)
+	defer c.Close()
+	// Unchanged topologies resent by the router are not printed.
+	var last *storage.Topology
+	err := c.WatchTopology(context.Background(), storage.ServiceAddr(*addr), func(t storage.Topology) error {
+		if last != nil && reflect.DeepEqual(*last, t) {
+			return nil
+		}
+		last = &t
+		fmt.Printf("Topology epoch %d: nodes %v, alive %v\n", t.Epoch, t.Nodes, t.Alive)
+		return nil
+	})
+	fmt.Fprintf(os.Stderr, "Error running %s: %v\n", watch, err)
+	os.Exit(1)
+}
+
+func suspicionCommand() {
+	c := client.NewPooled(storage.DefaultIdleTimeout)
+	defer c.Close()
+	nodes, err := c.Suspicion(storage.ServiceAddr(*addr))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running %s: %v\n", suspicion, err)
+		os.Exit(1)
+	}
+	for _, n := range nodes {
+		last := "never"
+		if !n.LastHeartbeat.IsZero() {
+			last = n.LastHeartbeat.Format(time.RFC3339Nano)
+		}
+		fmt.Printf("%s: phi %.2f from %d intervals, alive %v, last heartbeat %s\n", n.Node, n.Phi, n.Intervals, n.Alive, last)
+	}
+}
+
+func statusCommand() {
+	c := client.NewPooled(storage.DefaultIdleTimeout)
+	defer c.Close()
+	cs, err := c.ClusterStatus(storage.ServiceAddr(*addr))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running %s: %v\n", status, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Topology epoch %d, replication factor %d, quorum %d, leader %q\n",
+		cs.Epoch, cs.Replication.Factor, cs.Replication.Quorum, cs.Leader)
+	for _, n := range cs.Nodes {
+		state := "alive"
+		if !n.Alive {
+			state = "unavailable"
+		}
+		if n.Draining {
+			state += ", draining"
+		}
+		l := n.Load
+		fmt.Printf("%s: %s, %d records, %d bytes, %d bytes of memory, %.1f reads/s, %.1f writes/s\n",
+			n.Node, state, l.Records, l.Bytes, l.Memory, l.ReadRate, l.WriteRate)
+		if l.EngineError != "" {
+			fmt.Printf("  engine error: %s\n", l.EngineError)
+		}
+	}
+}
+
+func scanCommand() {
+	if *key > math.MaxUint32 || *end < 0 || *end > math.MaxUint32 {
+		fmt.Fprintln(os.Stderr, "-k and -e should be set to uint32 values")
+		os.Exit(2)
+	}
+
+	opts := storage.ScanOptions{
+		Start: storage.Key(*bkey),
+		End:   storage.Key(*bend),
+		Limit: *limit,
+		Token: *token,
+	}
+	if opts.Start == "" && *key >= 0 {
+		opts.Start = storage.RecordID(*key).Key()
+	}
+	if opts.End == "" && *end > 0 {
+		opts.End = storage.RecordID(*end).Key()
+	}
+
+	c := storage.NewPooledClient(storage.DefaultIdleTimeout)
+	defer c.Close()
+	next, err := c.Scan(storage.ServiceAddr(*addr), opts, func(k storage.Key, d []byte) error {
+		if id, ok := k.RecordID(); ok {
+			fmt.Printf("%d\t%q\n", id, d)
+			return nil
+		}
+		fmt.Printf("%q\t%q\n", k, d)
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning records: %v\n", err)
+		os.Exit(1)
+	}
+	if next != "" {
+		fmt.Printf("Next token %s\n", next)
+	}
+}
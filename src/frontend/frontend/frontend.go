@@ -1,6 +1,8 @@
 package frontend
 
 import (
+	"context"
+	"sort"
 	"sync"
 	"time"
 
@@ -32,6 +34,55 @@ type Config struct {
 	// RC specifies client for Router.
 	// RC -- клиент для router.
 	RC rclient.Client `yaml:"-"`
+	// ReadRepair enables read repair: once all replicas reply to Get,
+	// the ones which diverge from the quorum are repaired in the background.
+	// ReadRepair -- включить read repair: после ответа всех реплик на Get
+	// реплики, расходящиеся с кворумом, исправляются в фоне.
+	ReadRepair bool `yaml:"read_repair"`
+	// HintedHandoff enables hinted handoff: writes intended for unavailable
+	// nodes are kept by substitute nodes and replayed once they are available.
+	// Router and node clients have to support it.
+	// HintedHandoff -- включить hinted handoff: записи, предназначенные для
+	// недоступных node, сохраняются на замещающих node и передаются, когда
+	// те станут доступны. Клиенты router и node должны это поддерживать.
+	HintedHandoff bool `yaml:"hinted_handoff"`
+	// ID identifies the frontend in vector clocks of records it updates,
+	// it has to be unique within the cluster. Addr is used if it's empty.
+	// ID -- идентификатор Frontend в векторных часах обновляемых им записей,
+	// он должен быть уникален в кластере. Если он пуст, используется Addr.
+	ID string `yaml:"id"`
+	// TopologyRefresh is an interval to refresh the cached nodes served
+	// by Router and their liveness at. If it's set and the router client
+	// implements rclient.AliveClient or rclient.TopologyWatcher, replicas
+	// to write to are found by the frontend itself instead of a request
+	// to Router per write. With rclient.TopologyWatcher the frontend
+	// subscribes to the topology instead of refreshing it, renewing
+	// a failed subscription every TopologyRefresh.
+	// TopologyRefresh -- интервал обновления кэшированного списка node,
+	// обслуживаемых Router, и их доступности. Если он задан и клиент router
+	// реализует rclient.AliveClient или rclient.TopologyWatcher, реплики для
+	// записи находит сам Frontend вместо запроса к Router на каждую запись.
+	// С rclient.TopologyWatcher Frontend подписывается на топологию вместо ее
+	// обновления, возобновляя неуспешную подписку каждые TopologyRefresh.
+	TopologyRefresh time.Duration `yaml:"topology_refresh"`
+	// TopologyStaleness is the maximum age of the cached topology to find
+	// replicas to write to with, Router is asked once the cache is older.
+	// Three times TopologyRefresh if it's not set. Router resends
+	// an unchanged topology to subscribers every forget timeout,
+	// so with a subscription it has to exceed the forget timeout of Router.
+	// TopologyStaleness -- максимальный возраст кэшированной топологии,
+	// по которой ищутся реплики для записи; если кэш старше, запрос идет
+	// к Router. Если не задано, в три раза больше TopologyRefresh. Router
+	// повторно отправляет неизменную топологию подписчикам каждый forget timeout,
+	// поэтому при подписке значение должно превышать forget timeout Router.
+	TopologyStaleness time.Duration `yaml:"topology_staleness"`
+
+	// HasherOptions select the Hasher main creates NF with,
+	// it has to be the one of Router.
+	// HasherOptions выбирают Hasher, с которым main создает NF,
+	// он должен совпадать с Hasher Router.
+	router.HasherOptions `yaml:",inline"`
+
 	// NodesFinder specifies a NodeFinder to use.
 	// NodesFinder -- NodesFinder, который нужно использовать в Frontend.
 	NF router.NodesFinder `yaml:"-"`
@@ -40,9 +91,32 @@ type Config struct {
 // Frontend is a frontend service.
 type Frontend struct {
 	cfg Config
+	nc  storage.KeyClient
+	rc  rclient.ContextClient
 
-	nodes     []storage.ServiceAddr
+	topoLock  sync.RWMutex
+	topo      *topology
 	nodesOnce sync.Once
+	stop      chan struct{}
+	stopOnce  sync.Once
+
+	rrLock  sync.Mutex
+	rrStats ReadRepairStats
+}
+
+// ReadRepairStats counts replicas repaired by read repair.
+//
+// ReadRepairStats -- счетчики реплик, исправленных read repair.
+type ReadRepairStats struct {
+	// Divergent is a number of replicas found to diverge from the quorum.
+	// Divergent -- количество реплик, расходящихся с кворумом.
+	Divergent int
+	// Repaired is a number of replicas repaired.
+	// Repaired -- количество исправленных реплик.
+	Repaired int
+	// Failed is a number of replicas which failed to be repaired.
+	// Failed -- количество реплик, которые не удалось исправить.
+	Failed int
 }
 
 // New creates a new Frontend with a given cfg.
@@ -50,33 +124,82 @@ type Frontend struct {
 // New создает новый Frontend с данным cfg.
 func New(cfg Config) *Frontend {
 	return &Frontend{
-		cfg: cfg,
+		cfg:  cfg,
+		nc:   storage.WithKeys(cfg.NC),
+		rc:   rclient.WithContext(cfg.RC),
+		stop: make(chan struct{}),
 	}
 }
 
+// Stop stops refreshing the cached topology.
+//
+// Stop останавливает обновление кэшированной топологии.
+func (fe *Frontend) Stop() {
+	fe.stopOnce.Do(func() { close(fe.stop) })
+}
+
 // Put an item to the storage if an item for the given key doesn't exist.
 // Returns error otherwise.
 //
 // Put -- добавить запись в хранилище, если запись для данного ключа
 // не существует. Иначе вернуть ошибку.
 func (fe *Frontend) Put(k storage.RecordID, d []byte) error {
+	return fe.PutKeyContext(context.Background(), k.Key(), d)
+}
+
+// PutKey is Put of an item with a byte key.
+//
+// PutKey -- Put записи с байтовым ключом.
+func (fe *Frontend) PutKey(k storage.Key, d []byte) error {
+	return fe.PutKeyContext(context.Background(), k, d)
+}
 
-	nodes, err := fe.cfg.RC.NodesFind(fe.cfg.Router, k)
+// PutContext is Put bound to ctx: requests to Router and nodes
+// are cancelled with ctx and don't outlive its deadline.
+// A number of replicas to acknowledge the write is selected by
+// the storage.Consistency carried by ctx. The item expires at the time
+// set by storage.WithTTL if ctx carries one.
+//
+// PutContext -- Put, привязанный к ctx: запросы к Router и node
+// отменяются вместе с ctx и не превышают его deadline.
+// Количество реплик, которые должны подтвердить запись, задается
+// storage.Consistency, переданным в ctx. Запись истекает во время,
+// заданное storage.WithTTL, если оно передано в ctx.
+func (fe *Frontend) PutContext(ctx context.Context, k storage.RecordID, d []byte) error {
+	return fe.PutKeyContext(ctx, k.Key(), d)
+}
+
+// PutKeyContext is PutContext of an item with a byte key.
+//
+// PutKeyContext -- PutContext записи с байтовым ключом.
+func (fe *Frontend) PutKeyContext(ctx context.Context, k storage.Key, d []byte) error {
+	if k == "" {
+		return storage.ErrInvalidKey
+	}
+	w, err := storage.ConsistencyFromContext(ctx).Writes(fe.replication())
 	if err != nil {
 		return err
 	}
-	if len(nodes) < storage.MinRedundancy {
+	replicas, err := fe.replicas(ctx, k)
+	if err != nil {
+		return err
+	}
+	if len(replicas) < w {
 		return storage.ErrNotEnoughDaemons
 	}
 
-	results := make(chan error, len(nodes))
-	for i, node := range nodes {
-		go func(nodeIdx int, node storage.ServiceAddr) {
-			results <- fe.cfg.NC.Put(node, k, d)
-		}(i, node)
+	results := make(chan error, len(replicas))
+	for _, r := range replicas {
+		go func(r storage.Replica) {
+			if r.Hint != "" {
+				results <- fe.cfg.NC.(storage.HintClient).PutHintContext(ctx, r.Node, r.Hint, k, d)
+				return
+			}
+			results <- fe.nc.PutKeyContext(ctx, r.Node, k, d)
+		}(r)
 	}
 
-	err = checkErrors(results, len(nodes))
+	err = checkErrors(results, len(replicas), w)
 	close(results)
 	return err
 }
@@ -87,28 +210,115 @@ func (fe *Frontend) Put(k storage.RecordID, d []byte) error {
 // Del -- удалить запись из хранилища, если запись для данного ключа
 // существует. Иначе вернуть ошибку.
 func (fe *Frontend) Del(k storage.RecordID) error {
+	return fe.DelKeyContext(context.Background(), k.Key())
+}
+
+// DelKey is Del of an item with a byte key.
+//
+// DelKey -- Del записи с байтовым ключом.
+func (fe *Frontend) DelKey(k storage.Key) error {
+	return fe.DelKeyContext(context.Background(), k)
+}
 
-	nodes, err := fe.cfg.RC.NodesFind(fe.cfg.Router, k)
+// DelContext is Del bound to ctx: requests to Router and nodes
+// are cancelled with ctx and don't outlive its deadline.
+// A number of replicas to acknowledge the delete is selected by
+// the storage.Consistency carried by ctx.
+//
+// DelContext -- Del, привязанный к ctx: запросы к Router и node
+// отменяются вместе с ctx и не превышают его deadline.
+// Количество реплик, которые должны подтвердить удаление, задается
+// storage.Consistency, переданным в ctx.
+func (fe *Frontend) DelContext(ctx context.Context, k storage.RecordID) error {
+	return fe.DelKeyContext(ctx, k.Key())
+}
+
+// DelKeyContext is DelContext of an item with a byte key.
+//
+// DelKeyContext -- DelContext записи с байтовым ключом.
+func (fe *Frontend) DelKeyContext(ctx context.Context, k storage.Key) error {
+	if k == "" {
+		return storage.ErrInvalidKey
+	}
+	w, err := storage.ConsistencyFromContext(ctx).Writes(fe.replication())
 	if err != nil {
 		return err
 	}
-	if len(nodes) < storage.MinRedundancy {
+	replicas, err := fe.replicas(ctx, k)
+	if err != nil {
+		return err
+	}
+	if len(replicas) < w {
 		return storage.ErrNotEnoughDaemons
 	}
 
-	results := make(chan error, len(nodes))
-	for _, node := range nodes {
-		go func(node storage.ServiceAddr) {
-			results <- fe.cfg.NC.Del(node, k)
-		}(node)
+	results := make(chan error, len(replicas))
+	for _, r := range replicas {
+		go func(r storage.Replica) {
+			if r.Hint != "" {
+				results <- fe.cfg.NC.(storage.HintClient).DelHintContext(ctx, r.Node, r.Hint, k)
+				return
+			}
+			results <- fe.nc.DelKeyContext(ctx, r.Node, k)
+		}(r)
 	}
 
-	err = checkErrors(results, len(nodes))
+	err = checkErrors(results, len(replicas), w)
 	close(results)
 	return err
 }
 
-func checkErrors(errs <-chan error, readLimit int) error {
+// replicas returns nodes to write the record with key k to. With hinted handoff
+// enabled unavailable nodes are substituted by others keeping hints for them.
+// Replicas are found with the cached topology unless it's stale.
+func (fe *Frontend) replicas(ctx context.Context, k storage.Key) ([]storage.Replica, error) {
+	if fe.cfg.HintedHandoff {
+		hc, ok := fe.cfg.RC.(rclient.HintedClient)
+		if _, hinted := fe.cfg.NC.(storage.HintClient); ok && hinted {
+			if t := fe.freshTopology(); t != nil {
+				return t.nf.NodesFindHinted(k, t.nodes, t.isAlive, t.rep.Quorum)
+			}
+			if kc, ok := fe.cfg.RC.(rclient.KeyClient); ok {
+				return kc.NodesFindHintedKeyContext(ctx, fe.cfg.Router, k)
+			}
+			id, ok := k.RecordID()
+			if !ok {
+				return nil, storage.ErrKeyNotSupported
+			}
+			return hc.NodesFindHintedContext(ctx, fe.cfg.Router, id)
+		}
+	}
+
+	nodes, err := fe.nodesFind(ctx, k)
+	if err != nil {
+		return nil, err
+	}
+	replicas := make([]storage.Replica, 0, len(nodes))
+	for _, node := range nodes {
+		replicas = append(replicas, storage.Replica{Node: node})
+	}
+	return replicas, nil
+}
+
+// nodesFind returns alive nodes holding the record with key k, found with
+// the cached topology unless it's stale and by Router otherwise. A router
+// client which doesn't implement rclient.KeyClient only finds keys of RecordIDs.
+func (fe *Frontend) nodesFind(ctx context.Context, k storage.Key) ([]storage.ServiceAddr, error) {
+	if t := fe.freshTopology(); t != nil {
+		return t.nf.NodesFindAlive(k, t.nodes, t.isAlive, t.rep.Quorum)
+	}
+	if kc, ok := fe.cfg.RC.(rclient.KeyClient); ok {
+		return kc.NodesFindKeyContext(ctx, fe.cfg.Router, k)
+	}
+	id, ok := k.RecordID()
+	if !ok {
+		return nil, storage.ErrKeyNotSupported
+	}
+	return fe.rc.NodesFindContext(ctx, fe.cfg.Router, id)
+}
+
+// checkErrors reads readLimit errors and succeeds if required of them are nil.
+func checkErrors(errs <-chan error, readLimit int, required int) error {
 	oks := 0
 	resMap := make(map[error]int)
 
@@ -121,11 +331,11 @@ func checkErrors(errs <-chan error, readLimit int) error {
 		resMap[err]++
 	}
 
-	if oks >= storage.MinRedundancy {
+	if oks >= required {
 		return nil
 	}
 	for err, n := range resMap {
-		if n >= storage.MinRedundancy {
+		if n >= required {
 			return err
 		}
 	}
@@ -133,52 +343,380 @@ func checkErrors(errs <-chan error, readLimit int) error {
 }
 
 // Get an item from the storage if an item exists for the given key.
-// Returns error otherwise.
+// Returns error otherwise. If the item has concurrent versions,
+// storage.ErrConflict is returned and the client has to reconcile them
+// using GetVersions and Update.
 //
 // Get -- получить запись из хранилища, если запись для данного ключа
-// существует. Иначе вернуть ошибку.
+// существует. Иначе вернуть ошибку. Если у записи есть параллельные версии,
+// возвращается storage.ErrConflict, и клиент должен согласовать их
+// с помощью GetVersions и Update.
 func (fe *Frontend) Get(k storage.RecordID) ([]byte, error) {
-	fe.nodesOnce.Do(fe.initNodes)
+	return fe.GetKeyContext(context.Background(), k.Key())
+}
+
+// GetKey is Get of an item with a byte key.
+//
+// GetKey -- Get записи с байтовым ключом.
+func (fe *Frontend) GetKey(k storage.Key) ([]byte, error) {
+	return fe.GetKeyContext(context.Background(), k)
+}
+
+// GetContext is Get bound to ctx: requests to nodes are cancelled with ctx
+// and don't outlive its deadline. Requests still running when
+// the result is known are cancelled unless read repair is enabled.
+// A number of replicas which have to return the same reply is selected by
+// the storage.Consistency carried by ctx.
+//
+// GetContext -- Get, привязанный к ctx: запросы к node отменяются вместе с ctx
+// и не превышают его deadline. Запросы, не завершившиеся к моменту
+// получения результата, отменяются, если read repair не включен.
+// Количество реплик, которые должны вернуть одинаковый ответ, задается
+// storage.Consistency, переданным в ctx.
+func (fe *Frontend) GetContext(ctx context.Context, k storage.RecordID) ([]byte, error) {
+	return fe.GetKeyContext(ctx, k.Key())
+}
 
-	nodes := fe.cfg.NF.NodesFind(k, fe.nodes)
-	if len(nodes) < storage.MinRedundancy {
+// GetKeyContext is GetContext of an item with a byte key.
+//
+// GetKeyContext -- GetContext записи с байтовым ключом.
+func (fe *Frontend) GetKeyContext(ctx context.Context, k storage.Key) ([]byte, error) {
+	if k == "" {
+		return nil, storage.ErrInvalidKey
+	}
+	t := fe.topology()
+	r, err := storage.ConsistencyFromContext(ctx).Reads(t.rep)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := t.nf.NodesFindKey(k, t.nodes)
+	if len(nodes) < r {
 		return nil, storage.ErrNotEnoughDaemons
 	}
 
+	// Without read repair requests still running when the result is known
+	// are cancelled, otherwise their replies are needed to repair replicas.
+	// Results agreed on by fewer than a quorum of replicas
+	// are not used to repair others.
+	var replies chan replicaResult
+	if fe.cfg.ReadRepair && r >= t.rep.Quorum {
+		replies = make(chan replicaResult, len(nodes))
+	} else {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+	}
+
 	resChan := make(chan getResult, len(nodes))
 	endChan := make(chan getResult)
 
-	go checkResults(resChan, endChan, len(nodes))
+	go checkResults(resChan, endChan, len(nodes), r)
 
 	for _, node := range nodes {
 		go func(node storage.ServiceAddr) {
-			d, err := fe.cfg.NC.Get(node, k)
+			d, err := fe.nc.GetKeyContext(ctx, node, k)
 			resChan <- struct {
 				d   []byte
 				err error
 			}{d, err}
+			if replies != nil {
+				replies <- replicaResult{node: node, d: d, err: err}
+			}
 		}(node)
 	}
 
 	res := <-endChan
+	if replies != nil {
+		go fe.readRepair(k, res, replies, len(nodes))
+	}
 
 	return res.d, res.err
 }
 
-func (fe *Frontend) initNodes() {
-	var err error
-	var nodes []storage.ServiceAddr
+// ReadRepairStats returns counters of replicas repaired by read repair.
+//
+// ReadRepairStats возвращает счетчики реплик, исправленных read repair.
+func (fe *Frontend) ReadRepairStats() ReadRepairStats {
+	fe.rrLock.Lock()
+	defer fe.rrLock.Unlock()
+	return fe.rrStats
+}
 
+type replicaResult struct {
+	node storage.ServiceAddr
+	d    []byte
+	err  error
+}
+
+// readRepair waits for readLimit replies and brings replicas which diverge
+// from the quorum result res to it. Replicas which failed to reply are skipped.
+// If the nodes support versions, the versions of a replica agreeing with res
+// are merged into the others to keep their clocks and expiry.
+func (fe *Frontend) readRepair(k storage.Key, res getResult, replies <-chan replicaResult, readLimit int) {
+	if res.err != nil && res.err != storage.ErrRecordNotFound {
+		return
+	}
+	var (
+		source    storage.ServiceAddr
+		divergent []replicaResult
+	)
+	for i := 0; i < readLimit; i++ {
+		r := <-replies
+		if r.err != nil && r.err != storage.ErrRecordNotFound {
+			continue
+		}
+		if r.err == res.err && string(r.d) == string(res.d) {
+			if source == "" {
+				source = r.node
+			}
+			continue
+		}
+		divergent = append(divergent, r)
+	}
+
+	for _, r := range divergent {
+		ctx, cancel := context.WithTimeout(context.Background(), storage.Timeout)
+		err := fe.repairReplica(ctx, r.node, source, k, r.err == nil, res)
+		cancel()
+
+		fe.rrLock.Lock()
+		fe.rrStats.Divergent++
+		if err != nil {
+			fe.rrStats.Failed++
+		} else {
+			fe.rrStats.Repaired++
+		}
+		fe.rrLock.Unlock()
+	}
+}
+
+// repairReplica brings the replica on node to the quorum result res.
+// The record is deleted from the replica only if res is not found.
+// If the nodes support versions, the versions of source are merged into
+// the replica with Update, which keeps newer and concurrent versions
+// of the replica. A version of the replica having the clock of one
+// of them but different data is replaced with a conditional write.
+// Without versions the replica is deleted before res is put to it,
+// as records can't be replaced otherwise.
+func (fe *Frontend) repairReplica(ctx context.Context, node, source storage.ServiceAddr, k storage.Key, found bool, res getResult) error {
+	if res.err != nil {
+		if !found {
+			return nil
+		}
+		if err := fe.nc.DelKeyContext(ctx, node, k); err != nil && err != storage.ErrRecordNotFound {
+			return err
+		}
+		return nil
+	}
+
+	if vc, ok := fe.cfg.NC.(storage.VersionedClient); ok {
+		if source == "" {
+			return nil
+		}
+		versions, err := vc.GetVersionsContext(ctx, source, k)
+		if err != nil {
+			return err
+		}
+		var current []storage.Version
+		if found {
+			if current, err = vc.GetVersionsContext(ctx, node, k); err != nil && err != storage.ErrRecordNotFound {
+				return err
+			}
+		}
+		for _, v := range versions {
+			if err := fe.mergeVersion(ctx, vc, node, k, current, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if found {
+		if err := fe.nc.DelKeyContext(ctx, node, k); err != nil && err != storage.ErrRecordNotFound {
+			return err
+		}
+	}
+	if err := fe.nc.PutKeyContext(ctx, node, k, res.d); err != nil && err != storage.ErrRecordExists {
+		return err
+	}
+	return nil
+}
+
+// mergeVersion stores v on the replica on node holding current versions.
+func (fe *Frontend) mergeVersion(ctx context.Context, vc storage.VersionedClient, node storage.ServiceAddr, k storage.Key, current []storage.Version, v storage.Version) error {
+	for _, s := range current {
+		if s.Clock.Compare(v.Clock) != storage.Equal || string(s.Data) == string(v.Data) {
+			continue
+		}
+		cc, ok := fe.cfg.NC.(storage.ConditionalClient)
+		if !ok {
+			return storage.ErrConditionalNotSupported
+		}
+		return cc.PutIfVersionContext(ctx, node, k, storage.Condition{Clock: s.Clock}, v)
+	}
+	if err := vc.UpdateContext(ctx, node, k, v); err != nil && err != storage.ErrObsoleteVersion {
+		return err
+	}
+	return nil
+}
+
+// topology is a snapshot of the nodes served by Router.
+type topology struct {
+	nodes []storage.ServiceAddr
+	rep   storage.Replication
+	// nf places records as the cluster is configured to.
+	nf router.NodesFinder
+	// alive is a set of alive nodes, nil if it's unknown.
+	alive map[storage.ServiceAddr]bool
+	// updated is the time the snapshot was taken at.
+	updated time.Time
+}
+
+func (t *topology) isAlive(node storage.ServiceAddr) bool {
+	return t.alive[node]
+}
+
+// fetchTopology lists the nodes served by Router. Liveness of the nodes is
+// only fetched if the topology is cached, a failure to fetch it leaves
+// the liveness unknown.
+func (fe *Frontend) fetchTopology(ctx context.Context) (*topology, error) {
+	var (
+		placement storage.Placement
+		err       error
+	)
+	t := &topology{rep: storage.DefaultReplication}
+	if pc, ok := fe.cfg.RC.(rclient.PlacementClient); ok {
+		t.nodes, t.rep, placement, err = pc.ListPlacementContext(ctx, fe.cfg.Router)
+	} else if rc, ok := fe.cfg.RC.(rclient.ReplicationClient); ok {
+		t.nodes, t.rep, err = rc.ListReplicationContext(ctx, fe.cfg.Router)
+	} else {
+		t.nodes, err = fe.rc.ListContext(ctx, fe.cfg.Router)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.nf = fe.cfg.NF.WithReplicationFactor(t.rep.Factor).WithPlacement(placement)
+	t.updated = time.Now()
+
+	if ac, ok := fe.cfg.RC.(rclient.AliveClient); ok && fe.cfg.TopologyRefresh > 0 {
+		if alive, err := ac.AliveContext(ctx, fe.cfg.Router); err == nil {
+			t.alive = make(map[storage.ServiceAddr]bool, len(alive))
+			for _, node := range alive {
+				t.alive[node] = true
+			}
+		}
+	}
+	return t, nil
+}
+
+func (fe *Frontend) initNodes() {
 	for {
-		nodes, err = fe.cfg.RC.List(fe.cfg.Router)
+		t, err := fe.fetchTopology(context.Background())
 		if err == nil {
+			fe.setTopology(t)
 			break
 		}
 		time.Sleep(InitTimeout)
 	}
+	if fe.cfg.TopologyRefresh > 0 {
+		go fe.refreshTopology()
+	}
+}
+
+// refreshTopology keeps the cached topology up to date until the frontend
+// is stopped. If the router client implements rclient.TopologyWatcher,
+// the topology is pushed by Router and the subscription is renewed
+// every cfg.TopologyRefresh after a failure. Otherwise it's fetched every
+// cfg.TopologyRefresh, the cached topology is kept if fetching fails.
+func (fe *Frontend) refreshTopology() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-fe.stop
+		cancel()
+	}()
+
+	watcher, watched := fe.cfg.RC.(rclient.TopologyWatcher)
+	ticker := time.NewTicker(fe.cfg.TopologyRefresh)
+	defer ticker.Stop()
+	for {
+		if watched {
+			watcher.WatchTopology(ctx, fe.cfg.Router, func(t storage.Topology) error {
+				fe.setTopology(fe.newTopology(t))
+				return nil
+			})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if watched {
+			continue
+		}
+		fetchCtx, fetchCancel := context.WithTimeout(ctx, storage.Timeout)
+		t, err := fe.fetchTopology(fetchCtx)
+		fetchCancel()
+		if err == nil {
+			fe.setTopology(t)
+		}
+	}
+}
+
+// newTopology returns a snapshot of the topology t pushed by Router.
+func (fe *Frontend) newTopology(t storage.Topology) *topology {
+	alive := make(map[storage.ServiceAddr]bool, len(t.Alive))
+	for _, node := range t.Alive {
+		alive[node] = true
+	}
+	return &topology{
+		nodes:   t.Nodes,
+		rep:     t.Replication,
+		nf:      fe.cfg.NF.WithReplicationFactor(t.Replication.Factor).WithPlacement(t.Placement),
+		alive:   alive,
+		updated: time.Now(),
+	}
+}
+
+func (fe *Frontend) setTopology(t *topology) {
+	fe.topoLock.Lock()
+	defer fe.topoLock.Unlock()
+	fe.topo = t
+}
+
+// topology returns the cached topology, fetching it first if needed.
+func (fe *Frontend) topology() *topology {
+	fe.nodesOnce.Do(fe.initNodes)
+	fe.topoLock.RLock()
+	defer fe.topoLock.RUnlock()
+	return fe.topo
+}
+
+// freshTopology returns the cached topology to find replicas to write to with,
+// nil if it's not cached, its liveness is unknown or it's stale.
+func (fe *Frontend) freshTopology() *topology {
+	if fe.cfg.TopologyRefresh <= 0 {
+		return nil
+	}
+	staleness := fe.cfg.TopologyStaleness
+	if staleness <= 0 {
+		staleness = 3 * fe.cfg.TopologyRefresh
+	}
+	t := fe.topology()
+	if t.alive == nil || time.Since(t.updated) > staleness {
+		return nil
+	}
+	return t
+}
 
-	fe.nodes = nodes
-	return
+// replication returns the replication factor and quorum of the cluster,
+// storage.DefaultReplication if the router client doesn't report them.
+func (fe *Frontend) replication() storage.Replication {
+	if _, ok := fe.cfg.RC.(rclient.ReplicationClient); !ok {
+		return storage.DefaultReplication
+	}
+	return fe.topology().rep
 }
 
 type getResult struct {
@@ -186,7 +724,8 @@ type getResult struct {
 	err error
 }
 
-func checkResults(results <-chan getResult, endChan chan<- getResult, readLimit int) {
+// checkResults sends to endChan the first reply got from required of readLimit replicas.
+func checkResults(results <-chan getResult, endChan chan<- getResult, readLimit int, required int) {
 	resMap := make(map[string]int)
 	errMap := make(map[error]int)
 
@@ -196,7 +735,7 @@ func checkResults(results <-chan getResult, endChan chan<- getResult, readLimit
 			key := string(res.d)
 			resMap[key]++
 
-			if resMap[key] >= storage.MinRedundancy {
+			if resMap[key] >= required {
 				endChan <- res
 				return
 			}
@@ -204,7 +743,7 @@ func checkResults(results <-chan getResult, endChan chan<- getResult, readLimit
 			key := res.err
 			errMap[key]++
 
-			if errMap[key] >= storage.MinRedundancy {
+			if errMap[key] >= required {
 				endChan <- res
 				return
 			}
@@ -218,3 +757,727 @@ func checkResults(results <-chan getResult, endChan chan<- getResult, readLimit
 		err: storage.ErrQuorumNotReached,
 	}
 }
+
+// Scan returns records of the storage selected by opts in key order.
+// A record is returned if at least a quorum of nodes hold
+// the same data for it. If not all the selected records were returned,
+// a token resuming the scan is returned as well.
+//
+// Scan -- вернуть записи хранилища, выбранные opts, в порядке ключей.
+// Запись возвращается, если хотя бы кворум node хранят
+// для нее одинаковые данные. Если возвращены не все выбранные записи,
+// также возвращается токен для продолжения перечисления.
+func (fe *Frontend) Scan(opts storage.ScanOptions) ([]storage.Record, string, error) {
+	return fe.ScanContext(context.Background(), opts)
+}
+
+// ScanContext is Scan bound to ctx: requests to nodes are cancelled with ctx
+// and don't outlive its deadline.
+//
+// ScanContext -- Scan, привязанный к ctx: запросы к node отменяются вместе с ctx
+// и не превышают его deadline.
+func (fe *Frontend) ScanContext(ctx context.Context, opts storage.ScanOptions) ([]storage.Record, string, error) {
+	sc, ok := fe.cfg.NC.(storage.ScanClient)
+	if !ok {
+		return nil, "", storage.ErrScanNotSupported
+	}
+	if _, err := opts.From(); err != nil {
+		return nil, "", err
+	}
+
+	t := fe.topology()
+	if len(t.nodes) < t.rep.Quorum {
+		return nil, "", storage.ErrNotEnoughDaemons
+	}
+
+	results := make(chan scanResult, len(t.nodes))
+	for _, node := range t.nodes {
+		go func(node storage.ServiceAddr) {
+			var res scanResult
+			res.token, res.err = sc.ScanContext(ctx, node, opts, func(k storage.Key, d []byte) error {
+				res.records = append(res.records, storage.Record{Key: k, Data: d})
+				return nil
+			})
+			results <- res
+		}(node)
+	}
+
+	return mergeScans(results, len(t.nodes), t.rep.Quorum, opts.Limit)
+}
+
+type scanResult struct {
+	records []storage.Record
+	token   string
+	err     error
+}
+
+// mergeScans merges records scanned from readLimit nodes. A node stopped
+// at its token has not reported keys following it, so only keys preceding
+// the least of the tokens are merged.
+func mergeScans(results <-chan scanResult, readLimit int, quorum int, limit int) ([]storage.Record, string, error) {
+	var (
+		oks      int
+		errMap   = make(map[error]int)
+		bounded  bool
+		frontier storage.Key
+		replicas = make(map[storage.Key]map[string]int)
+	)
+	for i := 0; i < readLimit; i++ {
+		res := <-results
+		if res.err != nil {
+			errMap[res.err]++
+			continue
+		}
+		oks++
+		if res.token != "" {
+			next, err := storage.ScanOptions{Token: res.token}.From()
+			if err != nil {
+				errMap[err]++
+				continue
+			}
+			if !bounded || next < frontier {
+				bounded, frontier = true, next
+			}
+		}
+		for _, r := range res.records {
+			if replicas[r.Key] == nil {
+				replicas[r.Key] = make(map[string]int)
+			}
+			replicas[r.Key][string(r.Data)]++
+		}
+	}
+
+	if oks < quorum {
+		for err, n := range errMap {
+			if n >= quorum {
+				return nil, "", err
+			}
+		}
+		return nil, "", storage.ErrQuorumNotReached
+	}
+
+	var records []storage.Record
+	for k, data := range replicas {
+		if bounded && k >= frontier {
+			continue
+		}
+		for d, n := range data {
+			if n >= quorum {
+				records = append(records, storage.Record{Key: k, Data: []byte(d)})
+				break
+			}
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Key < records[j].Key })
+
+	if limit > 0 && len(records) > limit {
+		return records[:limit], storage.ScanToken(records[limit].Key), nil
+	}
+	if bounded {
+		return records, storage.ScanToken(frontier), nil
+	}
+	return records, "", nil
+}
+
+// GetVersions returns versions of an item which are not superseded by
+// each other, reconciled over the replicas. More than one version means
+// the item was updated concurrently.
+//
+// GetVersions -- вернуть версии записи, не замещенные друг другом,
+// согласованные между репликами. Несколько версий означают, что запись
+// обновлялась параллельно.
+func (fe *Frontend) GetVersions(k storage.Key) ([]storage.Version, error) {
+	return fe.GetVersionsContext(context.Background(), k)
+}
+
+// GetVersionsContext is GetVersions bound to ctx: requests to nodes
+// are cancelled with ctx and don't outlive its deadline.
+//
+// GetVersionsContext -- GetVersions, привязанный к ctx: запросы к node
+// отменяются вместе с ctx и не превышают его deadline.
+func (fe *Frontend) GetVersionsContext(ctx context.Context, k storage.Key) ([]storage.Version, error) {
+	vc, ok := fe.cfg.NC.(storage.VersionedClient)
+	if !ok {
+		return nil, storage.ErrVersionsNotSupported
+	}
+	t := fe.topology()
+	r, err := storage.ConsistencyFromContext(ctx).Reads(t.rep)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := t.nf.NodesFindKey(k, t.nodes)
+	if len(nodes) < r {
+		return nil, storage.ErrNotEnoughDaemons
+	}
+
+	type versionsResult struct {
+		versions []storage.Version
+		err      error
+	}
+	results := make(chan versionsResult, len(nodes))
+	for _, node := range nodes {
+		go func(node storage.ServiceAddr) {
+			versions, err := vc.GetVersionsContext(ctx, node, k)
+			results <- versionsResult{versions, err}
+		}(node)
+	}
+
+	var (
+		sets   [][]storage.Version
+		oks    int
+		errMap = make(map[error]int)
+	)
+	for range nodes {
+		res := <-results
+		switch res.err {
+		case nil:
+			sets = append(sets, res.versions)
+			fallthrough
+		case storage.ErrRecordNotFound:
+			oks++
+		default:
+			errMap[res.err]++
+		}
+	}
+	if oks < r {
+		for err, n := range errMap {
+			if n >= r {
+				return nil, err
+			}
+		}
+		return nil, storage.ErrQuorumNotReached
+	}
+	versions := storage.Reconcile(sets...)
+	if len(versions) == 0 {
+		return nil, storage.ErrRecordNotFound
+	}
+	return versions, nil
+}
+
+// Update stores v.Data as a new version of an item. v.Clock is a clock
+// the update is based on: one of the versions returned by GetVersions or
+// their storage.MergedClock to supersede all of them. An empty clock creates
+// the item or adds a version concurrent with the existing ones.
+// The version expires at v.Expires, or at the time set by storage.WithTTL
+// for UpdateContext if v.Expires is zero. Two updates of the same clock
+// through one frontend get the same clock, so the one with other data
+// than the stored one fails with storage.ErrConflict instead of being lost.
+//
+// Update -- сохранить v.Data как новую версию записи. v.Clock -- часы,
+// на которых основано обновление: одной из версий, возвращенных GetVersions,
+// или их storage.MergedClock, чтобы заместить их все. Пустые часы создают
+// запись или добавляют версию, параллельную существующим.
+// Версия истекает в v.Expires или, для UpdateContext, во время,
+// заданное storage.WithTTL, если v.Expires равно нулю. Два обновления одних
+// и тех же часов через один frontend получают одинаковые часы, поэтому
+// обновление с данными, отличными от сохраненных, завершается ошибкой
+// storage.ErrConflict, а не теряется.
+func (fe *Frontend) Update(k storage.Key, v storage.Version) error {
+	return fe.UpdateContext(context.Background(), k, v)
+}
+
+// UpdateContext is Update bound to ctx: requests to Router and nodes
+// are cancelled with ctx and don't outlive its deadline.
+//
+// UpdateContext -- Update, привязанный к ctx: запросы к Router и node
+// отменяются вместе с ctx и не превышают его deadline.
+func (fe *Frontend) UpdateContext(ctx context.Context, k storage.Key, v storage.Version) error {
+	vc, ok := fe.cfg.NC.(storage.VersionedClient)
+	if !ok {
+		return storage.ErrVersionsNotSupported
+	}
+	w, err := storage.ConsistencyFromContext(ctx).Writes(fe.replication())
+	if err != nil {
+		return err
+	}
+	nodes, err := fe.nodesFind(ctx, k)
+	if err != nil {
+		return err
+	}
+	if len(nodes) < w {
+		return storage.ErrNotEnoughDaemons
+	}
+
+	v = storage.Version{Clock: v.Clock.Increment(fe.id()), Data: v.Data, Expires: expiry(ctx, v)}
+
+	results := make(chan error, len(nodes))
+	for _, node := range nodes {
+		go func(node storage.ServiceAddr) {
+			results <- vc.UpdateContext(ctx, node, k, v)
+		}(node)
+	}
+
+	err = checkErrors(results, len(nodes), w)
+	close(results)
+	return err
+}
+
+// expiry returns the expiry time of a version written with ctx: v.Expires
+// if it's set, the one carried by ctx otherwise.
+func expiry(ctx context.Context, v storage.Version) int64 {
+	if v.Expires != 0 {
+		return v.Expires
+	}
+	return storage.ExpiryFromContext(ctx)
+}
+
+// id returns the writer id of the frontend in vector clocks.
+func (fe *Frontend) id() string {
+	if fe.cfg.ID != "" {
+		return fe.cfg.ID
+	}
+	return string(fe.cfg.Addr)
+}
+
+// PutIfVersion stores v.Data as the only version of an item if the item
+// satisfies cond on the replicas. cond is checked on the versions read
+// as by GetVersions first, so a write is not made on replicas lagging
+// behind the others if the others would reject it. Then cond is checked
+// by each replica, the write succeeds if the required number of replicas
+// accepted it. The new version's clock descends cond.Clock and v.Clock.
+// Returns the storage.ErrConditionFailed error if cond doesn't hold or
+// the replicas rejected the write. Concurrent conditional writes may both
+// pass the first check, replicas which accepted the failed one are
+// reconciled by read repair and anti-entropy.
+//
+// PutIfVersion -- сохранить v.Data как единственную версию записи, если
+// запись на репликах удовлетворяет cond. Сначала cond проверяется на версиях,
+// прочитанных как в GetVersions, поэтому запись не делается на отставших
+// репликах, если остальные ее отвергли бы. Затем cond проверяется каждой
+// репликой, запись успешна, если ее приняло требуемое число реплик. Часы
+// новой версии наследуют cond.Clock и v.Clock. Возвращает ошибку
+// storage.ErrConditionFailed, если cond не выполняется или реплики отвергли
+// запись. Параллельные условные записи могут обе пройти первую проверку,
+// реплики, принявшие неуспешную, согласуются read repair и anti-entropy.
+func (fe *Frontend) PutIfVersion(k storage.Key, cond storage.Condition, v storage.Version) error {
+	return fe.PutIfVersionContext(context.Background(), k, cond, v)
+}
+
+// PutIfVersionContext is PutIfVersion bound to ctx: requests to Router
+// and nodes are cancelled with ctx and don't outlive its deadline.
+//
+// PutIfVersionContext -- PutIfVersion, привязанный к ctx: запросы к Router
+// и node отменяются вместе с ctx и не превышают его deadline.
+func (fe *Frontend) PutIfVersionContext(ctx context.Context, k storage.Key, cond storage.Condition, v storage.Version) error {
+	if err := fe.checkCondition(ctx, k, cond); err != nil {
+		return err
+	}
+	v = storage.Version{Clock: cond.Clock.Merge(v.Clock).Increment(fe.id()), Data: v.Data, Expires: expiry(ctx, v)}
+	return fe.writeConditional(ctx, k, func(cc storage.ConditionalClient, node storage.ServiceAddr) error {
+		return cc.PutIfVersionContext(ctx, node, k, cond, v)
+	})
+}
+
+// CompareAndSwap replaces an item having the single version with clock
+// expected by d. See PutIfVersion.
+//
+// CompareAndSwap -- заменить запись, имеющую единственную версию с часами
+// expected, на d. См. PutIfVersion.
+func (fe *Frontend) CompareAndSwap(k storage.Key, expected storage.VectorClock, d []byte) error {
+	return fe.CompareAndSwapContext(context.Background(), k, expected, d)
+}
+
+// CompareAndSwapContext is CompareAndSwap bound to ctx: requests to Router and nodes
+// are cancelled with ctx and don't outlive its deadline.
+//
+// CompareAndSwapContext -- CompareAndSwap, привязанный к ctx: запросы к Router и node
+// отменяются вместе с ctx и не превышают его deadline.
+func (fe *Frontend) CompareAndSwapContext(ctx context.Context, k storage.Key, expected storage.VectorClock, d []byte) error {
+	return fe.PutIfVersionContext(ctx, k, storage.Condition{Clock: expected}, storage.Version{Data: d})
+}
+
+// DeleteIfVersion deletes an item if it satisfies cond on the replicas.
+// Returns the storage.ErrConditionFailed error if the replicas rejected
+// the deletion. See PutIfVersion.
+//
+// DeleteIfVersion -- удалить запись, если она удовлетворяет cond на репликах.
+// Возвращает ошибку storage.ErrConditionFailed, если реплики отвергли
+// удаление. См. PutIfVersion.
+func (fe *Frontend) DeleteIfVersion(k storage.Key, cond storage.Condition) error {
+	return fe.DeleteIfVersionContext(context.Background(), k, cond)
+}
+
+// DeleteIfVersionContext is DeleteIfVersion bound to ctx: requests to Router and nodes
+// are cancelled with ctx and don't outlive its deadline.
+//
+// DeleteIfVersionContext -- DeleteIfVersion, привязанный к ctx: запросы к Router и node
+// отменяются вместе с ctx и не превышают его deadline.
+func (fe *Frontend) DeleteIfVersionContext(ctx context.Context, k storage.Key, cond storage.Condition) error {
+	if err := fe.checkCondition(ctx, k, cond); err != nil {
+		return err
+	}
+	return fe.writeConditional(ctx, k, func(cc storage.ConditionalClient, node storage.ServiceAddr) error {
+		return cc.DeleteIfVersionContext(ctx, node, k, cond)
+	})
+}
+
+// Upsert stores v.Data as the only version of an item whether it exists
+// or not. The versions of the item are read first, and the new version's
+// clock descends all of them and v.Clock.
+//
+// Upsert -- сохранить v.Data как единственную версию записи независимо от того,
+// существует ли она. Сначала читаются версии записи, и часы новой версии
+// наследуют их все и v.Clock.
+func (fe *Frontend) Upsert(k storage.Key, v storage.Version) error {
+	return fe.UpsertContext(context.Background(), k, v)
+}
+
+// UpsertContext is Upsert bound to ctx: requests to Router and nodes
+// are cancelled with ctx and don't outlive its deadline.
+//
+// UpsertContext -- Upsert, привязанный к ctx: запросы к Router и node
+// отменяются вместе с ctx и не превышают его deadline.
+func (fe *Frontend) UpsertContext(ctx context.Context, k storage.Key, v storage.Version) error {
+	if _, ok := fe.cfg.NC.(storage.ConditionalClient); !ok {
+		return storage.ErrConditionalNotSupported
+	}
+	versions, err := fe.GetVersionsContext(ctx, k)
+	if err != nil && err != storage.ErrRecordNotFound {
+		return err
+	}
+	v = storage.Version{
+		Clock:   storage.MergedClock(versions).Merge(v.Clock).Increment(fe.id()),
+		Data:    v.Data,
+		Expires: expiry(ctx, v),
+	}
+	return fe.writeConditional(ctx, k, func(cc storage.ConditionalClient, node storage.ServiceAddr) error {
+		return cc.UpsertContext(ctx, node, k, v)
+	})
+}
+
+// checkCondition checks cond on the versions of k read from the replicas.
+// Returns storage.ErrConditionFailed if it doesn't hold.
+func (fe *Frontend) checkCondition(ctx context.Context, k storage.Key, cond storage.Condition) error {
+	if _, ok := fe.cfg.NC.(storage.ConditionalClient); !ok {
+		return storage.ErrConditionalNotSupported
+	}
+	versions, err := fe.GetVersionsContext(ctx, k)
+	if err != nil && err != storage.ErrRecordNotFound {
+		return err
+	}
+	if !cond.Holds(versions) {
+		return storage.ErrConditionFailed
+	}
+	return nil
+}
+
+// writeConditional calls write for each replica of k and waits for
+// the required number of them to succeed.
+func (fe *Frontend) writeConditional(ctx context.Context, k storage.Key, write func(cc storage.ConditionalClient, node storage.ServiceAddr) error) error {
+	cc, ok := fe.cfg.NC.(storage.ConditionalClient)
+	if !ok {
+		return storage.ErrConditionalNotSupported
+	}
+	w, err := storage.ConsistencyFromContext(ctx).Writes(fe.replication())
+	if err != nil {
+		return err
+	}
+	nodes, err := fe.nodesFind(ctx, k)
+	if err != nil {
+		return err
+	}
+	if len(nodes) < w {
+		return storage.ErrNotEnoughDaemons
+	}
+
+	results := make(chan error, len(nodes))
+	for _, node := range nodes {
+		go func(node storage.ServiceAddr) {
+			results <- write(cc, node)
+		}(node)
+	}
+
+	err = checkErrors(results, len(nodes), w)
+	close(results)
+	return err
+}
+
+// MultiGet gets items with the given keys. Keys are grouped by their replicas
+// found as by Get, and each node gets a single request for all the keys it
+// holds. The result of each key is evaluated as by Get, an empty key gets
+// storage.ErrInvalidKey. An error is returned only if the batch can't be
+// sent at all.
+//
+// MultiGet -- получить записи с данными ключами. Ключи группируются по репликам,
+// найденным так же, как в Get, и каждая node получает один запрос для всех
+// своих ключей. Результат каждого ключа вычисляется как в Get, пустой ключ
+// получает storage.ErrInvalidKey. Ошибка возвращается, только если пакет
+// не удалось отправить.
+func (fe *Frontend) MultiGet(keys []storage.Key) ([]storage.Result, error) {
+	return fe.MultiGetContext(context.Background(), keys)
+}
+
+// MultiGetContext is MultiGet bound to ctx: requests to nodes are cancelled
+// with ctx and don't outlive its deadline.
+//
+// MultiGetContext -- MultiGet, привязанный к ctx: запросы к node отменяются
+// вместе с ctx и не превышают его deadline.
+func (fe *Frontend) MultiGetContext(ctx context.Context, keys []storage.Key) ([]storage.Result, error) {
+	t := fe.topology()
+	r, err := storage.ConsistencyFromContext(ctx).Reads(t.rep)
+	if err != nil {
+		return nil, err
+	}
+
+	place := func(k storage.Key) ([]storage.Replica, error) {
+		nodes := t.nf.NodesFindKey(k, t.nodes)
+		replicas := make([]storage.Replica, 0, len(nodes))
+		for _, node := range nodes {
+			replicas = append(replicas, storage.Replica{Node: node})
+		}
+		return replicas, nil
+	}
+	bc, batched := fe.cfg.NC.(storage.BatchClient)
+	replies, errs := fe.sendBatch(keys, place, func(rep storage.Replica, idx []int) ([]storage.Result, error) {
+		nodeKeys := make([]storage.Key, len(idx))
+		for j, i := range idx {
+			nodeKeys[j] = keys[i]
+		}
+		if batched {
+			return bc.MultiGetContext(ctx, rep.Node, nodeKeys)
+		}
+		results := make([]storage.Result, len(nodeKeys))
+		for j, k := range nodeKeys {
+			results[j].Data, results[j].Err = fe.nc.GetKeyContext(ctx, rep.Node, k)
+		}
+		return results, nil
+	})
+
+	res := make([]storage.Result, len(keys))
+	for i, rs := range replies {
+		if errs[i] != nil {
+			res[i].Err = errs[i]
+			continue
+		}
+		if len(rs) < r {
+			res[i].Err = storage.ErrNotEnoughDaemons
+			continue
+		}
+		results := make(chan getResult, len(rs))
+		for _, reply := range rs {
+			results <- getResult{d: reply.Data, err: reply.Err}
+		}
+		endChan := make(chan getResult, 1)
+		checkResults(results, endChan, len(rs), r)
+		end := <-endChan
+		res[i] = storage.Result{Data: end.d, Err: end.err}
+	}
+	return res, nil
+}
+
+// MultiPut puts the given records as MultiGet gets them. Replicas of all
+// records are found locally with the cached topology, which is fetched once
+// for the batch if it's stale. Unavailable nodes are skipped as by Put, and
+// with cfg.HintedHandoff set writes to them are handed off to others one
+// record at a time. If liveness of nodes is unknown, Router finds replicas
+// of each record as in Put. The result of each record is evaluated as by Put,
+// a record with an empty key gets storage.ErrInvalidKey.
+//
+// MultiPut -- добавить данные записи так же, как MultiGet их получает. Реплики
+// всех записей находятся локально по кешированной топологии, которая
+// запрашивается один раз на пакет, если устарела. Недоступные node
+// пропускаются, как в Put, а если задан cfg.HintedHandoff, записи на них
+// передаются другим по одной. Если доступность node неизвестна, реплики
+// каждой записи находит Router, как в Put. Результат каждой записи вычисляется как в Put,
+// запись с пустым ключом получает storage.ErrInvalidKey.
+func (fe *Frontend) MultiPut(records []storage.Record) ([]storage.Result, error) {
+	return fe.MultiPutContext(context.Background(), records)
+}
+
+// MultiPutContext is MultiPut bound to ctx: requests to Router and nodes
+// are cancelled with ctx and don't outlive its deadline.
+//
+// MultiPutContext -- MultiPut, привязанный к ctx: запросы к Router и node
+// отменяются вместе с ctx и не превышают его deadline.
+func (fe *Frontend) MultiPutContext(ctx context.Context, records []storage.Record) ([]storage.Result, error) {
+	keys := make([]storage.Key, len(records))
+	for i, r := range records {
+		keys[i] = r.Key
+	}
+	bc, batched := fe.cfg.NC.(storage.BatchClient)
+	return fe.writeBatch(ctx, keys, func(rep storage.Replica, idx []int) ([]storage.Result, error) {
+		nodeRecords := make([]storage.Record, len(idx))
+		for j, i := range idx {
+			nodeRecords[j] = records[i]
+		}
+		if batched && rep.Hint == "" {
+			return bc.MultiPutContext(ctx, rep.Node, nodeRecords)
+		}
+		results := make([]storage.Result, len(nodeRecords))
+		for j, r := range nodeRecords {
+			if rep.Hint != "" {
+				results[j].Err = fe.cfg.NC.(storage.HintClient).PutHintContext(ctx, rep.Node, rep.Hint, r.Key, r.Data)
+				continue
+			}
+			results[j].Err = fe.nc.PutKeyContext(ctx, rep.Node, r.Key, r.Data)
+		}
+		return results, nil
+	})
+}
+
+// MultiDel deletes items with the given keys as MultiPut puts them.
+// The result of each key is evaluated as by Del, an empty key gets
+// storage.ErrInvalidKey.
+//
+// MultiDel -- удалить записи с данными ключами так же, как MultiPut их добавляет.
+// Результат каждого ключа вычисляется как в Del, пустой ключ получает
+// storage.ErrInvalidKey.
+func (fe *Frontend) MultiDel(keys []storage.Key) ([]storage.Result, error) {
+	return fe.MultiDelContext(context.Background(), keys)
+}
+
+// MultiDelContext is MultiDel bound to ctx: requests to Router and nodes
+// are cancelled with ctx and don't outlive its deadline.
+//
+// MultiDelContext -- MultiDel, привязанный к ctx: запросы к Router и node
+// отменяются вместе с ctx и не превышают его deadline.
+func (fe *Frontend) MultiDelContext(ctx context.Context, keys []storage.Key) ([]storage.Result, error) {
+	bc, batched := fe.cfg.NC.(storage.BatchClient)
+	return fe.writeBatch(ctx, keys, func(rep storage.Replica, idx []int) ([]storage.Result, error) {
+		nodeKeys := make([]storage.Key, len(idx))
+		for j, i := range idx {
+			nodeKeys[j] = keys[i]
+		}
+		if batched && rep.Hint == "" {
+			return bc.MultiDelContext(ctx, rep.Node, nodeKeys)
+		}
+		results := make([]storage.Result, len(nodeKeys))
+		for j, k := range nodeKeys {
+			if rep.Hint != "" {
+				results[j].Err = fe.cfg.NC.(storage.HintClient).DelHintContext(ctx, rep.Node, rep.Hint, k)
+				continue
+			}
+			results[j].Err = fe.nc.DelKeyContext(ctx, rep.Node, k)
+		}
+		return results, nil
+	})
+}
+
+// writeBatch sends a batch write with send to the replicas of keys placed
+// by writePlacement and evaluates the result of each key by the number
+// of replicas which acknowledged it.
+func (fe *Frontend) writeBatch(ctx context.Context, keys []storage.Key, send func(rep storage.Replica, idx []int) ([]storage.Result, error)) ([]storage.Result, error) {
+	t := fe.writeTopology(ctx)
+	rep := fe.replication()
+	if t != nil {
+		rep = t.rep
+	}
+	w, err := storage.ConsistencyFromContext(ctx).Writes(rep)
+	if err != nil {
+		return nil, err
+	}
+
+	replies, errs := fe.sendBatch(keys, fe.writePlacement(ctx, t), send)
+	res := make([]storage.Result, len(keys))
+	for i, rs := range replies {
+		if errs[i] != nil {
+			res[i].Err = errs[i]
+			continue
+		}
+		if len(rs) < w {
+			res[i].Err = storage.ErrNotEnoughDaemons
+			continue
+		}
+		results := make(chan error, len(rs))
+		for _, reply := range rs {
+			results <- reply.Err
+		}
+		res[i].Err = checkErrors(results, len(rs), w)
+	}
+	return res, nil
+}
+
+// writeTopology returns the topology with liveness of nodes to place keys
+// of a batch write with. A stale cached topology is fetched from Router
+// once for the whole batch. Returns nil if liveness of nodes is unknown.
+func (fe *Frontend) writeTopology(ctx context.Context) *topology {
+	if t := fe.freshTopology(); t != nil {
+		return t
+	}
+	if fe.cfg.TopologyRefresh > 0 {
+		if t, err := fe.fetchTopology(ctx); err == nil && t.alive != nil {
+			fe.setTopology(t)
+			return t
+		}
+	}
+	return nil
+}
+
+// writePlacement returns a function placing keys of a batch write on
+// the nodes of t as fe.replicas does with the cached topology. If t is nil,
+// Router finds replicas of every key of the batch.
+func (fe *Frontend) writePlacement(ctx context.Context, t *topology) func(k storage.Key) ([]storage.Replica, error) {
+	if t == nil {
+		return func(k storage.Key) ([]storage.Replica, error) {
+			return fe.replicas(ctx, k)
+		}
+	}
+	_, hc := fe.cfg.RC.(rclient.HintedClient)
+	_, hinted := fe.cfg.NC.(storage.HintClient)
+	return func(k storage.Key) ([]storage.Replica, error) {
+		if fe.cfg.HintedHandoff && hc && hinted {
+			return t.nf.NodesFindHinted(k, t.nodes, t.isAlive, t.rep.Quorum)
+		}
+		nodes, err := t.nf.NodesFindAlive(k, t.nodes, t.isAlive, t.rep.Quorum)
+		if err != nil {
+			return nil, err
+		}
+		replicas := make([]storage.Replica, 0, len(nodes))
+		for _, node := range nodes {
+			replicas = append(replicas, storage.Replica{Node: node})
+		}
+		return replicas, nil
+	}
+}
+
+// sendBatch groups keys by their replicas found with place and calls send
+// once for each replica with indices of the keys it holds. Returns
+// the replies of all replicas of each key, a failed send counts as a failed
+// reply for all its keys. Keys which are empty or can't be placed get
+// no replies, their errors are returned instead.
+func (fe *Frontend) sendBatch(keys []storage.Key, place func(k storage.Key) ([]storage.Replica, error), send func(rep storage.Replica, idx []int) ([]storage.Result, error)) ([][]storage.Result, []error) {
+	errs := make([]error, len(keys))
+	byReplica := make(map[storage.Replica][]int)
+	for i, k := range keys {
+		if k == "" {
+			errs[i] = storage.ErrInvalidKey
+			continue
+		}
+		replicas, err := place(k)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		for _, rep := range replicas {
+			byReplica[rep] = append(byReplica[rep], i)
+		}
+	}
+
+	var (
+		lock    sync.Mutex
+		wg      sync.WaitGroup
+		replies = make([][]storage.Result, len(keys))
+	)
+	for rep, idx := range byReplica {
+		wg.Add(1)
+		go func(rep storage.Replica, idx []int) {
+			defer wg.Done()
+			results, err := send(rep, idx)
+			if err == nil && len(results) != len(idx) {
+				err = storage.ErrUnknownStatus
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			for j, i := range idx {
+				if err != nil {
+					replies[i] = append(replies[i], storage.Result{Err: err})
+				} else {
+					replies[i] = append(replies[i], results[j])
+				}
+			}
+		}(rep, idx)
+	}
+	wg.Wait()
+	return replies, errs
+}
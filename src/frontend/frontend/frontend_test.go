@@ -1,10 +1,13 @@
 package frontend
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
 	"runtime"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -49,6 +52,60 @@ func (n *MockNode) Del(node storage.ServiceAddr, k storage.RecordID) error {
 	return n.del(node, k)
 }
 
+type MockContextNode struct {
+	MockNode
+	getContext func(ctx context.Context, node storage.ServiceAddr, k storage.RecordID) ([]byte, error)
+	putContext func(ctx context.Context, node storage.ServiceAddr, k storage.RecordID, d []byte) error
+}
+
+func (n *MockContextNode) PutContext(ctx context.Context, node storage.ServiceAddr, k storage.RecordID, d []byte) error {
+	return n.putContext(ctx, node, k, d)
+}
+
+func (n *MockContextNode) GetContext(ctx context.Context, node storage.ServiceAddr, k storage.RecordID) ([]byte, error) {
+	return n.getContext(ctx, node, k)
+}
+
+func (n *MockContextNode) DelContext(ctx context.Context, node storage.ServiceAddr, k storage.RecordID) error {
+	return n.del(node, k)
+}
+
+type MockScanNode struct {
+	MockNode
+	records map[storage.ServiceAddr]map[storage.Key]string
+	failed  map[storage.ServiceAddr]bool
+}
+
+func (n *MockScanNode) Scan(node storage.ServiceAddr, opts storage.ScanOptions, f func(k storage.Key, d []byte) error) (string, error) {
+	return n.ScanContext(context.Background(), node, opts, f)
+}
+
+func (n *MockScanNode) ScanContext(ctx context.Context, node storage.ServiceAddr, opts storage.ScanOptions, f func(k storage.Key, d []byte) error) (string, error) {
+	if n.failed[node] {
+		return "", errors.New("node failed")
+	}
+	from, err := opts.From()
+	if err != nil {
+		return "", err
+	}
+	var keys []storage.Key
+	for k := range n.records[node] {
+		if k >= from && opts.Contains(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for i, k := range keys {
+		if opts.Limit > 0 && i == opts.Limit {
+			return storage.ScanToken(k), nil
+		}
+		if err := f(k, []byte(n.records[node][k])); err != nil {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
 func nodesFind(t *testing.T, cfg Config, key storage.RecordID, nodes []storage.ServiceAddr, err error) func(router storage.ServiceAddr, k storage.RecordID) ([]storage.ServiceAddr, error) {
 	return func(router storage.ServiceAddr, k storage.RecordID) ([]storage.ServiceAddr, error) {
 		if router != cfg.Router {
@@ -210,6 +267,57 @@ func TestPutDel_Redundancy(t *testing.T) {
 	}
 }
 
+func TestPutDel_Consistency(t *testing.T) {
+	key := storage.RecordID(1)
+	testData := []byte("testtesttest")
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3"}
+	errDummy := fmt.Errorf("dummy error")
+
+	for _, test := range []struct {
+		consistency storage.Consistency
+		failed      int
+		err         error
+	}{
+		{consistency: storage.Consistency{Level: storage.LevelOne}, failed: 2},
+		{consistency: storage.Consistency{Level: storage.LevelQuorum}, failed: 2, err: errDummy},
+		{consistency: storage.Consistency{Level: storage.LevelAll}, failed: 0},
+		{consistency: storage.Consistency{Level: storage.LevelAll}, failed: 1, err: storage.ErrQuorumNotReached},
+		{consistency: storage.Consistency{Level: storage.LevelOne, W: 3}, failed: 1, err: storage.ErrQuorumNotReached},
+		{consistency: storage.Consistency{Level: storage.LevelAll, W: 1}, failed: 2},
+		{consistency: storage.Consistency{W: 4}, err: storage.ErrInvalidConsistency},
+		{consistency: storage.Consistency{Level: 42}, err: storage.ErrInvalidConsistency},
+	} {
+		t.Run(fmt.Sprintf("%v,w=%d,failed=%d", test.consistency.Level, test.consistency.W, test.failed), func(t *testing.T) {
+			rc.nodesFind = nodesFind(t, cfg, key, nodes, nil)
+			failed := func(node storage.ServiceAddr) error {
+				for _, n := range nodes[:test.failed] {
+					if n == node {
+						return errDummy
+					}
+				}
+				return nil
+			}
+			nc.put = put(t, nodes, key, testData, failed)
+			nc.del = del(t, nodes, key, failed)
+
+			fe := New(cfg)
+			ctx := storage.WithConsistency(context.Background(), test.consistency)
+			if err := fe.PutContext(ctx, key, testData); err != test.err {
+				t.Errorf("PutContext() got error %v, want %v", err, test.err)
+			}
+			if err := fe.DelContext(ctx, key); err != test.err {
+				t.Errorf("DelContext() got error %v, want %v", err, test.err)
+			}
+		})
+	}
+
+	rc.nodesFind = nodesFind(t, cfg, key, nodes[:2], nil)
+	ctx := storage.WithConsistency(context.Background(), storage.Consistency{Level: storage.LevelAll})
+	if err := New(cfg).PutContext(ctx, key, testData); err != storage.ErrNotEnoughDaemons {
+		t.Errorf("PutContext() got error %v, want %v", err, storage.ErrNotEnoughDaemons)
+	}
+}
+
 func eqTime(a, b time.Duration) bool {
 	const eps = 50 * time.Millisecond
 	diff := a - b
@@ -384,6 +492,55 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestGet_Consistency(t *testing.T) {
+	key := storage.RecordID(1)
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3"}
+	rc.list = func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+		return nodes, nil
+	}
+	data := map[storage.ServiceAddr][]byte{
+		nodes[0]: []byte("a"), nodes[1]: []byte("a"), nodes[2]: []byte("b"),
+	}
+
+	for _, test := range []struct {
+		consistency storage.Consistency
+		want        []byte
+		err         error
+	}{
+		{consistency: storage.Consistency{}, want: []byte("a")},
+		{consistency: storage.Consistency{Level: storage.LevelAll}, err: storage.ErrQuorumNotReached},
+		{consistency: storage.Consistency{R: 3}, err: storage.ErrQuorumNotReached},
+		{consistency: storage.Consistency{R: 4}, err: storage.ErrInvalidConsistency},
+	} {
+		t.Run(fmt.Sprintf("%v,r=%d", test.consistency.Level, test.consistency.R), func(t *testing.T) {
+			nc := new(MockNode)
+			nc.get = get(t, nodes, key, func(node storage.ServiceAddr) ([]byte, error) {
+				return data[node], nil
+			})
+			fe := New(Config{RC: &rc, NC: nc, NF: router.NewNodesFinder(router.NewMD5Hasher()), Router: "router"})
+			ctx := storage.WithConsistency(context.Background(), test.consistency)
+			got, err := fe.GetContext(ctx, key)
+			if err != test.err || !reflect.DeepEqual(got, test.want) {
+				t.Errorf("GetContext() got %q, %v, want %q, %v", got, err, test.want, test.err)
+			}
+		})
+	}
+
+	// A read of a single replica returns whichever replica replies first.
+	nc := new(MockNode)
+	nc.get = get(t, nodes, key, func(node storage.ServiceAddr) ([]byte, error) {
+		if node != nodes[2] {
+			time.Sleep(100 * time.Millisecond)
+		}
+		return data[node], nil
+	})
+	fe := New(Config{RC: &rc, NC: nc, NF: router.NewNodesFinder(router.NewMD5Hasher()), Router: "router"})
+	ctx := storage.WithConsistency(context.Background(), storage.Consistency{Level: storage.LevelOne})
+	if got, err := fe.GetContext(ctx, key); err != nil || string(got) != "b" {
+		t.Errorf("GetContext() got %q, %v, want %q", got, err, "b")
+	}
+}
+
 func TestGet_InitOnce(t *testing.T) {
 	key := storage.RecordID(1)
 	testData := []byte("test")
@@ -485,6 +642,1506 @@ func TestGet_Timing(t *testing.T) {
 	}
 }
 
+func TestGetContext_CancelSlow(t *testing.T) {
+	key := storage.RecordID(1)
+	testData := []byte("test")
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3"}
+
+	rc.list = func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+		return nodes, nil
+	}
+
+	cancelled := make(chan error, 1)
+	nc := new(MockContextNode)
+	nc.getContext = func(ctx context.Context, node storage.ServiceAddr, k storage.RecordID) ([]byte, error) {
+		if node != nodes[2] {
+			return testData, nil
+		}
+		select {
+		case <-ctx.Done():
+			cancelled <- ctx.Err()
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+			cancelled <- nil
+			return testData, nil
+		}
+	}
+
+	fe := New(Config{
+		RC: &rc,
+		NC: nc,
+		NF: router.NewNodesFinder(FakeHasher{
+			t: t,
+			hashes: map[storage.ServiceAddr]uint64{
+				nodes[0]: 1,
+				nodes[1]: 2,
+				nodes[2]: 3,
+			},
+		}),
+		Router: "router",
+	})
+
+	got, err := fe.GetContext(context.Background(), key)
+	if err != nil {
+		t.Fatalf("GetContext() error: %v", err)
+	}
+	if !reflect.DeepEqual(got, testData) {
+		t.Errorf("Wrong data: got %s, want %s", got, testData)
+	}
+	if err := <-cancelled; err != context.Canceled {
+		t.Errorf("Slow replica request got error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestGet_ReadRepair(t *testing.T) {
+	key := storage.RecordID(1)
+	testData := []byte("test")
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3"}
+
+	rc.list = func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+		return nodes, nil
+	}
+	nf := router.NewNodesFinder(FakeHasher{
+		t: t,
+		hashes: map[storage.ServiceAddr]uint64{
+			nodes[0]: 1,
+			nodes[1]: 2,
+			nodes[2]: 3,
+		},
+	})
+
+	for _, tc := range []struct {
+		name     string
+		replicas map[storage.ServiceAddr][]byte
+		want     []byte
+		wantErr  error
+		repaired storage.ServiceAddr
+	}{
+		{
+			name:     "missing",
+			replicas: map[storage.ServiceAddr][]byte{nodes[0]: testData, nodes[1]: testData},
+			want:     testData,
+			repaired: nodes[2],
+		},
+		{
+			name:     "diverged",
+			replicas: map[storage.ServiceAddr][]byte{nodes[0]: testData, nodes[1]: []byte("other"), nodes[2]: testData},
+			want:     testData,
+			repaired: nodes[1],
+		},
+		{
+			name:     "deleted",
+			replicas: map[storage.ServiceAddr][]byte{nodes[0]: testData},
+			wantErr:  storage.ErrRecordNotFound,
+			repaired: nodes[0],
+		},
+		{
+			name:     "consistent",
+			replicas: map[storage.ServiceAddr][]byte{nodes[0]: testData, nodes[1]: testData, nodes[2]: testData},
+			want:     testData,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var lock sync.Mutex
+			replicas := make(map[storage.ServiceAddr][]byte)
+			for node, d := range tc.replicas {
+				replicas[node] = d
+			}
+			nc := new(MockNode)
+			nc.get = func(node storage.ServiceAddr, k storage.RecordID) ([]byte, error) {
+				lock.Lock()
+				defer lock.Unlock()
+				d, ok := replicas[node]
+				if !ok {
+					return nil, storage.ErrRecordNotFound
+				}
+				return d, nil
+			}
+			nc.put = func(node storage.ServiceAddr, k storage.RecordID, d []byte) error {
+				lock.Lock()
+				defer lock.Unlock()
+				if _, ok := replicas[node]; ok {
+					return storage.ErrRecordExists
+				}
+				replicas[node] = d
+				return nil
+			}
+			nc.del = func(node storage.ServiceAddr, k storage.RecordID) error {
+				lock.Lock()
+				defer lock.Unlock()
+				if _, ok := replicas[node]; !ok {
+					return storage.ErrRecordNotFound
+				}
+				delete(replicas, node)
+				return nil
+			}
+
+			fe := New(Config{
+				RC:         &rc,
+				NC:         nc,
+				NF:         nf,
+				Router:     "router",
+				ReadRepair: true,
+			})
+			got, err := fe.Get(key)
+			if err != tc.wantErr || !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("Get() got %q, %v, want %q, %v", got, err, tc.want, tc.wantErr)
+			}
+
+			wantRepaired := 0
+			if tc.repaired != "" {
+				wantRepaired = 1
+			}
+			deadline := time.Now().Add(time.Second)
+			for fe.ReadRepairStats().Repaired < wantRepaired && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			time.Sleep(50 * time.Millisecond)
+			if stats := fe.ReadRepairStats(); stats.Repaired != wantRepaired || stats.Divergent != wantRepaired || stats.Failed != 0 {
+				t.Errorf("Wrong stats: got %+v, want %d repaired", stats, wantRepaired)
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			for _, node := range nodes {
+				d, ok := replicas[node]
+				if tc.want == nil && ok {
+					t.Errorf("Replica %q still holds %q", node, d)
+				}
+				if tc.want != nil && !reflect.DeepEqual(d, tc.want) {
+					t.Errorf("Replica %q holds %q, want %q", node, d, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGet_ReadRepairVersions(t *testing.T) {
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3"}
+	rc := MockRouter{
+		list: func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+			return nodes, nil
+		},
+	}
+	v := storage.Version{
+		Clock:   storage.VectorClock{"fe1": 1},
+		Data:    []byte("session"),
+		Expires: time.Now().Add(time.Minute).UnixNano(),
+	}
+	nc := &MockVersionedNode{
+		versions: map[storage.ServiceAddr][]storage.Version{nodes[0]: {v}, nodes[1]: {v}},
+		failed:   make(map[storage.ServiceAddr]bool),
+	}
+	nc.get = func(node storage.ServiceAddr, k storage.RecordID) ([]byte, error) {
+		nc.lock.Lock()
+		defer nc.lock.Unlock()
+		if len(nc.versions[node]) == 0 {
+			return nil, storage.ErrRecordNotFound
+		}
+		return nc.versions[node][0].Data, nil
+	}
+	nc.del = func(node storage.ServiceAddr, k storage.RecordID) error {
+		nc.lock.Lock()
+		defer nc.lock.Unlock()
+		delete(nc.versions, node)
+		return nil
+	}
+
+	fe := New(Config{RC: &rc, NC: nc, NF: router.NewNodesFinder(router.NewMD5Hasher()), Router: "router", ReadRepair: true})
+	if got, err := fe.Get(1); err != nil || string(got) != "session" {
+		t.Fatalf("Get() got %q, %v, want %q", got, err, "session")
+	}
+	deadline := time.Now().Add(time.Second)
+	for fe.ReadRepairStats().Repaired < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	nc.lock.Lock()
+	defer nc.lock.Unlock()
+	if got, want := nc.versions[nodes[2]], []storage.Version{v}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Repaired replica holds %v, want %v", got, want)
+	}
+}
+
+func TestGet_ReadRepairMerge(t *testing.T) {
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3"}
+	rc := MockRouter{
+		list: func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+			return nodes, nil
+		},
+	}
+	v := storage.Version{Clock: storage.VectorClock{"fe1": 1}, Data: []byte("session")}
+	for _, tc := range []struct {
+		name    string
+		replica []storage.Version
+		want    []storage.Version
+	}{
+		{
+			name:    "newer",
+			replica: []storage.Version{{Clock: storage.VectorClock{"fe1": 2}, Data: []byte("newer")}},
+			want:    []storage.Version{{Clock: storage.VectorClock{"fe1": 2}, Data: []byte("newer")}},
+		},
+		{
+			name:    "concurrent",
+			replica: []storage.Version{{Clock: storage.VectorClock{"fe2": 1}, Data: []byte("other")}},
+			want:    storage.Reconcile([]storage.Version{v, {Clock: storage.VectorClock{"fe2": 1}, Data: []byte("other")}}),
+		},
+		{
+			name:    "same clock",
+			replica: []storage.Version{{Clock: storage.VectorClock{"fe1": 1}, Data: []byte("other")}},
+			want:    []storage.Version{v},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			nc := &MockVersionedNode{
+				versions: map[storage.ServiceAddr][]storage.Version{nodes[0]: {v}, nodes[1]: {v}, nodes[2]: tc.replica},
+				failed:   make(map[storage.ServiceAddr]bool),
+			}
+			nc.get = func(node storage.ServiceAddr, k storage.RecordID) ([]byte, error) {
+				nc.lock.Lock()
+				defer nc.lock.Unlock()
+				if len(nc.versions[node]) > 1 {
+					return nil, storage.ErrConflict
+				}
+				return nc.versions[node][0].Data, nil
+			}
+			nc.del = func(node storage.ServiceAddr, k storage.RecordID) error {
+				t.Errorf("Replica %q was deleted", node)
+				return nil
+			}
+
+			fe := New(Config{RC: &rc, NC: nc, NF: router.NewNodesFinder(router.NewMD5Hasher()), Router: "router", ReadRepair: true})
+			if got, err := fe.Get(1); err != nil || string(got) != "session" {
+				t.Fatalf("Get() got %q, %v, want %q", got, err, "session")
+			}
+			deadline := time.Now().Add(time.Second)
+			for fe.ReadRepairStats().Divergent < 1 && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if stats := fe.ReadRepairStats(); stats.Repaired != 1 {
+				t.Errorf("Wrong stats: got %+v, want 1 repaired", stats)
+			}
+
+			nc.lock.Lock()
+			defer nc.lock.Unlock()
+			if got := nc.versions[nodes[2]]; !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Repaired replica holds %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPut_TTL(t *testing.T) {
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3"}
+	rc := MockRouter{
+		nodesFind: func(router storage.ServiceAddr, k storage.RecordID) ([]storage.ServiceAddr, error) {
+			return nodes, nil
+		},
+	}
+	var lock sync.Mutex
+	var got []int64
+	nc := new(MockContextNode)
+	nc.putContext = func(ctx context.Context, node storage.ServiceAddr, k storage.RecordID, d []byte) error {
+		lock.Lock()
+		defer lock.Unlock()
+		got = append(got, storage.ExpiryFromContext(ctx))
+		return nil
+	}
+
+	fe := New(Config{RC: &rc, NC: nc, Router: "router"})
+	expiry := time.Now().Add(time.Minute)
+	if err := fe.PutContext(storage.WithExpiry(context.Background(), expiry), 1, []byte("session")); err != nil {
+		t.Fatalf("PutContext() error: %v", err)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if len(got) != len(nodes) {
+		t.Fatalf("Got %d replica writes, want %d", len(got), len(nodes))
+	}
+	for _, e := range got {
+		if e != expiry.UnixNano() {
+			t.Errorf("Replica got expiry %v, want %v", time.Unix(0, e), expiry)
+		}
+	}
+}
+
+type MockBatchNode struct {
+	MockNode
+	lock    sync.Mutex
+	records map[storage.ServiceAddr]map[storage.Key][]byte
+	failed  map[storage.ServiceAddr]bool
+	calls   map[storage.ServiceAddr]int
+}
+
+func (n *MockBatchNode) call(node storage.ServiceAddr) error {
+	n.calls[node]++
+	if n.failed[node] {
+		return errors.New("node failed")
+	}
+	if n.records[node] == nil {
+		n.records[node] = make(map[storage.Key][]byte)
+	}
+	return nil
+}
+
+func (n *MockBatchNode) MultiGetContext(ctx context.Context, node storage.ServiceAddr, keys []storage.Key) ([]storage.Result, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if err := n.call(node); err != nil {
+		return nil, err
+	}
+	res := make([]storage.Result, len(keys))
+	for i, k := range keys {
+		d, ok := n.records[node][k]
+		if !ok {
+			res[i].Err = storage.ErrRecordNotFound
+		}
+		res[i].Data = d
+	}
+	return res, nil
+}
+
+func (n *MockBatchNode) MultiPutContext(ctx context.Context, node storage.ServiceAddr, records []storage.Record) ([]storage.Result, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if err := n.call(node); err != nil {
+		return nil, err
+	}
+	res := make([]storage.Result, len(records))
+	for i, r := range records {
+		if _, ok := n.records[node][r.Key]; ok {
+			res[i].Err = storage.ErrRecordExists
+			continue
+		}
+		n.records[node][r.Key] = r.Data
+	}
+	return res, nil
+}
+
+func (n *MockBatchNode) MultiDelContext(ctx context.Context, node storage.ServiceAddr, keys []storage.Key) ([]storage.Result, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if err := n.call(node); err != nil {
+		return nil, err
+	}
+	res := make([]storage.Result, len(keys))
+	for i, k := range keys {
+		if _, ok := n.records[node][k]; !ok {
+			res[i].Err = storage.ErrRecordNotFound
+			continue
+		}
+		delete(n.records[node], k)
+	}
+	return res, nil
+}
+
+func TestMulti(t *testing.T) {
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3", "node4", "node5"}
+	rc := new(MockAliveRouter)
+	rc.set(nodes, nil)
+	rc.list = func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+		return nodes, nil
+	}
+	nc := &MockBatchNode{
+		records: make(map[storage.ServiceAddr]map[storage.Key][]byte),
+		failed:  make(map[storage.ServiceAddr]bool),
+		calls:   make(map[storage.ServiceAddr]int),
+	}
+	nf := router.NewNodesFinder(router.NewMD5Hasher())
+	fe := New(Config{RC: rc, NC: nc, NF: nf, Router: "router", TopologyRefresh: time.Hour})
+	defer fe.Stop()
+
+	var records []storage.Record
+	var keys []storage.Key
+	for i := 0; i < 20; i++ {
+		k := storage.Key(fmt.Sprintf("key%d", i))
+		records = append(records, storage.Record{Key: k, Data: []byte(fmt.Sprintf("data%d", i))})
+		keys = append(keys, k)
+	}
+	results, err := fe.MultiPut(records)
+	if err != nil {
+		t.Fatalf("MultiPut() error: %v", err)
+	}
+	for i, r := range results {
+		if r.Err != nil {
+			t.Errorf("MultiPut() got error %v for key %q", r.Err, keys[i])
+		}
+	}
+	for _, node := range nodes {
+		if nc.calls[node] > 1 {
+			t.Errorf("Node %q got %d requests, want at most one", node, nc.calls[node])
+		}
+	}
+
+	// Keys held by both failed nodes don't reach the quorum.
+	nc.failed[nodes[0]] = true
+	nc.failed[nodes[1]] = true
+	results, err = fe.MultiGet(append(keys, "absent"))
+	if err != nil {
+		t.Fatalf("MultiGet() error: %v", err)
+	}
+	for i, k := range keys {
+		replicas := nf.NodesFindKey(k, nodes)
+		want := storage.Result{Data: records[i].Data}
+		if contains(replicas, nodes[0]) && contains(replicas, nodes[1]) {
+			want = storage.Result{Err: storage.ErrQuorumNotReached}
+		}
+		if !reflect.DeepEqual(results[i], want) {
+			t.Errorf("MultiGet() got %+v for key %q, want %+v", results[i], k, want)
+		}
+	}
+	if want := (storage.Result{Err: storage.ErrRecordNotFound}); !reflect.DeepEqual(results[len(keys)], want) {
+		t.Errorf("MultiGet() got %+v for a missing key, want %+v", results[len(keys)], want)
+	}
+
+	nc.failed[nodes[0]] = false
+	nc.failed[nodes[1]] = false
+	results, err = fe.MultiDel(keys[:2])
+	if err != nil {
+		t.Fatalf("MultiDel() error: %v", err)
+	}
+	if want := []storage.Result{{}, {}}; !reflect.DeepEqual(results, want) {
+		t.Errorf("MultiDel() got %v, want %v", results, want)
+	}
+	if _, err := fe.MultiGet(keys); err != nil {
+		t.Fatalf("MultiGet() error: %v", err)
+	}
+
+	ctx := storage.WithConsistency(context.Background(), storage.Consistency{W: 4})
+	if _, err := fe.MultiPutContext(ctx, records); err != storage.ErrInvalidConsistency {
+		t.Errorf("MultiPutContext() got error %v, want %v", err, storage.ErrInvalidConsistency)
+	}
+
+	results, err = fe.MultiPut([]storage.Record{{Key: "", Data: []byte("data")}, {Key: "new", Data: []byte("data")}})
+	if want := []storage.Result{{Err: storage.ErrInvalidKey}, {}}; err != nil || !reflect.DeepEqual(results, want) {
+		t.Errorf("MultiPut() got %v, %v, want %v", results, err, want)
+	}
+	for _, multi := range []func([]storage.Key) ([]storage.Result, error){fe.MultiGet, fe.MultiDel} {
+		results, err := multi([]storage.Key{""})
+		if want := []storage.Result{{Err: storage.ErrInvalidKey}}; err != nil || !reflect.DeepEqual(results, want) {
+			t.Errorf("Got %v, %v for an empty key, want %v", results, err, want)
+		}
+	}
+}
+
+func TestMulti_Alive(t *testing.T) {
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3", "node4"}
+	rc := new(MockAliveRouter)
+	rc.set(nodes[1:], nil)
+	rc.list = func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+		return nodes, nil
+	}
+	nc := &MockBatchNode{
+		records: make(map[storage.ServiceAddr]map[storage.Key][]byte),
+		failed:  make(map[storage.ServiceAddr]bool),
+		calls:   make(map[storage.ServiceAddr]int),
+	}
+	fe := New(Config{RC: rc, NC: nc, NF: router.NewNodesFinder(router.NewMD5Hasher()), Router: "router", TopologyRefresh: time.Hour})
+	defer fe.Stop()
+
+	// Writes skip the dead node the way Put does.
+	var records []storage.Record
+	for i := 0; i < 10; i++ {
+		records = append(records, storage.Record{Key: storage.Key(fmt.Sprintf("key%d", i)), Data: []byte("data")})
+	}
+	results, err := fe.MultiPut(records)
+	if err != nil {
+		t.Fatalf("MultiPut() error: %v", err)
+	}
+	for i, r := range results {
+		if r.Err != nil {
+			t.Errorf("MultiPut() got error %v for key %q", r.Err, records[i].Key)
+		}
+	}
+	if n := nc.calls[nodes[0]]; n != 0 {
+		t.Errorf("Dead node got %d requests, want none", n)
+	}
+}
+
+func TestMulti_RouterCalls(t *testing.T) {
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3", "node4"}
+	rc := new(MockAliveRouter)
+	rc.set(nodes[1:], nil)
+	var lists, finds int32
+	rc.list = func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+		atomic.AddInt32(&lists, 1)
+		return nodes, nil
+	}
+	rc.nodesFind = func(router storage.ServiceAddr, k storage.RecordID) ([]storage.ServiceAddr, error) {
+		atomic.AddInt32(&finds, 1)
+		return nodes[1:], nil
+	}
+	nc := &MockBatchNode{
+		records: make(map[storage.ServiceAddr]map[storage.Key][]byte),
+		failed:  make(map[storage.ServiceAddr]bool),
+		calls:   make(map[storage.ServiceAddr]int),
+	}
+	fe := New(Config{RC: rc, NC: nc, NF: router.NewNodesFinder(router.NewMD5Hasher()), Router: "router",
+		TopologyRefresh: time.Hour, TopologyStaleness: time.Millisecond})
+	defer fe.Stop()
+
+	check := func(first int, wantLists, wantFinds int32) {
+		var records []storage.Record
+		for i := first; i < first+20; i++ {
+			records = append(records, storage.Record{Key: storage.RecordID(i).Key(), Data: []byte("data")})
+		}
+		atomic.StoreInt32(&lists, 0)
+		atomic.StoreInt32(&finds, 0)
+		results, err := fe.MultiPut(records)
+		if err != nil {
+			t.Fatalf("MultiPut() error: %v", err)
+		}
+		for i, r := range results {
+			if r.Err != nil {
+				t.Errorf("MultiPut() got error %v for key %q", r.Err, records[i].Key)
+			}
+		}
+		if n := atomic.LoadInt32(&lists); n != wantLists {
+			t.Errorf("MultiPut() listed nodes %d times, want %d", n, wantLists)
+		}
+		if n := atomic.LoadInt32(&finds); n != wantFinds {
+			t.Errorf("MultiPut() asked Router for %d keys, want %d", n, wantFinds)
+		}
+	}
+
+	// A stale topology is fetched once for the whole batch.
+	time.Sleep(10 * time.Millisecond)
+	check(0, 1, 0)
+
+	// Router places every key only if liveness of nodes is unknown.
+	rc.set(nil, errors.New("router is unavailable"))
+	time.Sleep(10 * time.Millisecond)
+	check(20, 1, 20)
+}
+
+func TestMulti_HintedHandoff(t *testing.T) {
+	rc := &MockHintedRouter{
+		replicas: []storage.Replica{{Node: "node1"}, {Node: "node4", Hint: "node2"}, {Node: "node3"}},
+	}
+	direct := []storage.ServiceAddr{"node1", "node3"}
+	keys := []storage.Key{storage.RecordID(1).Key(), storage.RecordID(2).Key()}
+	records := []storage.Record{{Key: keys[0], Data: []byte("data")}, {Key: keys[1], Data: []byte("data")}}
+
+	nc := &MockHintNode{hints: make(map[storage.ServiceAddr]storage.ServiceAddr)}
+	var lock sync.Mutex
+	written := make(map[storage.ServiceAddr]int)
+	nc.put = func(node storage.ServiceAddr, k storage.RecordID, d []byte) error {
+		lock.Lock()
+		defer lock.Unlock()
+		written[node]++
+		return nil
+	}
+	nc.del = func(node storage.ServiceAddr, k storage.RecordID) error {
+		return nc.put(node, k, nil)
+	}
+	fe := New(Config{RC: rc, NC: nc, Router: "router", HintedHandoff: true})
+
+	results, err := fe.MultiPut(records)
+	if want := []storage.Result{{}, {}}; err != nil || !reflect.DeepEqual(results, want) {
+		t.Fatalf("MultiPut() got %v, %v, want %v", results, err, want)
+	}
+	results, err = fe.MultiDel(keys)
+	if want := []storage.Result{{}, {}}; err != nil || !reflect.DeepEqual(results, want) {
+		t.Fatalf("MultiDel() got %v, %v, want %v", results, err, want)
+	}
+	want := map[storage.ServiceAddr]int{direct[0]: 4, direct[1]: 4}
+	if !reflect.DeepEqual(written, want) {
+		t.Errorf("Written to %v, want %v", written, want)
+	}
+	if want := map[storage.ServiceAddr]storage.ServiceAddr{"node4": "node2"}; !reflect.DeepEqual(nc.hints, want) {
+		t.Errorf("Got hinted writes %v, want %v", nc.hints, want)
+	}
+}
+
+func contains(nodes []storage.ServiceAddr, node storage.ServiceAddr) bool {
+	for _, n := range nodes {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMulti_NotBatched(t *testing.T) {
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3"}
+	rc := MockRouter{
+		list: func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+			return nodes, nil
+		},
+	}
+	nc := new(MockNode)
+	nc.get = func(node storage.ServiceAddr, k storage.RecordID) ([]byte, error) {
+		if k == 2 {
+			return nil, storage.ErrRecordNotFound
+		}
+		return []byte("data"), nil
+	}
+	fe := New(Config{RC: &rc, NC: nc, NF: router.NewNodesFinder(router.NewMD5Hasher()), Router: "router"})
+	results, err := fe.MultiGet([]storage.Key{storage.RecordID(1).Key(), storage.RecordID(2).Key()})
+	want := []storage.Result{{Data: []byte("data")}, {Err: storage.ErrRecordNotFound}}
+	if err != nil || !reflect.DeepEqual(results, want) {
+		t.Errorf("MultiGet() got %v, %v, want %v", results, err, want)
+	}
+}
+
+type MockHintedRouter struct {
+	MockRouter
+	replicas []storage.Replica
+}
+
+func (r *MockHintedRouter) NodesFindHinted(router storage.ServiceAddr, k storage.RecordID) ([]storage.Replica, error) {
+	return r.replicas, nil
+}
+
+func (r *MockHintedRouter) NodesFindHintedContext(ctx context.Context, router storage.ServiceAddr, k storage.RecordID) ([]storage.Replica, error) {
+	return r.replicas, nil
+}
+
+func (r *MockHintedRouter) Alive(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+	return nil, nil
+}
+
+func (r *MockHintedRouter) AliveContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+	return nil, nil
+}
+
+type MockHintNode struct {
+	MockNode
+	lock  sync.Mutex
+	hints map[storage.ServiceAddr]storage.ServiceAddr
+}
+
+func (n *MockHintNode) PutHintContext(ctx context.Context, node, hint storage.ServiceAddr, k storage.Key, d []byte) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.hints[node] = hint
+	return nil
+}
+
+func (n *MockHintNode) DelHintContext(ctx context.Context, node, hint storage.ServiceAddr, k storage.Key) error {
+	return n.PutHintContext(ctx, node, hint, k, nil)
+}
+
+func TestPutDel_HintedHandoff(t *testing.T) {
+	key := storage.RecordID(1)
+	testData := []byte("test")
+	rc := &MockHintedRouter{
+		replicas: []storage.Replica{{Node: "node1"}, {Node: "node4", Hint: "node2"}, {Node: "node3"}},
+	}
+	rc.nodesFind = func(router storage.ServiceAddr, k storage.RecordID) ([]storage.ServiceAddr, error) {
+		return []storage.ServiceAddr{"node1", "node3"}, nil
+	}
+	direct := []storage.ServiceAddr{"node1", "node3"}
+
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enabled %v", enabled), func(t *testing.T) {
+			nc := &MockHintNode{hints: make(map[storage.ServiceAddr]storage.ServiceAddr)}
+			nc.put = put(t, direct, key, testData, nil)
+			fe := New(Config{
+				RC:            rc,
+				NC:            nc,
+				Router:        "router",
+				HintedHandoff: enabled,
+			})
+			if err := fe.Put(key, testData); err != nil {
+				t.Fatalf("Put() error: %v", err)
+			}
+			nc.del = del(t, direct, key, nil)
+			if err := fe.Del(key); err != nil {
+				t.Fatalf("Del() error: %v", err)
+			}
+
+			want := map[storage.ServiceAddr]storage.ServiceAddr{}
+			if enabled {
+				want["node4"] = "node2"
+			}
+			if !reflect.DeepEqual(nc.hints, want) {
+				t.Errorf("Got hinted writes %v, want %v", nc.hints, want)
+			}
+		})
+	}
+}
+
+type MockAliveRouter struct {
+	MockRouter
+	lock  sync.Mutex
+	alive []storage.ServiceAddr
+	err   error
+}
+
+func (r *MockAliveRouter) set(alive []storage.ServiceAddr, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.alive, r.err = alive, err
+}
+
+func (r *MockAliveRouter) Alive(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.alive, r.err
+}
+
+func (r *MockAliveRouter) AliveContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+	return r.Alive(router)
+}
+
+func TestPutDel_TopologyCache(t *testing.T) {
+	key := storage.RecordID(1)
+	testData := []byte("test")
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3"}
+	errRouter := errors.New("router is unavailable")
+	refresh := 20 * time.Millisecond
+
+	rc := new(MockAliveRouter)
+	rc.set(nodes[:2], nil)
+	rc.list = func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+		_, err := rc.Alive(router)
+		return nodes, err
+	}
+	var nodesFinds uint32
+	rc.nodesFind = func(router storage.ServiceAddr, k storage.RecordID) ([]storage.ServiceAddr, error) {
+		atomic.AddUint32(&nodesFinds, 1)
+		return nil, errRouter
+	}
+
+	var lock sync.Mutex
+	var written []storage.ServiceAddr
+	nc := new(MockNode)
+	nc.put = func(node storage.ServiceAddr, k storage.RecordID, d []byte) error {
+		lock.Lock()
+		defer lock.Unlock()
+		written = append(written, node)
+		return nil
+	}
+	nc.del = func(node storage.ServiceAddr, k storage.RecordID) error {
+		return nc.put(node, k, nil)
+	}
+	check := func(want []storage.ServiceAddr) {
+		t.Helper()
+		lock.Lock()
+		defer lock.Unlock()
+		sort.Slice(written, func(i, j int) bool { return written[i] < written[j] })
+		if !reflect.DeepEqual(written, want) {
+			t.Errorf("Written to %v, want %v", written, want)
+		}
+		written = nil
+	}
+
+	fe := New(Config{
+		RC:                rc,
+		NC:                nc,
+		NF:                router.NewNodesFinder(router.NewMD5Hasher()),
+		Router:            "router",
+		TopologyRefresh:   refresh,
+		TopologyStaleness: 5 * refresh,
+	})
+	defer fe.Stop()
+
+	// Replicas are found with the cached topology skipping dead nodes.
+	if err := fe.Put(key, testData); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	check(nodes[:2])
+	if err := fe.Del(key); err != nil {
+		t.Fatalf("Del() error: %v", err)
+	}
+	check(nodes[:2])
+
+	// The cache is refreshed in the background.
+	rc.set(nodes, nil)
+	time.Sleep(3 * refresh)
+	if err := fe.Put(key, testData); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	check(nodes)
+	if n := atomic.LoadUint32(&nodesFinds); n != 0 {
+		t.Errorf("NodesFind() was called %d times with a fresh topology", n)
+	}
+
+	// Router is asked once the cache is stale.
+	rc.set(nil, errRouter)
+	time.Sleep(10 * refresh)
+	if err := fe.Put(key, testData); err != errRouter {
+		t.Errorf("Put() with a stale topology got error %v, want %v", err, errRouter)
+	}
+	if n := atomic.LoadUint32(&nodesFinds); n != 1 {
+		t.Errorf("NodesFind() was called %d times with a stale topology, want 1", n)
+	}
+}
+
+type MockWatchRouter struct {
+	MockRouter
+	topologies chan storage.Topology
+	applied    chan struct{}
+}
+
+func (r *MockWatchRouter) WatchTopology(ctx context.Context, router storage.ServiceAddr, f func(storage.Topology) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case t := <-r.topologies:
+			if err := f(t); err != nil {
+				return err
+			}
+			r.applied <- struct{}{}
+		}
+	}
+}
+
+func TestPutDel_WatchTopology(t *testing.T) {
+	key := storage.RecordID(1)
+	testData := []byte("test")
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3"}
+	errRouter := errors.New("router is unavailable")
+	staleness := 100 * time.Millisecond
+
+	rc := &MockWatchRouter{
+		topologies: make(chan storage.Topology),
+		applied:    make(chan struct{}),
+	}
+	rc.list = func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+		return nodes, nil
+	}
+	rc.nodesFind = func(router storage.ServiceAddr, k storage.RecordID) ([]storage.ServiceAddr, error) {
+		return nil, errRouter
+	}
+
+	var lock sync.Mutex
+	var written []storage.ServiceAddr
+	nc := new(MockNode)
+	nc.put = func(node storage.ServiceAddr, k storage.RecordID, d []byte) error {
+		lock.Lock()
+		defer lock.Unlock()
+		written = append(written, node)
+		return nil
+	}
+	fe := New(Config{
+		RC:                rc,
+		NC:                nc,
+		NF:                router.NewNodesFinder(router.NewMD5Hasher()),
+		Router:            "router",
+		TopologyRefresh:   time.Hour,
+		TopologyStaleness: staleness,
+	})
+	defer fe.Stop()
+
+	// Liveness is unknown until the topology is pushed.
+	if err := fe.Put(key, testData); err != errRouter {
+		t.Errorf("Put() before a push got error %v, want %v", err, errRouter)
+	}
+
+	for _, alive := range [][]storage.ServiceAddr{nodes[:2], nodes} {
+		rc.topologies <- storage.Topology{Nodes: nodes, Alive: alive, Replication: storage.DefaultReplication}
+		<-rc.applied
+		if err := fe.Put(key, testData); err != nil {
+			t.Fatalf("Put() error: %v", err)
+		}
+		lock.Lock()
+		sort.Slice(written, func(i, j int) bool { return written[i] < written[j] })
+		if !reflect.DeepEqual(written, alive) {
+			t.Errorf("Written to %v, want %v", written, alive)
+		}
+		written = nil
+		lock.Unlock()
+	}
+
+	time.Sleep(2 * staleness)
+	if err := fe.Put(key, testData); err != errRouter {
+		t.Errorf("Put() with a stale topology got error %v, want %v", err, errRouter)
+	}
+}
+
+type MockPlacementRouter struct {
+	MockWatchRouter
+	placement storage.Placement
+}
+
+func (r *MockPlacementRouter) ListPlacement(router storage.ServiceAddr) ([]storage.ServiceAddr, storage.Replication, storage.Placement, error) {
+	nodes, err := r.List(router)
+	return nodes, storage.DefaultReplication, r.placement, err
+}
+
+func (r *MockPlacementRouter) ListPlacementContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, storage.Replication, storage.Placement, error) {
+	return r.ListPlacement(router)
+}
+
+func TestTopology_Placement(t *testing.T) {
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3"}
+	rc := &MockPlacementRouter{
+		MockWatchRouter: MockWatchRouter{
+			topologies: make(chan storage.Topology),
+			applied:    make(chan struct{}),
+		},
+		placement: storage.Placement{Capacity: map[storage.ServiceAddr]float64{"node1": 2}},
+	}
+	rc.list = func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+		return nodes, nil
+	}
+	fe := New(Config{
+		RC:              rc,
+		NC:              new(MockNode),
+		NF:              router.NewNodesFinder(router.NewMD5Hasher()),
+		Router:          "router",
+		TopologyRefresh: time.Hour,
+	})
+	defer fe.Stop()
+
+	// Records are placed locally the way Router places them.
+	if p := fe.topology().nf.Placement(); !reflect.DeepEqual(p, rc.placement) {
+		t.Errorf("Listed topology places records by %+v, want %+v", p, rc.placement)
+	}
+	pushed := storage.Placement{Capacity: map[storage.ServiceAddr]float64{"node2": 3}}
+	rc.topologies <- storage.Topology{Nodes: nodes, Alive: nodes, Replication: storage.DefaultReplication, Placement: pushed}
+	<-rc.applied
+	if p := fe.topology().nf.Placement(); !reflect.DeepEqual(p, pushed) {
+		t.Errorf("Pushed topology places records by %+v, want %+v", p, pushed)
+	}
+}
+
+func TestPutContext_Deadline(t *testing.T) {
+	key := storage.RecordID(1)
+	testData := []byte("test")
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3"}
+
+	rc.nodesFind = nodesFind(t, cfg, key, nodes, nil)
+	nc := new(MockContextNode)
+	nc.putContext = func(ctx context.Context, node storage.ServiceAddr, k storage.RecordID, d []byte) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	fe := New(Config{
+		RC:     &rc,
+		NC:     nc,
+		Router: "router",
+	})
+
+	timeout := 100 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := fe.PutContext(ctx, key, testData); err != context.DeadlineExceeded {
+		t.Errorf("PutContext() got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if diff := time.Since(start); !eqTime(diff, timeout) {
+		t.Errorf("PutContext() didn't respect the deadline: took %v", diff)
+	}
+}
+
+func TestScan(t *testing.T) {
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3"}
+	rc := MockRouter{
+		list: func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+			return nodes, nil
+		},
+	}
+	nc := &MockScanNode{
+		records: map[storage.ServiceAddr]map[storage.Key]string{
+			nodes[0]: {"1": "a", "2": "b", "4": "d", "5": "e", "7": "g"},
+			nodes[1]: {"1": "a", "2": "b", "4": "x", "6": "f", "7": "g"},
+			nodes[2]: {"1": "a", "3": "c", "4": "d", "6": "f", "7": "g", "8": "h"},
+		},
+		failed: make(map[storage.ServiceAddr]bool),
+	}
+	fe := New(Config{
+		RC:     &rc,
+		NC:     nc,
+		Router: "router",
+	})
+
+	// Records 3, 5 and 8 are held by a single node, record 4 has
+	// a diverged replica which is outvoted.
+	want := []storage.Record{
+		{Key: "1", Data: []byte("a")},
+		{Key: "2", Data: []byte("b")},
+		{Key: "4", Data: []byte("d")},
+		{Key: "6", Data: []byte("f")},
+		{Key: "7", Data: []byte("g")},
+	}
+	got, token, err := fe.Scan(storage.ScanOptions{})
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) || token != "" {
+		t.Errorf("Scan() got %v and token %q, want %v and no token", got, token, want)
+	}
+
+	for _, limit := range []int{1, 2, 3} {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			opts := storage.ScanOptions{Limit: limit}
+			var got []storage.Record
+			for i := 0; ; i++ {
+				if i > len(want)*2 {
+					t.Fatalf("Scan() didn't finish, got %v", got)
+				}
+				page, token, err := fe.Scan(opts)
+				if err != nil {
+					t.Fatalf("Scan() error: %v", err)
+				}
+				if len(page) > limit {
+					t.Fatalf("Scan() got %d records, limit is %d", len(page), limit)
+				}
+				got = append(got, page...)
+				if token == "" {
+					break
+				}
+				opts.Token = token
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Scan() got %v, want %v", got, want)
+			}
+		})
+	}
+
+	nc.failed[nodes[2]] = true
+	got, _, err = fe.Scan(storage.ScanOptions{Start: "1", End: "3"})
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("Scan() got %v, want %v", got, want[:2])
+	}
+
+	nc.failed[nodes[1]] = true
+	if _, _, err := fe.Scan(storage.ScanOptions{}); err != storage.ErrQuorumNotReached {
+		t.Errorf("Scan() got error %v, want %v", err, storage.ErrQuorumNotReached)
+	}
+}
+
+func TestScan_NotSupported(t *testing.T) {
+	fe := New(Config{
+		RC:     &rc,
+		NC:     new(MockNode),
+		Router: "router",
+	})
+	if _, _, err := fe.Scan(storage.ScanOptions{}); err != storage.ErrScanNotSupported {
+		t.Errorf("Scan() got error %v, want %v", err, storage.ErrScanNotSupported)
+	}
+}
+
+type MockVersionedNode struct {
+	MockNode
+	lock     sync.Mutex
+	versions map[storage.ServiceAddr][]storage.Version
+	failed   map[storage.ServiceAddr]bool
+}
+
+func (n *MockVersionedNode) GetVersionsContext(ctx context.Context, node storage.ServiceAddr, k storage.Key) ([]storage.Version, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.failed[node] {
+		return nil, errors.New("node failed")
+	}
+	if len(n.versions[node]) == 0 {
+		return nil, storage.ErrRecordNotFound
+	}
+	return n.versions[node], nil
+}
+
+func (n *MockVersionedNode) UpdateContext(ctx context.Context, node storage.ServiceAddr, k storage.Key, v storage.Version) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.failed[node] {
+		return errors.New("node failed")
+	}
+	for _, s := range n.versions[node] {
+		if s.Clock.Compare(v.Clock) == storage.Equal && !bytes.Equal(s.Data, v.Data) {
+			return storage.ErrConflict
+		}
+	}
+	n.versions[node] = storage.Reconcile(n.versions[node], []storage.Version{v})
+	return nil
+}
+
+func (n *MockVersionedNode) PutIfVersionContext(ctx context.Context, node storage.ServiceAddr, k storage.Key, cond storage.Condition, v storage.Version) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.failed[node] {
+		return errors.New("node failed")
+	}
+	if !cond.Holds(n.versions[node]) {
+		return storage.ErrConditionFailed
+	}
+	n.versions[node] = []storage.Version{v}
+	return nil
+}
+
+func (n *MockVersionedNode) DeleteIfVersionContext(ctx context.Context, node storage.ServiceAddr, k storage.Key, cond storage.Condition) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.failed[node] {
+		return errors.New("node failed")
+	}
+	if !cond.Holds(n.versions[node]) {
+		return storage.ErrConditionFailed
+	}
+	if len(n.versions[node]) == 0 {
+		return storage.ErrRecordNotFound
+	}
+	delete(n.versions, node)
+	return nil
+}
+
+func (n *MockVersionedNode) UpsertContext(ctx context.Context, node storage.ServiceAddr, k storage.Key, v storage.Version) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.failed[node] {
+		return errors.New("node failed")
+	}
+	for _, s := range n.versions[node] {
+		if s.Clock.Compare(v.Clock) == storage.Equal && !bytes.Equal(s.Data, v.Data) {
+			return storage.ErrConflict
+		}
+	}
+	n.versions[node] = []storage.Version{v}
+	return nil
+}
+
+func TestUpdateGetVersions(t *testing.T) {
+	key := storage.RecordID(1).Key()
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3"}
+	rc := MockRouter{
+		list: func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+			return nodes, nil
+		},
+		nodesFind: func(router storage.ServiceAddr, k storage.RecordID) ([]storage.ServiceAddr, error) {
+			return nodes, nil
+		},
+	}
+	nc := &MockVersionedNode{
+		versions: make(map[storage.ServiceAddr][]storage.Version),
+		failed:   make(map[storage.ServiceAddr]bool),
+	}
+	fe1 := New(Config{RC: &rc, NC: nc, NF: router.NewNodesFinder(router.NewMD5Hasher()), Router: "router", ID: "fe1"})
+	fe2 := New(Config{RC: &rc, NC: nc, NF: router.NewNodesFinder(router.NewMD5Hasher()), Router: "router", Addr: "fe2"})
+
+	if _, err := fe1.GetVersions(key); err != storage.ErrRecordNotFound {
+		t.Fatalf("GetVersions() got error %v, want %v", err, storage.ErrRecordNotFound)
+	}
+	if err := fe1.Update(key, storage.Version{Data: []byte("a")}); err != nil {
+		t.Fatalf("Update() error: %v", err)
+	}
+	versions, err := fe1.GetVersions(key)
+	want := []storage.Version{{Clock: storage.VectorClock{"fe1": 1}, Data: []byte("a")}}
+	if err != nil || !reflect.DeepEqual(versions, want) {
+		t.Fatalf("GetVersions() got %v, %v, want %v", versions, err, want)
+	}
+
+	// Both frontends update the record based on the same version,
+	// a failed node misses the second update.
+	if err := fe1.Update(key, storage.Version{Clock: versions[0].Clock, Data: []byte("b")}); err != nil {
+		t.Fatalf("Update() error: %v", err)
+	}
+	// Another update of the same version through fe1 gets the same clock,
+	// it must not be acknowledged and lost. Retrying the same one is fine.
+	if err := fe1.Update(key, storage.Version{Clock: versions[0].Clock, Data: []byte("x")}); err != storage.ErrConflict {
+		t.Errorf("Update() with the same clock got error %v, want %v", err, storage.ErrConflict)
+	}
+	if err := fe1.Update(key, storage.Version{Clock: versions[0].Clock, Data: []byte("b")}); err != nil {
+		t.Errorf("Update() retry error: %v", err)
+	}
+	nc.failed[nodes[2]] = true
+	if err := fe2.Update(key, storage.Version{Clock: versions[0].Clock, Data: []byte("c")}); err != nil {
+		t.Fatalf("Update() error: %v", err)
+	}
+	nc.failed[nodes[2]] = false
+	versions, err = fe1.GetVersions(key)
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("GetVersions() got %v, %v, want two siblings", versions, err)
+	}
+
+	if err := fe2.Update(key, storage.Version{Clock: storage.MergedClock(versions), Data: []byte("d")}); err != nil {
+		t.Fatalf("Update() error: %v", err)
+	}
+	versions, err = fe1.GetVersions(key)
+	want = []storage.Version{{Clock: storage.VectorClock{"fe1": 2, "fe2": 2}, Data: []byte("d")}}
+	if err != nil || !reflect.DeepEqual(versions, want) {
+		t.Errorf("GetVersions() got %v, %v, want %v", versions, err, want)
+	}
+
+	nc.failed[nodes[0]] = true
+	nc.failed[nodes[1]] = true
+	if _, err := fe1.GetVersions(key); err != storage.ErrQuorumNotReached {
+		t.Errorf("GetVersions() got error %v, want %v", err, storage.ErrQuorumNotReached)
+	}
+	if err := fe1.Update(key, storage.Version{}); err != storage.ErrQuorumNotReached {
+		t.Errorf("Update() got error %v, want %v", err, storage.ErrQuorumNotReached)
+	}
+}
+
+func TestUpdate_NotSupported(t *testing.T) {
+	key := storage.RecordID(1).Key()
+	fe := New(Config{
+		RC:     &rc,
+		NC:     new(MockNode),
+		Router: "router",
+	})
+	if err := fe.Update(key, storage.Version{}); err != storage.ErrVersionsNotSupported {
+		t.Errorf("Update() got error %v, want %v", err, storage.ErrVersionsNotSupported)
+	}
+}
+
+func TestConditionalWrites(t *testing.T) {
+	key := storage.RecordID(1).Key()
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3"}
+	rc := MockRouter{
+		list: func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+			return nodes, nil
+		},
+		nodesFind: func(router storage.ServiceAddr, k storage.RecordID) ([]storage.ServiceAddr, error) {
+			return nodes, nil
+		},
+	}
+	nc := &MockVersionedNode{
+		versions: make(map[storage.ServiceAddr][]storage.Version),
+		failed:   make(map[storage.ServiceAddr]bool),
+	}
+	fe := New(Config{RC: &rc, NC: nc, NF: router.NewNodesFinder(router.NewMD5Hasher()), Router: "router", ID: "fe1"})
+
+	if err := fe.PutIfVersion(key, storage.Condition{Absent: true}, storage.Version{Data: []byte("a")}); err != nil {
+		t.Fatalf("PutIfVersion() error: %v", err)
+	}
+	if err := fe.PutIfVersion(key, storage.Condition{Absent: true}, storage.Version{Data: []byte("b")}); err != storage.ErrConditionFailed {
+		t.Errorf("PutIfVersion() got error %v, want %v", err, storage.ErrConditionFailed)
+	}
+	versions, err := fe.GetVersions(key)
+	if err != nil {
+		t.Fatalf("GetVersions() error: %v", err)
+	}
+	clock := versions[0].Clock
+
+	// A node misses the swap, the quorum still accepts it.
+	nc.failed[nodes[2]] = true
+	if err := fe.CompareAndSwap(key, clock, []byte("b")); err != nil {
+		t.Fatalf("CompareAndSwap() error: %v", err)
+	}
+	nc.failed[nodes[2]] = false
+	if err := fe.CompareAndSwap(key, clock, []byte("c")); err != storage.ErrConditionFailed {
+		t.Errorf("CompareAndSwap() with a stale clock got error %v, want %v", err, storage.ErrConditionFailed)
+	}
+	// The lagging node would accept the stale swap, which is rejected
+	// by the quorum before it's written anywhere.
+	want := []storage.Version{{Clock: storage.VectorClock{"fe1": 2}, Data: []byte("b")}}
+	if versions, err := fe.GetVersions(key); err != nil || !reflect.DeepEqual(versions, want) {
+		t.Errorf("GetVersions() got %v, %v, want %v", versions, err, want)
+	}
+	nc.lock.Lock()
+	if got := nc.versions[nodes[2]]; len(got) != 1 || string(got[0].Data) != "a" {
+		t.Errorf("Lagging node holds %v, want the version %q", got, "a")
+	}
+	nc.lock.Unlock()
+
+	// Upsert supersedes the versions of all replicas including the stale one.
+	if err := fe.Upsert(key, storage.Version{Data: []byte("d")}); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	versions, err = fe.GetVersions(key)
+	want = []storage.Version{{Clock: storage.VectorClock{"fe1": 3}, Data: []byte("d")}}
+	if err != nil || !reflect.DeepEqual(versions, want) {
+		t.Fatalf("GetVersions() got %v, %v, want %v", versions, err, want)
+	}
+
+	if err := fe.DeleteIfVersion(key, storage.Condition{Clock: clock}); err != storage.ErrConditionFailed {
+		t.Errorf("DeleteIfVersion() got error %v, want %v", err, storage.ErrConditionFailed)
+	}
+	if err := fe.DeleteIfVersion(key, storage.Condition{Clock: versions[0].Clock}); err != nil {
+		t.Fatalf("DeleteIfVersion() error: %v", err)
+	}
+	if _, err := fe.GetVersions(key); err != storage.ErrRecordNotFound {
+		t.Errorf("GetVersions() got error %v, want %v", err, storage.ErrRecordNotFound)
+	}
+
+	nc.failed[nodes[0]] = true
+	nc.failed[nodes[1]] = true
+	if err := fe.CompareAndSwap(key, versions[0].Clock, nil); err != storage.ErrQuorumNotReached {
+		t.Errorf("CompareAndSwap() got error %v, want %v", err, storage.ErrQuorumNotReached)
+	}
+}
+
+func TestConditional_NotSupported(t *testing.T) {
+	key := storage.RecordID(1).Key()
+	fe := New(Config{
+		RC:     &rc,
+		NC:     new(MockNode),
+		Router: "router",
+	})
+	if err := fe.CompareAndSwap(key, nil, nil); err != storage.ErrConditionalNotSupported {
+		t.Errorf("CompareAndSwap() got error %v, want %v", err, storage.ErrConditionalNotSupported)
+	}
+	if err := fe.Upsert(key, storage.Version{}); err != storage.ErrConditionalNotSupported {
+		t.Errorf("Upsert() got error %v, want %v", err, storage.ErrConditionalNotSupported)
+	}
+}
+
+type MockReplicationRouter struct {
+	MockRouter
+	rep storage.Replication
+}
+
+func (r *MockReplicationRouter) ListReplication(router storage.ServiceAddr) ([]storage.ServiceAddr, storage.Replication, error) {
+	nodes, err := r.list(router)
+	return nodes, r.rep, err
+}
+
+func (r *MockReplicationRouter) ListReplicationContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, storage.Replication, error) {
+	return r.ListReplication(router)
+}
+
+func TestReplication(t *testing.T) {
+	key := storage.RecordID(1)
+	testData := []byte("test")
+	nodes := []storage.ServiceAddr{"node1"}
+	rc := MockReplicationRouter{
+		MockRouter: MockRouter{
+			list: func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+				return nodes, nil
+			},
+		},
+		rep: storage.Replication{Factor: 1, Quorum: 1},
+	}
+	rc.nodesFind = nodesFind(t, cfg, key, nodes, nil)
+	nc := new(MockNode)
+	nc.put = put(t, nodes, key, testData, nil)
+	nc.get = get(t, nodes, key, func(node storage.ServiceAddr) ([]byte, error) {
+		return testData, nil
+	})
+	fe := New(Config{RC: &rc, NC: nc, NF: router.NewNodesFinder(router.NewMD5Hasher()), Router: "router"})
+
+	// A single node reaches the quorum of a cluster with replication factor 1.
+	if err := fe.Put(key, testData); err != nil {
+		t.Errorf("Put() error: %v", err)
+	}
+	if got, err := fe.Get(key); err != nil || !reflect.DeepEqual(got, testData) {
+		t.Errorf("Get() got %q, %v, want %q", got, err, testData)
+	}
+	ctx := storage.WithConsistency(context.Background(), storage.Consistency{R: 2})
+	if _, err := fe.GetContext(ctx, key); err != storage.ErrInvalidConsistency {
+		t.Errorf("GetContext() got error %v, want %v", err, storage.ErrInvalidConsistency)
+	}
+
+	// Records are placed on as many nodes as the router reports.
+	nodes = []storage.ServiceAddr{"node1", "node2", "node3", "node4", "node5"}
+	rc.rep = storage.Replication{Factor: 5, Quorum: 3}
+	nc.get = get(t, nodes, key, func(node storage.ServiceAddr) ([]byte, error) {
+		if node == nodes[0] || node == nodes[1] {
+			return nil, storage.ErrRecordNotFound
+		}
+		return testData, nil
+	})
+	fe = New(Config{RC: &rc, NC: nc, NF: router.NewNodesFinder(router.NewMD5Hasher()), Router: "router"})
+	if got, err := fe.Get(key); err != nil || !reflect.DeepEqual(got, testData) {
+		t.Errorf("Get() got %q, %v, want %q", got, err, testData)
+	}
+}
+
+type MockKeyRouter struct {
+	MockRouter
+	nodesFindKey func(router storage.ServiceAddr, k storage.Key) ([]storage.ServiceAddr, error)
+}
+
+func (r *MockKeyRouter) NodesFindKeyContext(ctx context.Context, router storage.ServiceAddr, k storage.Key) ([]storage.ServiceAddr, error) {
+	return r.nodesFindKey(router, k)
+}
+
+func (r *MockKeyRouter) NodesFindHintedKeyContext(ctx context.Context, router storage.ServiceAddr, k storage.Key) ([]storage.Replica, error) {
+	return nil, errors.New("not hinted")
+}
+
+type MockKeyNode struct {
+	MockNode
+	lock    sync.Mutex
+	records map[storage.ServiceAddr]map[storage.Key][]byte
+}
+
+func (n *MockKeyNode) PutKeyContext(ctx context.Context, node storage.ServiceAddr, k storage.Key, d []byte) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if _, ok := n.records[node][k]; ok {
+		return storage.ErrRecordExists
+	}
+	if n.records[node] == nil {
+		n.records[node] = make(map[storage.Key][]byte)
+	}
+	n.records[node][k] = d
+	return nil
+}
+
+func (n *MockKeyNode) GetKeyContext(ctx context.Context, node storage.ServiceAddr, k storage.Key) ([]byte, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	d, ok := n.records[node][k]
+	if !ok {
+		return nil, storage.ErrRecordNotFound
+	}
+	return d, nil
+}
+
+func (n *MockKeyNode) DelKeyContext(ctx context.Context, node storage.ServiceAddr, k storage.Key) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if _, ok := n.records[node][k]; !ok {
+		return storage.ErrRecordNotFound
+	}
+	delete(n.records[node], k)
+	return nil
+}
+
+func TestKeys(t *testing.T) {
+	key := storage.Key("user:1")
+	testData := []byte("test")
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3"}
+	nf := router.NewNodesFinder(router.NewMD5Hasher())
+	rc := MockKeyRouter{
+		MockRouter: MockRouter{
+			list: func(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+				return nodes, nil
+			},
+		},
+		nodesFindKey: func(router storage.ServiceAddr, k storage.Key) ([]storage.ServiceAddr, error) {
+			return nf.NodesFindKey(k, nodes), nil
+		},
+	}
+	nc := &MockKeyNode{records: make(map[storage.ServiceAddr]map[storage.Key][]byte)}
+	fe := New(Config{RC: &rc, NC: nc, NF: nf, Router: "router"})
+
+	if err := fe.PutKey(key, testData); err != nil {
+		t.Fatalf("PutKey() error: %v", err)
+	}
+	for _, node := range nodes {
+		if got := nc.records[node][key]; !reflect.DeepEqual(got, testData) {
+			t.Errorf("Node %q holds %q, want %q", node, got, testData)
+		}
+	}
+	if got, err := fe.GetKey(key); err != nil || !reflect.DeepEqual(got, testData) {
+		t.Errorf("GetKey() got %q, %v, want %q", got, err, testData)
+	}
+	if err := fe.DelKey(key); err != nil {
+		t.Errorf("DelKey() error: %v", err)
+	}
+	if _, err := fe.GetKey(key); err != storage.ErrRecordNotFound {
+		t.Errorf("GetKey() got error %v, want %v", err, storage.ErrRecordNotFound)
+	}
+
+	// A RecordID is stored with its byte key.
+	if err := fe.Put(1, testData); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	if got, err := fe.GetKey(storage.RecordID(1).Key()); err != nil || !reflect.DeepEqual(got, testData) {
+		t.Errorf("GetKey() got %q, %v, want %q", got, err, testData)
+	}
+
+	if err := fe.PutKey("", testData); err != storage.ErrInvalidKey {
+		t.Errorf("PutKey() got error %v, want %v", err, storage.ErrInvalidKey)
+	}
+
+	// A router client without byte keys only finds keys of RecordIDs.
+	fe = New(Config{RC: &rc.MockRouter, NC: nc, NF: nf, Router: "router"})
+	if err := fe.PutKey(key, testData); err != storage.ErrKeyNotSupported {
+		t.Errorf("PutKey() got error %v, want %v", err, storage.ErrKeyNotSupported)
+	}
+}
+
 func TestParallelOps(t *testing.T) {
 	key := storage.RecordID(1)
 	testData := []byte("test")
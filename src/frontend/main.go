@@ -55,7 +55,10 @@ func main() {
 	cfg.NC = storage.NewClient()
 	cfg.RC = rclient.New()
 
-	hasher := router.NewMD5Hasher()
+	hasher, err := cfg.NewHasher()
+	if err != nil {
+		log.Fatalf("Failed to create hasher: %v", err)
+	}
 	cfg.NF = router.NewNodesFinder(hasher)
 
 	fe := frontend.New(cfg)
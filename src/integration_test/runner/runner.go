@@ -17,6 +17,7 @@ const heartbeat = time.Second
 type nodeService struct {
 	node *node.Node
 	srv  *storage.Server
+	rc   client.RouterClient
 }
 
 type routerService struct {
@@ -27,6 +28,8 @@ type routerService struct {
 type frontendService struct {
 	fe  *frontend.Frontend
 	srv *storage.Server
+	nc  storage.StorageClient
+	rc  client.RouterClient
 }
 
 type Runner struct {
@@ -45,11 +48,12 @@ func (r *Runner) StartNodes(nodes []storage.ServiceAddr, router storage.ServiceA
 	}
 	r.nodes = make(map[storage.ServiceAddr]nodeService)
 	for _, addr := range nodes {
+		rc := client.NewPooled(storage.DefaultIdleTimeout)
 		cfg := node.Config{
 			Addr:      addr,
 			Router:    router,
 			Heartbeat: heartbeat,
-			Client:    client.New(),
+			Client:    rc,
 		}
 		n := node.New(cfg)
 		n.Heartbeats()
@@ -57,6 +61,7 @@ func (r *Runner) StartNodes(nodes []storage.ServiceAddr, router storage.ServiceA
 		r.nodes[addr] = nodeService{
 			node: n,
 			srv:  srv,
+			rc:   rc,
 		}
 		go func(srv *storage.Server) {
 			if err := srv.ListenAndServe(); err != nil {
@@ -72,6 +77,7 @@ func (r *Runner) StopNodes() {
 	for _, n := range r.nodes {
 		n.node.Stop()
 		n.srv.Stop()
+		n.rc.Close()
 	}
 	r.nodes = nil
 }
@@ -115,11 +121,13 @@ func (r *Runner) StartFrontends(addrs []storage.ServiceAddr, routerAddr storage.
 	defer r.Unlock()
 	r.stopFrontends()
 	for _, addr := range addrs {
+		nc := storage.NewPooledClient(storage.DefaultIdleTimeout)
+		rc := client.NewPooled(storage.DefaultIdleTimeout)
 		cfg := frontend.Config{
 			Addr:   addr,
 			Router: routerAddr,
-			NC:     storage.NewClient(),
-			RC:     client.New(),
+			NC:     nc,
+			RC:     rc,
 			NF:     router.NewNodesFinder(router.NewMD5Hasher()),
 		}
 
@@ -128,6 +136,8 @@ func (r *Runner) StartFrontends(addrs []storage.ServiceAddr, routerAddr storage.
 		r.fe = append(r.fe, frontendService{
 			fe:  fe,
 			srv: srv,
+			nc:  nc,
+			rc:  rc,
 		})
 
 		go func(srv *storage.Server) {
@@ -147,6 +157,8 @@ func (r *Runner) StopFrontends() {
 func (r *Runner) stopFrontends() {
 	for _, fe := range r.fe {
 		fe.srv.Stop()
+		fe.nc.Close()
+		fe.rc.Close()
 	}
 	r.fe = nil
 }
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"storage"
+)
+
+// Names of engines to be used in node configuration.
+//
+// Названия engines, используемые в конфигурации node.
+const (
+	MapName     = "map"
+	LogName     = "log"
+	ShardedName = "sharded"
+)
+
+// Engine is the common interface of record storages used by a node.
+// Engine has the same semantics as storage.Storage for records with byte keys
+// and must be safe for concurrent use.
+//
+// Engine -- общий интерфейс хранилищ записей, используемых node.
+// Семантика Engine совпадает с storage.Storage для записей с байтовыми
+// ключами, Engine должен быть безопасен для конкурентного использования.
+type Engine interface {
+	// Put stores a record, ErrRecordExists is returned if k is already stored.
+	// Put сохраняет запись, если k уже есть, возвращается ErrRecordExists.
+	Put(k storage.Key, d []byte) error
+	// Get returns the data of a record or ErrRecordNotFound.
+	// Get возвращает данные записи или ErrRecordNotFound.
+	Get(k storage.Key) ([]byte, error)
+	// Del deletes a record, ErrRecordNotFound is returned if k is not stored.
+	// Del удаляет запись, если k нет, возвращается ErrRecordNotFound.
+	Del(k storage.Key) error
+
+	// Set stores a record replacing the existing one if any.
+	// Set сохраняет запись, заменяя существующую, если она есть.
+	Set(k storage.Key, d []byte) error
+
+	// Range calls f for every record until f returns false.
+	// f must not call methods of the engine.
+	// Range вызывает f для каждой записи, пока f не вернет false.
+	// f не должна вызывать методы engine.
+	Range(f func(k storage.Key, d []byte) bool)
+
+	// Close releases resources held by the engine.
+	// Close освобождает ресурсы, занятые engine.
+	Close() error
+}
+
+// Stats are the size of the records stored by an engine and its health.
+//
+// Stats -- размер записей, хранящихся в engine, и его состояние.
+type Stats struct {
+	// Records is a number of records.
+	// Records -- количество записей.
+	Records int
+	// Bytes is the total size of keys and data of the records.
+	// Bytes -- суммарный размер ключей и данных записей.
+	Bytes int64
+	// Err is the last error of writing to durable storage, nil if the engine is healthy.
+	// Err -- последняя ошибка записи в постоянное хранилище, nil, если engine исправен.
+	Err error
+}
+
+// StatsEngine is an Engine keeping its Stats up to date.
+//
+// StatsEngine -- Engine, поддерживающий актуальность своих Stats.
+type StatsEngine interface {
+	Engine
+
+	// Stats returns the current Stats of the engine.
+	// Stats возвращает текущие Stats engine.
+	Stats() Stats
+}
@@ -0,0 +1,262 @@
+package engine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+
+	"storage"
+	"storage/wal"
+)
+
+func engines(t *testing.T) (map[string]Engine, func()) {
+	dir, err := ioutil.TempDir("", "engine")
+	if err != nil {
+		t.Fatalf("TempDir() error: %v", err)
+	}
+	l, err := OpenLog(LogOptions{
+		Options:       wal.Options{Dir: dir, Sync: wal.SyncNone},
+		SnapshotEvery: 10,
+	})
+	if err != nil {
+		t.Fatalf("OpenLog() error: %v", err)
+	}
+	return map[string]Engine{
+		MapName:     NewMap(),
+		ShardedName: NewSharded(4),
+		LogName:     l,
+	}, func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestPutGetDel(t *testing.T) {
+	es, cleanup := engines(t)
+	defer cleanup()
+
+	for name, e := range es {
+		t.Run(name, func(t *testing.T) {
+			key := storage.RecordID(1).Key()
+			data := []byte("some data")
+
+			if _, err := e.Get(key); err != storage.ErrRecordNotFound {
+				t.Fatalf("Get(): got error %v, want %v", err, storage.ErrRecordNotFound)
+			}
+			if err := e.Del(key); err != storage.ErrRecordNotFound {
+				t.Fatalf("Del(): got error %v, want %v", err, storage.ErrRecordNotFound)
+			}
+			if err := e.Put(key, data); err != nil {
+				t.Fatalf("Put() error: %v", err)
+			}
+			if err := e.Put(key, []byte("other data")); err != storage.ErrRecordExists {
+				t.Fatalf("Put() got error: %v, want %v", err, storage.ErrRecordExists)
+			}
+			got, err := e.Get(key)
+			if err != nil {
+				t.Fatalf("Get() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, data) {
+				t.Errorf("Wrong data: got %s, want %s", got, data)
+			}
+			if err := e.Del(key); err != nil {
+				t.Fatalf("Del() error %v", err)
+			}
+			if _, err := e.Get(key); err != storage.ErrRecordNotFound {
+				t.Errorf("Get(): got error %v, want %v", err, storage.ErrRecordNotFound)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	es, cleanup := engines(t)
+	defer cleanup()
+
+	for name, e := range es {
+		t.Run(name, func(t *testing.T) {
+			key := storage.RecordID(1).Key()
+			for _, d := range [][]byte{[]byte("some data"), []byte("other data")} {
+				if err := e.Set(key, d); err != nil {
+					t.Fatalf("Set() error: %v", err)
+				}
+				got, err := e.Get(key)
+				if err != nil {
+					t.Fatalf("Get() error: %v", err)
+				}
+				if !reflect.DeepEqual(got, d) {
+					t.Errorf("Wrong data: got %s, want %s", got, d)
+				}
+			}
+		})
+	}
+}
+
+func TestParallelOps(t *testing.T) {
+	es, cleanup := engines(t)
+	defer cleanup()
+
+	const (
+		workers = 8
+		n       = 200
+	)
+	for name, e := range es {
+		t.Run(name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := 0; i < n; i++ {
+						key := storage.RecordID(w*n + i).Key()
+						d := []byte(fmt.Sprintf("data%d", w*n+i))
+						if err := e.Put(key, d); err != nil {
+							t.Errorf("Put(%q) error: %v", key, err)
+							return
+						}
+						got, err := e.Get(key)
+						if err != nil || !reflect.DeepEqual(got, d) {
+							t.Errorf("Get(%q): got %s, %v, want %s", key, got, err, d)
+							return
+						}
+						if i%2 == 0 {
+							if err := e.Del(key); err != nil {
+								t.Errorf("Del(%q) error: %v", key, err)
+								return
+							}
+						}
+					}
+				}(w)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestRange(t *testing.T) {
+	es, cleanup := engines(t)
+	defer cleanup()
+
+	const n = 50
+	for name, e := range es {
+		t.Run(name, func(t *testing.T) {
+			want := make(map[storage.Key][]byte)
+			for i := 0; i < n; i++ {
+				key := storage.RecordID(i).Key()
+				d := []byte(fmt.Sprintf("data%d", i))
+				if err := e.Put(key, d); err != nil {
+					t.Fatalf("Put() error: %v", err)
+				}
+				want[key] = d
+			}
+
+			got := make(map[storage.Key][]byte)
+			e.Range(func(k storage.Key, d []byte) bool {
+				got[k] = d
+				return true
+			})
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Range() got %v records, want %v", len(got), len(want))
+			}
+
+			calls := 0
+			e.Range(func(k storage.Key, d []byte) bool {
+				calls++
+				return calls < 10
+			})
+			if calls != 10 {
+				t.Errorf("Range() didn't stop: f was called %d times, want 10", calls)
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	es, cleanup := engines(t)
+	defer cleanup()
+
+	for name, e := range es {
+		t.Run(name, func(t *testing.T) {
+			se, ok := e.(StatsEngine)
+			if !ok {
+				t.Fatalf("%T is not a StatsEngine", e)
+			}
+			for i := 0; i < 20; i++ {
+				if err := e.Put(storage.RecordID(i).Key(), []byte("data")); err != nil {
+					t.Fatalf("Put() error: %v", err)
+				}
+			}
+			if err := e.Set(storage.RecordID(0).Key(), []byte("more data")); err != nil {
+				t.Fatalf("Set() error: %v", err)
+			}
+			if err := e.Set(storage.RecordID(20).Key(), []byte("new")); err != nil {
+				t.Fatalf("Set() error: %v", err)
+			}
+			if err := e.Del(storage.RecordID(1).Key()); err != nil {
+				t.Fatalf("Del() error: %v", err)
+			}
+
+			want := Stats{Records: 20, Bytes: 18*(4+4) + (4 + 9) + (4 + 3)}
+			if got := se.Stats(); got != want {
+				t.Errorf("Stats() got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLog_ByteKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "engine")
+	if err != nil {
+		t.Fatalf("TempDir() error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	opts := LogOptions{
+		Options:       wal.Options{Dir: dir, Sync: wal.SyncNone},
+		SnapshotEvery: 4,
+	}
+	l, err := OpenLog(opts)
+	if err != nil {
+		t.Fatalf("OpenLog() error: %v", err)
+	}
+	// Four-byte keys are logged as RecordIDs were, others with their length.
+	keys := []storage.Key{"", "a", "abcd", storage.RecordID(7).Key(), "user:1", storage.Key(make([]byte, 300))}
+	want := make(map[storage.Key][]byte)
+	for i, k := range keys {
+		d := []byte(fmt.Sprintf("data%d", i))
+		if err := l.Put(k, d); err != nil {
+			t.Fatalf("Put(%q) error: %v", k, err)
+		}
+		want[k] = d
+	}
+	if err := l.Del("a"); err != nil {
+		t.Fatalf("Del() error: %v", err)
+	}
+	delete(want, "a")
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	l, err = OpenLog(opts)
+	if err != nil {
+		t.Fatalf("OpenLog() error: %v", err)
+	}
+	defer l.Close()
+	got := make(map[storage.Key][]byte)
+	l.Range(func(k storage.Key, d []byte) bool {
+		got[k] = d
+		return true
+	})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Recovered records %q, want %q", got, want)
+	}
+	var bytes int64
+	for k, d := range want {
+		bytes += int64(len(k) + len(d))
+	}
+	if got, want := l.Stats(), (Stats{Records: len(want), Bytes: bytes}); got != want {
+		t.Errorf("Stats() of the recovered log got %+v, want %+v", got, want)
+	}
+}
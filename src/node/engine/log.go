@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"log"
+	"sync"
+
+	"storage"
+	"storage/wal"
+)
+
+// LogOptions stores configuration for a Log.
+//
+// LogOptions -- содержит конфигурацию Log.
+type LogOptions struct {
+	wal.Options
+
+	// SnapshotEvery is a number of log records after which a snapshot is taken.
+	// Snapshots are not taken if SnapshotEvery is zero.
+	// SnapshotEvery -- количество записей в логе, после которого делается snapshot.
+	// Если SnapshotEvery равен нулю, snapshots не делаются.
+	SnapshotEvery int
+}
+
+// Log is a log-structured on-disk Engine. Every change is appended to
+// a write-ahead log before it is applied to the in-memory index,
+// the log is compacted into a snapshot every LogOptions.SnapshotEvery records.
+//
+// Log -- Engine, хранящий записи на диске в виде лога. Каждое изменение
+// дописывается в write-ahead лог перед применением к индексу в памяти,
+// каждые LogOptions.SnapshotEvery записей лог сжимается в snapshot.
+type Log struct {
+	opts LogOptions
+
+	records map[storage.Key][]byte
+	bytes   int64
+	lock    sync.RWMutex
+
+	wal *wal.Log
+	// err is the last failure to append to the log or to take a snapshot.
+	err error
+}
+
+// OpenLog creates a Log recovering records stored in opts.Dir.
+//
+// OpenLog создает Log, восстанавливая записи, сохраненные в opts.Dir.
+func OpenLog(opts LogOptions) (*Log, error) {
+	l := &Log{
+		opts:    opts,
+		records: make(map[storage.Key][]byte, 100),
+	}
+	w, err := wal.Open(opts.Options, l.apply)
+	if err != nil {
+		return nil, err
+	}
+	l.wal = w
+	return l, nil
+}
+
+func (l *Log) apply(r wal.Record) {
+	if d, ok := l.records[r.Key]; ok {
+		l.bytes -= int64(len(r.Key) + len(d))
+	}
+	switch r.Op {
+	case wal.OpPut:
+		l.records[r.Key] = r.Data
+		l.bytes += int64(len(r.Key) + len(r.Data))
+	case wal.OpDel:
+		delete(l.records, r.Key)
+	}
+}
+
+// write logs r and applies it, the caller must hold l.lock.
+func (l *Log) write(r wal.Record) error {
+	if err := l.wal.Append(r); err != nil {
+		l.err = err
+		return err
+	}
+	l.apply(r)
+	l.err = nil
+
+	if l.opts.SnapshotEvery > 0 && l.wal.Len() >= l.opts.SnapshotEvery {
+		err := l.wal.Snapshot(func(put func(storage.Key, []byte) error) error {
+			for k, d := range l.records {
+				if err := put(k, d); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			// The record is already in the log, so it is safe to retry later.
+			log.Printf("Failed to take snapshot: %v", err)
+			l.err = err
+		}
+	}
+	return nil
+}
+
+// Put appends a record to the log and stores it, ErrRecordExists
+// is returned if k is already stored.
+//
+// Put дописывает запись в лог и сохраняет ее, если k уже есть,
+// возвращается ErrRecordExists.
+func (l *Log) Put(k storage.Key, d []byte) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if _, ok := l.records[k]; ok {
+		return storage.ErrRecordExists
+	}
+	return l.write(wal.Record{Op: wal.OpPut, Key: k, Data: d})
+}
+
+// Set appends a record to the log and stores it replacing the existing one if any.
+//
+// Set дописывает запись в лог и сохраняет ее, заменяя существующую, если она есть.
+func (l *Log) Set(k storage.Key, d []byte) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.write(wal.Record{Op: wal.OpPut, Key: k, Data: d})
+}
+
+// Del appends a deletion to the log and deletes a record,
+// ErrRecordNotFound is returned if k is not stored.
+//
+// Del дописывает удаление в лог и удаляет запись,
+// если k нет, возвращается ErrRecordNotFound.
+func (l *Log) Del(k storage.Key) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if _, ok := l.records[k]; !ok {
+		return storage.ErrRecordNotFound
+	}
+	return l.write(wal.Record{Op: wal.OpDel, Key: k})
+}
+
+// Get returns the data of a record from the in-memory index or ErrRecordNotFound.
+//
+// Get возвращает данные записи из индекса в памяти или ErrRecordNotFound.
+func (l *Log) Get(k storage.Key) ([]byte, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	data, ok := l.records[k]
+	if !ok {
+		return nil, storage.ErrRecordNotFound
+	}
+	return data, nil
+}
+
+// Range calls f for every record until f returns false.
+//
+// Range вызывает f для каждой записи, пока f не вернет false.
+func (l *Log) Range(f func(k storage.Key, d []byte) bool) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	for k, d := range l.records {
+		if !f(k, d) {
+			return
+		}
+	}
+}
+
+// Stats returns the number and the size of the records and the last
+// failure to write the log or a snapshot.
+//
+// Stats возвращает количество и размер записей и последнюю
+// ошибку записи лога или snapshot.
+func (l *Log) Stats() Stats {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return Stats{Records: len(l.records), Bytes: l.bytes, Err: l.err}
+}
+
+// Close flushes and closes the write-ahead log.
+//
+// Close сбрасывает на диск и закрывает write-ahead лог.
+func (l *Log) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.wal.Close()
+}
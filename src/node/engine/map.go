@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"sync"
+
+	"storage"
+)
+
+// Map is an in-memory Engine based on a map.
+//
+// Map -- Engine, хранящий записи в памяти в map.
+type Map struct {
+	records map[storage.Key][]byte
+	bytes   int64
+	lock    sync.RWMutex
+}
+
+// NewMap creates a new empty Map.
+//
+// NewMap создает новый пустой Map.
+func NewMap() *Map {
+	return &Map{
+		records: make(map[storage.Key][]byte, 100),
+	}
+}
+
+// Put stores a record, ErrRecordExists is returned if k is already stored.
+//
+// Put сохраняет запись, если k уже есть, возвращается ErrRecordExists.
+func (m *Map) Put(k storage.Key, d []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.records[k]; ok {
+		return storage.ErrRecordExists
+	}
+	m.records[k] = d
+	m.bytes += int64(len(k) + len(d))
+	return nil
+}
+
+// Set stores a record replacing the existing one if any.
+//
+// Set сохраняет запись, заменяя существующую, если она есть.
+func (m *Map) Set(k storage.Key, d []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if old, ok := m.records[k]; ok {
+		m.bytes -= int64(len(k) + len(old))
+	}
+	m.records[k] = d
+	m.bytes += int64(len(k) + len(d))
+	return nil
+}
+
+// Del deletes a record, ErrRecordNotFound is returned if k is not stored.
+//
+// Del удаляет запись, если k нет, возвращается ErrRecordNotFound.
+func (m *Map) Del(k storage.Key) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	d, ok := m.records[k]
+	if !ok {
+		return storage.ErrRecordNotFound
+	}
+	delete(m.records, k)
+	m.bytes -= int64(len(k) + len(d))
+	return nil
+}
+
+// Get returns the data of a record or ErrRecordNotFound.
+//
+// Get возвращает данные записи или ErrRecordNotFound.
+func (m *Map) Get(k storage.Key) ([]byte, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	data, ok := m.records[k]
+	if !ok {
+		return nil, storage.ErrRecordNotFound
+	}
+	return data, nil
+}
+
+// Range calls f for every record until f returns false.
+//
+// Range вызывает f для каждой записи, пока f не вернет false.
+func (m *Map) Range(f func(k storage.Key, d []byte) bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	for k, d := range m.records {
+		if !f(k, d) {
+			return
+		}
+	}
+}
+
+// Stats returns the number and the size of the records.
+//
+// Stats возвращает количество и размер записей.
+func (m *Map) Stats() Stats {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return Stats{Records: len(m.records), Bytes: m.bytes}
+}
+
+// Close does nothing, records are kept only in memory.
+//
+// Close ничего не делает, записи хранятся только в памяти.
+func (m *Map) Close() error {
+	return nil
+}
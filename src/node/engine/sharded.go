@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"hash/fnv"
+
+	"storage"
+)
+
+// DefaultShards is a number of shards used if none is specified.
+//
+// DefaultShards -- количество shards, используемое по умолчанию.
+const DefaultShards = 16
+
+// Sharded is an in-memory Engine which splits records between
+// several independently locked Maps to reduce lock contention.
+//
+// Sharded -- Engine, хранящий записи в памяти и распределяющий их
+// между несколькими Map с независимыми блокировками.
+type Sharded struct {
+	shards []*Map
+}
+
+// NewSharded creates a new empty Sharded with n shards.
+// DefaultShards is used if n is not positive.
+//
+// NewSharded создает новый пустой Sharded с n shards.
+// Если n не положительно, используется DefaultShards.
+func NewSharded(n int) *Sharded {
+	if n <= 0 {
+		n = DefaultShards
+	}
+	s := &Sharded{
+		shards: make([]*Map, n),
+	}
+	for i := range s.shards {
+		s.shards[i] = NewMap()
+	}
+	return s
+}
+
+func (s *Sharded) shard(k storage.Key) *Map {
+	h := fnv.New32a()
+	h.Write([]byte(k))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// Put stores a record in the shard of k, ErrRecordExists is returned
+// if k is already stored.
+//
+// Put сохраняет запись в shard для k, если k уже есть,
+// возвращается ErrRecordExists.
+func (s *Sharded) Put(k storage.Key, d []byte) error {
+	return s.shard(k).Put(k, d)
+}
+
+// Set stores a record in the shard of k replacing the existing one if any.
+//
+// Set сохраняет запись в shard для k, заменяя существующую, если она есть.
+func (s *Sharded) Set(k storage.Key, d []byte) error {
+	return s.shard(k).Set(k, d)
+}
+
+// Del deletes a record from the shard of k, ErrRecordNotFound is returned
+// if k is not stored.
+//
+// Del удаляет запись из shard для k, если k нет,
+// возвращается ErrRecordNotFound.
+func (s *Sharded) Del(k storage.Key) error {
+	return s.shard(k).Del(k)
+}
+
+// Get returns the data of a record from the shard of k or ErrRecordNotFound.
+//
+// Get возвращает данные записи из shard для k или ErrRecordNotFound.
+func (s *Sharded) Get(k storage.Key) ([]byte, error) {
+	return s.shard(k).Get(k)
+}
+
+// Range calls f for every record of every shard until f returns false.
+//
+// Range вызывает f для каждой записи каждого shard, пока f не вернет false.
+func (s *Sharded) Range(f func(k storage.Key, d []byte) bool) {
+	for _, shard := range s.shards {
+		stopped := false
+		shard.Range(func(k storage.Key, d []byte) bool {
+			if !f(k, d) {
+				stopped = true
+				return false
+			}
+			return true
+		})
+		if stopped {
+			return
+		}
+	}
+}
+
+// Stats returns the number and the size of the records of all shards.
+//
+// Stats возвращает количество и размер записей всех shards.
+func (s *Sharded) Stats() Stats {
+	var stats Stats
+	for _, shard := range s.shards {
+		ss := shard.Stats()
+		stats.Records += ss.Records
+		stats.Bytes += ss.Bytes
+	}
+	return stats
+}
+
+// Close does nothing, records are kept only in memory.
+//
+// Close ничего не делает, записи хранятся только в памяти.
+func (s *Sharded) Close() error {
+	return nil
+}
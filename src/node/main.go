@@ -9,6 +9,7 @@ import (
 
 	"node/node"
 	"router/client"
+	"router/router"
 	"storage"
 )
 
@@ -55,9 +56,30 @@ func main() {
 	}
 
 	cfg.Client = client.New()
+	cfg.NodeClient = storage.NewClient()
+	hasher, err := cfg.NewHasher()
+	if err != nil {
+		log.Fatalf("Failed to create hasher: %v", err)
+	}
+	cfg.NodesFinder = router.NewNodesFinder(hasher)
 
-	st := node.New(cfg)
+	st, err := node.Open(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open node storage: %v", err)
+	}
 	st.Heartbeats()
+	if cfg.RebalanceInterval > 0 {
+		st.Rebalancing()
+	}
+	if cfg.AntiEntropyInterval > 0 {
+		st.AntiEntropy()
+	}
+	if cfg.HintInterval > 0 {
+		st.HintedHandoff()
+	}
+	if cfg.ExpiryInterval > 0 {
+		st.Expirer()
+	}
 
 	srv := storage.NewServer(st, string(cfg.Addr))
 	if err := srv.ListenAndServe(); err != nil {
@@ -0,0 +1,420 @@
+package node
+
+import (
+	"context"
+	"log"
+	"time"
+
+	router "router/client"
+	rtr "router/router"
+	"storage"
+)
+
+// AntiEntropyStats describes anti-entropy rounds performed by the node.
+//
+// AntiEntropyStats описывает выполненные node раунды anti-entropy.
+type AntiEntropyStats struct {
+	// Rounds is a number of finished rounds.
+	// Rounds -- количество завершенных раундов.
+	Rounds int
+	// Exchanges is a number of Merkle tree exchanges with other replicas.
+	// Exchanges -- количество обменов деревьями Меркла с другими репликами.
+	Exchanges int
+	// FailedExchanges is a number of exchanges which failed.
+	// FailedExchanges -- количество неудавшихся обменов.
+	FailedExchanges int
+	// DivergentKeys is a number of keys found to differ between replicas.
+	// DivergentKeys -- количество ключей, различающихся между репликами.
+	DivergentKeys int
+	// RepairedKeys is a number of keys whose replicas were repaired.
+	// RepairedKeys -- количество ключей, реплики которых были исправлены.
+	RepairedKeys int
+	// UnresolvedKeys is a number of divergent keys the replicas
+	// of which don't reach a quorum.
+	// UnresolvedKeys -- количество различающихся ключей, реплики
+	// которых не достигают кворума.
+	UnresolvedKeys int
+}
+
+// AntiEntropy starts comparing records of the node with other replicas
+// each time interval set by cfg.AntiEntropyInterval. For each other node
+// Merkle trees over records replicated to both nodes are exchanged,
+// and keys within differing leaves are compared. A divergent key is
+// deleted from all its replicas if a quorum of them lacks it, otherwise
+// the versions of all replicas are reconciled and merged into each of them.
+// AntiEntropy stops when the node is closed.
+//
+// AntiEntropy запускает сравнение записей node с другими репликами через
+// каждый интервал времени, заданный в cfg.AntiEntropyInterval. С каждой
+// другой node происходит обмен деревьями Меркла над записями, реплицированными
+// на обе node, и сравниваются ключи из различающихся листьев. Различающийся
+// ключ удаляется со всех реплик, если его нет у кворума из них, иначе
+// версии всех реплик согласуются и объединяются с каждой из них.
+// AntiEntropy останавливается при закрытии node.
+func (node *Node) AntiEntropy() {
+	tc, ok := node.cfg.Client.(router.TopologyClient)
+	if !ok {
+		log.Printf("Anti-entropy is disabled: router client doesn't report topology")
+		return
+	}
+	if _, ok := node.cfg.NodeClient.(antiEntropyClient); !ok {
+		log.Printf("Anti-entropy is disabled: node client doesn't support Merkle trees and scans")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-node.done
+		cancel()
+	}()
+
+	go func() {
+		t := time.NewTicker(node.cfg.AntiEntropyInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				return
+			}
+
+			node.refreshReplication(ctx)
+			nodes, _, err := tc.TopologyContext(ctx, node.cfg.Router)
+			if err != nil {
+				log.Printf("Failed to get topology: %v", err)
+				continue
+			}
+			node.antiEntropyRound(ctx, nodes)
+		}
+	}()
+}
+
+// AntiEntropyStats returns statistics of anti-entropy rounds.
+//
+// AntiEntropyStats возвращает статистику раундов anti-entropy.
+func (node *Node) AntiEntropyStats() AntiEntropyStats {
+	node.aeLock.Lock()
+	defer node.aeLock.Unlock()
+	return node.aeStats
+}
+
+func (node *Node) updateStats(f func(s *AntiEntropyStats)) {
+	node.aeLock.Lock()
+	defer node.aeLock.Unlock()
+	f(&node.aeStats)
+}
+
+// TreeContext builds a Merkle tree over records of the node
+// which are replicated to opts.Peer as well.
+//
+// TreeContext строит дерево Меркла над записями node,
+// которые также реплицированы на opts.Peer.
+func (node *Node) TreeContext(ctx context.Context, opts storage.TreeOptions) (*storage.MerkleTree, error) {
+	if node.cfg.NodesFinder == (rtr.NodesFinder{}) {
+		return nil, storage.ErrTreeNotSupported
+	}
+	tree := storage.NewMerkleTree(opts.Depth)
+	now := time.Now()
+	node.engine.Range(func(k storage.Key, d []byte) bool {
+		if !node.shared(k, opts.Peer, opts.Nodes) {
+			return ctx.Err() == nil
+		}
+		// Replicas reclaim expired versions independently,
+		// they don't count as a divergence.
+		if d, ok := unexpiredValue(d, now); ok {
+			tree.Add(k, d)
+		}
+		return ctx.Err() == nil
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+type antiEntropyClient interface {
+	storage.Client
+	storage.ScanClient
+	storage.TreeClient
+}
+
+// shared reports whether k is replicated to both the node and peer.
+func (node *Node) shared(k storage.Key, peer storage.ServiceAddr, nodes []storage.ServiceAddr) bool {
+	owners := node.nodesFinder().NodesFindKey(k, nodes)
+	return contains(owners, node.cfg.Addr) && contains(owners, peer)
+}
+
+func (node *Node) antiEntropyRound(ctx context.Context, nodes []storage.ServiceAddr) {
+	for _, peer := range nodes {
+		if peer == node.cfg.Addr {
+			continue
+		}
+		keys, err := node.divergentKeys(ctx, peer, nodes)
+		if ctx.Err() != nil {
+			return
+		}
+		node.updateStats(func(s *AntiEntropyStats) {
+			s.Exchanges++
+			if err != nil {
+				s.FailedExchanges++
+			}
+			s.DivergentKeys += len(keys)
+		})
+		if err != nil {
+			log.Printf("Anti-entropy with %q failed: %v", peer, err)
+			continue
+		}
+
+		for _, k := range keys {
+			repaired, err := node.repair(ctx, k, nodes)
+			if err != nil {
+				log.Printf("Failed to repair record %q: %v", k, err)
+			}
+			node.updateStats(func(s *AntiEntropyStats) {
+				if repaired {
+					s.RepairedKeys++
+				} else if err == storage.ErrQuorumNotReached {
+					s.UnresolvedKeys++
+				}
+			})
+		}
+	}
+	node.updateStats(func(s *AntiEntropyStats) { s.Rounds++ })
+}
+
+// divergentKeys exchanges Merkle trees with peer and returns keys
+// replicated to both nodes which differ between them.
+func (node *Node) divergentKeys(ctx context.Context, peer storage.ServiceAddr, nodes []storage.ServiceAddr) ([]storage.Key, error) {
+	nc := node.cfg.NodeClient.(antiEntropyClient)
+
+	local, err := node.TreeContext(ctx, storage.TreeOptions{Peer: peer, Nodes: nodes, Depth: storage.MerkleDepth})
+	if err != nil {
+		return nil, err
+	}
+	remote, err := nc.TreeContext(ctx, peer, storage.TreeOptions{Peer: node.cfg.Addr, Nodes: nodes, Depth: storage.MerkleDepth})
+	if err != nil {
+		return nil, err
+	}
+	ranges, err := local.Diff(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []storage.Key
+	for _, r := range ranges {
+		records := make(map[storage.Key][]byte)
+		if _, err := node.ScanContext(ctx, r, func(k storage.Key, d []byte) error {
+			if node.shared(k, peer, nodes) {
+				records[k] = d
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+
+		seen := make(map[storage.Key]bool)
+		// Scans show a single version of a record, so records with
+		// siblings are compared with all their versions by repair.
+		for k := range records {
+			if versions, err := node.GetVersions(k); err == nil && len(versions) > 1 {
+				keys = append(keys, k)
+				seen[k] = true
+			}
+		}
+		if _, err := nc.ScanContext(ctx, peer, r, func(k storage.Key, d []byte) error {
+			if !node.shared(k, peer, nodes) {
+				return nil
+			}
+			if seen[k] {
+				return nil
+			}
+			seen[k] = true
+			if ld, ok := records[k]; !ok || string(ld) != string(d) {
+				keys = append(keys, k)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		for k := range records {
+			if !seen[k] {
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// replica is a state of a record on one of the nodes it's replicated to.
+type replica struct {
+	node     storage.ServiceAddr
+	versions []storage.Version
+	found    bool
+}
+
+// repair brings all replicas of k to the same state and reports whether
+// any replica was changed. If a quorum of replicas lacks the record,
+// it's deleted from the others. Otherwise the versions of all replicas
+// are reconciled and merged into each diverging replica with Update,
+// so newer and concurrent versions held by a minority are kept. Versions
+// with equal clocks and different data aren't ordered by their clocks,
+// records written without versions diverge so, and the versions held by
+// a quorum replace them.
+func (node *Node) repair(ctx context.Context, k storage.Key, nodes []storage.ServiceAddr) (bool, error) {
+	var replicas []replica
+	var sets [][]storage.Version
+	votes := make(map[string]int)
+	needed := node.replication().Quorum
+	for _, owner := range node.nodesFinder().NodesFindKey(k, nodes) {
+		versions, err := node.getReplica(ctx, owner, k)
+		if err != nil && err != storage.ErrRecordNotFound {
+			continue
+		}
+		r := replica{node: owner, versions: versions, found: err == nil}
+		replicas = append(replicas, r)
+		votes[r.key()]++
+		if r.found {
+			sets = append(sets, versions)
+		}
+	}
+
+	var quorum *replica
+	for i, r := range replicas {
+		if votes[r.key()] >= needed {
+			quorum = &replicas[i]
+			break
+		}
+	}
+
+	if quorum != nil && !quorum.found {
+		repaired := false
+		for _, r := range replicas {
+			if !r.found {
+				continue
+			}
+			if err := node.delReplica(ctx, r.node, k); err != nil && err != storage.ErrRecordNotFound {
+				return repaired, err
+			}
+			repaired = true
+		}
+		return repaired, nil
+	}
+	if quorum == nil && len(sets) < needed {
+		return false, storage.ErrQuorumNotReached
+	}
+
+	target := replica{versions: storage.Reconcile(sets...), found: true}
+	if collide(sets) {
+		if quorum == nil {
+			return false, storage.ErrQuorumNotReached
+		}
+		target = *quorum
+	}
+
+	repaired := false
+	for _, r := range replicas {
+		if r.key() == target.key() {
+			continue
+		}
+		if err := node.mergeReplica(ctx, r, k, target.versions); err != nil {
+			return repaired, err
+		}
+		repaired = true
+	}
+	return repaired, nil
+}
+
+// collide reports whether the sets hold versions with equal clocks
+// and different data.
+func collide(sets [][]storage.Version) bool {
+	var all []storage.Version
+	for _, set := range sets {
+		all = append(all, set...)
+	}
+	for i, a := range all {
+		for _, b := range all[i+1:] {
+			if a.Clock.Compare(b.Clock) == storage.Equal && string(a.Data) != string(b.Data) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mergeReplica brings versions to the replica r with Update. A version
+// of r having the clock of one of versions but different data is replaced
+// with it by a conditional write, so the replica never lacks the record.
+func (node *Node) mergeReplica(ctx context.Context, r replica, k storage.Key, versions []storage.Version) error {
+	var rest []storage.Version
+	for _, v := range versions {
+		replaced := false
+		for _, s := range r.versions {
+			if v.Clock.Compare(s.Clock) == storage.Equal && string(v.Data) != string(s.Data) {
+				if err := node.replaceReplica(ctx, r.node, k, storage.Condition{Clock: s.Clock}, v); err != nil {
+					return err
+				}
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			rest = append(rest, v)
+		}
+	}
+	if len(rest) == 0 {
+		return nil
+	}
+	return node.updateReplica(ctx, r.node, k, rest)
+}
+
+// key distinguishes states of replicas, missing records are distinct from any versions.
+func (r replica) key() string {
+	if !r.found {
+		return ""
+	}
+	return string(encodeVersions(storage.Reconcile(r.versions)))
+}
+
+func (node *Node) getReplica(ctx context.Context, owner storage.ServiceAddr, k storage.Key) ([]storage.Version, error) {
+	if owner == node.cfg.Addr {
+		return node.GetVersions(k)
+	}
+	if vc, ok := node.cfg.NodeClient.(storage.VersionedClient); ok {
+		return vc.GetVersionsContext(ctx, owner, k)
+	}
+	d, err := storage.WithKeys(node.cfg.NodeClient).GetKeyContext(ctx, owner, k)
+	if err != nil {
+		return nil, err
+	}
+	return []storage.Version{{Clock: storage.VectorClock{}, Data: d}}, nil
+}
+
+func (node *Node) updateReplica(ctx context.Context, owner storage.ServiceAddr, k storage.Key, versions []storage.Version) error {
+	if owner == node.cfg.Addr {
+		for _, v := range versions {
+			if err := node.Update(k, v); err != nil && err != storage.ErrObsoleteVersion {
+				return err
+			}
+		}
+		return nil
+	}
+	return node.sendVersions(ctx, owner, k, versions)
+}
+
+func (node *Node) replaceReplica(ctx context.Context, owner storage.ServiceAddr, k storage.Key, cond storage.Condition, v storage.Version) error {
+	if owner == node.cfg.Addr {
+		return node.PutIfVersion(k, cond, v)
+	}
+	cc, ok := node.cfg.NodeClient.(storage.ConditionalClient)
+	if !ok {
+		return storage.ErrConditionalNotSupported
+	}
+	return cc.PutIfVersionContext(ctx, owner, k, cond, v)
+}
+
+func (node *Node) delReplica(ctx context.Context, owner storage.ServiceAddr, k storage.Key) error {
+	if owner == node.cfg.Addr {
+		return node.DelKey(k)
+	}
+	return storage.WithKeys(node.cfg.NodeClient).DelKeyContext(ctx, owner, k)
+}
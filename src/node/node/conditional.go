@@ -0,0 +1,139 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+
+	"storage"
+)
+
+// PutIfVersion replaces all versions of a record with v if the record
+// satisfies cond. Returns the storage.ErrConditionFailed error otherwise.
+//
+// PutIfVersion заменяет все версии записи на v, если запись удовлетворяет
+// cond. Иначе возвращает ошибку storage.ErrConditionFailed.
+func (node *Node) PutIfVersion(k storage.Key, cond storage.Condition, v storage.Version) error {
+	lock := node.lock(k)
+	lock.Lock()
+	defer lock.Unlock()
+
+	versions, err := node.GetVersions(k)
+	if err != nil && err != storage.ErrRecordNotFound {
+		return err
+	}
+	if !cond.Holds(versions) {
+		return storage.ErrConditionFailed
+	}
+	return node.engine.Set(k, encodeVersions([]storage.Version{v}))
+}
+
+// PutIfVersionContext is PutIfVersion which is not performed if ctx is already done.
+//
+// PutIfVersionContext -- PutIfVersion, который не выполняется, если ctx уже завершен.
+func (node *Node) PutIfVersionContext(ctx context.Context, k storage.Key, cond storage.Condition, v storage.Version) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	atomic.AddUint64(&node.writes, 1)
+	return node.PutIfVersion(k, cond, v)
+}
+
+// CompareAndSwap replaces a record having the single version with clock
+// expected by v. Returns the storage.ErrConditionFailed error if the record
+// doesn't exist, has another version or concurrent versions.
+//
+// CompareAndSwap заменяет запись, имеющую единственную версию с часами
+// expected, на v. Возвращает ошибку storage.ErrConditionFailed, если записи
+// нет, у нее другая версия или параллельные версии.
+func (node *Node) CompareAndSwap(k storage.Key, expected storage.VectorClock, v storage.Version) error {
+	return node.PutIfVersion(k, storage.Condition{Clock: expected}, v)
+}
+
+// DeleteIfVersion deletes a record if it satisfies cond. Returns
+// the storage.ErrConditionFailed error otherwise, and
+// the storage.ErrRecordNotFound error if the record doesn't exist.
+//
+// DeleteIfVersion удаляет запись, если она удовлетворяет cond. Иначе
+// возвращает ошибку storage.ErrConditionFailed, и ошибку
+// storage.ErrRecordNotFound, если записи не существует.
+func (node *Node) DeleteIfVersion(k storage.Key, cond storage.Condition) error {
+	lock := node.lock(k)
+	lock.Lock()
+	defer lock.Unlock()
+
+	versions, err := node.GetVersions(k)
+	if err != nil && err != storage.ErrRecordNotFound {
+		return err
+	}
+	if !cond.Holds(versions) {
+		return storage.ErrConditionFailed
+	}
+	if versions == nil {
+		// An expired record which isn't reclaimed yet is deleted.
+		node.engine.Del(k)
+		return storage.ErrRecordNotFound
+	}
+	return node.engine.Del(k)
+}
+
+// DeleteIfVersionContext is DeleteIfVersion which is not performed if ctx is already done.
+//
+// DeleteIfVersionContext -- DeleteIfVersion, который не выполняется, если ctx уже завершен.
+func (node *Node) DeleteIfVersionContext(ctx context.Context, k storage.Key, cond storage.Condition) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	atomic.AddUint64(&node.writes, 1)
+	return node.DeleteIfVersion(k, cond)
+}
+
+// Upsert replaces all versions of a record with v, creating the record
+// if it doesn't exist. Unlike Update, versions concurrent with v are dropped.
+// Returns the storage.ErrObsoleteVersion error if a stored version descends v,
+// and the storage.ErrConflict error if a stored version has the clock of v
+// but different data.
+//
+// Upsert заменяет все версии записи на v, создавая запись, если ее
+// не существует. В отличие от Update, версии, параллельные v, удаляются.
+// Возвращает ошибку storage.ErrObsoleteVersion, если сохраненная версия
+// наследует v, и ошибку storage.ErrConflict, если у сохраненной версии
+// часы v, но другие данные.
+func (node *Node) Upsert(k storage.Key, v storage.Version) error {
+	lock := node.lock(k)
+	lock.Lock()
+	defer lock.Unlock()
+
+	versions, err := node.GetVersions(k)
+	if err == storage.ErrRecordNotFound {
+		return node.engine.Set(k, encodeVersions([]storage.Version{v}))
+	}
+	if err != nil {
+		return err
+	}
+	for _, s := range versions {
+		switch v.Clock.Compare(s.Clock) {
+		case storage.Equal:
+			if !bytes.Equal(v.Data, s.Data) {
+				return storage.ErrConflict
+			}
+			if len(versions) == 1 {
+				return nil
+			}
+		case storage.Before:
+			return storage.ErrObsoleteVersion
+		}
+	}
+	return node.engine.Set(k, encodeVersions([]storage.Version{v}))
+}
+
+// UpsertContext is Upsert which is not performed if ctx is already done.
+//
+// UpsertContext -- Upsert, который не выполняется, если ctx уже завершен.
+func (node *Node) UpsertContext(ctx context.Context, k storage.Key, v storage.Version) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	atomic.AddUint64(&node.writes, 1)
+	return node.Upsert(k, v)
+}
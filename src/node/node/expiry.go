@@ -0,0 +1,139 @@
+package node
+
+import (
+	"bytes"
+	"log"
+	"time"
+
+	"storage"
+)
+
+// ExpiryStats describes records reclaimed by the expirer.
+//
+// ExpiryStats описывает записи, удаленные expirer.
+type ExpiryStats struct {
+	// Sweeps is a number of completed sweeps.
+	// Sweeps -- количество завершенных проходов.
+	Sweeps int
+	// Expired is a number of reclaimed expired versions.
+	// Expired -- количество удаленных истекших версий.
+	Expired int
+}
+
+// Expirer starts reclaiming expired records each time interval set by
+// cfg.ExpiryInterval. Expired records are hidden from reads before they
+// are reclaimed. Expirer stops when the node is closed.
+//
+// Expirer запускает удаление истекших записей через каждый интервал времени,
+// заданный в cfg.ExpiryInterval. Истекшие записи скрыты от чтения до их
+// удаления. Expirer останавливается при закрытии node.
+func (node *Node) Expirer() {
+	go func() {
+		t := time.NewTicker(node.cfg.ExpiryInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+			case <-node.done:
+				return
+			}
+			if n := node.expire(time.Now()); n > 0 {
+				log.Printf("Reclaimed %d expired versions", n)
+			}
+		}
+	}()
+}
+
+// ExpiryStats returns statistics of the expirer.
+//
+// ExpiryStats возвращает статистику expirer.
+func (node *Node) ExpiryStats() ExpiryStats {
+	node.expLock.Lock()
+	defer node.expLock.Unlock()
+	return node.expStats
+}
+
+// expire reclaims versions expired by now and returns their number.
+func (node *Node) expire(now time.Time) int {
+	var keys []storage.Key
+	node.engine.Range(func(k storage.Key, d []byte) bool {
+		if hasExpired(d, now) {
+			keys = append(keys, k)
+		}
+		return true
+	})
+
+	expired := 0
+	for _, k := range keys {
+		n, err := node.expireRecord(k, now)
+		if err != nil {
+			log.Printf("Failed to reclaim expired record %q: %v", k, err)
+		}
+		expired += n
+	}
+
+	node.expLock.Lock()
+	defer node.expLock.Unlock()
+	node.expStats.Sweeps++
+	node.expStats.Expired += expired
+	return expired
+}
+
+// expireRecord removes versions of a record expired by now, and the record
+// itself if all of them have. Returns the number of removed versions.
+func (node *Node) expireRecord(k storage.Key, now time.Time) (int, error) {
+	lock := node.lock(k)
+	lock.Lock()
+	defer lock.Unlock()
+
+	b, err := node.engine.Get(k)
+	if err == storage.ErrRecordNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	versions, err := decodeVersions(b)
+	if err != nil {
+		return 0, err
+	}
+	live := storage.Unexpired(versions, now)
+	switch {
+	case len(live) == len(versions):
+		return 0, nil
+	case len(live) == 0:
+		err = node.engine.Del(k)
+	default:
+		err = node.engine.Set(k, encodeVersions(live))
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(versions) - len(live), nil
+}
+
+// hasExpired reports whether an engine value holds versions expired by now.
+func hasExpired(d []byte, now time.Time) bool {
+	if !bytes.HasPrefix(d, expiringMagic) {
+		return false
+	}
+	versions, err := decodeVersions(d)
+	if err != nil {
+		return false
+	}
+	return len(storage.Unexpired(versions, now)) < len(versions)
+}
+
+// unexpiredValue returns an engine value without versions expired by now,
+// false if all of them have.
+func unexpiredValue(d []byte, now time.Time) ([]byte, bool) {
+	if !hasExpired(d, now) {
+		return d, true
+	}
+	versions, _ := decodeVersions(d)
+	live := storage.Unexpired(versions, now)
+	if len(live) == 0 {
+		return nil, false
+	}
+	return encodeVersions(live), true
+}
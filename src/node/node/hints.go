@@ -0,0 +1,163 @@
+package node
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	router "router/client"
+	"storage"
+)
+
+// HintStats describes writes kept by the node for unavailable nodes.
+//
+// HintStats описывает записи, хранимые node для недоступных node.
+type HintStats struct {
+	// Stored is a number of hinted writes accepted by the node.
+	// Stored -- количество принятых node записей с подсказками.
+	Stored int
+	// Replayed is a number of hinted writes handed off to their nodes.
+	// Replayed -- количество записей с подсказками, переданных их node.
+	Replayed int
+	// Pending is a number of hinted writes waiting for their nodes.
+	// Pending -- количество записей с подсказками, ожидающих свои node.
+	Pending int
+}
+
+// hintedWrite is a write intended for another node.
+type hintedWrite struct {
+	del     bool
+	k       storage.Key
+	data    []byte
+	expires int64
+}
+
+// PutHintContext keeps a Put intended for the node hint until it's replayed
+// by HintedHandoff.
+//
+// PutHintContext сохраняет Put, предназначенный для node hint, пока он
+// не будет передан ей HintedHandoff.
+func (node *Node) PutHintContext(ctx context.Context, hint storage.ServiceAddr, k storage.Key, d []byte) error {
+	return node.addHint(ctx, hint, hintedWrite{k: k, data: d, expires: storage.ExpiryFromContext(ctx)})
+}
+
+// DelHintContext keeps a Del intended for the node hint until it's replayed
+// by HintedHandoff.
+//
+// DelHintContext сохраняет Del, предназначенный для node hint, пока он
+// не будет передан ей HintedHandoff.
+func (node *Node) DelHintContext(ctx context.Context, hint storage.ServiceAddr, k storage.Key) error {
+	return node.addHint(ctx, hint, hintedWrite{del: true, k: k})
+}
+
+func (node *Node) addHint(ctx context.Context, owner storage.ServiceAddr, h hintedWrite) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	atomic.AddUint64(&node.writes, 1)
+	if node.cfg.HintInterval <= 0 {
+		return storage.ErrHintNotSupported
+	}
+	node.hintLock.Lock()
+	defer node.hintLock.Unlock()
+	node.hints[owner] = append(node.hints[owner], h)
+	node.hintStats.Stored++
+	node.hintStats.Pending++
+	return nil
+}
+
+// HintStats returns statistics of hinted writes.
+//
+// HintStats возвращает статистику записей с подсказками.
+func (node *Node) HintStats() HintStats {
+	node.hintLock.Lock()
+	defer node.hintLock.Unlock()
+	return node.hintStats
+}
+
+// HintedHandoff starts replaying hinted writes each time interval set by
+// cfg.HintInterval. Writes are replayed in the order they were accepted,
+// once Router receives heartbeats of the nodes they are intended for.
+// Hints are kept in memory, writes they miss after a restart are repaired
+// by anti-entropy. HintedHandoff stops when the node is closed.
+//
+// HintedHandoff запускает передачу записей с подсказками через каждый интервал
+// времени, заданный в cfg.HintInterval. Записи передаются в порядке их получения,
+// как только Router получает heartbeats node, для которых они предназначены.
+// Подсказки хранятся в памяти, записи, потерянные при перезапуске, исправляются
+// anti-entropy. HintedHandoff останавливается при закрытии node.
+func (node *Node) HintedHandoff() {
+	hc, ok := node.cfg.Client.(router.HintedClient)
+	if !ok {
+		log.Printf("Hinted handoff is disabled: router client doesn't report alive nodes")
+		return
+	}
+
+	go func() {
+		t := time.NewTicker(node.cfg.HintInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+			case <-node.done:
+				return
+			}
+
+			if node.HintStats().Pending == 0 {
+				continue
+			}
+			alive, err := hc.Alive(node.cfg.Router)
+			if err != nil {
+				log.Printf("Failed to get alive nodes: %v", err)
+				continue
+			}
+			for _, owner := range alive {
+				node.replayHints(owner)
+			}
+		}
+	}()
+}
+
+// replayHints hands hinted writes off to owner until one of them fails.
+func (node *Node) replayHints(owner storage.ServiceAddr) {
+	for {
+		node.hintLock.Lock()
+		hints := node.hints[owner]
+		node.hintLock.Unlock()
+		if len(hints) == 0 {
+			return
+		}
+
+		h := hints[0]
+		nc := storage.WithKeys(node.cfg.NodeClient)
+		var err error
+		if h.del {
+			if err = nc.DelKeyContext(context.Background(), owner, h.k); err == storage.ErrRecordNotFound {
+				err = nil
+			}
+		} else {
+			ctx := context.Background()
+			if h.expires != 0 {
+				ctx = storage.WithExpiry(ctx, time.Unix(0, h.expires))
+			}
+			if err = nc.PutKeyContext(ctx, owner, h.k, h.data); err == storage.ErrRecordExists {
+				err = nil
+			}
+		}
+		if err != nil {
+			log.Printf("Failed to replay hinted write of record %q to %q: %v", h.k, owner, err)
+			return
+		}
+
+		node.hintLock.Lock()
+		// Only the replaying goroutine removes hints, new ones are appended.
+		node.hints[owner] = node.hints[owner][1:]
+		if len(node.hints[owner]) == 0 {
+			delete(node.hints, owner)
+		}
+		node.hintStats.Replayed++
+		node.hintStats.Pending--
+		node.hintLock.Unlock()
+	}
+}
@@ -0,0 +1,51 @@
+package node
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"node/engine"
+	"storage"
+)
+
+// Load returns the load of the node reported to the router in heartbeats:
+// the number and size of its records, the heap memory it allocated,
+// the rates of reads and writes served since the previous call of Load
+// and the last error of its engine.
+//
+// Load возвращает нагрузку node, передаваемую router в heartbeats:
+// количество и размер ее записей, выделенную ею память в куче, частоту
+// чтений и записей, обслуженных с предыдущего вызова Load, и последнюю
+// ошибку ее engine.
+func (node *Node) Load() storage.NodeLoad {
+	var load storage.NodeLoad
+	if se, ok := node.engine.(engine.StatsEngine); ok {
+		stats := se.Stats()
+		load.Records, load.Bytes = int64(stats.Records), stats.Bytes
+		if stats.Err != nil {
+			load.EngineError = stats.Err.Error()
+		}
+	} else {
+		node.engine.Range(func(k storage.Key, d []byte) bool {
+			load.Records++
+			load.Bytes += int64(len(k) + len(d))
+			return true
+		})
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	load.Memory = mem.HeapAlloc
+
+	reads, writes := atomic.LoadUint64(&node.reads), atomic.LoadUint64(&node.writes)
+	now := time.Now()
+	node.loadLock.Lock()
+	defer node.loadLock.Unlock()
+	if elapsed := now.Sub(node.loadSampled).Seconds(); elapsed > 0 {
+		load.ReadRate = float64(reads-node.loadReads) / elapsed
+		load.WriteRate = float64(writes-node.loadWrites) / elapsed
+	}
+	node.loadReads, node.loadWrites, node.loadSampled = reads, writes, now
+	return load
+}
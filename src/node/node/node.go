@@ -1,11 +1,19 @@
 package node
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"node/engine"
 	router "router/client"
+	rtr "router/router"
 	"storage"
+	"storage/wal"
 )
 
 // Config stores configuration for a Node service.
@@ -22,32 +30,235 @@ type Config struct {
 	// Heartbeat -- интервал между двумя heartbeats.
 	Heartbeat time.Duration
 
+	// Engine is a name of the record storage engine: map, sharded or log.
+	// If Engine is empty, log is used if DataDir is set and map otherwise.
+	// Engine -- название engine для хранения записей: map, sharded или log.
+	// Если Engine не задан, используется log, если задана DataDir, иначе map.
+	Engine string
+	// Shards is a number of shards of the sharded engine.
+	// Shards -- количество shards для engine sharded.
+	Shards int
+
+	// DataDir is a directory to keep the write-ahead log and snapshots
+	// of the log engine in.
+	// DataDir -- директория для write-ahead лога и snapshots engine log.
+	DataDir string `yaml:"data_dir"`
+	// Sync is a write-ahead log fsync policy: always, interval or none.
+	// Sync -- политика fsync для write-ahead лога: always, interval или none.
+	Sync wal.SyncMode
+	// SyncInterval is a time interval between fsyncs for the interval policy.
+	// SyncInterval -- интервал между fsync для политики interval.
+	SyncInterval time.Duration `yaml:"sync_interval"`
+	// SnapshotEvery is a number of log records after which a snapshot is taken.
+	// Snapshots are not taken if SnapshotEvery is zero.
+	// SnapshotEvery -- количество записей в логе, после которого делается snapshot.
+	// Если SnapshotEvery равен нулю, snapshots не делаются.
+	SnapshotEvery int `yaml:"snapshot_every"`
+
+	// RebalanceInterval is a time interval between checks of the topology
+	// served by Router. Records are not rebalanced if RebalanceInterval is zero.
+	// RebalanceInterval -- интервал между проверками топологии, обслуживаемой Router.
+	// Если RebalanceInterval равен нулю, перебалансировка не выполняется.
+	RebalanceInterval time.Duration `yaml:"rebalance_interval"`
+	// RebalanceRate limits a number of records sent to other nodes per second
+	// during rebalancing. The rate is not limited if RebalanceRate is zero.
+	// RebalanceRate -- ограничение количества записей, отправляемых другим node
+	// за секунду при перебалансировке. Если RebalanceRate равен нулю, оно не ограничено.
+	RebalanceRate int `yaml:"rebalance_rate"`
+
+	// AntiEntropyInterval is a time interval between anti-entropy rounds
+	// comparing records with other replicas. Anti-entropy is disabled
+	// if AntiEntropyInterval is zero.
+	// AntiEntropyInterval -- интервал между раундами anti-entropy, сравнивающими
+	// записи с другими репликами. Если AntiEntropyInterval равен нулю, anti-entropy отключена.
+	AntiEntropyInterval time.Duration `yaml:"anti_entropy_interval"`
+	// HintInterval is a time interval between attempts to hand writes kept
+	// for unavailable nodes off to them. Hinted writes are not accepted
+	// if HintInterval is zero.
+	// HintInterval -- интервал между попытками передать записи, сохраненные
+	// для недоступных node. Если HintInterval равен нулю, записи с подсказками
+	// не принимаются.
+	HintInterval time.Duration `yaml:"hint_interval"`
+	// ExpiryInterval is a time interval between sweeps reclaiming expired
+	// records. Expired records are hidden but not reclaimed if ExpiryInterval is zero.
+	// ExpiryInterval -- интервал между проходами, удаляющими истекшие записи.
+	// Если ExpiryInterval равен нулю, истекшие записи скрываются, но не удаляются.
+	ExpiryInterval time.Duration `yaml:"expiry_interval"`
+
+	// HasherOptions select the Hasher main creates NodesFinder with,
+	// it has to be the one of Router.
+	// HasherOptions выбирают Hasher, с которым main создает NodesFinder,
+	// он должен совпадать с Hasher Router.
+	rtr.HasherOptions `yaml:",inline"`
+
 	// Client specifies client for Router.
 	// Client -- клиент для Router.
 	Client router.Client `yaml:"-"`
+	// NodeClient specifies client for other nodes.
+	// NodeClient -- клиент для других node.
+	NodeClient storage.Client `yaml:"-"`
+	// NodesFinder specifies a NodesFinder to use.
+	// NodesFinder -- NodesFinder, который нужно использовать в Node.
+	NodesFinder rtr.NodesFinder `yaml:"-"`
 }
 
 // Node is a Node service.
 type Node struct {
+	// reads and writes count requests served by the node, they are accessed
+	// atomically and come first to be 64-bit aligned.
+	reads  uint64
+	writes uint64
+
 	cfg    Config
 	hbStop chan struct{}
 
-	storage map[storage.RecordID][]byte
-	lock    sync.RWMutex
+	engine engine.Engine
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	rbLock     sync.Mutex
+	rbProgress RebalanceProgress
+
+	aeLock  sync.Mutex
+	aeStats AntiEntropyStats
+
+	locks [lockStripes]sync.Mutex
+
+	repLock   sync.Mutex
+	rep       storage.Replication
+	placement storage.Placement
+
+	hintLock  sync.Mutex
+	hints     map[storage.ServiceAddr][]hintedWrite
+	hintStats HintStats
+
+	expLock  sync.Mutex
+	expStats ExpiryStats
+
+	// loadLock guards the request counts reported in the previous Load
+	// and the time it was sampled at.
+	loadLock    sync.Mutex
+	loadReads   uint64
+	loadWrites  uint64
+	loadSampled time.Time
 }
 
 // New creates a new Node with a given cfg.
+// Panics if the engine can't be opened, use Open to handle the error.
 //
 // New создает новый Node с данным cfg.
+// Паникует, если не удалось открыть engine,
+// для обработки ошибки используйте Open.
 func New(cfg Config) *Node {
-	n := &Node{
-		cfg:     cfg,
-		hbStop:  make(chan struct{}),
-		storage: make(map[storage.RecordID][]byte, 100),
+	n, err := Open(cfg)
+	if err != nil {
+		panic(err)
 	}
 	return n
 }
 
+// Open creates a new Node with a given cfg opening the engine selected by cfg.Engine.
+//
+// Open создает новый Node с данным cfg, открывая engine, выбранный в cfg.Engine.
+func Open(cfg Config) (*Node, error) {
+	e, err := openEngine(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Node{
+		cfg:    cfg,
+		hbStop: make(chan struct{}),
+		engine: e,
+		done:   make(chan struct{}),
+		hints:  make(map[storage.ServiceAddr][]hintedWrite),
+		rep:    storage.DefaultReplication,
+
+		loadSampled: time.Now(),
+	}, nil
+}
+
+func openEngine(cfg Config) (engine.Engine, error) {
+	name := cfg.Engine
+	if name == "" {
+		name = engine.MapName
+		if cfg.DataDir != "" {
+			name = engine.LogName
+		}
+	}
+
+	switch name {
+	case engine.MapName:
+		return engine.NewMap(), nil
+	case engine.ShardedName:
+		return engine.NewSharded(cfg.Shards), nil
+	case engine.LogName:
+		if cfg.DataDir == "" {
+			return nil, fmt.Errorf("DataDir should be set for engine %q", name)
+		}
+		return engine.OpenLog(engine.LogOptions{
+			Options: wal.Options{
+				Dir:          cfg.DataDir,
+				Sync:         cfg.Sync,
+				SyncInterval: cfg.SyncInterval,
+			},
+			SnapshotEvery: cfg.SnapshotEvery,
+		})
+	default:
+		return nil, fmt.Errorf("Unknown engine %q", name)
+	}
+}
+
+// Close stops rebalancing and closes the engine.
+//
+// Close останавливает перебалансировку и закрывает engine.
+func (node *Node) Close() error {
+	node.closeOnce.Do(func() {
+		close(node.done)
+	})
+	return node.engine.Close()
+}
+
+// refreshReplication updates the replication factor and quorum of the cluster
+// and its placement setting if the router client reports them.
+func (node *Node) refreshReplication(ctx context.Context) {
+	var (
+		rep       storage.Replication
+		placement storage.Placement
+		err       error
+	)
+	if pc, ok := node.cfg.Client.(router.PlacementClient); ok {
+		_, rep, placement, err = pc.ListPlacementContext(ctx, node.cfg.Router)
+	} else if rc, ok := node.cfg.Client.(router.ReplicationClient); ok {
+		_, rep, err = rc.ListReplicationContext(ctx, node.cfg.Router)
+	} else {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get replication: %v", err)
+		return
+	}
+	node.repLock.Lock()
+	defer node.repLock.Unlock()
+	node.rep = rep
+	node.placement = placement
+}
+
+// replication returns the replication factor and quorum of the cluster.
+func (node *Node) replication() storage.Replication {
+	node.repLock.Lock()
+	defer node.repLock.Unlock()
+	return node.rep
+}
+
+// nodesFinder returns cfg.NodesFinder placing records on as many nodes
+// and as unevenly as the cluster is configured to.
+func (node *Node) nodesFinder() rtr.NodesFinder {
+	node.repLock.Lock()
+	defer node.repLock.Unlock()
+	return node.cfg.NodesFinder.WithReplicationFactor(node.rep.Factor).WithPlacement(node.placement)
+}
+
 // Heartbeats runs heartbeats from node to a router
 // each time interval set by cfg.Heartbeat.
 //
@@ -60,17 +271,26 @@ func (node *Node) Heartbeats() {
 		for {
 			select {
 			case <-t.C:
-				node.cfg.Client.Heartbeat(node.cfg.Router, node.cfg.Addr)
+				node.heartbeat()
 			case <-node.hbStop:
 				t.Stop()
 				return
-			default:
 			}
 		}
 
 	}()
 }
 
+// heartbeat sends a heartbeat to the router reporting the load of the node
+// if the router client supports it.
+func (node *Node) heartbeat() {
+	if lc, ok := node.cfg.Client.(router.LoadClient); ok {
+		lc.HeartbeatLoad(node.cfg.Router, node.cfg.Addr, node.Load())
+		return
+	}
+	node.cfg.Client.Heartbeat(node.cfg.Router, node.cfg.Addr)
+}
+
 // Stop stops heartbeats
 //
 // Stop останавливает отправку heartbeats.
@@ -79,18 +299,36 @@ func (node *Node) Stop() {
 }
 
 // Put an item to the node if an item for the given key doesn't exist.
-// Returns the storage.ErrRecordExists error otherwise.
+// Returns the storage.ErrRecordExists error otherwise. An expired item
+// is treated as a missing one.
 //
 // Put -- добавить запись в node, если запись для данного ключа
-// не существует. Иначе вернуть ошибку storage.ErrRecordExists.
+// не существует. Иначе вернуть ошибку storage.ErrRecordExists. Истекшая
+// запись считается отсутствующей.
 func (node *Node) Put(k storage.RecordID, d []byte) error {
-	node.lock.Lock()
-	defer node.lock.Unlock()
-	if _, ok := node.storage[k]; ok {
+	return node.PutKey(k.Key(), d)
+}
+
+// PutKey is Put of an item with a byte key.
+//
+// PutKey -- Put записи с байтовым ключом.
+func (node *Node) PutKey(k storage.Key, d []byte) error {
+	return node.put(k, storage.Version{Clock: storage.VectorClock{}, Data: d})
+}
+
+func (node *Node) put(k storage.Key, v storage.Version) error {
+	lock := node.lock(k)
+	lock.Lock()
+	defer lock.Unlock()
+
+	_, err := node.GetVersions(k)
+	if err == nil {
 		return storage.ErrRecordExists
 	}
-	node.storage[k] = d
-	return nil
+	if err != storage.ErrRecordNotFound {
+		return err
+	}
+	return node.engine.Set(k, encodeVersions([]storage.Version{v}))
 }
 
 // Del an item from the node if an item exists for the given key.
@@ -99,26 +337,169 @@ func (node *Node) Put(k storage.RecordID, d []byte) error {
 // Del -- удалить запись из node, если запись для данного ключа
 // существует. Иначе вернуть ошибку storage.ErrRecordNotFound.
 func (node *Node) Del(k storage.RecordID) error {
-	node.lock.Lock()
-	defer node.lock.Unlock()
-	if _, ok := node.storage[k]; !ok {
-		return storage.ErrRecordNotFound
+	return node.DelKey(k.Key())
+}
+
+// DelKey is Del of an item with a byte key.
+//
+// DelKey -- Del записи с байтовым ключом.
+func (node *Node) DelKey(k storage.Key) error {
+	lock := node.lock(k)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, err := node.GetVersions(k); err != nil {
+		if err == storage.ErrRecordNotFound {
+			// An expired record which isn't reclaimed yet is deleted.
+			node.engine.Del(k)
+		}
+		return err
 	}
-	delete(node.storage, k)
-	return nil
+	return node.engine.Del(k)
 }
 
 // Get an item from the node if an item exists for the given key.
-// Returns the storage.ErrRecordNotFound error otherwise.
+// Returns the storage.ErrRecordNotFound error otherwise, and
+// the storage.ErrConflict error if the item has concurrent versions.
 //
 // Get -- получить запись из node, если запись для данного ключа
-// существует. Иначе вернуть ошибку storage.ErrRecordNotFound.
+// существует. Иначе вернуть ошибку storage.ErrRecordNotFound, и ошибку
+// storage.ErrConflict, если у записи есть параллельные версии.
 func (node *Node) Get(k storage.RecordID) ([]byte, error) {
-	node.lock.RLock()
-	defer node.lock.RUnlock()
-	data, ok := node.storage[k]
-	if !ok {
-		return nil, storage.ErrRecordNotFound
+	return node.GetKey(k.Key())
+}
+
+// GetKey is Get of an item with a byte key.
+//
+// GetKey -- Get записи с байтовым ключом.
+func (node *Node) GetKey(k storage.Key) ([]byte, error) {
+	versions, err := node.GetVersions(k)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) > 1 {
+		return nil, storage.ErrConflict
+	}
+	return versions[0].Data, nil
+}
+
+// PutContext is Put which is not performed if ctx is already done.
+// The item expires at the time set by storage.WithTTL or storage.WithExpiry
+// if ctx carries one.
+//
+// PutContext -- Put, который не выполняется, если ctx уже завершен.
+// Запись истекает во время, заданное storage.WithTTL или storage.WithExpiry,
+// если оно передано в ctx.
+func (node *Node) PutContext(ctx context.Context, k storage.RecordID, d []byte) error {
+	return node.PutKeyContext(ctx, k.Key(), d)
+}
+
+// PutKeyContext is PutContext of an item with a byte key.
+//
+// PutKeyContext -- PutContext записи с байтовым ключом.
+func (node *Node) PutKeyContext(ctx context.Context, k storage.Key, d []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	atomic.AddUint64(&node.writes, 1)
+	return node.put(k, storage.Version{
+		Clock:   storage.VectorClock{},
+		Data:    d,
+		Expires: storage.ExpiryFromContext(ctx),
+	})
+}
+
+// DelContext is Del which is not performed if ctx is already done.
+//
+// DelContext -- Del, который не выполняется, если ctx уже завершен.
+func (node *Node) DelContext(ctx context.Context, k storage.RecordID) error {
+	return node.DelKeyContext(ctx, k.Key())
+}
+
+// DelKeyContext is DelContext of an item with a byte key.
+//
+// DelKeyContext -- DelContext записи с байтовым ключом.
+func (node *Node) DelKeyContext(ctx context.Context, k storage.Key) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	atomic.AddUint64(&node.writes, 1)
+	return node.DelKey(k)
+}
+
+// GetContext is Get which is not performed if ctx is already done.
+//
+// GetContext -- Get, который не выполняется, если ctx уже завершен.
+func (node *Node) GetContext(ctx context.Context, k storage.RecordID) ([]byte, error) {
+	return node.GetKeyContext(ctx, k.Key())
+}
+
+// GetKeyContext is GetContext of an item with a byte key.
+//
+// GetKeyContext -- GetContext записи с байтовым ключом.
+func (node *Node) GetKeyContext(ctx context.Context, k storage.Key) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	atomic.AddUint64(&node.reads, 1)
+	return node.GetKey(k)
+}
+
+// Scan calls f for each record of the node selected by opts in key order.
+// If opts.Limit records were enumerated and there are more of them,
+// a token resuming the scan is returned. A record with concurrent versions
+// is enumerated with data of the first of them in canonical order,
+// use GetVersions to get all of them.
+//
+// Scan -- вызвать f для каждой записи node, выбранной opts, в порядке ключей.
+// Если перечислено opts.Limit записей и остались другие, возвращается
+// токен для продолжения перечисления. Запись с параллельными версиями
+// перечисляется с данными первой из них в каноническом порядке,
+// для получения всех версий используйте GetVersions.
+func (node *Node) Scan(opts storage.ScanOptions, f func(k storage.Key, d []byte) error) (string, error) {
+	return node.ScanContext(context.Background(), opts, f)
+}
+
+// ScanContext is Scan which is stopped once ctx is done.
+//
+// ScanContext -- Scan, который прекращается при завершении ctx.
+func (node *Node) ScanContext(ctx context.Context, opts storage.ScanOptions, f func(k storage.Key, d []byte) error) (string, error) {
+	atomic.AddUint64(&node.reads, 1)
+	from, err := opts.From()
+	if err != nil {
+		return "", err
+	}
+
+	var keys []storage.Key
+	node.engine.Range(func(k storage.Key, d []byte) bool {
+		if k >= from && opts.Contains(k) {
+			keys = append(keys, k)
+		}
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	n := 0
+	for _, k := range keys {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		// Records deleted since the keys were collected are skipped.
+		versions, err := node.GetVersions(k)
+		if err == storage.ErrRecordNotFound {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		d := versions[0].Data
+		if opts.Limit > 0 && n == opts.Limit {
+			return storage.ScanToken(k), nil
+		}
+		if err := f(k, d); err != nil {
+			return "", err
+		}
+		n++
 	}
-	return data, nil
+	return "", nil
 }
@@ -1,16 +1,22 @@
 package node
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"sync"
 	"testing"
 	"time"
 
+	"node/engine"
+	rtr "router/router"
 	"storage"
+	"storage/wal"
 )
 
 var cfg = Config{
@@ -72,6 +78,97 @@ func TestDel(t *testing.T) {
 	}
 }
 
+func TestScan(t *testing.T) {
+	s := New(cfg)
+	defer s.Close()
+	for i := 0; i < 30; i++ {
+		if err := s.Put(storage.RecordID(i), []byte(fmt.Sprintf("data%d", i))); err != nil {
+			t.Fatalf("Put() error: %v", err)
+		}
+	}
+	if err := s.Del(15); err != nil {
+		t.Fatalf("Del() error: %v", err)
+	}
+
+	scan := func(opts storage.ScanOptions) ([]storage.RecordID, string) {
+		var keys []storage.RecordID
+		token, err := s.Scan(opts, func(k storage.Key, d []byte) error {
+			id, _ := k.RecordID()
+			keys = append(keys, id)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Scan() error: %v", err)
+		}
+		return keys, token
+	}
+
+	opts := storage.ScanOptions{Start: storage.RecordID(10).Key(), End: storage.RecordID(25).Key(), Limit: 5}
+	keys, token := scan(opts)
+	if want := []storage.RecordID{10, 11, 12, 13, 14}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("Scan() got keys %v, want %v", keys, want)
+	}
+	opts.Token = token
+	keys, token = scan(opts)
+	if want := []storage.RecordID{16, 17, 18, 19, 20}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("Scan() got keys %v, want %v", keys, want)
+	}
+	opts.Token = token
+	keys, token = scan(opts)
+	if want := []storage.RecordID{21, 22, 23, 24}; !reflect.DeepEqual(keys, want) || token != "" {
+		t.Fatalf("Scan() got keys %v and token %q, want %v and no token", keys, token, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.ScanContext(ctx, storage.ScanOptions{}, func(storage.Key, []byte) error { return nil }); err != context.Canceled {
+		t.Errorf("ScanContext() got error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestByteKeys(t *testing.T) {
+	c, cleanup := persistentCfg(t)
+	defer cleanup()
+	s, err := Open(c)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	for _, k := range []storage.Key{"user:1", "user:2", "user:10", "session:1"} {
+		if err := s.PutKey(k, []byte("data of "+k)); err != nil {
+			t.Fatalf("PutKey(%q) error: %v", k, err)
+		}
+	}
+	if err := s.PutKey("user:1", nil); err != storage.ErrRecordExists {
+		t.Errorf("PutKey() got error %v, want %v", err, storage.ErrRecordExists)
+	}
+	if err := s.Put(1, []byte("id")); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	if got, err := s.GetKey(storage.RecordID(1).Key()); err != nil || string(got) != "id" {
+		t.Errorf("GetKey() of a RecordID got %q, %v, want %q", got, err, "id")
+	}
+	if err := s.DelKey("user:2"); err != nil {
+		t.Fatalf("DelKey() error: %v", err)
+	}
+
+	s = reopen(t, s, c)
+	defer s.Close()
+	if got, err := s.GetKey("user:10"); err != nil || string(got) != "data of user:10" {
+		t.Errorf("GetKey() got %q, %v, want %q", got, err, "data of user:10")
+	}
+	var keys []storage.Key
+	_, err = s.Scan(storage.ScanOptions{Start: "user:", End: "user;"}, func(k storage.Key, d []byte) error {
+		keys = append(keys, k)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	if want := []storage.Key{"user:1", "user:10"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Scan() got keys %q, want %q", keys, want)
+	}
+}
+
 func TestParallelOps(t *testing.T) {
 	s := New(cfg)
 	var keys []storage.RecordID
@@ -232,6 +329,1002 @@ func TestStopHeartbeat(t *testing.T) {
 	c.Unlock()
 }
 
+func persistentCfg(t *testing.T) (Config, func()) {
+	dir, err := ioutil.TempDir("", "node")
+	if err != nil {
+		t.Fatalf("TempDir() error: %v", err)
+	}
+	c := cfg
+	c.DataDir = dir
+	return c, func() { os.RemoveAll(dir) }
+}
+
+func reopen(t *testing.T, s *Node, c Config) *Node {
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	s, err := Open(c)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	return s
+}
+
+func checkRecords(t *testing.T, s *Node, want map[storage.RecordID][]byte, n int) {
+	for i := 0; i < n; i++ {
+		key := storage.RecordID(i)
+		got, err := s.Get(key)
+		d, ok := want[key]
+		if !ok {
+			if err != storage.ErrRecordNotFound {
+				t.Errorf("Get(%v): got error %v, want %v", key, err, storage.ErrRecordNotFound)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Get(%v) error: %v", key, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, d) {
+			t.Errorf("Wrong data for %v: got %s, want %s", key, got, d)
+		}
+	}
+}
+
+func TestRestart(t *testing.T) {
+	for _, mode := range []wal.SyncMode{wal.SyncAlways, wal.SyncInterval, wal.SyncNone} {
+		t.Run(string(mode), func(t *testing.T) {
+			c, cleanup := persistentCfg(t)
+			defer cleanup()
+			c.Sync = mode
+
+			s, err := Open(c)
+			if err != nil {
+				t.Fatalf("Open() error: %v", err)
+			}
+
+			const n = 50
+			want := make(map[storage.RecordID][]byte)
+			for i := 0; i < n; i++ {
+				key := storage.RecordID(i)
+				d := []byte(fmt.Sprintf("data%d", i))
+				if err := s.Put(key, d); err != nil {
+					t.Fatalf("Put() error: %v", err)
+				}
+				want[key] = d
+			}
+			for i := 0; i < n; i += 3 {
+				key := storage.RecordID(i)
+				if err := s.Del(key); err != nil {
+					t.Fatalf("Del() error: %v", err)
+				}
+				delete(want, key)
+			}
+
+			s = reopen(t, s, c)
+			defer s.Close()
+			checkRecords(t, s, want, n)
+
+			if err := s.Put(1, []byte("again")); err != storage.ErrRecordExists {
+				t.Errorf("Put() got error: %v, want %v", err, storage.ErrRecordExists)
+			}
+		})
+	}
+}
+
+func TestRestart_TornWrite(t *testing.T) {
+	c, cleanup := persistentCfg(t)
+	defer cleanup()
+
+	s, err := Open(c)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	want := map[storage.RecordID][]byte{
+		1: []byte("one"),
+		2: []byte("two"),
+	}
+	for k, d := range want {
+		if err := s.Put(k, d); err != nil {
+			t.Fatalf("Put() error: %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	// Simulate a crash in the middle of appending a record.
+	f, err := os.OpenFile(filepath.Join(c.DataDir, "wal.log"), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile() error: %v", err)
+	}
+	f.Write([]byte{0xde, 0xad, 0xbe, 0xef, 42, 0, 0})
+	f.Close()
+
+	s, err = Open(c)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	checkRecords(t, s, want, 3)
+
+	if err := s.Put(3, []byte("three")); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	want[3] = []byte("three")
+
+	s = reopen(t, s, c)
+	defer s.Close()
+	checkRecords(t, s, want, 4)
+}
+
+func TestSnapshot(t *testing.T) {
+	c, cleanup := persistentCfg(t)
+	defer cleanup()
+	c.SnapshotEvery = 10
+
+	s, err := Open(c)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+
+	const n = 25
+	want := make(map[storage.RecordID][]byte)
+	for i := 0; i < n; i++ {
+		key := storage.RecordID(i)
+		d := []byte(fmt.Sprintf("data%d", i))
+		if err := s.Put(key, d); err != nil {
+			t.Fatalf("Put() error: %v", err)
+		}
+		want[key] = d
+	}
+	if err := s.Del(0); err != nil {
+		t.Fatalf("Del() error: %v", err)
+	}
+	delete(want, 0)
+
+	snapshot, err := os.Stat(filepath.Join(c.DataDir, "snapshot"))
+	if err != nil {
+		t.Fatalf("Snapshot was not taken: %v", err)
+	}
+	log, err := os.Stat(filepath.Join(c.DataDir, "wal.log"))
+	if err != nil {
+		t.Fatalf("Stat() error: %v", err)
+	}
+	if log.Size() >= snapshot.Size() {
+		t.Errorf("Log was not truncated after snapshot: log size %d, snapshot size %d", log.Size(), snapshot.Size())
+	}
+
+	s = reopen(t, s, c)
+	defer s.Close()
+	checkRecords(t, s, want, n)
+}
+
+func TestOpen_Engines(t *testing.T) {
+	c, cleanup := persistentCfg(t)
+	defer cleanup()
+
+	for _, test := range []struct {
+		engine  string
+		dataDir string
+		want    interface{}
+		fails   bool
+	}{
+		{want: &engine.Map{}},
+		{dataDir: c.DataDir, want: &engine.Log{}},
+		{engine: engine.MapName, want: &engine.Map{}},
+		{engine: engine.ShardedName, want: &engine.Sharded{}},
+		{engine: engine.LogName, dataDir: c.DataDir, want: &engine.Log{}},
+		{engine: engine.LogName, fails: true},
+		{engine: "unknown", fails: true},
+	} {
+		t.Run(fmt.Sprintf("engine=%q,dataDir=%v", test.engine, test.dataDir != ""), func(t *testing.T) {
+			c := cfg
+			c.Engine = test.engine
+			c.DataDir = test.dataDir
+			s, err := Open(c)
+			if test.fails {
+				if err == nil {
+					t.Fatalf("Open() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open() error: %v", err)
+			}
+			defer s.Close()
+			if got, want := reflect.TypeOf(s.engine), reflect.TypeOf(test.want); got != want {
+				t.Errorf("Wrong engine: got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestOpen_UnknownSyncMode(t *testing.T) {
+	c, cleanup := persistentCfg(t)
+	defer cleanup()
+	c.Sync = "sometimes"
+
+	if _, err := Open(c); err == nil {
+		t.Errorf("Open() expected error for sync mode %q", c.Sync)
+	}
+}
+
+type FakeTopologyClient struct {
+	FakeClientStopHeartbeat
+
+	lock  sync.Mutex
+	nodes []storage.ServiceAddr
+	epoch uint64
+}
+
+func (c *FakeTopologyClient) setTopology(nodes []storage.ServiceAddr, epoch uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.nodes = nodes
+	c.epoch = epoch
+}
+
+func (c *FakeTopologyClient) Topology(router storage.ServiceAddr) ([]storage.ServiceAddr, uint64, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.nodes, c.epoch, nil
+}
+
+func (c *FakeTopologyClient) TopologyContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, uint64, error) {
+	return c.Topology(router)
+}
+
+type FakeNodeClient struct {
+	sync.Mutex
+	puts map[storage.ServiceAddr]map[storage.RecordID][]byte
+}
+
+func (c *FakeNodeClient) Put(node storage.ServiceAddr, k storage.RecordID, d []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.puts[node] == nil {
+		c.puts[node] = make(map[storage.RecordID][]byte)
+	}
+	c.puts[node][k] = d
+	return nil
+}
+
+func (c *FakeNodeClient) Get(node storage.ServiceAddr, k storage.RecordID) ([]byte, error) {
+	return nil, storage.ErrRecordNotFound
+}
+
+func (c *FakeNodeClient) Del(node storage.ServiceAddr, k storage.RecordID) error {
+	return nil
+}
+
+func TestRebalancing(t *testing.T) {
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3", "node4"}
+	rc := &FakeTopologyClient{}
+	rc.setTopology(nodes[:3], 1)
+	nc := &FakeNodeClient{puts: make(map[storage.ServiceAddr]map[storage.RecordID][]byte)}
+	nf := rtr.NewNodesFinder(rtr.NewMD5Hasher())
+
+	s := New(Config{
+		Addr:              nodes[0],
+		Client:            rc,
+		NodeClient:        nc,
+		NodesFinder:       nf,
+		RebalanceInterval: 10 * time.Millisecond,
+	})
+	defer s.Close()
+
+	const n = 100
+	for i := 0; i < n; i++ {
+		if err := s.Put(storage.RecordID(i), []byte(fmt.Sprintf("data%d", i))); err != nil {
+			t.Fatalf("Put() error: %v", err)
+		}
+	}
+
+	s.Rebalancing()
+	time.Sleep(50 * time.Millisecond)
+	rc.setTopology(nodes, 2)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for p := s.RebalanceProgress(); p.Epoch != 2 || !p.Done; p = s.RebalanceProgress() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Rebalancing didn't finish: %+v", p)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	wantCopied, wantDeleted := 0, 0
+	for i := 0; i < n; i++ {
+		key := storage.RecordID(i)
+		owners := nf.NodesFindKey(key.Key(), nodes)
+		_, local := s.engine.Get(key.Key())
+		_, sent := nc.puts[nodes[3]][key]
+		if contains(owners, nodes[3]) {
+			wantCopied++
+			if !sent {
+				t.Errorf("Record %v was not sent to its new owner", key)
+			}
+		} else if sent {
+			t.Errorf("Record %v was sent to %q which doesn't own it", key, nodes[3])
+		}
+		if contains(owners, nodes[0]) {
+			if local != nil {
+				t.Errorf("Record %v owned by the node was deleted", key)
+			}
+		} else {
+			wantDeleted++
+			if local == nil {
+				t.Errorf("Record %v not owned by the node anymore was kept", key)
+			}
+		}
+	}
+	for _, node := range nodes[1:3] {
+		if len(nc.puts[node]) != 0 {
+			t.Errorf("Records were sent to %q which already owned them", node)
+		}
+	}
+
+	p := s.RebalanceProgress()
+	if p.Total != n || p.Scanned != n || p.Copied != wantCopied || p.Deleted != wantDeleted || p.Failed != 0 {
+		t.Errorf("Wrong progress: got %+v, want %d copied, %d deleted", p, wantCopied, wantDeleted)
+	}
+}
+
+// FakeCluster is a node client calling nodes directly.
+type FakeCluster struct {
+	nodes map[storage.ServiceAddr]*Node
+}
+
+func (c *FakeCluster) Put(node storage.ServiceAddr, k storage.RecordID, d []byte) error {
+	return c.nodes[node].Put(k, d)
+}
+
+func (c *FakeCluster) Get(node storage.ServiceAddr, k storage.RecordID) ([]byte, error) {
+	return c.nodes[node].Get(k)
+}
+
+func (c *FakeCluster) Del(node storage.ServiceAddr, k storage.RecordID) error {
+	return c.nodes[node].Del(k)
+}
+
+func (c *FakeCluster) PutKeyContext(ctx context.Context, node storage.ServiceAddr, k storage.Key, d []byte) error {
+	return c.nodes[node].PutKeyContext(ctx, k, d)
+}
+
+func (c *FakeCluster) GetKeyContext(ctx context.Context, node storage.ServiceAddr, k storage.Key) ([]byte, error) {
+	return c.nodes[node].GetKeyContext(ctx, k)
+}
+
+func (c *FakeCluster) DelKeyContext(ctx context.Context, node storage.ServiceAddr, k storage.Key) error {
+	return c.nodes[node].DelKeyContext(ctx, k)
+}
+
+func (c *FakeCluster) Scan(node storage.ServiceAddr, opts storage.ScanOptions, f func(k storage.Key, d []byte) error) (string, error) {
+	return c.nodes[node].Scan(opts, f)
+}
+
+func (c *FakeCluster) ScanContext(ctx context.Context, node storage.ServiceAddr, opts storage.ScanOptions, f func(k storage.Key, d []byte) error) (string, error) {
+	return c.nodes[node].ScanContext(ctx, opts, f)
+}
+
+func (c *FakeCluster) Tree(node storage.ServiceAddr, opts storage.TreeOptions) (*storage.MerkleTree, error) {
+	return c.TreeContext(context.Background(), node, opts)
+}
+
+func (c *FakeCluster) TreeContext(ctx context.Context, node storage.ServiceAddr, opts storage.TreeOptions) (*storage.MerkleTree, error) {
+	return c.nodes[node].TreeContext(ctx, opts)
+}
+
+func (c *FakeCluster) GetVersionsContext(ctx context.Context, node storage.ServiceAddr, k storage.Key) ([]storage.Version, error) {
+	return c.nodes[node].GetVersionsContext(ctx, k)
+}
+
+func (c *FakeCluster) UpdateContext(ctx context.Context, node storage.ServiceAddr, k storage.Key, v storage.Version) error {
+	return c.nodes[node].UpdateContext(ctx, k, v)
+}
+
+func (c *FakeCluster) PutIfVersionContext(ctx context.Context, node storage.ServiceAddr, k storage.Key, cond storage.Condition, v storage.Version) error {
+	return c.nodes[node].PutIfVersionContext(ctx, k, cond, v)
+}
+
+func (c *FakeCluster) DeleteIfVersionContext(ctx context.Context, node storage.ServiceAddr, k storage.Key, cond storage.Condition) error {
+	return c.nodes[node].DeleteIfVersionContext(ctx, k, cond)
+}
+
+func (c *FakeCluster) UpsertContext(ctx context.Context, node storage.ServiceAddr, k storage.Key, v storage.Version) error {
+	return c.nodes[node].UpsertContext(ctx, k, v)
+}
+
+func TestAntiEntropy(t *testing.T) {
+	addrs := []storage.ServiceAddr{"node1", "node2", "node3", "node4"}
+	nf := rtr.NewNodesFinder(rtr.NewMD5Hasher())
+	rc := &FakeTopologyClient{}
+	rc.setTopology(addrs, 1)
+	cluster := &FakeCluster{nodes: make(map[storage.ServiceAddr]*Node)}
+	for _, addr := range addrs {
+		s := New(Config{
+			Addr:                addr,
+			Client:              rc,
+			NodeClient:          cluster,
+			NodesFinder:         nf,
+			AntiEntropyInterval: 10 * time.Millisecond,
+		})
+		defer s.Close()
+		cluster.nodes[addr] = s
+	}
+
+	const n = 200
+	for i := 0; i < n; i++ {
+		k := storage.RecordID(i)
+		for _, owner := range nf.NodesFindKey(k.Key(), addrs) {
+			if err := cluster.Put(owner, k, []byte(fmt.Sprintf("data%d", i))); err != nil {
+				t.Fatalf("Put() error: %v", err)
+			}
+		}
+	}
+
+	// Record 0 missed a write, record 1 missed a delete,
+	// record 2 has a diverged replica and record n was written to a single replica.
+	// A single replica of record 3 has a newer version, and two replicas
+	// of record 4 were updated concurrently.
+	owners := func(k storage.RecordID) []storage.ServiceAddr { return nf.NodesFindKey(k.Key(), addrs) }
+	cluster.Del(owners(0)[0], 0)
+	cluster.Del(owners(1)[0], 1)
+	cluster.Del(owners(1)[1], 1)
+	cluster.Del(owners(2)[2], 2)
+	cluster.Put(owners(2)[2], 2, []byte("diverged"))
+	cluster.Put(owners(n)[1], n, []byte("partial"))
+	newer := storage.Version{Clock: storage.VectorClock{"fe1": 1}, Data: []byte("newer")}
+	cluster.nodes[owners(3)[1]].Update(storage.RecordID(3).Key(), newer)
+	siblings := []storage.Version{
+		{Clock: storage.VectorClock{"fe1": 1}, Data: []byte("sibling1")},
+		{Clock: storage.VectorClock{"fe2": 1}, Data: []byte("sibling2")},
+	}
+	cluster.nodes[owners(4)[0]].Update(storage.RecordID(4).Key(), siblings[0])
+	cluster.nodes[owners(4)[2]].Update(storage.RecordID(4).Key(), siblings[1])
+
+	for _, s := range cluster.nodes {
+		s.AntiEntropy()
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for _, s := range cluster.nodes {
+		for s.AntiEntropyStats().Rounds == 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf("Anti-entropy round didn't finish")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	for i := 0; i <= n; i++ {
+		k := storage.RecordID(i)
+		want := []byte(fmt.Sprintf("data%d", i))
+		if i == 1 || i == n {
+			want = nil
+		}
+		if i == 3 {
+			want = newer.Data
+		}
+		if i == 4 {
+			for _, owner := range owners(k) {
+				got, err := cluster.nodes[owner].GetVersions(k.Key())
+				if want := storage.Reconcile(siblings); err != nil || !reflect.DeepEqual(got, want) {
+					t.Errorf("Record %v on %q: got %v, %v, want %v", k, owner, got, err, want)
+				}
+			}
+			continue
+		}
+		for _, owner := range owners(k) {
+			got, err := cluster.Get(owner, k)
+			if want == nil && err != storage.ErrRecordNotFound {
+				t.Errorf("Record %v on %q: got %q, %v, want it deleted", k, owner, got, err)
+			}
+			if want != nil && !reflect.DeepEqual(got, want) {
+				t.Errorf("Record %v on %q: got %q, %v, want %q", k, owner, got, err, want)
+			}
+		}
+	}
+
+	var stats AntiEntropyStats
+	for _, s := range cluster.nodes {
+		st := s.AntiEntropyStats()
+		stats.RepairedKeys += st.RepairedKeys
+		stats.FailedExchanges += st.FailedExchanges
+	}
+	if stats.RepairedKeys < 6 || stats.FailedExchanges != 0 {
+		t.Errorf("Wrong stats: %+v, want at least 6 keys repaired", stats)
+	}
+}
+
+type FakeHintedClient struct {
+	FakeClientStopHeartbeat
+
+	lock  sync.Mutex
+	alive []storage.ServiceAddr
+}
+
+func (c *FakeHintedClient) setAlive(nodes ...storage.ServiceAddr) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.alive = nodes
+}
+
+func (c *FakeHintedClient) NodesFindHinted(router storage.ServiceAddr, k storage.RecordID) ([]storage.Replica, error) {
+	return nil, nil
+}
+
+func (c *FakeHintedClient) NodesFindHintedContext(ctx context.Context, router storage.ServiceAddr, k storage.RecordID) ([]storage.Replica, error) {
+	return nil, nil
+}
+
+func (c *FakeHintedClient) Alive(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.alive, nil
+}
+
+func (c *FakeHintedClient) AliveContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+	return c.Alive(router)
+}
+
+func TestHintedHandoff(t *testing.T) {
+	owner := storage.ServiceAddr("node2")
+	rc := &FakeHintedClient{}
+	rc.setAlive("node1")
+	nc := &FakeNodeClient{puts: make(map[storage.ServiceAddr]map[storage.RecordID][]byte)}
+	s := New(Config{
+		Addr:         "node1",
+		Client:       rc,
+		NodeClient:   nc,
+		HintInterval: 10 * time.Millisecond,
+	})
+	defer s.Close()
+	s.HintedHandoff()
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		if err := s.PutHintContext(ctx, owner, storage.RecordID(i).Key(), []byte(fmt.Sprintf("data%d", i))); err != nil {
+			t.Fatalf("PutHintContext() error: %v", err)
+		}
+	}
+	if err := s.DelHintContext(ctx, owner, storage.RecordID(3).Key()); err != nil {
+		t.Fatalf("DelHintContext() error: %v", err)
+	}
+	if _, err := s.Get(1); err != storage.ErrRecordNotFound {
+		t.Errorf("Hinted record was stored locally: Get() got error %v, want %v", err, storage.ErrRecordNotFound)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if stats := s.HintStats(); stats.Pending != 11 || stats.Replayed != 0 {
+		t.Fatalf("Hints were replayed to an unavailable node: %+v", stats)
+	}
+
+	rc.setAlive("node1", owner)
+	deadline := time.Now().Add(time.Second)
+	for s.HintStats().Pending != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Hints were not replayed: %+v", s.HintStats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stats := s.HintStats(); stats.Stored != 11 || stats.Replayed != 11 {
+		t.Errorf("Wrong stats: %+v, want 11 hints stored and replayed", stats)
+	}
+	nc.Lock()
+	defer nc.Unlock()
+	if len(nc.puts[owner]) != 10 {
+		t.Errorf("Got %d records replayed to %q, want 10", len(nc.puts[owner]), owner)
+	}
+}
+
+func TestHintedHandoff_Disabled(t *testing.T) {
+	s := New(cfg)
+	defer s.Close()
+	if err := s.PutHintContext(context.Background(), "node2", "key", nil); err != storage.ErrHintNotSupported {
+		t.Errorf("PutHintContext() got error %v, want %v", err, storage.ErrHintNotSupported)
+	}
+}
+
+func TestUpdate_Versions(t *testing.T) {
+	s := New(cfg)
+	defer s.Close()
+	key := storage.Key("key")
+
+	v1 := storage.Version{Clock: storage.VectorClock{"fe1": 1}, Data: []byte("v1")}
+	if err := s.Update(key, v1); err != nil {
+		t.Fatalf("Update() error: %v", err)
+	}
+	if err := s.Update(key, v1); err != nil {
+		t.Errorf("Update() with the same version error: %v", err)
+	}
+	if err := s.Update(key, storage.Version{Clock: v1.Clock, Data: []byte("other")}); err != storage.ErrConflict {
+		t.Errorf("Update() with the same clock and other data got error %v, want %v", err, storage.ErrConflict)
+	}
+	if got, err := s.GetKey(key); err != nil || string(got) != "v1" {
+		t.Errorf("Get() got %q, %v, want %q", got, err, "v1")
+	}
+	if err := s.Update(key, storage.Version{Clock: storage.VectorClock{}}); err != storage.ErrObsoleteVersion {
+		t.Errorf("Update() got error %v, want %v", err, storage.ErrObsoleteVersion)
+	}
+
+	// Two frontends update the record concurrently.
+	v2 := storage.Version{Clock: storage.VectorClock{"fe1": 2}, Data: []byte("v2")}
+	v3 := storage.Version{Clock: storage.VectorClock{"fe1": 1, "fe2": 1}, Data: []byte("v3")}
+	for _, v := range []storage.Version{v2, v3} {
+		if err := s.Update(key, v); err != nil {
+			t.Fatalf("Update() error: %v", err)
+		}
+	}
+	versions, err := s.GetVersions(key)
+	if err != nil {
+		t.Fatalf("GetVersions() error: %v", err)
+	}
+	if want := storage.Reconcile([]storage.Version{v2, v3}); !reflect.DeepEqual(versions, want) {
+		t.Errorf("GetVersions() got %v, want %v", versions, want)
+	}
+	if _, err := s.GetKey(key); err != storage.ErrConflict {
+		t.Errorf("Get() got error %v, want %v", err, storage.ErrConflict)
+	}
+
+	// A version with the merged clock supersedes the siblings.
+	v4 := storage.Version{Clock: storage.MergedClock(versions).Increment("fe1"), Data: []byte("v4")}
+	if err := s.Update(key, v4); err != nil {
+		t.Fatalf("Update() error: %v", err)
+	}
+	if got, err := s.GetKey(key); err != nil || string(got) != "v4" {
+		t.Errorf("Get() got %q, %v, want %q", got, err, "v4")
+	}
+}
+
+func TestPutIfVersion(t *testing.T) {
+	s := New(cfg)
+	defer s.Close()
+	key := storage.Key("key")
+
+	v1 := storage.Version{Clock: storage.VectorClock{"fe1": 1}, Data: []byte("v1")}
+	if err := s.CompareAndSwap(key, storage.VectorClock{}, v1); err != storage.ErrConditionFailed {
+		t.Errorf("CompareAndSwap() on a missing record got error %v, want %v", err, storage.ErrConditionFailed)
+	}
+	if err := s.PutIfVersion(key, storage.Condition{Absent: true}, v1); err != nil {
+		t.Fatalf("PutIfVersion() error: %v", err)
+	}
+	if err := s.PutIfVersion(key, storage.Condition{Absent: true}, v1); err != storage.ErrConditionFailed {
+		t.Errorf("PutIfVersion() on an existing record got error %v, want %v", err, storage.ErrConditionFailed)
+	}
+
+	v2 := storage.Version{Clock: v1.Clock.Increment("fe1"), Data: []byte("v2")}
+	if err := s.CompareAndSwap(key, v1.Clock, v2); err != nil {
+		t.Fatalf("CompareAndSwap() error: %v", err)
+	}
+	if err := s.CompareAndSwap(key, v1.Clock, v2); err != storage.ErrConditionFailed {
+		t.Errorf("CompareAndSwap() with a stale clock got error %v, want %v", err, storage.ErrConditionFailed)
+	}
+	if got, err := s.GetKey(key); err != nil || string(got) != "v2" {
+		t.Errorf("Get() got %q, %v, want %q", got, err, "v2")
+	}
+
+	// A record with siblings can't be swapped, the merged clock doesn't match either.
+	v3 := storage.Version{Clock: storage.VectorClock{"fe1": 1, "fe2": 1}, Data: []byte("v3")}
+	if err := s.Update(key, v3); err != nil {
+		t.Fatalf("Update() error: %v", err)
+	}
+	versions, _ := s.GetVersions(key)
+	merged := storage.MergedClock(versions)
+	if err := s.CompareAndSwap(key, merged, storage.Version{Clock: merged.Increment("fe1")}); err != storage.ErrConditionFailed {
+		t.Errorf("CompareAndSwap() on siblings got error %v, want %v", err, storage.ErrConditionFailed)
+	}
+}
+
+func TestDeleteIfVersion(t *testing.T) {
+	s := New(cfg)
+	defer s.Close()
+	key := storage.Key("key")
+
+	if err := s.DeleteIfVersion(key, storage.Condition{Absent: true}); err != storage.ErrRecordNotFound {
+		t.Errorf("DeleteIfVersion() got error %v, want %v", err, storage.ErrRecordNotFound)
+	}
+	v1 := storage.Version{Clock: storage.VectorClock{"fe1": 1}, Data: []byte("v1")}
+	if err := s.Update(key, v1); err != nil {
+		t.Fatalf("Update() error: %v", err)
+	}
+	if err := s.DeleteIfVersion(key, storage.Condition{Clock: storage.VectorClock{"fe1": 2}}); err != storage.ErrConditionFailed {
+		t.Errorf("DeleteIfVersion() got error %v, want %v", err, storage.ErrConditionFailed)
+	}
+	if err := s.DeleteIfVersion(key, storage.Condition{Clock: v1.Clock}); err != nil {
+		t.Fatalf("DeleteIfVersion() error: %v", err)
+	}
+	if _, err := s.GetKey(key); err != storage.ErrRecordNotFound {
+		t.Errorf("Get() got error %v, want %v", err, storage.ErrRecordNotFound)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	s := New(cfg)
+	defer s.Close()
+	key := storage.Key("key")
+
+	v1 := storage.Version{Clock: storage.VectorClock{"fe1": 1}, Data: []byte("v1")}
+	v2 := storage.Version{Clock: storage.VectorClock{"fe2": 1}, Data: []byte("v2")}
+	for _, v := range []storage.Version{v1, v2} {
+		if err := s.Update(key, v); err != nil {
+			t.Fatalf("Update() error: %v", err)
+		}
+	}
+
+	// A concurrent version replaces the siblings.
+	v3 := storage.Version{Clock: storage.VectorClock{"fe3": 1}, Data: []byte("v3")}
+	if err := s.Upsert(key, v3); err != nil {
+		t.Fatalf("Upsert() error: %v", err)
+	}
+	versions, err := s.GetVersions(key)
+	if err != nil {
+		t.Fatalf("GetVersions() error: %v", err)
+	}
+	if want := []storage.Version{v3}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("GetVersions() got %v, want %v", versions, want)
+	}
+	if err := s.Upsert(key, v3); err != nil {
+		t.Errorf("Upsert() with the same version error: %v", err)
+	}
+	if err := s.Upsert(key, storage.Version{Clock: v3.Clock, Data: []byte("other")}); err != storage.ErrConflict {
+		t.Errorf("Upsert() with the same clock and other data got error %v, want %v", err, storage.ErrConflict)
+	}
+	if err := s.Upsert(key, storage.Version{Clock: storage.VectorClock{}}); err != storage.ErrObsoleteVersion {
+		t.Errorf("Upsert() got error %v, want %v", err, storage.ErrObsoleteVersion)
+	}
+	if err := s.Upsert("other", v1); err != nil {
+		t.Errorf("Upsert() of a new record error: %v", err)
+	}
+}
+
+func TestDecodeVersions(t *testing.T) {
+	versions := []storage.Version{
+		{Clock: storage.VectorClock{"a": 1, "b": 300}, Data: []byte("data")},
+		{Clock: storage.VectorClock{"c": 1}, Data: []byte{}},
+	}
+	got, err := decodeVersions(encodeVersions(versions))
+	if err != nil || !reflect.DeepEqual(got, versions) {
+		t.Errorf("decodeVersions() got %v, %v, want %v", got, err, versions)
+	}
+
+	// Values written before records were versioned.
+	got, err = decodeVersions([]byte("legacy"))
+	want := []storage.Version{{Clock: storage.VectorClock{}, Data: []byte("legacy")}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeVersions() got %v, %v, want %v", got, err, want)
+	}
+
+	if _, err := decodeVersions(encodeVersions(versions)[:10]); err != errCorruptedVersions {
+		t.Errorf("decodeVersions() got error %v, want %v", err, errCorruptedVersions)
+	}
+
+	versions[1].Expires = time.Now().UnixNano()
+	got, err = decodeVersions(encodeVersions(versions))
+	if err != nil || !reflect.DeepEqual(got, versions) {
+		t.Errorf("decodeVersions() of expiring versions got %v, %v, want %v", got, err, versions)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	s := New(cfg)
+	defer s.Close()
+	expired := storage.WithExpiry(context.Background(), time.Now().Add(-time.Second))
+	expiring := storage.WithTTL(context.Background(), time.Minute)
+
+	if err := s.PutContext(expired, 1, []byte("expired")); err != nil {
+		t.Fatalf("PutContext() error: %v", err)
+	}
+	if _, err := s.Get(1); err != storage.ErrRecordNotFound {
+		t.Errorf("Get() of an expired record got error %v, want %v", err, storage.ErrRecordNotFound)
+	}
+	if err := s.PutContext(expiring, 1, []byte("expiring")); err != nil {
+		t.Fatalf("PutContext() over an expired record error: %v", err)
+	}
+	if got, err := s.Get(1); err != nil || string(got) != "expiring" {
+		t.Errorf("Get() got %q, %v, want %q", got, err, "expiring")
+	}
+	if err := s.Put(1, []byte("data")); err != storage.ErrRecordExists {
+		t.Errorf("Put() got error %v, want %v", err, storage.ErrRecordExists)
+	}
+
+	if err := s.PutContext(expired, 2, []byte("expired")); err != nil {
+		t.Fatalf("PutContext() error: %v", err)
+	}
+	if err := s.Del(2); err != storage.ErrRecordNotFound {
+		t.Errorf("Del() of an expired record got error %v, want %v", err, storage.ErrRecordNotFound)
+	}
+	if err := s.PutContext(expired, 3, []byte("expired")); err != nil {
+		t.Fatalf("PutContext() error: %v", err)
+	}
+	var keys []storage.Key
+	s.Scan(storage.ScanOptions{}, func(k storage.Key, d []byte) error {
+		keys = append(keys, k)
+		return nil
+	})
+	if want := []storage.Key{storage.RecordID(1).Key()}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Scan() got keys %v, want %v", keys, want)
+	}
+}
+
+func TestExpirer(t *testing.T) {
+	s := New(Config{Addr: "node1", ExpiryInterval: 10 * time.Millisecond})
+	defer s.Close()
+	expiry := time.Now().Add(50 * time.Millisecond)
+	ctx := storage.WithExpiry(context.Background(), expiry)
+	for i := 0; i < 3; i++ {
+		if err := s.PutContext(ctx, storage.RecordID(i), []byte("session")); err != nil {
+			t.Fatalf("PutContext() error: %v", err)
+		}
+	}
+	// An expiring sibling is reclaimed without the record.
+	for _, v := range []storage.Version{
+		{Clock: storage.VectorClock{"fe1": 1}, Data: []byte("forever")},
+		{Clock: storage.VectorClock{"fe2": 1}, Data: []byte("session"), Expires: expiry.UnixNano()},
+	} {
+		if err := s.Update(storage.RecordID(3).Key(), v); err != nil {
+			t.Fatalf("Update() error: %v", err)
+		}
+	}
+	s.Expirer()
+
+	deadline := time.Now().Add(time.Second)
+	for s.ExpiryStats().Expired != 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Records were not reclaimed: %+v", s.ExpiryStats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := s.engine.Get(storage.RecordID(i).Key()); err != storage.ErrRecordNotFound {
+			t.Errorf("Expired record %d is kept by the engine: %v", i, err)
+		}
+	}
+	if got, err := s.Get(3); err != nil || string(got) != "forever" {
+		t.Errorf("Get() got %q, %v, want %q", got, err, "forever")
+	}
+}
+
+func TestSendVersions_Expiry(t *testing.T) {
+	nodes := map[storage.ServiceAddr]*Node{}
+	c := &FakeCluster{nodes: nodes}
+	for _, addr := range []storage.ServiceAddr{"node1", "node2"} {
+		nodes[addr] = New(Config{Addr: addr, NodeClient: c})
+		defer nodes[addr].Close()
+	}
+	ctx := storage.WithTTL(context.Background(), time.Minute)
+	if err := nodes["node1"].PutContext(ctx, 1, []byte("session")); err != nil {
+		t.Fatalf("PutContext() error: %v", err)
+	}
+	key := storage.RecordID(1).Key()
+	versions, err := nodes["node1"].GetVersions(key)
+	if err != nil {
+		t.Fatalf("GetVersions() error: %v", err)
+	}
+	if err := nodes["node1"].sendVersions(context.Background(), "node2", key, versions); err != nil {
+		t.Fatalf("sendVersions() error: %v", err)
+	}
+	if got, err := nodes["node2"].GetVersions(key); err != nil || !reflect.DeepEqual(got, versions) {
+		t.Errorf("GetVersions() got %v, %v, want %v", got, err, versions)
+	}
+}
+
+type FakeReplicationClient struct {
+	FakeTopologyClient
+	rep storage.Replication
+}
+
+func (c *FakeReplicationClient) ListReplication(router storage.ServiceAddr) ([]storage.ServiceAddr, storage.Replication, error) {
+	nodes, _, err := c.Topology(router)
+	return nodes, c.rep, err
+}
+
+func (c *FakeReplicationClient) ListReplicationContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, storage.Replication, error) {
+	return c.ListReplication(router)
+}
+
+type FakeLoadClient struct {
+	FakeClientStopHeartbeat
+	loads []storage.NodeLoad
+}
+
+func (c *FakeLoadClient) HeartbeatLoad(router, node storage.ServiceAddr, load storage.NodeLoad) error {
+	c.Lock()
+	defer c.Unlock()
+	c.loads = append(c.loads, load)
+	return nil
+}
+
+func (c *FakeLoadClient) HeartbeatLoadContext(ctx context.Context, router, node storage.ServiceAddr, load storage.NodeLoad) error {
+	return c.HeartbeatLoad(router, node, load)
+}
+
+func TestHeartbeat_Load(t *testing.T) {
+	c := &FakeLoadClient{FakeClientStopHeartbeat: FakeClientStopHeartbeat{t: t}}
+	s := New(Config{
+		Client: c,
+		Addr:   "test",
+	})
+	defer s.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := s.PutContext(ctx, storage.RecordID(i), []byte("data")); err != nil {
+			t.Fatalf("PutContext() error: %v", err)
+		}
+	}
+	if _, err := s.GetContext(ctx, 1); err != nil {
+		t.Fatalf("GetContext() error: %v", err)
+	}
+	s.heartbeat()
+	s.heartbeat()
+
+	if c.received || len(c.loads) != 2 {
+		t.Fatalf("Got %d heartbeats with loads, want 2 and no plain heartbeats", len(c.loads))
+	}
+	load := c.loads[0]
+	if load.Records != 3 || load.Bytes <= 3*int64(4+len("data")) || load.Memory == 0 || load.EngineError != "" {
+		t.Errorf("Heartbeat reported load %+v, want 3 healthy records", load)
+	}
+	if load.ReadRate <= 0 || load.WriteRate <= 0 {
+		t.Errorf("Heartbeat reported load %+v, want positive request rates", load)
+	}
+	// Rates are measured since the previous heartbeat.
+	if load := c.loads[1]; load.ReadRate != 0 || load.WriteRate != 0 {
+		t.Errorf("Heartbeat reported load %+v, want zero request rates", load)
+	}
+}
+
+func TestRefreshReplication(t *testing.T) {
+	rc := &FakeReplicationClient{rep: storage.Replication{Factor: 5, Quorum: 3}}
+	s := New(Config{
+		Client:      rc,
+		NodesFinder: rtr.NewNodesFinder(rtr.NewMD5Hasher()),
+	})
+	defer s.Close()
+	if rep := s.replication(); rep != storage.DefaultReplication {
+		t.Errorf("replication() = %+v before refresh, want %+v", rep, storage.DefaultReplication)
+	}
+	s.refreshReplication(context.Background())
+	if rep := s.replication(); rep != rc.rep {
+		t.Errorf("replication() = %+v, want %+v", rep, rc.rep)
+	}
+	if rf := s.nodesFinder().ReplicationFactor(); rf != 5 {
+		t.Errorf("nodesFinder() places records on %d nodes, want 5", rf)
+	}
+}
+
+type FakePlacementClient struct {
+	FakeReplicationClient
+	placement storage.Placement
+}
+
+func (c *FakePlacementClient) ListPlacement(router storage.ServiceAddr) ([]storage.ServiceAddr, storage.Replication, storage.Placement, error) {
+	nodes, rep, err := c.ListReplication(router)
+	return nodes, rep, c.placement, err
+}
+
+func (c *FakePlacementClient) ListPlacementContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, storage.Replication, storage.Placement, error) {
+	return c.ListPlacement(router)
+}
+
+func TestRefreshReplication_Placement(t *testing.T) {
+	rc := &FakePlacementClient{
+		FakeReplicationClient: FakeReplicationClient{rep: storage.DefaultReplication},
+		placement:             storage.Placement{Capacity: map[storage.ServiceAddr]float64{"node1": 2}},
+	}
+	s := New(Config{
+		Client:      rc,
+		NodesFinder: rtr.NewNodesFinder(rtr.NewMD5Hasher()),
+	})
+	defer s.Close()
+	if p := s.nodesFinder().Placement(); p.Weighted() {
+		t.Errorf("nodesFinder() places records by %+v before refresh, want evenly", p)
+	}
+	s.refreshReplication(context.Background())
+	if p := s.nodesFinder().Placement(); !reflect.DeepEqual(p, rc.placement) {
+		t.Errorf("nodesFinder() places records by %+v, want %+v", p, rc.placement)
+	}
+}
+
 func TestMain(m *testing.M) {
 	rand.Seed(time.Now().UnixNano())
 	os.Exit(m.Run())
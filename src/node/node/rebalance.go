@@ -0,0 +1,207 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	router "router/client"
+	"storage"
+)
+
+var errClosed = errors.New("Node is closed")
+
+// RebalanceProgress describes the latest rebalancing pass.
+//
+// RebalanceProgress описывает последний проход перебалансировки.
+type RebalanceProgress struct {
+	// Epoch is a topology epoch the pass moves records to.
+	// Epoch -- эпоха топологии, к которой переносятся записи.
+	Epoch uint64
+	// Total is a number of records to check.
+	// Total -- количество записей, которые нужно проверить.
+	Total int
+	// Scanned is a number of records checked so far.
+	// Scanned -- количество уже проверенных записей.
+	Scanned int
+	// Copied is a number of records sent to their new owners.
+	// Copied -- количество записей, отправленных новым владельцам.
+	Copied int
+	// Deleted is a number of records dropped as not owned by the node anymore.
+	// Deleted -- количество удаленных записей, которые больше не принадлежат node.
+	Deleted int
+	// Failed is a number of records which failed to be sent.
+	// Failed -- количество записей, которые не удалось отправить.
+	Failed int
+	// Done is set when the pass is finished.
+	// Done -- проход завершен.
+	Done bool
+}
+
+// Rebalancing starts watching the topology served by the router
+// each time interval set by cfg.RebalanceInterval. When the topology epoch changes,
+// records are sent to the nodes which newly own them according to cfg.NodesFinder,
+// and records the node doesn't own anymore are deleted once sent.
+// The first observed topology is taken as the current placement.
+// Rebalancing stops when the node is closed.
+//
+// Rebalancing запускает наблюдение за топологией, обслуживаемой router,
+// через каждый интервал времени, заданный в cfg.RebalanceInterval. При смене эпохи
+// топологии записи отправляются node, которые стали их владельцами согласно cfg.NodesFinder,
+// а записи, которые больше не принадлежат node, после отправки удаляются.
+// Первая полученная топология считается текущим размещением.
+// Rebalancing останавливается при закрытии node.
+func (node *Node) Rebalancing() {
+	tc, ok := node.cfg.Client.(router.TopologyClient)
+	if !ok {
+		log.Printf("Rebalancing is disabled: router client doesn't report topology")
+		return
+	}
+
+	go func() {
+		t := time.NewTicker(node.cfg.RebalanceInterval)
+		defer t.Stop()
+
+		var (
+			placed []storage.ServiceAddr
+			epoch  uint64
+			known  bool
+		)
+		for {
+			select {
+			case <-t.C:
+			case <-node.done:
+				return
+			}
+
+			nodes, e, err := tc.Topology(node.cfg.Router)
+			if err != nil {
+				log.Printf("Failed to get topology: %v", err)
+				continue
+			}
+			if !known {
+				placed, epoch, known = nodes, e, true
+				continue
+			}
+			if e == epoch {
+				continue
+			}
+
+			node.refreshReplication(context.Background())
+			p, err := node.rebalance(placed, nodes, e)
+			if err == errClosed {
+				return
+			}
+			// Records which failed to be sent are kept, so the pass
+			// is repeated until all of them reach their new owners.
+			if p.Failed == 0 {
+				placed, epoch = nodes, e
+			}
+		}
+	}()
+}
+
+// RebalanceProgress returns the progress of the latest rebalancing pass.
+//
+// RebalanceProgress возвращает состояние последнего прохода перебалансировки.
+func (node *Node) RebalanceProgress() RebalanceProgress {
+	node.rbLock.Lock()
+	defer node.rbLock.Unlock()
+	return node.rbProgress
+}
+
+func (node *Node) updateProgress(f func(p *RebalanceProgress)) RebalanceProgress {
+	node.rbLock.Lock()
+	defer node.rbLock.Unlock()
+	f(&node.rbProgress)
+	return node.rbProgress
+}
+
+func (node *Node) rebalance(placed, nodes []storage.ServiceAddr, epoch uint64) (RebalanceProgress, error) {
+	nf := node.nodesFinder()
+	var keys []storage.Key
+	node.engine.Range(func(k storage.Key, d []byte) bool {
+		keys = append(keys, k)
+		return true
+	})
+	node.updateProgress(func(p *RebalanceProgress) {
+		*p = RebalanceProgress{Epoch: epoch, Total: len(keys)}
+	})
+	log.Printf("Rebalancing %d records to topology epoch %d", len(keys), epoch)
+
+	var throttle <-chan time.Time
+	if node.cfg.RebalanceRate > 0 {
+		t := time.NewTicker(time.Second / time.Duration(node.cfg.RebalanceRate))
+		defer t.Stop()
+		throttle = t.C
+	}
+
+	for i, k := range keys {
+		versions, err := node.GetVersions(k)
+		if err != nil {
+			node.updateProgress(func(p *RebalanceProgress) { p.Scanned++ })
+			continue
+		}
+
+		oldOwners := nf.NodesFindKey(k, placed)
+		newOwners := nf.NodesFindKey(k, nodes)
+		copied, failed := 0, false
+		for _, owner := range newOwners {
+			if owner == node.cfg.Addr || contains(oldOwners, owner) {
+				continue
+			}
+			if throttle != nil {
+				select {
+				case <-throttle:
+				case <-node.done:
+					return node.RebalanceProgress(), errClosed
+				}
+			}
+			select {
+			case <-node.done:
+				return node.RebalanceProgress(), errClosed
+			default:
+			}
+
+			if err := node.sendVersions(context.Background(), owner, k, versions); err != nil {
+				log.Printf("Failed to send record %q to %q: %v", k, owner, err)
+				failed = true
+				continue
+			}
+			copied++
+		}
+
+		deleted := 0
+		if !failed && !contains(newOwners, node.cfg.Addr) {
+			if err := node.DelKey(k); err == nil {
+				deleted++
+			}
+		}
+
+		p := node.updateProgress(func(p *RebalanceProgress) {
+			p.Scanned++
+			p.Copied += copied
+			p.Deleted += deleted
+			if failed {
+				p.Failed++
+			}
+		})
+		if (i+1)%1000 == 0 {
+			log.Printf("Rebalancing progress: %+v", p)
+		}
+	}
+
+	p := node.updateProgress(func(p *RebalanceProgress) { p.Done = true })
+	log.Printf("Rebalancing finished: %+v", p)
+	return p, nil
+}
+
+func contains(nodes []storage.ServiceAddr, node storage.ServiceAddr) bool {
+	for _, n := range nodes {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"storage"
+)
+
+// versionsMagic prefixes engine values holding versions of a record.
+// Values without it were written before records were versioned and are
+// read as a single version with an empty clock. Values prefixed with
+// expiringMagic hold the expiry time of each version as well, they're
+// written only for records with expiring versions.
+var (
+	versionsMagic = []byte("\xffvc1")
+	expiringMagic = []byte("\xffvc2")
+)
+
+var errCorruptedVersions = errors.New("Corrupted record versions")
+
+// lockStripes is a number of locks serializing updates of records.
+const lockStripes = 64
+
+// encodeVersions encodes versions canonically: clocks sorted by writer,
+// versions in the order given by storage.Reconcile.
+func encodeVersions(versions []storage.Version) []byte {
+	expiring := false
+	for _, v := range versions {
+		if v.Expires != 0 {
+			expiring = true
+		}
+	}
+
+	var buf bytes.Buffer
+	if expiring {
+		buf.Write(expiringMagic)
+	} else {
+		buf.Write(versionsMagic)
+	}
+	var tmp [binary.MaxVarintLen64]byte
+	putUvarint := func(n uint64) {
+		buf.Write(tmp[:binary.PutUvarint(tmp[:], n)])
+	}
+
+	putUvarint(uint64(len(versions)))
+	for _, v := range versions {
+		ids := make([]string, 0, len(v.Clock))
+		for id := range v.Clock {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		putUvarint(uint64(len(ids)))
+		for _, id := range ids {
+			putUvarint(uint64(len(id)))
+			buf.WriteString(id)
+			putUvarint(v.Clock[id])
+		}
+		putUvarint(uint64(len(v.Data)))
+		buf.Write(v.Data)
+		if expiring {
+			putUvarint(uint64(v.Expires))
+		}
+	}
+	return buf.Bytes()
+}
+
+func decodeVersions(b []byte) ([]storage.Version, error) {
+	expiring := bytes.HasPrefix(b, expiringMagic)
+	if !expiring && !bytes.HasPrefix(b, versionsMagic) {
+		return []storage.Version{{Clock: storage.VectorClock{}, Data: b}}, nil
+	}
+	r := bytes.NewReader(b[len(versionsMagic):])
+	readBytes := func() ([]byte, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n > uint64(r.Len()) {
+			return nil, errCorruptedVersions
+		}
+		res := make([]byte, n)
+		r.Read(res)
+		return res, nil
+	}
+
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errCorruptedVersions
+	}
+	var versions []storage.Version
+	for i := uint64(0); i < n; i++ {
+		ids, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, errCorruptedVersions
+		}
+		v := storage.Version{Clock: storage.VectorClock{}}
+		for j := uint64(0); j < ids; j++ {
+			id, err := readBytes()
+			if err != nil {
+				return nil, err
+			}
+			if v.Clock[string(id)], err = binary.ReadUvarint(r); err != nil {
+				return nil, errCorruptedVersions
+			}
+		}
+		if v.Data, err = readBytes(); err != nil {
+			return nil, err
+		}
+		if expiring {
+			expires, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, errCorruptedVersions
+			}
+			v.Expires = int64(expires)
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// GetVersions returns all unexpired versions of a record which are not superseded
+// by each other. Returns the storage.ErrRecordNotFound error if the record doesn't exist
+// or all its versions have expired.
+//
+// GetVersions возвращает все неистекшие версии записи, не замещенные друг другом.
+// Возвращает ошибку storage.ErrRecordNotFound, если записи не существует
+// или все ее версии истекли.
+func (node *Node) GetVersions(k storage.Key) ([]storage.Version, error) {
+	b, err := node.engine.Get(k)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := decodeVersions(b)
+	if err != nil {
+		return nil, err
+	}
+	versions = storage.Unexpired(versions, time.Now())
+	if len(versions) == 0 {
+		return nil, storage.ErrRecordNotFound
+	}
+	return versions, nil
+}
+
+// GetVersionsContext is GetVersions which is not performed if ctx is already done.
+//
+// GetVersionsContext -- GetVersions, который не выполняется, если ctx уже завершен.
+func (node *Node) GetVersionsContext(ctx context.Context, k storage.Key) ([]storage.Version, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	atomic.AddUint64(&node.reads, 1)
+	return node.GetVersions(k)
+}
+
+// Update stores a version of a record. Versions v.Clock descends are replaced,
+// versions concurrent with v are kept as its siblings. Returns
+// the storage.ErrObsoleteVersion error if a stored version descends v,
+// and the storage.ErrConflict error if a stored version has the clock of v
+// but different data: two writes got the same clock, and the later one
+// would be lost otherwise. Updating a record with a version it already
+// has does nothing.
+//
+// Update сохраняет версию записи. Версии, которым наследует v.Clock, заменяются,
+// версии, параллельные v, сохраняются вместе с ней. Возвращает ошибку
+// storage.ErrObsoleteVersion, если сохраненная версия наследует v,
+// и ошибку storage.ErrConflict, если у сохраненной версии часы v, но другие
+// данные: две записи получили одинаковые часы, и иначе более поздняя
+// была бы потеряна. Повторное сохранение уже имеющейся версии ничего не делает.
+func (node *Node) Update(k storage.Key, v storage.Version) error {
+	lock := node.lock(k)
+	lock.Lock()
+	defer lock.Unlock()
+
+	versions, err := node.GetVersions(k)
+	if err == storage.ErrRecordNotFound {
+		// Set replaces an expired record which isn't reclaimed yet.
+		return node.engine.Set(k, encodeVersions([]storage.Version{v}))
+	}
+	if err != nil {
+		return err
+	}
+	for _, s := range versions {
+		switch v.Clock.Compare(s.Clock) {
+		case storage.Equal:
+			if !bytes.Equal(v.Data, s.Data) {
+				return storage.ErrConflict
+			}
+			return nil
+		case storage.Before:
+			return storage.ErrObsoleteVersion
+		}
+	}
+
+	return node.engine.Set(k, encodeVersions(storage.Reconcile(versions, []storage.Version{v})))
+}
+
+// UpdateContext is Update which is not performed if ctx is already done.
+//
+// UpdateContext -- Update, который не выполняется, если ctx уже завершен.
+func (node *Node) UpdateContext(ctx context.Context, k storage.Key, v storage.Version) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	atomic.AddUint64(&node.writes, 1)
+	return node.Update(k, v)
+}
+
+// sendVersions stores versions of a record on another node. If the node client
+// doesn't support versions, only a record without siblings is sent with Put.
+func (node *Node) sendVersions(ctx context.Context, owner storage.ServiceAddr, k storage.Key, versions []storage.Version) error {
+	if vc, ok := node.cfg.NodeClient.(storage.VersionedClient); ok {
+		for _, v := range versions {
+			if err := vc.UpdateContext(ctx, owner, k, v); err != nil && err != storage.ErrObsoleteVersion {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(versions) > 1 {
+		return storage.ErrConflict
+	}
+	if versions[0].Expires != 0 {
+		ctx = storage.WithExpiry(ctx, time.Unix(0, versions[0].Expires))
+	}
+	err := storage.WithKeys(node.cfg.NodeClient).PutKeyContext(ctx, owner, k, versions[0].Data)
+	if err == storage.ErrRecordExists {
+		return nil
+	}
+	return err
+}
+
+// lock returns the lock serializing updates of the record with key k.
+func (node *Node) lock(k storage.Key) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(k))
+	return &node.locks[h.Sum32()%lockStripes]
+}
@@ -5,8 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"router/pb"
 	"storage"
@@ -18,33 +23,235 @@ type Client interface {
 	List(router storage.ServiceAddr) ([]storage.ServiceAddr, error)
 }
 
-type RouterClient struct{}
+// ContextClient is a Client whose requests are bound to a context:
+// the request is cancelled with ctx and doesn't outlive its deadline.
+type ContextClient interface {
+	HeartbeatContext(ctx context.Context, router, node storage.ServiceAddr) error
+	NodesFindContext(ctx context.Context, router storage.ServiceAddr, k storage.RecordID) ([]storage.ServiceAddr, error)
+	ListContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, error)
+}
 
-var defaultClient Client = RouterClient{}
+// WithContext returns c as a ContextClient. If c doesn't implement ContextClient,
+// ctx is only checked before a request is sent.
+func WithContext(c Client) ContextClient {
+	if cc, ok := c.(ContextClient); ok {
+		return cc
+	}
+	return contextClient{c}
+}
+
+type contextClient struct {
+	c Client
+}
 
+func (c contextClient) HeartbeatContext(ctx context.Context, router, node storage.ServiceAddr) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.c.Heartbeat(router, node)
+}
+
+func (c contextClient) NodesFindContext(ctx context.Context, router storage.ServiceAddr, k storage.RecordID) ([]storage.ServiceAddr, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.c.NodesFind(router, k)
+}
+
+func (c contextClient) ListContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.c.List(router)
+}
+
+// RouterClient is a Client keeping connections to routers in a storage.ConnPool.
+// The zero RouterClient dials a new connection for every request.
+//
+// A router address may list replicas of a router separated by commas,
+// see Routers. A request is sent to the replica which answered the last one
+// and fails over to the others if the replica is unavailable or is not
+// the leader for a request changing the cluster state.
+type RouterClient struct {
+	pool    *storage.ConnPool
+	leaders *leaders
+}
+
+// Routers returns the address of a router replicated on replicas.
+func Routers(replicas ...storage.ServiceAddr) storage.ServiceAddr {
+	addrs := make([]string, len(replicas))
+	for i, r := range replicas {
+		addrs[i] = string(r)
+	}
+	return storage.ServiceAddr(strings.Join(addrs, ","))
+}
+
+// leaders remembers the replica of each router which answered the last request.
+type leaders struct {
+	lock sync.Mutex
+	m    map[storage.ServiceAddr]storage.ServiceAddr
+}
+
+func (l *leaders) get(router storage.ServiceAddr) storage.ServiceAddr {
+	if l == nil {
+		return ""
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.m[router]
+}
+
+func (l *leaders) set(router, replica storage.ServiceAddr) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.m[router] = replica
+}
+
+// notLeaderError is storage.ErrNotLeader carrying the address of the leader.
+type notLeaderError storage.ServiceAddr
+
+func (e notLeaderError) Error() string {
+	return storage.ErrNotLeader.Error()
+}
+
+// New creates a RouterClient with its own connection pool.
+// Close should be called to release the pooled connections.
 func New() Client {
-	return defaultClient
+	return NewPooled(storage.DefaultIdleTimeout)
+}
+
+// NewPooled creates a RouterClient closing connections idle for longer than idleTimeout.
+func NewPooled(idleTimeout time.Duration) RouterClient {
+	return RouterClient{
+		pool:    storage.NewConnPool(idleTimeout),
+		leaders: &leaders{m: make(map[storage.ServiceAddr]storage.ServiceAddr)},
+	}
 }
 
-func (c RouterClient) do(addr storage.ServiceAddr, cb func(client pb.RouterClient) ([]storage.ServiceAddr, error)) ([]storage.ServiceAddr, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), storage.Timeout)
-	defer cancel()
-	conn, err := grpc.DialContext(ctx, string(addr), grpc.WithInsecure())
+// Close closes pooled connections.
+func (c RouterClient) Close() error {
+	if c.pool == nil {
+		return nil
+	}
+	return c.pool.Close()
+}
+
+// do calls cb with a client of a replica of router failing over to the others.
+func (c RouterClient) do(ctx context.Context, router storage.ServiceAddr, cb func(client pb.RouterClient) ([]storage.ServiceAddr, error)) ([]storage.ServiceAddr, error) {
+	// The replica which answered the last request goes first,
+	// the leader replica goes next if a follower reports it.
+	replicas := strings.Split(string(router), ",")
+	tried := make(map[storage.ServiceAddr]bool, len(replicas))
+	for _, r := range replicas {
+		tried[storage.ServiceAddr(r)] = false
+	}
+	next := c.leaders.get(router)
+	var (
+		nodes []storage.ServiceAddr
+		err   error
+	)
+	for attempt := 0; attempt < len(replicas); attempt++ {
+		if done, ok := tried[next]; !ok || done {
+			for _, r := range replicas {
+				if !tried[storage.ServiceAddr(r)] {
+					next = storage.ServiceAddr(r)
+					break
+				}
+			}
+		}
+		tried[next] = true
+
+		nodes, err = c.doReplica(ctx, next, cb)
+		if !failover(err) || ctx.Err() != nil {
+			if err == nil {
+				c.leaders.set(router, next)
+			}
+			break
+		}
+		log.Printf("Router replica %q failed: %v", next, err)
+		if leader, ok := err.(notLeaderError); ok {
+			next = storage.ServiceAddr(leader)
+		}
+	}
+	if _, ok := err.(notLeaderError); ok {
+		err = storage.ErrNotLeader
+	}
+	return nodes, err
+}
+
+// failover reports whether a request failed with err has to be sent to another replica.
+func failover(err error) bool {
+	if _, ok := err.(notLeaderError); ok || err == storage.ErrNotLeader {
+		return true
+	}
+	code := status.Code(err)
+	return code == codes.Unavailable || code == codes.DeadlineExceeded
+}
+
+func (c RouterClient) doReplica(ctx context.Context, addr storage.ServiceAddr, cb func(client pb.RouterClient) ([]storage.ServiceAddr, error)) ([]storage.ServiceAddr, error) {
+	if c.pool == nil {
+		ctx, cancel := context.WithTimeout(ctx, storage.Timeout)
+		defer cancel()
+		conn, err := grpc.DialContext(ctx, string(addr), grpc.WithInsecure())
+		if err != nil {
+			return nil, fmt.Errorf("Error dialing %q: %v", addr, err)
+		}
+		defer conn.Close()
+		return cb(pb.NewRouterClient(conn))
+	}
+
+	conn, release, err := c.pool.Get(addr)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	nodes, err := cb(pb.NewRouterClient(conn))
 	if err != nil {
-		return nil, fmt.Errorf("Error dialing %q: %v", addr, err)
+		c.pool.Failed(addr, conn, err)
 	}
-	defer conn.Close()
-	client := pb.NewRouterClient(conn)
-	return cb(client)
+	return nodes, err
 }
 
 func (c RouterClient) Heartbeat(router, node storage.ServiceAddr) error {
+	return c.HeartbeatContext(context.Background(), router, node)
+}
+
+func (c RouterClient) HeartbeatContext(ctx context.Context, router, node storage.ServiceAddr) error {
+	return c.heartbeat(ctx, router, node, nil)
+}
+
+// LoadClient sends heartbeats reporting the load of a node.
+type LoadClient interface {
+	HeartbeatLoad(router, node storage.ServiceAddr, load storage.NodeLoad) error
+	HeartbeatLoadContext(ctx context.Context, router, node storage.ServiceAddr, load storage.NodeLoad) error
+}
+
+func (c RouterClient) HeartbeatLoad(router, node storage.ServiceAddr, load storage.NodeLoad) error {
+	return c.HeartbeatLoadContext(context.Background(), router, node, load)
+}
+
+func (c RouterClient) HeartbeatLoadContext(ctx context.Context, router, node storage.ServiceAddr, load storage.NodeLoad) error {
+	return c.heartbeat(ctx, router, node, &pb.NodeLoad{
+		Records:     load.Records,
+		Bytes:       load.Bytes,
+		Memory:      load.Memory,
+		ReadRate:    load.ReadRate,
+		WriteRate:   load.WriteRate,
+		EngineError: load.EngineError,
+	})
+}
+
+func (c RouterClient) heartbeat(ctx context.Context, router, node storage.ServiceAddr, load *pb.NodeLoad) error {
 	log.Printf("Hearbeat request to %q", router)
-	_, err := c.do(router, func(client pb.RouterClient) ([]storage.ServiceAddr, error) {
-		ctx, cancel := context.WithTimeout(context.Background(), storage.Timeout)
+	_, err := c.do(ctx, router, func(client pb.RouterClient) ([]storage.ServiceAddr, error) {
+		ctx, cancel := context.WithTimeout(ctx, storage.Timeout)
 		defer cancel()
 		req := pb.HBRequest{
 			Node: string(node),
+			Load: load,
 		}
 		reply, err := client.Heartbeat(ctx, &req)
 		if err != nil {
@@ -56,6 +263,9 @@ func (c RouterClient) Heartbeat(router, node storage.ServiceAddr) error {
 		if status == storage.StatusOk {
 			return nil, nil
 		}
+		if status == storage.StatusNotLeader {
+			return nil, notLeaderError(reply.Leader)
+		}
 
 		if err := status.ToError(); err != storage.ErrUnknownStatus {
 			return nil, err
@@ -66,14 +276,19 @@ func (c RouterClient) Heartbeat(router, node storage.ServiceAddr) error {
 }
 
 func (c RouterClient) NodesFind(router storage.ServiceAddr, k storage.RecordID) ([]storage.ServiceAddr, error) {
-	log.Printf("NodesFind request: key = %v", k)
-	return c.do(router, func(client pb.RouterClient) ([]storage.ServiceAddr, error) {
-		ctx, cancel := context.WithTimeout(context.Background(), storage.Timeout)
+	return c.NodesFindContext(context.Background(), router, k)
+}
+
+func (c RouterClient) NodesFindContext(ctx context.Context, router storage.ServiceAddr, k storage.RecordID) ([]storage.ServiceAddr, error) {
+	return c.NodesFindKeyContext(ctx, router, k.Key())
+}
+
+func (c RouterClient) NodesFindKeyContext(ctx context.Context, router storage.ServiceAddr, k storage.Key) ([]storage.ServiceAddr, error) {
+	log.Printf("NodesFind request: key = %s", storage.FormatKey(k))
+	return c.do(ctx, router, func(client pb.RouterClient) ([]storage.ServiceAddr, error) {
+		ctx, cancel := context.WithTimeout(ctx, storage.Timeout)
 		defer cancel()
-		req := pb.NFRequest{
-			Key: uint32(k),
-		}
-		reply, err := client.NodesFind(ctx, &req)
+		reply, err := client.NodesFind(ctx, nfRequest(k))
 		if err != nil {
 			return nil, err
 		}
@@ -96,9 +311,50 @@ func (c RouterClient) NodesFind(router storage.ServiceAddr, k storage.RecordID)
 }
 
 func (c RouterClient) List(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+	return c.ListContext(context.Background(), router)
+}
+
+func (c RouterClient) ListContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+	nodes, _, err := c.TopologyContext(ctx, router)
+	return nodes, err
+}
+
+func (c RouterClient) Topology(router storage.ServiceAddr) ([]storage.ServiceAddr, uint64, error) {
+	return c.TopologyContext(context.Background(), router)
+}
+
+func (c RouterClient) TopologyContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, uint64, error) {
+	nodes, epoch, _, _, err := c.list(ctx, router)
+	return nodes, epoch, err
+}
+
+func (c RouterClient) ListReplication(router storage.ServiceAddr) ([]storage.ServiceAddr, storage.Replication, error) {
+	return c.ListReplicationContext(context.Background(), router)
+}
+
+func (c RouterClient) ListReplicationContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, storage.Replication, error) {
+	nodes, _, rep, _, err := c.list(ctx, router)
+	return nodes, rep, err
+}
+
+func (c RouterClient) ListPlacement(router storage.ServiceAddr) ([]storage.ServiceAddr, storage.Replication, storage.Placement, error) {
+	return c.ListPlacementContext(context.Background(), router)
+}
+
+func (c RouterClient) ListPlacementContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, storage.Replication, storage.Placement, error) {
+	nodes, _, rep, placement, err := c.list(ctx, router)
+	return nodes, rep, placement, err
+}
+
+func (c RouterClient) list(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, uint64, storage.Replication, storage.Placement, error) {
 	log.Printf("List request")
-	return c.do(router, func(client pb.RouterClient) ([]storage.ServiceAddr, error) {
-		ctx, cancel := context.WithTimeout(context.Background(), storage.Timeout)
+	var (
+		epoch     uint64
+		rep       storage.Replication
+		placement storage.Placement
+	)
+	nodes, err := c.do(ctx, router, func(client pb.RouterClient) ([]storage.ServiceAddr, error) {
+		ctx, cancel := context.WithTimeout(ctx, storage.Timeout)
 		defer cancel()
 		reply, err := client.List(ctx, &pb.Empty{})
 		if err != nil {
@@ -108,6 +364,13 @@ func (c RouterClient) List(router storage.ServiceAddr) ([]storage.ServiceAddr, e
 		status := storage.StatusCode(reply.Status)
 
 		if status == storage.StatusOk {
+			epoch = reply.Epoch
+			// Routers which don't report replication use the defaults.
+			rep = storage.Replication{
+				Factor: int(reply.ReplicationFactor),
+				Quorum: int(reply.Quorum),
+			}.Normalize()
+			placement = placementFromPB(reply.Capacity, reply.Labels, reply.Spread)
 			nodes := make([]storage.ServiceAddr, 0, len(reply.Nodes))
 			for _, node := range reply.Nodes {
 				nodes = append(nodes, storage.ServiceAddr(node))
@@ -120,4 +383,363 @@ func (c RouterClient) List(router storage.ServiceAddr) ([]storage.ServiceAddr, e
 		}
 		return nil, errors.New(reply.Error)
 	})
+	return nodes, epoch, rep, placement, err
+}
+
+func placementFromPB(capacity map[string]float64, labels map[string]*pb.NodeLabels, spread string) storage.Placement {
+	p := storage.Placement{Spread: storage.FailureDomain(spread)}
+	if len(capacity) != 0 {
+		p.Capacity = make(map[storage.ServiceAddr]float64, len(capacity))
+		for node, w := range capacity {
+			p.Capacity[storage.ServiceAddr(node)] = w
+		}
+	}
+	if len(labels) != 0 {
+		p.Labels = make(map[storage.ServiceAddr]storage.NodeLabels, len(labels))
+		for node, l := range labels {
+			if l != nil {
+				p.Labels[storage.ServiceAddr(node)] = storage.NodeLabels{Zone: l.Zone, Rack: l.Rack}
+			}
+		}
+	}
+	return p
+}
+
+// TopologyClient returns the nodes served by a router
+// along with the topology epoch they belong to.
+type TopologyClient interface {
+	Topology(router storage.ServiceAddr) ([]storage.ServiceAddr, uint64, error)
+	TopologyContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, uint64, error)
+}
+
+// TopologyWatcher subscribes to the topology of a router.
+type TopologyWatcher interface {
+	// WatchTopology calls f with the topology of router and then on every change
+	// of it, and periodically while it's unchanged. Returns when ctx is done,
+	// the subscription fails or f returns an error.
+	WatchTopology(ctx context.Context, router storage.ServiceAddr, f func(storage.Topology) error) error
+}
+
+func (c RouterClient) WatchTopology(ctx context.Context, router storage.ServiceAddr, f func(storage.Topology) error) error {
+	log.Printf("WatchTopology request to %q", router)
+	_, err := c.do(ctx, router, func(client pb.RouterClient) ([]storage.ServiceAddr, error) {
+		stream, err := client.WatchTopology(ctx, &pb.Empty{})
+		if err != nil {
+			return nil, err
+		}
+		for {
+			reply, err := stream.Recv()
+			if err != nil {
+				return nil, err
+			}
+			t := storage.Topology{
+				Epoch: reply.Epoch,
+				Replication: storage.Replication{
+					Factor: int(reply.ReplicationFactor),
+					Quorum: int(reply.Quorum),
+				}.Normalize(),
+				Placement: placementFromPB(reply.Capacity, reply.Labels, reply.Spread),
+			}
+			t.Nodes = make([]storage.ServiceAddr, 0, len(reply.Nodes))
+			for _, node := range reply.Nodes {
+				t.Nodes = append(t.Nodes, storage.ServiceAddr(node))
+			}
+			t.Alive = make([]storage.ServiceAddr, 0, len(reply.Alive))
+			for _, node := range reply.Alive {
+				t.Alive = append(t.Alive, storage.ServiceAddr(node))
+			}
+			if err := f(t); err != nil {
+				return nil, err
+			}
+		}
+	})
+	return err
+}
+
+// ReplicationClient returns the nodes served by a router along with
+// the replication factor and quorum of the cluster.
+type ReplicationClient interface {
+	ListReplication(router storage.ServiceAddr) ([]storage.ServiceAddr, storage.Replication, error)
+	ListReplicationContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, storage.Replication, error)
+}
+
+// PlacementClient returns the nodes served by a router along with
+// the replication and placement settings of the cluster.
+type PlacementClient interface {
+	ListPlacement(router storage.ServiceAddr) ([]storage.ServiceAddr, storage.Replication, storage.Placement, error)
+	ListPlacementContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, storage.Replication, storage.Placement, error)
+}
+
+// HintedClient finds replicas for hinted handoff and reports
+// which nodes are alive according to a router.
+type HintedClient interface {
+	NodesFindHinted(router storage.ServiceAddr, k storage.RecordID) ([]storage.Replica, error)
+	NodesFindHintedContext(ctx context.Context, router storage.ServiceAddr, k storage.RecordID) ([]storage.Replica, error)
+	AliveClient
+}
+
+// AliveClient reports which nodes are alive according to a router.
+type AliveClient interface {
+	Alive(router storage.ServiceAddr) ([]storage.ServiceAddr, error)
+	AliveContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, error)
+}
+
+func (c RouterClient) NodesFindHinted(router storage.ServiceAddr, k storage.RecordID) ([]storage.Replica, error) {
+	return c.NodesFindHintedContext(context.Background(), router, k)
+}
+
+func (c RouterClient) NodesFindHintedContext(ctx context.Context, router storage.ServiceAddr, k storage.RecordID) ([]storage.Replica, error) {
+	return c.NodesFindHintedKeyContext(ctx, router, k.Key())
+}
+
+func (c RouterClient) NodesFindHintedKeyContext(ctx context.Context, router storage.ServiceAddr, k storage.Key) ([]storage.Replica, error) {
+	log.Printf("NodesFindHinted request: key = %s", storage.FormatKey(k))
+	var replicas []storage.Replica
+	_, err := c.do(ctx, router, func(client pb.RouterClient) ([]storage.ServiceAddr, error) {
+		ctx, cancel := context.WithTimeout(ctx, storage.Timeout)
+		defer cancel()
+		reply, err := client.NodesFindHinted(ctx, nfRequest(k))
+		if err != nil {
+			return nil, err
+		}
+
+		status := storage.StatusCode(reply.Status)
+
+		if status == storage.StatusOk {
+			if len(reply.Hints) != len(reply.Nodes) {
+				return nil, errors.New("Hints don't match nodes")
+			}
+			for i, node := range reply.Nodes {
+				replicas = append(replicas, storage.Replica{
+					Node: storage.ServiceAddr(node),
+					Hint: storage.ServiceAddr(reply.Hints[i]),
+				})
+			}
+			return nil, nil
+		}
+
+		if err := status.ToError(); err != storage.ErrUnknownStatus {
+			return nil, err
+		}
+		return nil, errors.New(reply.Error)
+	})
+	return replicas, err
+}
+
+// KeyClient finds nodes for records with byte keys. The methods taking
+// a storage.RecordID are its compatibility path.
+type KeyClient interface {
+	NodesFindKeyContext(ctx context.Context, router storage.ServiceAddr, k storage.Key) ([]storage.ServiceAddr, error)
+	NodesFindHintedKeyContext(ctx context.Context, router storage.ServiceAddr, k storage.Key) ([]storage.Replica, error)
+}
+
+// nfRequest returns a request for nodes of k. The RecordID of k is sent
+// along with it for routers which don't know byte keys.
+func nfRequest(k storage.Key) *pb.NFRequest {
+	id, _ := k.RecordID()
+	return &pb.NFRequest{
+		Key:      uint32(id),
+		KeyBytes: []byte(k),
+	}
+}
+
+func (c RouterClient) Alive(router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+	return c.AliveContext(context.Background(), router)
+}
+
+func (c RouterClient) AliveContext(ctx context.Context, router storage.ServiceAddr) ([]storage.ServiceAddr, error) {
+	log.Printf("Alive request")
+	return c.do(ctx, router, func(client pb.RouterClient) ([]storage.ServiceAddr, error) {
+		ctx, cancel := context.WithTimeout(ctx, storage.Timeout)
+		defer cancel()
+		reply, err := client.Alive(ctx, &pb.Empty{})
+		if err != nil {
+			return nil, err
+		}
+
+		status := storage.StatusCode(reply.Status)
+
+		if status == storage.StatusOk {
+			nodes := make([]storage.ServiceAddr, 0, len(reply.Nodes))
+			for _, node := range reply.Nodes {
+				nodes = append(nodes, storage.ServiceAddr(node))
+			}
+			return nodes, nil
+		}
+
+		if err := status.ToError(); err != storage.ErrUnknownStatus {
+			return nil, err
+		}
+		return nil, errors.New(reply.Error)
+	})
+}
+
+// SuspicionClient reports the state of nodes in the failure detector of a router.
+type SuspicionClient interface {
+	Suspicion(router storage.ServiceAddr) ([]storage.NodeSuspicion, error)
+	SuspicionContext(ctx context.Context, router storage.ServiceAddr) ([]storage.NodeSuspicion, error)
+}
+
+func (c RouterClient) Suspicion(router storage.ServiceAddr) ([]storage.NodeSuspicion, error) {
+	return c.SuspicionContext(context.Background(), router)
+}
+
+func (c RouterClient) SuspicionContext(ctx context.Context, router storage.ServiceAddr) ([]storage.NodeSuspicion, error) {
+	log.Printf("Suspicion request")
+	var nodes []storage.NodeSuspicion
+	_, err := c.do(ctx, router, func(client pb.RouterClient) ([]storage.ServiceAddr, error) {
+		ctx, cancel := context.WithTimeout(ctx, storage.Timeout)
+		defer cancel()
+		reply, err := client.Suspicion(ctx, &pb.Empty{})
+		if err != nil {
+			return nil, err
+		}
+
+		status := storage.StatusCode(reply.Status)
+
+		if status == storage.StatusOk {
+			nodes = make([]storage.NodeSuspicion, 0, len(reply.Nodes))
+			for _, n := range reply.Nodes {
+				nodes = append(nodes, suspicionFromPB(n))
+			}
+			return nil, nil
+		}
+
+		if err := status.ToError(); err != storage.ErrUnknownStatus {
+			return nil, err
+		}
+		return nil, errors.New(reply.Error)
+	})
+	return nodes, err
+}
+
+func suspicionFromPB(n *pb.NodeSuspicion) storage.NodeSuspicion {
+	s := storage.NodeSuspicion{
+		Node:      storage.ServiceAddr(n.Node),
+		Phi:       n.Phi,
+		Alive:     n.Alive,
+		Intervals: int(n.Intervals),
+	}
+	if n.LastHeartbeat != 0 {
+		s.LastHeartbeat = time.Unix(0, n.LastHeartbeat)
+	}
+	return s
+}
+
+// StatusClient reports the state of the cluster served by a router
+// along with the loads of its nodes.
+type StatusClient interface {
+	ClusterStatus(router storage.ServiceAddr) (storage.ClusterStatus, error)
+	ClusterStatusContext(ctx context.Context, router storage.ServiceAddr) (storage.ClusterStatus, error)
+}
+
+func (c RouterClient) ClusterStatus(router storage.ServiceAddr) (storage.ClusterStatus, error) {
+	return c.ClusterStatusContext(context.Background(), router)
+}
+
+func (c RouterClient) ClusterStatusContext(ctx context.Context, router storage.ServiceAddr) (storage.ClusterStatus, error) {
+	log.Printf("ClusterStatus request")
+	var cs storage.ClusterStatus
+	_, err := c.do(ctx, router, func(client pb.RouterClient) ([]storage.ServiceAddr, error) {
+		ctx, cancel := context.WithTimeout(ctx, storage.Timeout)
+		defer cancel()
+		reply, err := client.ClusterStatus(ctx, &pb.Empty{})
+		if err != nil {
+			return nil, err
+		}
+
+		status := storage.StatusCode(reply.Status)
+
+		if status == storage.StatusOk {
+			cs = storage.ClusterStatus{
+				Epoch: reply.Epoch,
+				Replication: storage.Replication{
+					Factor: int(reply.ReplicationFactor),
+					Quorum: int(reply.Quorum),
+				},
+				Leader: storage.ServiceAddr(reply.Leader),
+				Nodes:  make([]storage.NodeStatus, 0, len(reply.Nodes)),
+			}
+			for _, n := range reply.Nodes {
+				ns := storage.NodeStatus{Draining: n.Draining}
+				if n.Suspicion != nil {
+					ns.NodeSuspicion = suspicionFromPB(n.Suspicion)
+				}
+				if l := n.Load; l != nil {
+					ns.Load = storage.NodeLoad{
+						Records:     l.Records,
+						Bytes:       l.Bytes,
+						Memory:      l.Memory,
+						ReadRate:    l.ReadRate,
+						WriteRate:   l.WriteRate,
+						EngineError: l.EngineError,
+					}
+				}
+				cs.Nodes = append(cs.Nodes, ns)
+			}
+			return nil, nil
+		}
+
+		if err := status.ToError(); err != storage.ErrUnknownStatus {
+			return nil, err
+		}
+		return nil, errors.New(reply.Error)
+	})
+	return cs, err
+}
+
+// AdminClient changes the set of nodes served by a router.
+// Every method returns the new topology epoch.
+type AdminClient interface {
+	AddNode(router, node storage.ServiceAddr) (uint64, error)
+	RemoveNode(router, node storage.ServiceAddr) (uint64, error)
+	DrainNode(router, node storage.ServiceAddr) (uint64, error)
+}
+
+func (c RouterClient) AddNode(router, node storage.ServiceAddr) (uint64, error) {
+	log.Printf("AddNode request to %q: node = %q", router, node)
+	return c.changeNode(router, node, pb.RouterClient.AddNode)
+}
+
+func (c RouterClient) RemoveNode(router, node storage.ServiceAddr) (uint64, error) {
+	log.Printf("RemoveNode request to %q: node = %q", router, node)
+	return c.changeNode(router, node, pb.RouterClient.RemoveNode)
+}
+
+func (c RouterClient) DrainNode(router, node storage.ServiceAddr) (uint64, error) {
+	log.Printf("DrainNode request to %q: node = %q", router, node)
+	return c.changeNode(router, node, pb.RouterClient.DrainNode)
+}
+
+type changeNodeFunc func(pb.RouterClient, context.Context, *pb.NodeRequest, ...grpc.CallOption) (*pb.NodeReply, error)
+
+func (c RouterClient) changeNode(router, node storage.ServiceAddr, change changeNodeFunc) (uint64, error) {
+	var epoch uint64
+	_, err := c.do(context.Background(), router, func(client pb.RouterClient) ([]storage.ServiceAddr, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), storage.Timeout)
+		defer cancel()
+		req := pb.NodeRequest{
+			Node: string(node),
+		}
+		reply, err := change(client, ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+		epoch = reply.Epoch
+
+		status := storage.StatusCode(reply.Status)
+
+		if status == storage.StatusOk {
+			return nil, nil
+		}
+		if status == storage.StatusNotLeader {
+			return nil, notLeaderError(reply.Leader)
+		}
+
+		if err := status.ToError(); err != storage.ErrUnknownStatus {
+			return nil, err
+		}
+		return nil, errors.New(reply.Error)
+	})
+	return epoch, err
 }
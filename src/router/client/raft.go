@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"router/pb"
+	"router/raft"
+	"storage"
+)
+
+// RaftTransport is a raft.Transport sending requests between replicas
+// of a router. Connections are kept in a storage.ConnPool.
+type RaftTransport struct {
+	pool *storage.ConnPool
+}
+
+// NewRaftTransport creates a RaftTransport closing connections idle for longer than idleTimeout.
+// Close should be called to release the pooled connections.
+func NewRaftTransport(idleTimeout time.Duration) RaftTransport {
+	return RaftTransport{
+		pool: storage.NewConnPool(idleTimeout),
+	}
+}
+
+// Close closes pooled connections.
+func (t RaftTransport) Close() error {
+	return t.pool.Close()
+}
+
+func (t RaftTransport) do(peer storage.ServiceAddr, cb func(client pb.RouterClient) error) error {
+	conn, release, err := t.pool.Get(peer)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if err := cb(pb.NewRouterClient(conn)); err != nil {
+		t.pool.Failed(peer, conn, err)
+		return err
+	}
+	return nil
+}
+
+func (t RaftTransport) RequestVote(ctx context.Context, peer storage.ServiceAddr, req raft.VoteRequest) (raft.VoteReply, error) {
+	var reply raft.VoteReply
+	err := t.do(peer, func(client pb.RouterClient) error {
+		r, err := client.RequestVote(ctx, &pb.VoteRequest{
+			Term:         req.Term,
+			Candidate:    string(req.Candidate),
+			LastLogIndex: req.LastLogIndex,
+			LastLogTerm:  req.LastLogTerm,
+		})
+		if err != nil {
+			return err
+		}
+		reply = raft.VoteReply{Term: r.Term, Granted: r.Granted}
+		return nil
+	})
+	return reply, err
+}
+
+func (t RaftTransport) AppendEntries(ctx context.Context, peer storage.ServiceAddr, req raft.AppendRequest) (raft.AppendReply, error) {
+	entries := make([]*pb.Entry, 0, len(req.Entries))
+	for _, e := range req.Entries {
+		entries = append(entries, &pb.Entry{Index: e.Index, Term: e.Term, Cmd: e.Cmd})
+	}
+	var reply raft.AppendReply
+	err := t.do(peer, func(client pb.RouterClient) error {
+		r, err := client.AppendEntries(ctx, &pb.AppendRequest{
+			Term:         req.Term,
+			Leader:       string(req.Leader),
+			PrevLogIndex: req.PrevLogIndex,
+			PrevLogTerm:  req.PrevLogTerm,
+			Entries:      entries,
+			LeaderCommit: req.LeaderCommit,
+		})
+		if err != nil {
+			return err
+		}
+		reply = raft.AppendReply{Term: r.Term, Success: r.Success, LastIndex: r.LastIndex}
+		return nil
+	})
+	return reply, err
+}
+
+func (t RaftTransport) InstallSnapshot(ctx context.Context, peer storage.ServiceAddr, req raft.SnapshotRequest) (raft.SnapshotReply, error) {
+	var reply raft.SnapshotReply
+	err := t.do(peer, func(client pb.RouterClient) error {
+		r, err := client.InstallSnapshot(ctx, &pb.SnapshotRequest{
+			Term:      req.Term,
+			Leader:    string(req.Leader),
+			LastIndex: req.LastIndex,
+			LastTerm:  req.LastTerm,
+			Data:      req.Data,
+		})
+		if err != nil {
+			return err
+		}
+		reply = raft.SnapshotReply{Term: r.Term}
+		return nil
+	})
+	return reply, err
+}
@@ -7,8 +7,10 @@ import (
 
 	yaml "gopkg.in/yaml.v2"
 
+	"router/client"
 	"router/router"
 	"router/server"
+	"storage"
 )
 
 func usage() {
@@ -37,10 +39,25 @@ func parseConfig(fname string) (cfg router.Config, err error) {
 	if cfg.ForgetTimeout == 0 {
 		return cfg, fmt.Errorf("Failed to parse config file %q: ForgetTimeout should be set and be positive", fname)
 	}
+	if cfg.PhiThreshold < 0 {
+		return cfg, fmt.Errorf("Failed to parse config file %q: PhiThreshold should not be negative", fname)
+	}
+	if len(cfg.Peers) > 0 && !hasPeer(cfg.Peers, cfg.Addr) {
+		return cfg, fmt.Errorf("Failed to parse config file %q: Peers should include Addr", fname)
+	}
 
 	return cfg, nil
 }
 
+func hasPeer(peers []storage.ServiceAddr, addr storage.ServiceAddr) bool {
+	for _, p := range peers {
+		if p == addr {
+			return true
+		}
+	}
+	return false
+}
+
 func main() {
 	if len(os.Args) != 2 {
 		usage()
@@ -52,9 +69,18 @@ func main() {
 		log.Fatal(err)
 	}
 
-	hasher := router.NewMD5Hasher()
+	hasher, err := cfg.NewHasher()
+	if err != nil {
+		log.Fatalf("Failed to create hasher: %v", err)
+	}
 	cfg.NodesFinder = router.NewNodesFinder(hasher)
 
+	if len(cfg.Peers) > 1 {
+		t := client.NewRaftTransport(storage.DefaultIdleTimeout)
+		defer t.Close()
+		cfg.Transport = t
+	}
+
 	r, err := router.New(cfg)
 	if err != nil {
 		log.Fatalf("Failed to create router: %v", err)
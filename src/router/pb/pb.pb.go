@@ -24,17 +24,18 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type HBRequest struct {
-	Node                 string   `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Node                 string    `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Load                 *NodeLoad `protobuf:"bytes,2,opt,name=load,proto3" json:"load,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
 }
 
 func (m *HBRequest) Reset()         { *m = HBRequest{} }
 func (m *HBRequest) String() string { return proto.CompactTextString(m) }
 func (*HBRequest) ProtoMessage()    {}
 func (*HBRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_fb5e298dfa49bbfc, []int{0}
+	return fileDescriptor_pb_cd483b2a1564d205, []int{0}
 }
 func (m *HBRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_HBRequest.Unmarshal(m, b)
@@ -61,9 +62,95 @@ func (m *HBRequest) GetNode() string {
 	return ""
 }
 
+func (m *HBRequest) GetLoad() *NodeLoad {
+	if m != nil {
+		return m.Load
+	}
+	return nil
+}
+
+type NodeLoad struct {
+	Records              int64    `protobuf:"varint,1,opt,name=records,proto3" json:"records,omitempty"`
+	Bytes                int64    `protobuf:"varint,2,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	Memory               uint64   `protobuf:"varint,3,opt,name=memory,proto3" json:"memory,omitempty"`
+	ReadRate             float64  `protobuf:"fixed64,4,opt,name=read_rate,json=readRate,proto3" json:"read_rate,omitempty"`
+	WriteRate            float64  `protobuf:"fixed64,5,opt,name=write_rate,json=writeRate,proto3" json:"write_rate,omitempty"`
+	EngineError          string   `protobuf:"bytes,6,opt,name=engine_error,json=engineError,proto3" json:"engine_error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NodeLoad) Reset()         { *m = NodeLoad{} }
+func (m *NodeLoad) String() string { return proto.CompactTextString(m) }
+func (*NodeLoad) ProtoMessage()    {}
+func (*NodeLoad) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_cd483b2a1564d205, []int{1}
+}
+func (m *NodeLoad) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeLoad.Unmarshal(m, b)
+}
+func (m *NodeLoad) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeLoad.Marshal(b, m, deterministic)
+}
+func (dst *NodeLoad) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeLoad.Merge(dst, src)
+}
+func (m *NodeLoad) XXX_Size() int {
+	return xxx_messageInfo_NodeLoad.Size(m)
+}
+func (m *NodeLoad) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeLoad.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeLoad proto.InternalMessageInfo
+
+func (m *NodeLoad) GetRecords() int64 {
+	if m != nil {
+		return m.Records
+	}
+	return 0
+}
+
+func (m *NodeLoad) GetBytes() int64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+func (m *NodeLoad) GetMemory() uint64 {
+	if m != nil {
+		return m.Memory
+	}
+	return 0
+}
+
+func (m *NodeLoad) GetReadRate() float64 {
+	if m != nil {
+		return m.ReadRate
+	}
+	return 0
+}
+
+func (m *NodeLoad) GetWriteRate() float64 {
+	if m != nil {
+		return m.WriteRate
+	}
+	return 0
+}
+
+func (m *NodeLoad) GetEngineError() string {
+	if m != nil {
+		return m.EngineError
+	}
+	return ""
+}
+
 type HBReply struct {
 	Status               int32    `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
 	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Leader               string   `protobuf:"bytes,3,opt,name=leader,proto3" json:"leader,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -73,7 +160,7 @@ func (m *HBReply) Reset()         { *m = HBReply{} }
 func (m *HBReply) String() string { return proto.CompactTextString(m) }
 func (*HBReply) ProtoMessage()    {}
 func (*HBReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_fb5e298dfa49bbfc, []int{1}
+	return fileDescriptor_pb_cd483b2a1564d205, []int{2}
 }
 func (m *HBReply) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_HBReply.Unmarshal(m, b)
@@ -107,8 +194,16 @@ func (m *HBReply) GetError() string {
 	return ""
 }
 
+func (m *HBReply) GetLeader() string {
+	if m != nil {
+		return m.Leader
+	}
+	return ""
+}
+
 type NFRequest struct {
 	Key                  uint32   `protobuf:"varint,1,opt,name=key,proto3" json:"key,omitempty"`
+	KeyBytes             []byte   `protobuf:"bytes,2,opt,name=key_bytes,json=keyBytes,proto3" json:"key_bytes,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -118,7 +213,7 @@ func (m *NFRequest) Reset()         { *m = NFRequest{} }
 func (m *NFRequest) String() string { return proto.CompactTextString(m) }
 func (*NFRequest) ProtoMessage()    {}
 func (*NFRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_fb5e298dfa49bbfc, []int{2}
+	return fileDescriptor_pb_cd483b2a1564d205, []int{3}
 }
 func (m *NFRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_NFRequest.Unmarshal(m, b)
@@ -145,10 +240,19 @@ func (m *NFRequest) GetKey() uint32 {
 	return 0
 }
 
+func (m *NFRequest) GetKeyBytes() []byte {
+	if m != nil {
+		return m.KeyBytes
+	}
+	return nil
+}
+
 type NFReply struct {
 	Status               int32    `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
 	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
 	Nodes                []string `protobuf:"bytes,3,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	Epoch                uint64   `protobuf:"varint,4,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Hints                []string `protobuf:"bytes,5,rep,name=hints,proto3" json:"hints,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -158,7 +262,7 @@ func (m *NFReply) Reset()         { *m = NFReply{} }
 func (m *NFReply) String() string { return proto.CompactTextString(m) }
 func (*NFReply) ProtoMessage()    {}
 func (*NFReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_fb5e298dfa49bbfc, []int{3}
+	return fileDescriptor_pb_cd483b2a1564d205, []int{4}
 }
 func (m *NFReply) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_NFReply.Unmarshal(m, b)
@@ -199,6 +303,20 @@ func (m *NFReply) GetNodes() []string {
 	return nil
 }
 
+func (m *NFReply) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *NFReply) GetHints() []string {
+	if m != nil {
+		return m.Hints
+	}
+	return nil
+}
+
 type Empty struct {
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
@@ -209,7 +327,7 @@ func (m *Empty) Reset()         { *m = Empty{} }
 func (m *Empty) String() string { return proto.CompactTextString(m) }
 func (*Empty) ProtoMessage()    {}
 func (*Empty) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_fb5e298dfa49bbfc, []int{4}
+	return fileDescriptor_pb_cd483b2a1564d205, []int{5}
 }
 func (m *Empty) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Empty.Unmarshal(m, b)
@@ -230,19 +348,25 @@ func (m *Empty) XXX_DiscardUnknown() {
 var xxx_messageInfo_Empty proto.InternalMessageInfo
 
 type ListReply struct {
-	Status               int32    `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
-	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
-	Nodes                []string `protobuf:"bytes,3,rep,name=nodes,proto3" json:"nodes,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Status               int32                  `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	Error                string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Nodes                []string               `protobuf:"bytes,3,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	Epoch                uint64                 `protobuf:"varint,4,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	ReplicationFactor    uint32                 `protobuf:"varint,5,opt,name=replication_factor,json=replicationFactor,proto3" json:"replication_factor,omitempty"`
+	Quorum               uint32                 `protobuf:"varint,6,opt,name=quorum,proto3" json:"quorum,omitempty"`
+	Capacity             map[string]float64     `protobuf:"bytes,7,rep,name=capacity,proto3" json:"capacity,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"fixed64,2,opt,name=value,proto3"`
+	Labels               map[string]*NodeLabels `protobuf:"bytes,8,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Spread               string                 `protobuf:"bytes,9,opt,name=spread,proto3" json:"spread,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
 }
 
 func (m *ListReply) Reset()         { *m = ListReply{} }
 func (m *ListReply) String() string { return proto.CompactTextString(m) }
 func (*ListReply) ProtoMessage()    {}
 func (*ListReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_fb5e298dfa49bbfc, []int{5}
+	return fileDescriptor_pb_cd483b2a1564d205, []int{6}
 }
 func (m *ListReply) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListReply.Unmarshal(m, b)
@@ -283,118 +407,1230 @@ func (m *ListReply) GetNodes() []string {
 	return nil
 }
 
-func init() {
-	proto.RegisterType((*HBRequest)(nil), "HBRequest")
-	proto.RegisterType((*HBReply)(nil), "HBReply")
-	proto.RegisterType((*NFRequest)(nil), "NFRequest")
-	proto.RegisterType((*NFReply)(nil), "NFReply")
-	proto.RegisterType((*Empty)(nil), "Empty")
-	proto.RegisterType((*ListReply)(nil), "ListReply")
+func (m *ListReply) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+func (m *ListReply) GetReplicationFactor() uint32 {
+	if m != nil {
+		return m.ReplicationFactor
+	}
+	return 0
+}
 
-// RouterClient is the client API for Router service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type RouterClient interface {
-	Heartbeat(ctx context.Context, in *HBRequest, opts ...grpc.CallOption) (*HBReply, error)
-	NodesFind(ctx context.Context, in *NFRequest, opts ...grpc.CallOption) (*NFReply, error)
-	List(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListReply, error)
+func (m *ListReply) GetQuorum() uint32 {
+	if m != nil {
+		return m.Quorum
+	}
+	return 0
 }
 
-type routerClient struct {
-	cc *grpc.ClientConn
+func (m *ListReply) GetCapacity() map[string]float64 {
+	if m != nil {
+		return m.Capacity
+	}
+	return nil
 }
 
-func NewRouterClient(cc *grpc.ClientConn) RouterClient {
-	return &routerClient{cc}
+func (m *ListReply) GetLabels() map[string]*NodeLabels {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
 }
 
-func (c *routerClient) Heartbeat(ctx context.Context, in *HBRequest, opts ...grpc.CallOption) (*HBReply, error) {
-	out := new(HBReply)
-	err := c.cc.Invoke(ctx, "/Router/Heartbeat", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *ListReply) GetSpread() string {
+	if m != nil {
+		return m.Spread
 	}
-	return out, nil
+	return ""
 }
 
-func (c *routerClient) NodesFind(ctx context.Context, in *NFRequest, opts ...grpc.CallOption) (*NFReply, error) {
-	out := new(NFReply)
-	err := c.cc.Invoke(ctx, "/Router/NodesFind", in, out, opts...)
-	if err != nil {
-		return nil, err
+type NodeLabels struct {
+	Zone                 string   `protobuf:"bytes,1,opt,name=zone,proto3" json:"zone,omitempty"`
+	Rack                 string   `protobuf:"bytes,2,opt,name=rack,proto3" json:"rack,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NodeLabels) Reset()         { *m = NodeLabels{} }
+func (m *NodeLabels) String() string { return proto.CompactTextString(m) }
+func (*NodeLabels) ProtoMessage()    {}
+func (*NodeLabels) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_cd483b2a1564d205, []int{7}
+}
+func (m *NodeLabels) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeLabels.Unmarshal(m, b)
+}
+func (m *NodeLabels) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeLabels.Marshal(b, m, deterministic)
+}
+func (dst *NodeLabels) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeLabels.Merge(dst, src)
+}
+func (m *NodeLabels) XXX_Size() int {
+	return xxx_messageInfo_NodeLabels.Size(m)
+}
+func (m *NodeLabels) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeLabels.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeLabels proto.InternalMessageInfo
+
+func (m *NodeLabels) GetZone() string {
+	if m != nil {
+		return m.Zone
 	}
-	return out, nil
+	return ""
 }
 
-func (c *routerClient) List(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListReply, error) {
-	out := new(ListReply)
-	err := c.cc.Invoke(ctx, "/Router/List", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *NodeLabels) GetRack() string {
+	if m != nil {
+		return m.Rack
 	}
-	return out, nil
+	return ""
 }
 
-// RouterServer is the server API for Router service.
-type RouterServer interface {
-	Heartbeat(context.Context, *HBRequest) (*HBReply, error)
-	NodesFind(context.Context, *NFRequest) (*NFReply, error)
-	List(context.Context, *Empty) (*ListReply, error)
+type NodeRequest struct {
+	Node                 string   `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func RegisterRouterServer(s *grpc.Server, srv RouterServer) {
-	s.RegisterService(&_Router_serviceDesc, srv)
+func (m *NodeRequest) Reset()         { *m = NodeRequest{} }
+func (m *NodeRequest) String() string { return proto.CompactTextString(m) }
+func (*NodeRequest) ProtoMessage()    {}
+func (*NodeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_cd483b2a1564d205, []int{8}
+}
+func (m *NodeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeRequest.Unmarshal(m, b)
+}
+func (m *NodeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeRequest.Marshal(b, m, deterministic)
+}
+func (dst *NodeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeRequest.Merge(dst, src)
+}
+func (m *NodeRequest) XXX_Size() int {
+	return xxx_messageInfo_NodeRequest.Size(m)
+}
+func (m *NodeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeRequest.DiscardUnknown(m)
 }
 
-func _Router_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(HBRequest)
-	if err := dec(in); err != nil {
-		return nil, err
-	}
-	if interceptor == nil {
-		return srv.(RouterServer).Heartbeat(ctx, in)
+var xxx_messageInfo_NodeRequest proto.InternalMessageInfo
+
+func (m *NodeRequest) GetNode() string {
+	if m != nil {
+		return m.Node
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/Router/Heartbeat",
+	return ""
+}
+
+type NodeReply struct {
+	Status               int32    `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Epoch                uint64   `protobuf:"varint,3,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Leader               string   `protobuf:"bytes,4,opt,name=leader,proto3" json:"leader,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NodeReply) Reset()         { *m = NodeReply{} }
+func (m *NodeReply) String() string { return proto.CompactTextString(m) }
+func (*NodeReply) ProtoMessage()    {}
+func (*NodeReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_cd483b2a1564d205, []int{9}
+}
+func (m *NodeReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeReply.Unmarshal(m, b)
+}
+func (m *NodeReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeReply.Marshal(b, m, deterministic)
+}
+func (dst *NodeReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeReply.Merge(dst, src)
+}
+func (m *NodeReply) XXX_Size() int {
+	return xxx_messageInfo_NodeReply.Size(m)
+}
+func (m *NodeReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeReply proto.InternalMessageInfo
+
+func (m *NodeReply) GetStatus() int32 {
+	if m != nil {
+		return m.Status
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(RouterServer).Heartbeat(ctx, req.(*HBRequest))
+	return 0
+}
+
+func (m *NodeReply) GetError() string {
+	if m != nil {
+		return m.Error
 	}
-	return interceptor(ctx, in, info, handler)
+	return ""
 }
 
-func _Router_NodesFind_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(NFRequest)
-	if err := dec(in); err != nil {
-		return nil, err
+func (m *NodeReply) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
 	}
-	if interceptor == nil {
-		return srv.(RouterServer).NodesFind(ctx, in)
+	return 0
+}
+
+func (m *NodeReply) GetLeader() string {
+	if m != nil {
+		return m.Leader
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/Router/NodesFind",
+	return ""
+}
+
+type TopologyReply struct {
+	Epoch                uint64                 `protobuf:"varint,1,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Nodes                []string               `protobuf:"bytes,2,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	Alive                []string               `protobuf:"bytes,3,rep,name=alive,proto3" json:"alive,omitempty"`
+	ReplicationFactor    uint32                 `protobuf:"varint,4,opt,name=replication_factor,json=replicationFactor,proto3" json:"replication_factor,omitempty"`
+	Quorum               uint32                 `protobuf:"varint,5,opt,name=quorum,proto3" json:"quorum,omitempty"`
+	Capacity             map[string]float64     `protobuf:"bytes,6,rep,name=capacity,proto3" json:"capacity,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"fixed64,2,opt,name=value,proto3"`
+	Labels               map[string]*NodeLabels `protobuf:"bytes,7,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Spread               string                 `protobuf:"bytes,8,opt,name=spread,proto3" json:"spread,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *TopologyReply) Reset()         { *m = TopologyReply{} }
+func (m *TopologyReply) String() string { return proto.CompactTextString(m) }
+func (*TopologyReply) ProtoMessage()    {}
+func (*TopologyReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_cd483b2a1564d205, []int{10}
+}
+func (m *TopologyReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TopologyReply.Unmarshal(m, b)
+}
+func (m *TopologyReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TopologyReply.Marshal(b, m, deterministic)
+}
+func (dst *TopologyReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TopologyReply.Merge(dst, src)
+}
+func (m *TopologyReply) XXX_Size() int {
+	return xxx_messageInfo_TopologyReply.Size(m)
+}
+func (m *TopologyReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_TopologyReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TopologyReply proto.InternalMessageInfo
+
+func (m *TopologyReply) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(RouterServer).NodesFind(ctx, req.(*NFRequest))
+	return 0
+}
+
+func (m *TopologyReply) GetNodes() []string {
+	if m != nil {
+		return m.Nodes
 	}
-	return interceptor(ctx, in, info, handler)
+	return nil
 }
 
-func _Router_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Empty)
-	if err := dec(in); err != nil {
-		return nil, err
+func (m *TopologyReply) GetAlive() []string {
+	if m != nil {
+		return m.Alive
+	}
+	return nil
+}
+
+func (m *TopologyReply) GetReplicationFactor() uint32 {
+	if m != nil {
+		return m.ReplicationFactor
+	}
+	return 0
+}
+
+func (m *TopologyReply) GetQuorum() uint32 {
+	if m != nil {
+		return m.Quorum
+	}
+	return 0
+}
+
+func (m *TopologyReply) GetCapacity() map[string]float64 {
+	if m != nil {
+		return m.Capacity
+	}
+	return nil
+}
+
+func (m *TopologyReply) GetLabels() map[string]*NodeLabels {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *TopologyReply) GetSpread() string {
+	if m != nil {
+		return m.Spread
+	}
+	return ""
+}
+
+type NodeSuspicion struct {
+	Node                 string   `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Phi                  float64  `protobuf:"fixed64,2,opt,name=phi,proto3" json:"phi,omitempty"`
+	Alive                bool     `protobuf:"varint,3,opt,name=alive,proto3" json:"alive,omitempty"`
+	Intervals            uint32   `protobuf:"varint,4,opt,name=intervals,proto3" json:"intervals,omitempty"`
+	LastHeartbeat        int64    `protobuf:"varint,5,opt,name=last_heartbeat,json=lastHeartbeat,proto3" json:"last_heartbeat,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NodeSuspicion) Reset()         { *m = NodeSuspicion{} }
+func (m *NodeSuspicion) String() string { return proto.CompactTextString(m) }
+func (*NodeSuspicion) ProtoMessage()    {}
+func (*NodeSuspicion) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_cd483b2a1564d205, []int{11}
+}
+func (m *NodeSuspicion) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeSuspicion.Unmarshal(m, b)
+}
+func (m *NodeSuspicion) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeSuspicion.Marshal(b, m, deterministic)
+}
+func (dst *NodeSuspicion) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeSuspicion.Merge(dst, src)
+}
+func (m *NodeSuspicion) XXX_Size() int {
+	return xxx_messageInfo_NodeSuspicion.Size(m)
+}
+func (m *NodeSuspicion) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeSuspicion.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeSuspicion proto.InternalMessageInfo
+
+func (m *NodeSuspicion) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *NodeSuspicion) GetPhi() float64 {
+	if m != nil {
+		return m.Phi
+	}
+	return 0
+}
+
+func (m *NodeSuspicion) GetAlive() bool {
+	if m != nil {
+		return m.Alive
+	}
+	return false
+}
+
+func (m *NodeSuspicion) GetIntervals() uint32 {
+	if m != nil {
+		return m.Intervals
+	}
+	return 0
+}
+
+func (m *NodeSuspicion) GetLastHeartbeat() int64 {
+	if m != nil {
+		return m.LastHeartbeat
+	}
+	return 0
+}
+
+type SuspicionReply struct {
+	Status               int32            `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	Error                string           `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Nodes                []*NodeSuspicion `protobuf:"bytes,3,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *SuspicionReply) Reset()         { *m = SuspicionReply{} }
+func (m *SuspicionReply) String() string { return proto.CompactTextString(m) }
+func (*SuspicionReply) ProtoMessage()    {}
+func (*SuspicionReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_cd483b2a1564d205, []int{12}
+}
+func (m *SuspicionReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SuspicionReply.Unmarshal(m, b)
+}
+func (m *SuspicionReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SuspicionReply.Marshal(b, m, deterministic)
+}
+func (dst *SuspicionReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SuspicionReply.Merge(dst, src)
+}
+func (m *SuspicionReply) XXX_Size() int {
+	return xxx_messageInfo_SuspicionReply.Size(m)
+}
+func (m *SuspicionReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_SuspicionReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SuspicionReply proto.InternalMessageInfo
+
+func (m *SuspicionReply) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *SuspicionReply) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *SuspicionReply) GetNodes() []*NodeSuspicion {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+type NodeStatus struct {
+	Suspicion            *NodeSuspicion `protobuf:"bytes,1,opt,name=suspicion,proto3" json:"suspicion,omitempty"`
+	Draining             bool           `protobuf:"varint,2,opt,name=draining,proto3" json:"draining,omitempty"`
+	Load                 *NodeLoad      `protobuf:"bytes,3,opt,name=load,proto3" json:"load,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *NodeStatus) Reset()         { *m = NodeStatus{} }
+func (m *NodeStatus) String() string { return proto.CompactTextString(m) }
+func (*NodeStatus) ProtoMessage()    {}
+func (*NodeStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_cd483b2a1564d205, []int{13}
+}
+func (m *NodeStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeStatus.Unmarshal(m, b)
+}
+func (m *NodeStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeStatus.Marshal(b, m, deterministic)
+}
+func (dst *NodeStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeStatus.Merge(dst, src)
+}
+func (m *NodeStatus) XXX_Size() int {
+	return xxx_messageInfo_NodeStatus.Size(m)
+}
+func (m *NodeStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeStatus proto.InternalMessageInfo
+
+func (m *NodeStatus) GetSuspicion() *NodeSuspicion {
+	if m != nil {
+		return m.Suspicion
+	}
+	return nil
+}
+
+func (m *NodeStatus) GetDraining() bool {
+	if m != nil {
+		return m.Draining
+	}
+	return false
+}
+
+func (m *NodeStatus) GetLoad() *NodeLoad {
+	if m != nil {
+		return m.Load
+	}
+	return nil
+}
+
+type ClusterStatusReply struct {
+	Status               int32         `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	Error                string        `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Epoch                uint64        `protobuf:"varint,3,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	ReplicationFactor    uint32        `protobuf:"varint,4,opt,name=replication_factor,json=replicationFactor,proto3" json:"replication_factor,omitempty"`
+	Quorum               uint32        `protobuf:"varint,5,opt,name=quorum,proto3" json:"quorum,omitempty"`
+	Leader               string        `protobuf:"bytes,6,opt,name=leader,proto3" json:"leader,omitempty"`
+	Nodes                []*NodeStatus `protobuf:"bytes,7,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *ClusterStatusReply) Reset()         { *m = ClusterStatusReply{} }
+func (m *ClusterStatusReply) String() string { return proto.CompactTextString(m) }
+func (*ClusterStatusReply) ProtoMessage()    {}
+func (*ClusterStatusReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_cd483b2a1564d205, []int{14}
+}
+func (m *ClusterStatusReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ClusterStatusReply.Unmarshal(m, b)
+}
+func (m *ClusterStatusReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ClusterStatusReply.Marshal(b, m, deterministic)
+}
+func (dst *ClusterStatusReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ClusterStatusReply.Merge(dst, src)
+}
+func (m *ClusterStatusReply) XXX_Size() int {
+	return xxx_messageInfo_ClusterStatusReply.Size(m)
+}
+func (m *ClusterStatusReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ClusterStatusReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ClusterStatusReply proto.InternalMessageInfo
+
+func (m *ClusterStatusReply) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *ClusterStatusReply) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *ClusterStatusReply) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *ClusterStatusReply) GetReplicationFactor() uint32 {
+	if m != nil {
+		return m.ReplicationFactor
+	}
+	return 0
+}
+
+func (m *ClusterStatusReply) GetQuorum() uint32 {
+	if m != nil {
+		return m.Quorum
+	}
+	return 0
+}
+
+func (m *ClusterStatusReply) GetLeader() string {
+	if m != nil {
+		return m.Leader
+	}
+	return ""
+}
+
+func (m *ClusterStatusReply) GetNodes() []*NodeStatus {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+type Entry struct {
+	Index                uint64   `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Term                 uint64   `protobuf:"varint,2,opt,name=term,proto3" json:"term,omitempty"`
+	Cmd                  []byte   `protobuf:"bytes,3,opt,name=cmd,proto3" json:"cmd,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Entry) Reset()         { *m = Entry{} }
+func (m *Entry) String() string { return proto.CompactTextString(m) }
+func (*Entry) ProtoMessage()    {}
+func (*Entry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_cd483b2a1564d205, []int{15}
+}
+func (m *Entry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Entry.Unmarshal(m, b)
+}
+func (m *Entry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Entry.Marshal(b, m, deterministic)
+}
+func (dst *Entry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Entry.Merge(dst, src)
+}
+func (m *Entry) XXX_Size() int {
+	return xxx_messageInfo_Entry.Size(m)
+}
+func (m *Entry) XXX_DiscardUnknown() {
+	xxx_messageInfo_Entry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Entry proto.InternalMessageInfo
+
+func (m *Entry) GetIndex() uint64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *Entry) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func (m *Entry) GetCmd() []byte {
+	if m != nil {
+		return m.Cmd
+	}
+	return nil
+}
+
+type VoteRequest struct {
+	Term                 uint64   `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	Candidate            string   `protobuf:"bytes,2,opt,name=candidate,proto3" json:"candidate,omitempty"`
+	LastLogIndex         uint64   `protobuf:"varint,3,opt,name=last_log_index,json=lastLogIndex,proto3" json:"last_log_index,omitempty"`
+	LastLogTerm          uint64   `protobuf:"varint,4,opt,name=last_log_term,json=lastLogTerm,proto3" json:"last_log_term,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VoteRequest) Reset()         { *m = VoteRequest{} }
+func (m *VoteRequest) String() string { return proto.CompactTextString(m) }
+func (*VoteRequest) ProtoMessage()    {}
+func (*VoteRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_cd483b2a1564d205, []int{16}
+}
+func (m *VoteRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VoteRequest.Unmarshal(m, b)
+}
+func (m *VoteRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VoteRequest.Marshal(b, m, deterministic)
+}
+func (dst *VoteRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VoteRequest.Merge(dst, src)
+}
+func (m *VoteRequest) XXX_Size() int {
+	return xxx_messageInfo_VoteRequest.Size(m)
+}
+func (m *VoteRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_VoteRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VoteRequest proto.InternalMessageInfo
+
+func (m *VoteRequest) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func (m *VoteRequest) GetCandidate() string {
+	if m != nil {
+		return m.Candidate
+	}
+	return ""
+}
+
+func (m *VoteRequest) GetLastLogIndex() uint64 {
+	if m != nil {
+		return m.LastLogIndex
+	}
+	return 0
+}
+
+func (m *VoteRequest) GetLastLogTerm() uint64 {
+	if m != nil {
+		return m.LastLogTerm
+	}
+	return 0
+}
+
+type VoteReply struct {
+	Term                 uint64   `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	Granted              bool     `protobuf:"varint,2,opt,name=granted,proto3" json:"granted,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VoteReply) Reset()         { *m = VoteReply{} }
+func (m *VoteReply) String() string { return proto.CompactTextString(m) }
+func (*VoteReply) ProtoMessage()    {}
+func (*VoteReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_cd483b2a1564d205, []int{17}
+}
+func (m *VoteReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VoteReply.Unmarshal(m, b)
+}
+func (m *VoteReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VoteReply.Marshal(b, m, deterministic)
+}
+func (dst *VoteReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VoteReply.Merge(dst, src)
+}
+func (m *VoteReply) XXX_Size() int {
+	return xxx_messageInfo_VoteReply.Size(m)
+}
+func (m *VoteReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_VoteReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VoteReply proto.InternalMessageInfo
+
+func (m *VoteReply) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func (m *VoteReply) GetGranted() bool {
+	if m != nil {
+		return m.Granted
+	}
+	return false
+}
+
+type AppendRequest struct {
+	Term                 uint64   `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	Leader               string   `protobuf:"bytes,2,opt,name=leader,proto3" json:"leader,omitempty"`
+	PrevLogIndex         uint64   `protobuf:"varint,3,opt,name=prev_log_index,json=prevLogIndex,proto3" json:"prev_log_index,omitempty"`
+	PrevLogTerm          uint64   `protobuf:"varint,4,opt,name=prev_log_term,json=prevLogTerm,proto3" json:"prev_log_term,omitempty"`
+	Entries              []*Entry `protobuf:"bytes,5,rep,name=entries,proto3" json:"entries,omitempty"`
+	LeaderCommit         uint64   `protobuf:"varint,6,opt,name=leader_commit,json=leaderCommit,proto3" json:"leader_commit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AppendRequest) Reset()         { *m = AppendRequest{} }
+func (m *AppendRequest) String() string { return proto.CompactTextString(m) }
+func (*AppendRequest) ProtoMessage()    {}
+func (*AppendRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_cd483b2a1564d205, []int{18}
+}
+func (m *AppendRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AppendRequest.Unmarshal(m, b)
+}
+func (m *AppendRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AppendRequest.Marshal(b, m, deterministic)
+}
+func (dst *AppendRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AppendRequest.Merge(dst, src)
+}
+func (m *AppendRequest) XXX_Size() int {
+	return xxx_messageInfo_AppendRequest.Size(m)
+}
+func (m *AppendRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AppendRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AppendRequest proto.InternalMessageInfo
+
+func (m *AppendRequest) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func (m *AppendRequest) GetLeader() string {
+	if m != nil {
+		return m.Leader
+	}
+	return ""
+}
+
+func (m *AppendRequest) GetPrevLogIndex() uint64 {
+	if m != nil {
+		return m.PrevLogIndex
+	}
+	return 0
+}
+
+func (m *AppendRequest) GetPrevLogTerm() uint64 {
+	if m != nil {
+		return m.PrevLogTerm
+	}
+	return 0
+}
+
+func (m *AppendRequest) GetEntries() []*Entry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+func (m *AppendRequest) GetLeaderCommit() uint64 {
+	if m != nil {
+		return m.LeaderCommit
+	}
+	return 0
+}
+
+type AppendReply struct {
+	Term                 uint64   `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	Success              bool     `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	LastIndex            uint64   `protobuf:"varint,3,opt,name=last_index,json=lastIndex,proto3" json:"last_index,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AppendReply) Reset()         { *m = AppendReply{} }
+func (m *AppendReply) String() string { return proto.CompactTextString(m) }
+func (*AppendReply) ProtoMessage()    {}
+func (*AppendReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_cd483b2a1564d205, []int{19}
+}
+func (m *AppendReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AppendReply.Unmarshal(m, b)
+}
+func (m *AppendReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AppendReply.Marshal(b, m, deterministic)
+}
+func (dst *AppendReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AppendReply.Merge(dst, src)
+}
+func (m *AppendReply) XXX_Size() int {
+	return xxx_messageInfo_AppendReply.Size(m)
+}
+func (m *AppendReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_AppendReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AppendReply proto.InternalMessageInfo
+
+func (m *AppendReply) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func (m *AppendReply) GetSuccess() bool {
+	if m != nil {
+		return m.Success
+	}
+	return false
+}
+
+func (m *AppendReply) GetLastIndex() uint64 {
+	if m != nil {
+		return m.LastIndex
+	}
+	return 0
+}
+
+type SnapshotRequest struct {
+	Term                 uint64   `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	Leader               string   `protobuf:"bytes,2,opt,name=leader,proto3" json:"leader,omitempty"`
+	LastIndex            uint64   `protobuf:"varint,3,opt,name=last_index,json=lastIndex,proto3" json:"last_index,omitempty"`
+	LastTerm             uint64   `protobuf:"varint,4,opt,name=last_term,json=lastTerm,proto3" json:"last_term,omitempty"`
+	Data                 []byte   `protobuf:"bytes,5,opt,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SnapshotRequest) Reset()         { *m = SnapshotRequest{} }
+func (m *SnapshotRequest) String() string { return proto.CompactTextString(m) }
+func (*SnapshotRequest) ProtoMessage()    {}
+func (*SnapshotRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_cd483b2a1564d205, []int{20}
+}
+func (m *SnapshotRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SnapshotRequest.Unmarshal(m, b)
+}
+func (m *SnapshotRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SnapshotRequest.Marshal(b, m, deterministic)
+}
+func (dst *SnapshotRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SnapshotRequest.Merge(dst, src)
+}
+func (m *SnapshotRequest) XXX_Size() int {
+	return xxx_messageInfo_SnapshotRequest.Size(m)
+}
+func (m *SnapshotRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SnapshotRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SnapshotRequest proto.InternalMessageInfo
+
+func (m *SnapshotRequest) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func (m *SnapshotRequest) GetLeader() string {
+	if m != nil {
+		return m.Leader
+	}
+	return ""
+}
+
+func (m *SnapshotRequest) GetLastIndex() uint64 {
+	if m != nil {
+		return m.LastIndex
+	}
+	return 0
+}
+
+func (m *SnapshotRequest) GetLastTerm() uint64 {
+	if m != nil {
+		return m.LastTerm
+	}
+	return 0
+}
+
+func (m *SnapshotRequest) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+type SnapshotReply struct {
+	Term                 uint64   `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SnapshotReply) Reset()         { *m = SnapshotReply{} }
+func (m *SnapshotReply) String() string { return proto.CompactTextString(m) }
+func (*SnapshotReply) ProtoMessage()    {}
+func (*SnapshotReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_cd483b2a1564d205, []int{21}
+}
+func (m *SnapshotReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SnapshotReply.Unmarshal(m, b)
+}
+func (m *SnapshotReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SnapshotReply.Marshal(b, m, deterministic)
+}
+func (dst *SnapshotReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SnapshotReply.Merge(dst, src)
+}
+func (m *SnapshotReply) XXX_Size() int {
+	return xxx_messageInfo_SnapshotReply.Size(m)
+}
+func (m *SnapshotReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_SnapshotReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SnapshotReply proto.InternalMessageInfo
+
+func (m *SnapshotReply) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*HBRequest)(nil), "HBRequest")
+	proto.RegisterType((*NodeLoad)(nil), "NodeLoad")
+	proto.RegisterType((*HBReply)(nil), "HBReply")
+	proto.RegisterType((*NFRequest)(nil), "NFRequest")
+	proto.RegisterType((*NFReply)(nil), "NFReply")
+	proto.RegisterType((*Empty)(nil), "Empty")
+	proto.RegisterType((*ListReply)(nil), "ListReply")
+	proto.RegisterMapType((map[string]float64)(nil), "ListReply.CapacityEntry")
+	proto.RegisterMapType((map[string]*NodeLabels)(nil), "ListReply.LabelsEntry")
+	proto.RegisterType((*NodeLabels)(nil), "NodeLabels")
+	proto.RegisterType((*NodeRequest)(nil), "NodeRequest")
+	proto.RegisterType((*NodeReply)(nil), "NodeReply")
+	proto.RegisterType((*TopologyReply)(nil), "TopologyReply")
+	proto.RegisterMapType((map[string]float64)(nil), "TopologyReply.CapacityEntry")
+	proto.RegisterMapType((map[string]*NodeLabels)(nil), "TopologyReply.LabelsEntry")
+	proto.RegisterType((*NodeSuspicion)(nil), "NodeSuspicion")
+	proto.RegisterType((*SuspicionReply)(nil), "SuspicionReply")
+	proto.RegisterType((*NodeStatus)(nil), "NodeStatus")
+	proto.RegisterType((*ClusterStatusReply)(nil), "ClusterStatusReply")
+	proto.RegisterType((*Entry)(nil), "Entry")
+	proto.RegisterType((*VoteRequest)(nil), "VoteRequest")
+	proto.RegisterType((*VoteReply)(nil), "VoteReply")
+	proto.RegisterType((*AppendRequest)(nil), "AppendRequest")
+	proto.RegisterType((*AppendReply)(nil), "AppendReply")
+	proto.RegisterType((*SnapshotRequest)(nil), "SnapshotRequest")
+	proto.RegisterType((*SnapshotReply)(nil), "SnapshotReply")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// RouterClient is the client API for Router service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type RouterClient interface {
+	Heartbeat(ctx context.Context, in *HBRequest, opts ...grpc.CallOption) (*HBReply, error)
+	NodesFind(ctx context.Context, in *NFRequest, opts ...grpc.CallOption) (*NFReply, error)
+	List(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListReply, error)
+	NodesFindHinted(ctx context.Context, in *NFRequest, opts ...grpc.CallOption) (*NFReply, error)
+	Alive(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListReply, error)
+	AddNode(ctx context.Context, in *NodeRequest, opts ...grpc.CallOption) (*NodeReply, error)
+	RemoveNode(ctx context.Context, in *NodeRequest, opts ...grpc.CallOption) (*NodeReply, error)
+	DrainNode(ctx context.Context, in *NodeRequest, opts ...grpc.CallOption) (*NodeReply, error)
+	WatchTopology(ctx context.Context, in *Empty, opts ...grpc.CallOption) (Router_WatchTopologyClient, error)
+	Suspicion(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SuspicionReply, error)
+	ClusterStatus(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ClusterStatusReply, error)
+	RequestVote(ctx context.Context, in *VoteRequest, opts ...grpc.CallOption) (*VoteReply, error)
+	AppendEntries(ctx context.Context, in *AppendRequest, opts ...grpc.CallOption) (*AppendReply, error)
+	InstallSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*SnapshotReply, error)
+}
+
+type routerClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewRouterClient(cc *grpc.ClientConn) RouterClient {
+	return &routerClient{cc}
+}
+
+func (c *routerClient) Heartbeat(ctx context.Context, in *HBRequest, opts ...grpc.CallOption) (*HBReply, error) {
+	out := new(HBReply)
+	err := c.cc.Invoke(ctx, "/Router/Heartbeat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routerClient) NodesFind(ctx context.Context, in *NFRequest, opts ...grpc.CallOption) (*NFReply, error) {
+	out := new(NFReply)
+	err := c.cc.Invoke(ctx, "/Router/NodesFind", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routerClient) List(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListReply, error) {
+	out := new(ListReply)
+	err := c.cc.Invoke(ctx, "/Router/List", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routerClient) NodesFindHinted(ctx context.Context, in *NFRequest, opts ...grpc.CallOption) (*NFReply, error) {
+	out := new(NFReply)
+	err := c.cc.Invoke(ctx, "/Router/NodesFindHinted", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routerClient) Alive(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ListReply, error) {
+	out := new(ListReply)
+	err := c.cc.Invoke(ctx, "/Router/Alive", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routerClient) AddNode(ctx context.Context, in *NodeRequest, opts ...grpc.CallOption) (*NodeReply, error) {
+	out := new(NodeReply)
+	err := c.cc.Invoke(ctx, "/Router/AddNode", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routerClient) RemoveNode(ctx context.Context, in *NodeRequest, opts ...grpc.CallOption) (*NodeReply, error) {
+	out := new(NodeReply)
+	err := c.cc.Invoke(ctx, "/Router/RemoveNode", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routerClient) DrainNode(ctx context.Context, in *NodeRequest, opts ...grpc.CallOption) (*NodeReply, error) {
+	out := new(NodeReply)
+	err := c.cc.Invoke(ctx, "/Router/DrainNode", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routerClient) WatchTopology(ctx context.Context, in *Empty, opts ...grpc.CallOption) (Router_WatchTopologyClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Router_serviceDesc.Streams[0], "/Router/WatchTopology", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &routerWatchTopologyClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Router_WatchTopologyClient interface {
+	Recv() (*TopologyReply, error)
+	grpc.ClientStream
+}
+
+type routerWatchTopologyClient struct {
+	grpc.ClientStream
+}
+
+func (x *routerWatchTopologyClient) Recv() (*TopologyReply, error) {
+	m := new(TopologyReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *routerClient) Suspicion(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*SuspicionReply, error) {
+	out := new(SuspicionReply)
+	err := c.cc.Invoke(ctx, "/Router/Suspicion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routerClient) ClusterStatus(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ClusterStatusReply, error) {
+	out := new(ClusterStatusReply)
+	err := c.cc.Invoke(ctx, "/Router/ClusterStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routerClient) RequestVote(ctx context.Context, in *VoteRequest, opts ...grpc.CallOption) (*VoteReply, error) {
+	out := new(VoteReply)
+	err := c.cc.Invoke(ctx, "/Router/RequestVote", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routerClient) AppendEntries(ctx context.Context, in *AppendRequest, opts ...grpc.CallOption) (*AppendReply, error) {
+	out := new(AppendReply)
+	err := c.cc.Invoke(ctx, "/Router/AppendEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *routerClient) InstallSnapshot(ctx context.Context, in *SnapshotRequest, opts ...grpc.CallOption) (*SnapshotReply, error) {
+	out := new(SnapshotReply)
+	err := c.cc.Invoke(ctx, "/Router/InstallSnapshot", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RouterServer is the server API for Router service.
+type RouterServer interface {
+	Heartbeat(context.Context, *HBRequest) (*HBReply, error)
+	NodesFind(context.Context, *NFRequest) (*NFReply, error)
+	List(context.Context, *Empty) (*ListReply, error)
+	NodesFindHinted(context.Context, *NFRequest) (*NFReply, error)
+	Alive(context.Context, *Empty) (*ListReply, error)
+	AddNode(context.Context, *NodeRequest) (*NodeReply, error)
+	RemoveNode(context.Context, *NodeRequest) (*NodeReply, error)
+	DrainNode(context.Context, *NodeRequest) (*NodeReply, error)
+	WatchTopology(*Empty, Router_WatchTopologyServer) error
+	Suspicion(context.Context, *Empty) (*SuspicionReply, error)
+	ClusterStatus(context.Context, *Empty) (*ClusterStatusReply, error)
+	RequestVote(context.Context, *VoteRequest) (*VoteReply, error)
+	AppendEntries(context.Context, *AppendRequest) (*AppendReply, error)
+	InstallSnapshot(context.Context, *SnapshotRequest) (*SnapshotReply, error)
+}
+
+func RegisterRouterServer(s *grpc.Server, srv RouterServer) {
+	s.RegisterService(&_Router_serviceDesc, srv)
+}
+
+func _Router_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HBRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouterServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Router/Heartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouterServer).Heartbeat(ctx, req.(*HBRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Router_NodesFind_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NFRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouterServer).NodesFind(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Router/NodesFind",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouterServer).NodesFind(ctx, req.(*NFRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Router_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
 	if interceptor == nil {
 		return srv.(RouterServer).List(ctx, in)
@@ -409,6 +1645,207 @@ func _Router_List_Handler(srv interface{}, ctx context.Context, dec func(interfa
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Router_NodesFindHinted_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NFRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouterServer).NodesFindHinted(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Router/NodesFindHinted",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouterServer).NodesFindHinted(ctx, req.(*NFRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Router_Alive_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouterServer).Alive(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Router/Alive",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouterServer).Alive(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Router_AddNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouterServer).AddNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Router/AddNode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouterServer).AddNode(ctx, req.(*NodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Router_RemoveNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouterServer).RemoveNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Router/RemoveNode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouterServer).RemoveNode(ctx, req.(*NodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Router_DrainNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouterServer).DrainNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Router/DrainNode",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouterServer).DrainNode(ctx, req.(*NodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Router_WatchTopology_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RouterServer).WatchTopology(m, &routerWatchTopologyServer{stream})
+}
+
+type Router_WatchTopologyServer interface {
+	Send(*TopologyReply) error
+	grpc.ServerStream
+}
+
+type routerWatchTopologyServer struct {
+	grpc.ServerStream
+}
+
+func (x *routerWatchTopologyServer) Send(m *TopologyReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Router_Suspicion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouterServer).Suspicion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Router/Suspicion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouterServer).Suspicion(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Router_ClusterStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouterServer).ClusterStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Router/ClusterStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouterServer).ClusterStatus(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Router_RequestVote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouterServer).RequestVote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Router/RequestVote",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouterServer).RequestVote(ctx, req.(*VoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Router_AppendEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AppendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouterServer).AppendEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Router/AppendEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouterServer).AppendEntries(ctx, req.(*AppendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Router_InstallSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RouterServer).InstallSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Router/InstallSnapshot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RouterServer).InstallSnapshot(ctx, req.(*SnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Router_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "Router",
 	HandlerType: (*RouterServer)(nil),
@@ -425,28 +1862,138 @@ var _Router_serviceDesc = grpc.ServiceDesc{
 			MethodName: "List",
 			Handler:    _Router_List_Handler,
 		},
+		{
+			MethodName: "NodesFindHinted",
+			Handler:    _Router_NodesFindHinted_Handler,
+		},
+		{
+			MethodName: "Alive",
+			Handler:    _Router_Alive_Handler,
+		},
+		{
+			MethodName: "AddNode",
+			Handler:    _Router_AddNode_Handler,
+		},
+		{
+			MethodName: "RemoveNode",
+			Handler:    _Router_RemoveNode_Handler,
+		},
+		{
+			MethodName: "DrainNode",
+			Handler:    _Router_DrainNode_Handler,
+		},
+		{
+			MethodName: "Suspicion",
+			Handler:    _Router_Suspicion_Handler,
+		},
+		{
+			MethodName: "ClusterStatus",
+			Handler:    _Router_ClusterStatus_Handler,
+		},
+		{
+			MethodName: "RequestVote",
+			Handler:    _Router_RequestVote_Handler,
+		},
+		{
+			MethodName: "AppendEntries",
+			Handler:    _Router_AppendEntries_Handler,
+		},
+		{
+			MethodName: "InstallSnapshot",
+			Handler:    _Router_InstallSnapshot_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchTopology",
+			Handler:       _Router_WatchTopology_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "pb.proto",
 }
 
-func init() { proto.RegisterFile("pb.proto", fileDescriptor_pb_fb5e298dfa49bbfc) }
-
-var fileDescriptor_pb_fb5e298dfa49bbfc = []byte{
-	// 237 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x51, 0x4d, 0x4b, 0xc4, 0x30,
-	0x10, 0xed, 0xda, 0xed, 0x47, 0x06, 0x04, 0x19, 0x44, 0x4a, 0x51, 0x5c, 0xc6, 0x8b, 0xa7, 0x1c,
-	0xf4, 0xe0, 0x5d, 0x70, 0xd9, 0x83, 0xae, 0x90, 0x7f, 0xd0, 0xb2, 0x39, 0x2c, 0x6a, 0x13, 0x93,
-	0xe9, 0xa1, 0xff, 0x5e, 0x26, 0xd6, 0xde, 0x85, 0xbd, 0xcd, 0x9b, 0xbc, 0xf7, 0x78, 0x79, 0x03,
-	0xb5, 0xef, 0xb5, 0x0f, 0x8e, 0x1d, 0xdd, 0x82, 0xda, 0x3d, 0x1b, 0xfb, 0x3d, 0xda, 0xc8, 0x88,
-	0xb0, 0x1e, 0xdc, 0xc1, 0x36, 0xab, 0xcd, 0xea, 0x5e, 0x99, 0x34, 0xd3, 0x13, 0x54, 0x42, 0xf0,
-	0x9f, 0x13, 0x5e, 0x41, 0x19, 0xb9, 0xe3, 0x31, 0x26, 0x42, 0x61, 0x66, 0x84, 0x97, 0x50, 0xd8,
-	0x10, 0x5c, 0x68, 0xce, 0x92, 0xee, 0x17, 0xd0, 0x0d, 0xa8, 0xfd, 0xf6, 0xcf, 0xf9, 0x02, 0xf2,
-	0x0f, 0x3b, 0x25, 0xdd, 0xb9, 0x91, 0x91, 0xde, 0xa0, 0x92, 0xe7, 0x7f, 0xfb, 0xca, 0x56, 0x82,
-	0xc5, 0x26, 0xdf, 0xe4, 0xb2, 0x4d, 0x80, 0x2a, 0x28, 0x5e, 0xbe, 0x3c, 0x4f, 0xf4, 0x0e, 0xea,
-	0xf5, 0x18, 0xf9, 0x64, 0xce, 0x0f, 0x1e, 0x4a, 0xe3, 0x46, 0xb6, 0x01, 0xef, 0x40, 0xed, 0x6c,
-	0x17, 0xb8, 0xb7, 0x1d, 0x23, 0xe8, 0xa5, 0xb7, 0xb6, 0xd6, 0x73, 0x45, 0x94, 0x09, 0x69, 0x2f,
-	0xba, 0xed, 0x71, 0x38, 0x20, 0xe8, 0xa5, 0x82, 0xb6, 0xd6, 0xf3, 0x7f, 0x29, 0xc3, 0x6b, 0x58,
-	0x4b, 0x48, 0x2c, 0x75, 0x0a, 0xdd, 0x82, 0x5e, 0x32, 0x53, 0xd6, 0x97, 0xe9, 0x34, 0x8f, 0x3f,
-	0x01, 0x00, 0x00, 0xff, 0xff, 0xce, 0x93, 0xe4, 0x6f, 0xa6, 0x01, 0x00, 0x00,
+func init() { proto.RegisterFile("pb.proto", fileDescriptor_pb_cd483b2a1564d205) }
+
+var fileDescriptor_pb_cd483b2a1564d205 = []byte{
+	// 1253 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x57, 0xed, 0x8a, 0xe4, 0x44,
+	0x17, 0xee, 0x4c, 0xfa, 0x23, 0x39, 0xe9, 0xf4, 0xec, 0x5b, 0xef, 0xb0, 0x84, 0x76, 0x16, 0x7a,
+	0x6b, 0x77, 0xb5, 0x45, 0x8d, 0xd2, 0xae, 0xb0, 0xae, 0x20, 0xec, 0x8e, 0x33, 0xcc, 0xc2, 0x30,
+	0x42, 0xcd, 0xa2, 0xe0, 0x9f, 0xa6, 0x26, 0x29, 0xbb, 0xc3, 0xa4, 0x53, 0xd9, 0x4a, 0xf5, 0x68,
+	0x7b, 0x07, 0xfe, 0x10, 0xbc, 0x07, 0x2f, 0x40, 0xbc, 0x8f, 0xbd, 0x02, 0xaf, 0x46, 0xaa, 0x2a,
+	0x49, 0xa7, 0xe7, 0x13, 0x86, 0x15, 0xfc, 0x97, 0x73, 0xea, 0x9c, 0xaa, 0xf3, 0xf1, 0x3c, 0x55,
+	0x27, 0xe0, 0xe4, 0xa7, 0x61, 0x2e, 0xb8, 0xe4, 0xf8, 0x6b, 0x70, 0x0f, 0x5f, 0x12, 0xf6, 0x66,
+	0xc9, 0x0a, 0x89, 0x10, 0xb4, 0x33, 0x1e, 0xb3, 0xc0, 0x1a, 0x59, 0x63, 0x97, 0xe8, 0x6f, 0xf4,
+	0x00, 0xda, 0x29, 0xa7, 0x71, 0xb0, 0x35, 0xb2, 0xc6, 0xde, 0xc4, 0x0d, 0x8f, 0x79, 0xcc, 0x8e,
+	0x38, 0x8d, 0x89, 0x56, 0xe3, 0xbf, 0x2c, 0x70, 0x2a, 0x15, 0x0a, 0xa0, 0x27, 0x58, 0xc4, 0x45,
+	0x5c, 0xe8, 0x2d, 0x6c, 0x52, 0x89, 0x68, 0x07, 0x3a, 0xa7, 0x2b, 0xc9, 0x0a, 0xbd, 0x8d, 0x4d,
+	0x8c, 0x80, 0xee, 0x43, 0x77, 0xc1, 0x16, 0x5c, 0xac, 0x02, 0x7b, 0x64, 0x8d, 0xdb, 0xa4, 0x94,
+	0xd0, 0x7b, 0xe0, 0x0a, 0x46, 0xe3, 0xa9, 0xa0, 0x92, 0x05, 0xed, 0x91, 0x35, 0xb6, 0x88, 0xa3,
+	0x14, 0x84, 0x4a, 0x15, 0x10, 0xfc, 0x24, 0x12, 0xc9, 0xcc, 0x6a, 0x47, 0xaf, 0xba, 0x5a, 0xa3,
+	0x97, 0x1f, 0x42, 0x9f, 0x65, 0xb3, 0x24, 0x63, 0x53, 0x26, 0x04, 0x17, 0x41, 0x57, 0xe7, 0xe2,
+	0x19, 0xdd, 0xbe, 0x52, 0xe1, 0x6f, 0xa1, 0xa7, 0x72, 0xce, 0xd3, 0x95, 0x8a, 0xa0, 0x90, 0x54,
+	0x2e, 0x4d, 0xc0, 0x1d, 0x52, 0x4a, 0x2a, 0x5e, 0xe3, 0xbe, 0xa5, 0xdd, 0x8d, 0xa0, 0xac, 0x53,
+	0x46, 0x63, 0x26, 0x74, 0xbc, 0x2e, 0x29, 0x25, 0xfc, 0x1c, 0xdc, 0xe3, 0x83, 0xaa, 0x88, 0xf7,
+	0xc0, 0x3e, 0x63, 0x2b, 0xbd, 0x9f, 0x4f, 0xd4, 0xa7, 0x4a, 0xe7, 0x8c, 0xad, 0xa6, 0xeb, 0x02,
+	0xf4, 0x89, 0x73, 0xc6, 0x56, 0x2f, 0x95, 0x8c, 0x57, 0xd0, 0x3b, 0x3e, 0xb8, 0x4b, 0x30, 0x3b,
+	0xd0, 0x51, 0x0d, 0x2a, 0x02, 0x7b, 0x64, 0x2b, 0xad, 0x16, 0xb4, 0x6d, 0xce, 0xa3, 0xb9, 0x2e,
+	0x5b, 0x9b, 0x18, 0x41, 0x69, 0xe7, 0x49, 0x26, 0x8b, 0xa0, 0x63, 0x6c, 0xb5, 0x80, 0x7b, 0xd0,
+	0xd9, 0x5f, 0xe4, 0x72, 0x85, 0xff, 0xb4, 0xc1, 0x3d, 0x4a, 0x0a, 0xf9, 0x6f, 0x87, 0xf1, 0x09,
+	0x20, 0xc1, 0xf2, 0x34, 0x89, 0xa8, 0x4c, 0x78, 0x36, 0xfd, 0x91, 0x46, 0x92, 0x0b, 0xdd, 0x42,
+	0x9f, 0xfc, 0xaf, 0xb1, 0x72, 0xa0, 0x17, 0x54, 0x20, 0x6f, 0x96, 0x5c, 0x2c, 0x17, 0xba, 0x89,
+	0x3e, 0x29, 0x25, 0xf4, 0x14, 0x9c, 0x88, 0xe6, 0x34, 0x4a, 0xe4, 0x2a, 0xe8, 0x8d, 0xec, 0xb1,
+	0x37, 0x09, 0xc2, 0x3a, 0xfc, 0x70, 0xaf, 0x5c, 0xda, 0xcf, 0xa4, 0x58, 0x91, 0xda, 0x12, 0x85,
+	0xd0, 0x4d, 0xe9, 0x29, 0x4b, 0x8b, 0xc0, 0xd1, 0x3e, 0xf7, 0x1b, 0x3e, 0x47, 0x7a, 0xc1, 0x78,
+	0x94, 0x56, 0xba, 0x0c, 0xb9, 0x42, 0x5d, 0xe0, 0x9a, 0x66, 0x1b, 0x69, 0xf8, 0x15, 0xf8, 0x1b,
+	0x47, 0x34, 0x1b, 0xee, 0x9a, 0x86, 0xef, 0x40, 0xe7, 0x9c, 0xa6, 0x4b, 0xa6, 0x2b, 0x65, 0x11,
+	0x23, 0x3c, 0xdf, 0x7a, 0x66, 0x0d, 0x0f, 0xc0, 0x6b, 0x9c, 0x75, 0x85, 0xeb, 0xc3, 0xa6, 0xab,
+	0x37, 0xf1, 0x0c, 0xdf, 0xb4, 0x4b, 0x63, 0x1f, 0xfc, 0x14, 0x60, 0xbd, 0xa0, 0x78, 0xfb, 0x0b,
+	0xcf, 0x6a, 0xde, 0xaa, 0x6f, 0xa5, 0x13, 0x34, 0x3a, 0x2b, 0x9b, 0xa5, 0xbf, 0xf1, 0x43, 0xf0,
+	0x94, 0xd7, 0x0d, 0x74, 0xc7, 0x33, 0x70, 0x8d, 0xc9, 0x9d, 0x90, 0x60, 0x7a, 0x6e, 0x37, 0x7b,
+	0xbe, 0xe6, 0x4c, 0x7b, 0x83, 0x33, 0x7f, 0xd8, 0xe0, 0xbf, 0xe6, 0x39, 0x4f, 0xf9, 0x6c, 0x65,
+	0x4e, 0xab, 0xfd, 0xad, 0x0b, 0xd0, 0x35, 0xf8, 0xda, 0xba, 0x80, 0x2f, 0x9a, 0x26, 0xe7, 0xac,
+	0x42, 0x9d, 0x16, 0xae, 0xc1, 0x57, 0xfb, 0x76, 0x7c, 0x75, 0x36, 0xf0, 0xf5, 0xac, 0x81, 0xaf,
+	0xae, 0xc6, 0xca, 0x6e, 0xb8, 0x11, 0xea, 0xb5, 0x18, 0x9b, 0xd4, 0x18, 0x33, 0xb8, 0x1c, 0x5e,
+	0xf0, 0xbb, 0x19, 0x67, 0xce, 0x7f, 0x0f, 0x67, 0xbf, 0x5b, 0xe0, 0xab, 0x95, 0x93, 0x65, 0x91,
+	0x27, 0x51, 0xc2, 0xb3, 0x2b, 0xdf, 0x88, 0x7b, 0x60, 0xe7, 0xf3, 0xa4, 0x8c, 0x42, 0x7d, 0x36,
+	0xfb, 0x63, 0x8d, 0x9d, 0xaa, 0x3f, 0xbb, 0xe0, 0x26, 0x99, 0x64, 0xe2, 0x9c, 0xa6, 0x45, 0xd9,
+	0x96, 0xb5, 0x02, 0x3d, 0x81, 0x41, 0x4a, 0x0b, 0x39, 0x9d, 0x33, 0x2a, 0xe4, 0x29, 0xa3, 0x52,
+	0xb7, 0xc5, 0x26, 0xbe, 0xd2, 0x1e, 0x56, 0x4a, 0x1c, 0xc3, 0xa0, 0x8e, 0xe6, 0x2e, 0x30, 0x7d,
+	0xdc, 0xbc, 0xb0, 0xbc, 0xc9, 0x20, 0xdc, 0xc8, 0xaf, 0x04, 0x18, 0x5e, 0x1a, 0x82, 0x9d, 0x98,
+	0x9d, 0x3e, 0x06, 0xb7, 0xa8, 0x2c, 0xf4, 0x21, 0x97, 0xfd, 0xd6, 0x06, 0x68, 0x08, 0x4e, 0x2c,
+	0x68, 0x92, 0x25, 0xd9, 0x4c, 0x1f, 0xed, 0x90, 0x5a, 0xae, 0x9f, 0x53, 0xfb, 0xea, 0xe7, 0xf4,
+	0x6f, 0x0b, 0xd0, 0x5e, 0xba, 0x2c, 0x24, 0x13, 0xe6, 0xe8, 0x77, 0x47, 0xc4, 0x77, 0x44, 0x8e,
+	0x35, 0x9f, 0xbb, 0x4d, 0x3e, 0x2b, 0x40, 0x99, 0xb2, 0x1a, 0xe4, 0x1b, 0x40, 0x95, 0x39, 0x94,
+	0x35, 0xdd, 0x83, 0x8e, 0x81, 0xe3, 0x0e, 0x74, 0x92, 0x2c, 0x66, 0x3f, 0x57, 0x4c, 0xd7, 0x82,
+	0x42, 0x96, 0x64, 0x62, 0xa1, 0x73, 0x69, 0x13, 0xfd, 0xad, 0x90, 0x15, 0x2d, 0x4c, 0xb5, 0xfa,
+	0x44, 0x7d, 0xe2, 0x5f, 0x2d, 0xf0, 0xbe, 0xe3, 0xb2, 0x79, 0x89, 0x69, 0x2f, 0xab, 0xe1, 0xb5,
+	0x0b, 0x6e, 0x44, 0xb3, 0x38, 0x89, 0xd5, 0x84, 0x60, 0x4a, 0xb3, 0x56, 0xa0, 0xc7, 0x25, 0xce,
+	0x52, 0x3e, 0x9b, 0x9a, 0x30, 0x4c, 0x9d, 0xfa, 0x4a, 0x7b, 0xc4, 0x67, 0xaf, 0x74, 0x34, 0x18,
+	0xfc, 0xda, 0x4a, 0x1f, 0x60, 0x5e, 0x32, 0xaf, 0x34, 0x7a, 0xcd, 0xc4, 0x02, 0x7f, 0x09, 0xae,
+	0x09, 0x45, 0xf5, 0xe8, 0xaa, 0x40, 0x02, 0xe8, 0xcd, 0x04, 0xcd, 0x24, 0x8b, 0x4b, 0x20, 0x54,
+	0x22, 0x7e, 0x6b, 0x81, 0xff, 0x22, 0xcf, 0x59, 0x16, 0xdf, 0x94, 0xc8, 0xba, 0xd8, 0x5b, 0x1b,
+	0xc5, 0x7e, 0x0c, 0x83, 0x5c, 0xb0, 0xf3, 0xcb, 0x29, 0x28, 0x6d, 0x33, 0x85, 0xda, 0xaa, 0x99,
+	0x42, 0x69, 0xa4, 0x52, 0x40, 0x23, 0xe8, 0xb1, 0x4c, 0x8a, 0x84, 0x99, 0xd9, 0xc0, 0x9b, 0x74,
+	0x43, 0x73, 0x3d, 0x55, 0x6a, 0xf4, 0x08, 0x7c, 0x73, 0xea, 0x34, 0xe2, 0x8b, 0x45, 0x22, 0x83,
+	0x6e, 0x59, 0x2d, 0xad, 0xdc, 0xd3, 0x3a, 0xfc, 0x03, 0x78, 0x55, 0x36, 0x37, 0xd4, 0xa2, 0x58,
+	0x46, 0x11, 0x2b, 0x8a, 0xaa, 0x16, 0xa5, 0xa8, 0x26, 0x3a, 0x5d, 0xea, 0x66, 0x26, 0xae, 0xd2,
+	0xe8, 0x34, 0xf0, 0x6f, 0x16, 0x6c, 0x9f, 0x64, 0x34, 0x2f, 0xe6, 0x5c, 0xde, 0xa5, 0x58, 0x37,
+	0x6f, 0xaf, 0xa6, 0x33, 0xbd, 0xdc, 0xa8, 0x90, 0xa3, 0x14, 0xba, 0x3c, 0x08, 0xda, 0x31, 0x95,
+	0x54, 0x73, 0xa0, 0x4f, 0xf4, 0x37, 0x7e, 0x04, 0xfe, 0x3a, 0x9c, 0x6b, 0xb2, 0x9d, 0xbc, 0x6d,
+	0x43, 0x97, 0xf0, 0xa5, 0x64, 0x02, 0x3d, 0x02, 0xb7, 0xbe, 0xbd, 0x10, 0x84, 0xf5, 0xb8, 0x3d,
+	0x74, 0xc2, 0x72, 0x0c, 0xc5, 0x2d, 0x65, 0xa4, 0x08, 0x53, 0x1c, 0x24, 0x59, 0x8c, 0x20, 0xac,
+	0xc7, 0xc9, 0xa1, 0x13, 0x96, 0xe3, 0x21, 0x6e, 0xa1, 0x5d, 0x68, 0xab, 0x99, 0x05, 0x75, 0x43,
+	0x3d, 0xb7, 0x0d, 0x61, 0x3d, 0xc2, 0xe0, 0x16, 0xfa, 0x10, 0xb6, 0xeb, 0x2d, 0x0e, 0xd5, 0xb5,
+	0x7a, 0xfd, 0x46, 0x0f, 0xa0, 0xf3, 0x42, 0xdf, 0xc8, 0x57, 0xef, 0xf4, 0x04, 0x7a, 0x2f, 0xe2,
+	0x58, 0x6d, 0x86, 0xfa, 0x61, 0x63, 0x62, 0x18, 0x42, 0x58, 0x0f, 0x07, 0xb8, 0x85, 0xc6, 0x00,
+	0x84, 0x2d, 0xf8, 0x39, 0xbb, 0xd5, 0xf2, 0x03, 0x70, 0xbf, 0x51, 0x37, 0xe0, 0xad, 0x86, 0x1f,
+	0x81, 0xff, 0x3d, 0x95, 0xd1, 0xbc, 0x7a, 0x36, 0xeb, 0x00, 0x07, 0x9b, 0x2f, 0x29, 0x6e, 0x7d,
+	0x66, 0xa1, 0xf7, 0xc1, 0x5d, 0xbf, 0x4b, 0x95, 0xe1, 0x76, 0xb8, 0xf9, 0x3a, 0xe0, 0x16, 0x0a,
+	0xc1, 0xdf, 0xb8, 0x53, 0x6b, 0xdb, 0xff, 0x87, 0x97, 0xef, 0x5a, 0x5d, 0x48, 0xaf, 0x8c, 0x4e,
+	0xb1, 0x1b, 0xf5, 0xc3, 0xc6, 0x7d, 0x33, 0x84, 0xb0, 0xa6, 0x3c, 0x6e, 0xa1, 0x4f, 0x2b, 0x16,
+	0xef, 0x97, 0x6c, 0x19, 0x84, 0x1b, 0xac, 0x1e, 0xf6, 0xc3, 0x06, 0x2f, 0x70, 0x0b, 0x7d, 0x01,
+	0xdb, 0xaf, 0xb2, 0x42, 0xd2, 0x34, 0xad, 0x30, 0x84, 0xee, 0x85, 0x17, 0xd0, 0x3d, 0x1c, 0x84,
+	0x1b, 0x00, 0xc3, 0xad, 0xd3, 0xae, 0xfe, 0x5b, 0xfb, 0xfc, 0x9f, 0x01, 0x00, 0xb5, 0x5b, 0xdd,
+	0x63, 0xb9, 0x0d, 0x00, 0x00,
 }
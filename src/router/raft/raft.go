@@ -0,0 +1,1015 @@
+package raft
+
+import (
+	"context"
+	"encoding/binary"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"storage"
+	"storage/wal"
+)
+
+// DefaultElectionTimeout is an election timeout used if Config.ElectionTimeout is not set.
+//
+// DefaultElectionTimeout -- таймаут выборов, используемый, если Config.ElectionTimeout не задан.
+const DefaultElectionTimeout = time.Second
+
+// DefaultSnapshotThreshold is a number of applied entries after which the log
+// is compacted if Config.SnapshotThreshold is not set.
+//
+// DefaultSnapshotThreshold -- количество примененных записей, после которого
+// журнал сжимается, если Config.SnapshotThreshold не задан.
+const DefaultSnapshotThreshold = 1024
+
+// maxAppendEntries is a maximum number of entries sent in a single AppendEntries request.
+const maxAppendEntries = 256
+
+// StateMachine is a state replicated by Raft. Apply is called for each
+// committed command in log order and its result is returned by Propose.
+// Snapshot returns the state to compact the log with, Restore replaces
+// the state with a snapshot. The methods are never called concurrently
+// and must not call Raft.
+//
+// StateMachine -- состояние, реплицируемое Raft. Apply вызывается для каждой
+// зафиксированной команды в порядке журнала, и его результат возвращается Propose.
+// Snapshot возвращает состояние для сжатия журнала, Restore заменяет
+// состояние снимком. Методы не вызываются параллельно и не должны
+// обращаться к Raft.
+type StateMachine interface {
+	Apply(cmd []byte) interface{}
+	Snapshot() ([]byte, error)
+	Restore(snapshot []byte) error
+}
+
+// Transport sends Raft requests to other members of the group.
+//
+// Transport отправляет запросы Raft другим членам группы.
+type Transport interface {
+	RequestVote(ctx context.Context, peer storage.ServiceAddr, req VoteRequest) (VoteReply, error)
+	AppendEntries(ctx context.Context, peer storage.ServiceAddr, req AppendRequest) (AppendReply, error)
+	InstallSnapshot(ctx context.Context, peer storage.ServiceAddr, req SnapshotRequest) (SnapshotReply, error)
+}
+
+// Config stores configuration of a member of a Raft group.
+//
+// Config -- содержит конфигурацию члена группы Raft.
+type Config struct {
+	// ID is an address of the member.
+	// ID -- адрес члена группы.
+	ID storage.ServiceAddr
+	// Peers are addresses of all members of the group, ID included.
+	// Peers -- адреса всех членов группы, включая ID.
+	Peers []storage.ServiceAddr
+
+	// ElectionTimeout is a time after which a follower which got no requests
+	// from a leader starts an election. It's randomized within
+	// [ElectionTimeout, 2*ElectionTimeout) for every election.
+	// ElectionTimeout -- время, по истечении которого follower, не получавший
+	// запросов от leader, начинает выборы. Для каждых выборов выбирается
+	// случайно в [ElectionTimeout, 2*ElectionTimeout).
+	ElectionTimeout time.Duration
+	// HeartbeatInterval is an interval of leader's requests to idle followers,
+	// a fifth of ElectionTimeout if it's not set.
+	// HeartbeatInterval -- интервал запросов leader к простаивающим follower,
+	// пятая часть ElectionTimeout, если не задан.
+	HeartbeatInterval time.Duration
+	// SnapshotThreshold is a number of applied entries after which
+	// the log is compacted.
+	// SnapshotThreshold -- количество примененных записей, после которого
+	// журнал сжимается.
+	SnapshotThreshold int
+	// Dir is a directory to keep the term, the vote and the log of the member
+	// in. They are written before the member replies to requests, so
+	// a restarted member neither votes twice in a term nor loses committed
+	// entries. The member keeps them in memory only if Dir is empty.
+	// Dir -- директория, в которой хранятся срок, голос и журнал члена группы.
+	// Они записываются до того, как член группы отвечает на запросы, поэтому
+	// перезапущенный член группы не голосует дважды за срок и не теряет
+	// зафиксированные записи. Если Dir пуст, они хранятся только в памяти.
+	Dir string
+
+	Transport    Transport
+	StateMachine StateMachine
+}
+
+// Entry is an entry of the replicated log. Entries with an empty command
+// are appended by new leaders and are not applied.
+//
+// Entry -- запись реплицируемого журнала. Записи с пустой командой
+// добавляются новыми leader и не применяются.
+type Entry struct {
+	Index uint64
+	Term  uint64
+	Cmd   []byte
+}
+
+// VoteRequest is a request of a candidate for a vote.
+//
+// VoteRequest -- запрос голоса кандидатом.
+type VoteRequest struct {
+	Term         uint64
+	Candidate    storage.ServiceAddr
+	LastLogIndex uint64
+	LastLogTerm  uint64
+}
+
+// VoteReply is a reply to VoteRequest.
+//
+// VoteReply -- ответ на VoteRequest.
+type VoteReply struct {
+	Term    uint64
+	Granted bool
+}
+
+// AppendRequest is a request of a leader to append entries following
+// PrevLogIndex to the log of a follower.
+//
+// AppendRequest -- запрос leader на добавление записей, следующих за PrevLogIndex,
+// в журнал follower.
+type AppendRequest struct {
+	Term         uint64
+	Leader       storage.ServiceAddr
+	PrevLogIndex uint64
+	PrevLogTerm  uint64
+	Entries      []Entry
+	LeaderCommit uint64
+}
+
+// AppendReply is a reply to AppendRequest. LastIndex is the index of the last
+// entry matching the leader's log if the request succeeded, a hint on where
+// the logs may match otherwise.
+//
+// AppendReply -- ответ на AppendRequest. LastIndex -- индекс последней записи,
+// совпадающей с журналом leader, если запрос успешен, и подсказка, где журналы
+// могут совпадать, иначе.
+type AppendReply struct {
+	Term      uint64
+	Success   bool
+	LastIndex uint64
+}
+
+// SnapshotRequest is a request of a leader to replace the state of a follower
+// lagging behind the compacted log.
+//
+// SnapshotRequest -- запрос leader на замену состояния follower,
+// отставшего от сжатого журнала.
+type SnapshotRequest struct {
+	Term      uint64
+	Leader    storage.ServiceAddr
+	LastIndex uint64
+	LastTerm  uint64
+	Data      []byte
+}
+
+// SnapshotReply is a reply to SnapshotRequest.
+//
+// SnapshotReply -- ответ на SnapshotRequest.
+type SnapshotReply struct {
+	Term uint64
+}
+
+type role int
+
+const (
+	follower role = iota
+	candidate
+	leader
+)
+
+type result struct {
+	v   interface{}
+	err error
+}
+
+type waiter struct {
+	term uint64
+	ch   chan result
+}
+
+// Raft is a member of a Raft group replicating a StateMachine.
+// Without Config.Dir the log is kept in memory: a restarted member rejoins
+// the group with an empty log and gets the state from the leader.
+//
+// Raft -- член группы Raft, реплицирующей StateMachine.
+// Без Config.Dir журнал хранится в памяти: перезапущенный член группы
+// присоединяется к ней с пустым журналом и получает состояние от leader.
+type Raft struct {
+	cfg Config
+
+	lock     sync.Mutex
+	role     role
+	term     uint64
+	votedFor storage.ServiceAddr
+	leader   storage.ServiceAddr
+	deadline time.Time
+
+	// log[0] holds the index and term of the last entry compacted into snapshot.
+	log         []Entry
+	snapshot    []byte
+	commitIndex uint64
+	lastApplied uint64
+	waiters     map[uint64]waiter
+
+	// wal keeps the state in Config.Dir, nil if it's kept in memory only.
+	wal       *wal.Log
+	savedTerm uint64
+	savedVote storage.ServiceAddr
+
+	nextIndex   map[storage.ServiceAddr]uint64
+	matchIndex  map[storage.ServiceAddr]uint64
+	lastContact map[storage.ServiceAddr]time.Time
+	signals     map[storage.ServiceAddr]chan struct{}
+
+	stopped bool
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// New creates a new Raft with a given cfg restoring the state kept
+// in cfg.Dir. Start has to be called for it to take part in the group.
+// Returns an error if the state can't be restored.
+//
+// New создает новый Raft с данным cfg, восстанавливая состояние, хранящееся
+// в cfg.Dir. Чтобы он участвовал в группе, нужно вызвать Start.
+// Возвращает ошибку, если состояние не удалось восстановить.
+func New(cfg Config) (*Raft, error) {
+	if cfg.ElectionTimeout <= 0 {
+		cfg.ElectionTimeout = DefaultElectionTimeout
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = cfg.ElectionTimeout / 5
+	}
+	if cfg.SnapshotThreshold <= 0 {
+		cfg.SnapshotThreshold = DefaultSnapshotThreshold
+	}
+	if !contains(cfg.Peers, cfg.ID) {
+		cfg.Peers = append(append([]storage.ServiceAddr(nil), cfg.Peers...), cfg.ID)
+	}
+	r := &Raft{
+		cfg:     cfg,
+		log:     []Entry{{}},
+		waiters: make(map[uint64]waiter),
+		stop:    make(chan struct{}),
+	}
+	if cfg.Dir != "" {
+		if err := r.restore(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Start starts election timer of the member.
+//
+// Start запускает таймер выборов члена группы.
+func (r *Raft) Start() {
+	r.lock.Lock()
+	r.resetDeadlineLocked()
+	r.lock.Unlock()
+
+	r.wg.Add(1)
+	go r.run()
+}
+
+// Stop stops the member. Pending proposals fail with storage.ErrNotLeader.
+//
+// Stop останавливает члена группы. Ожидающие предложения завершаются
+// ошибкой storage.ErrNotLeader.
+func (r *Raft) Stop() {
+	r.lock.Lock()
+	r.stopped = true
+	r.lock.Unlock()
+	close(r.stop)
+	r.wg.Wait()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.role = follower
+	r.leader = ""
+	r.failWaitersLocked(0)
+	if r.wal != nil {
+		if err := r.wal.Close(); err != nil {
+			log.Printf("Failed to close Raft log: %v", err)
+		}
+		r.wal = nil
+	}
+}
+
+// Leader returns the address of the current leader known to the member,
+// an empty address if it's unknown.
+//
+// Leader возвращает адрес известного члену группы текущего leader,
+// пустой адрес, если он неизвестен.
+func (r *Raft) Leader() storage.ServiceAddr {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.leader
+}
+
+// IsLeader reports whether the member is the leader of the group.
+//
+// IsLeader сообщает, является ли член группы ее leader.
+func (r *Raft) IsLeader() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.role == leader
+}
+
+// Propose appends cmd to the log and waits for it to be applied,
+// an empty cmd is not applied. Returns the result of StateMachine.Apply for cmd or storage.ErrNotLeader
+// error if the member is not the leader or has lost its leadership
+// before cmd was committed.
+//
+// Propose добавляет cmd в журнал и ожидает ее применения,
+// пустая cmd не применяется. Возвращает результат StateMachine.Apply для cmd или ошибку storage.ErrNotLeader,
+// если член группы не является leader или потерял лидерство до фиксации cmd.
+func (r *Raft) Propose(ctx context.Context, cmd []byte) (interface{}, error) {
+	r.lock.Lock()
+	if r.role != leader {
+		r.lock.Unlock()
+		return nil, storage.ErrNotLeader
+	}
+	index, err := r.appendLocked(cmd)
+	if err != nil {
+		r.lock.Unlock()
+		return nil, err
+	}
+	ch := make(chan result, 1)
+	r.waiters[index] = waiter{term: r.term, ch: ch}
+	r.advanceCommitLocked()
+	r.lock.Unlock()
+
+	select {
+	case res := <-ch:
+		return res.v, res.err
+	case <-ctx.Done():
+		r.lock.Lock()
+		delete(r.waiters, index)
+		r.lock.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// RequestVote handles a VoteRequest of a candidate.
+//
+// RequestVote обрабатывает VoteRequest кандидата.
+func (r *Raft) RequestVote(req VoteRequest) VoteReply {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if req.Term > r.term {
+		r.stepDownLocked(req.Term)
+		if err := r.saveStateLocked(); err != nil {
+			log.Printf("Failed to save Raft term: %v", err)
+			return VoteReply{Term: r.term}
+		}
+	}
+	reply := VoteReply{Term: r.term}
+	if req.Term < r.term || (r.votedFor != "" && r.votedFor != req.Candidate) {
+		return reply
+	}
+	lastTerm := r.log[len(r.log)-1].Term
+	if req.LastLogTerm < lastTerm || (req.LastLogTerm == lastTerm && req.LastLogIndex < r.lastIndexLocked()) {
+		return reply
+	}
+	r.votedFor = req.Candidate
+	if err := r.saveStateLocked(); err != nil {
+		// A vote which may be forgotten after a restart is not granted.
+		log.Printf("Failed to save Raft vote: %v", err)
+		r.votedFor = ""
+		return reply
+	}
+	r.resetDeadlineLocked()
+	reply.Granted = true
+	return reply
+}
+
+// AppendEntries handles an AppendRequest of a leader.
+//
+// AppendEntries обрабатывает AppendRequest leader.
+func (r *Raft) AppendEntries(req AppendRequest) AppendReply {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if req.Term < r.term {
+		return AppendReply{Term: r.term}
+	}
+	r.followLocked(req.Term, req.Leader)
+	if err := r.saveStateLocked(); err != nil {
+		log.Printf("Failed to save Raft term: %v", err)
+		return AppendReply{Term: r.term, LastIndex: r.lastIndexLocked()}
+	}
+
+	// Entries compacted into the snapshot are committed and match the leader's.
+	entries := req.Entries
+	prevIndex, prevTerm := req.PrevLogIndex, req.PrevLogTerm
+	if first := r.log[0]; prevIndex < first.Index {
+		skip := first.Index - prevIndex
+		if uint64(len(entries)) < skip {
+			return AppendReply{Term: r.term, Success: true, LastIndex: prevIndex + uint64(len(entries))}
+		}
+		entries = entries[skip:]
+		prevIndex, prevTerm = first.Index, first.Term
+	}
+
+	if prevIndex > r.lastIndexLocked() {
+		return AppendReply{Term: r.term, LastIndex: r.lastIndexLocked()}
+	}
+	if t := r.termAtLocked(prevIndex); t != prevTerm {
+		// Skip the whole conflicting term at once.
+		i := prevIndex
+		for i > r.log[0].Index+1 && r.termAtLocked(i-1) == t {
+			i--
+		}
+		return AppendReply{Term: r.term, LastIndex: i - 1}
+	}
+
+	for i, e := range entries {
+		if e.Index <= r.lastIndexLocked() {
+			if r.termAtLocked(e.Index) == e.Term {
+				continue
+			}
+			if err := r.truncateLocked(e.Index); err != nil {
+				log.Printf("Failed to truncate Raft log: %v", err)
+				return AppendReply{Term: r.term, LastIndex: r.lastIndexLocked()}
+			}
+		}
+		if err := r.saveEntriesLocked(entries[i:]); err != nil {
+			log.Printf("Failed to save Raft log: %v", err)
+			return AppendReply{Term: r.term, LastIndex: r.lastIndexLocked()}
+		}
+		r.log = append(r.log, entries[i:]...)
+		break
+	}
+
+	last := prevIndex + uint64(len(entries))
+	if req.LeaderCommit > r.commitIndex {
+		commit := req.LeaderCommit
+		if commit > last {
+			commit = last
+		}
+		if commit > r.commitIndex {
+			r.commitIndex = commit
+			r.applyLocked()
+		}
+	}
+	return AppendReply{Term: r.term, Success: true, LastIndex: last}
+}
+
+// InstallSnapshot handles a SnapshotRequest of a leader.
+//
+// InstallSnapshot обрабатывает SnapshotRequest leader.
+func (r *Raft) InstallSnapshot(req SnapshotRequest) SnapshotReply {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if req.Term < r.term {
+		return SnapshotReply{Term: r.term}
+	}
+	r.followLocked(req.Term, req.Leader)
+	if err := r.saveStateLocked(); err != nil {
+		log.Printf("Failed to save Raft term: %v", err)
+		return SnapshotReply{Term: r.term}
+	}
+	if req.LastIndex <= r.lastApplied {
+		return SnapshotReply{Term: r.term}
+	}
+	if err := r.cfg.StateMachine.Restore(req.Data); err != nil {
+		return SnapshotReply{Term: r.term}
+	}
+
+	// Entries following the snapshot are kept if the logs match at its end.
+	base := Entry{Index: req.LastIndex, Term: req.LastTerm}
+	if req.LastIndex <= r.lastIndexLocked() && r.termAtLocked(req.LastIndex) == req.LastTerm {
+		r.log = append([]Entry{base}, r.log[req.LastIndex-r.log[0].Index+1:]...)
+	} else {
+		r.failWaitersLocked(r.log[0].Index + 1)
+		r.log = []Entry{base}
+	}
+	r.snapshot = req.Data
+	r.lastApplied = req.LastIndex
+	if r.commitIndex < req.LastIndex {
+		r.commitIndex = req.LastIndex
+	}
+	if err := r.saveSnapshotLocked(); err != nil {
+		log.Printf("Failed to save Raft snapshot: %v", err)
+	}
+	r.applyLocked()
+	return SnapshotReply{Term: r.term}
+}
+
+func (r *Raft) run() {
+	defer r.wg.Done()
+	t := time.NewTicker(r.cfg.ElectionTimeout / 10)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-r.stop:
+			return
+		}
+		r.lock.Lock()
+		switch {
+		case r.role == leader && !r.hasQuorumContactLocked():
+			// A leader cut off from the majority can't commit anything,
+			// it steps down so that clients look for the new one.
+			r.role = follower
+			r.leader = ""
+			r.resetDeadlineLocked()
+		case r.role != leader && !r.stopped && time.Now().After(r.deadline):
+			r.startElectionLocked()
+		}
+		r.lock.Unlock()
+	}
+}
+
+func (r *Raft) majority() int {
+	return len(r.cfg.Peers)/2 + 1
+}
+
+func (r *Raft) resetDeadlineLocked() {
+	timeout := r.cfg.ElectionTimeout + time.Duration(rand.Int63n(int64(r.cfg.ElectionTimeout)))
+	r.deadline = time.Now().Add(timeout)
+}
+
+func (r *Raft) hasQuorumContactLocked() bool {
+	n := 1
+	for peer, t := range r.lastContact {
+		if peer != r.cfg.ID && time.Since(t) < r.cfg.ElectionTimeout {
+			n++
+		}
+	}
+	return n >= r.majority()
+}
+
+func (r *Raft) lastIndexLocked() uint64 {
+	return r.log[len(r.log)-1].Index
+}
+
+// termAtLocked returns the term of the entry with index i,
+// which must not precede log[0].
+func (r *Raft) termAtLocked(i uint64) uint64 {
+	return r.log[i-r.log[0].Index].Term
+}
+
+// stepDownLocked makes the member a follower of term.
+func (r *Raft) stepDownLocked(term uint64) {
+	r.term = term
+	r.votedFor = ""
+	r.role = follower
+	r.leader = ""
+}
+
+// followLocked makes the member a follower of the leader of term.
+func (r *Raft) followLocked(term uint64, leader storage.ServiceAddr) {
+	if term > r.term || r.role != follower {
+		r.stepDownLocked(term)
+	}
+	r.leader = leader
+	r.resetDeadlineLocked()
+}
+
+func (r *Raft) startElectionLocked() {
+	r.role = candidate
+	r.term++
+	r.votedFor = r.cfg.ID
+	r.leader = ""
+	r.resetDeadlineLocked()
+	if err := r.saveStateLocked(); err != nil {
+		// The election is retried once the deadline passes again.
+		log.Printf("Failed to save Raft term: %v", err)
+		return
+	}
+
+	term := r.term
+	votes := 1
+	if votes >= r.majority() {
+		r.becomeLeaderLocked()
+		return
+	}
+	req := VoteRequest{
+		Term:         term,
+		Candidate:    r.cfg.ID,
+		LastLogIndex: r.lastIndexLocked(),
+		LastLogTerm:  r.log[len(r.log)-1].Term,
+	}
+	for _, peer := range r.cfg.Peers {
+		if peer == r.cfg.ID {
+			continue
+		}
+		go func(peer storage.ServiceAddr) {
+			ctx, cancel := context.WithTimeout(context.Background(), r.cfg.ElectionTimeout/2)
+			defer cancel()
+			reply, err := r.cfg.Transport.RequestVote(ctx, peer, req)
+			if err != nil {
+				return
+			}
+
+			r.lock.Lock()
+			defer r.lock.Unlock()
+			if reply.Term > r.term {
+				r.stepDownLocked(reply.Term)
+				return
+			}
+			if r.role != candidate || r.term != term || !reply.Granted {
+				return
+			}
+			votes++
+			if votes >= r.majority() && !r.stopped {
+				r.becomeLeaderLocked()
+			}
+		}(peer)
+	}
+}
+
+func (r *Raft) becomeLeaderLocked() {
+	r.role = leader
+	r.leader = r.cfg.ID
+	r.nextIndex = make(map[storage.ServiceAddr]uint64)
+	r.matchIndex = make(map[storage.ServiceAddr]uint64)
+	r.lastContact = make(map[storage.ServiceAddr]time.Time)
+	r.signals = make(map[storage.ServiceAddr]chan struct{})
+
+	// An entry of the new term commits the entries of the previous ones.
+	if _, err := r.appendLocked(nil); err != nil {
+		log.Printf("Failed to save Raft log: %v", err)
+	}
+	for _, peer := range r.cfg.Peers {
+		if peer == r.cfg.ID {
+			continue
+		}
+		r.nextIndex[peer] = r.lastIndexLocked()
+		r.lastContact[peer] = time.Now()
+		r.signals[peer] = make(chan struct{}, 1)
+		r.wg.Add(1)
+		go r.replicate(peer, r.term, r.signals[peer])
+	}
+	r.advanceCommitLocked()
+}
+
+// appendLocked appends an entry with cmd to the log of the leader and
+// signals the followers to replicate it. Returns the index of the entry.
+func (r *Raft) appendLocked(cmd []byte) (uint64, error) {
+	e := Entry{Index: r.lastIndexLocked() + 1, Term: r.term, Cmd: cmd}
+	if err := r.saveEntriesLocked([]Entry{e}); err != nil {
+		return 0, err
+	}
+	r.log = append(r.log, e)
+	r.matchIndex[r.cfg.ID] = e.Index
+	for _, s := range r.signals {
+		select {
+		case s <- struct{}{}:
+		default:
+		}
+	}
+	return e.Index, nil
+}
+
+// replicate sends entries to peer while the member is the leader of term.
+func (r *Raft) replicate(peer storage.ServiceAddr, term uint64, signal <-chan struct{}) {
+	defer r.wg.Done()
+	t := time.NewTicker(r.cfg.HeartbeatInterval)
+	defer t.Stop()
+	for {
+		more, ok := r.sendTo(peer, term)
+		if !ok {
+			return
+		}
+		if more {
+			continue
+		}
+		select {
+		case <-signal:
+		case <-t.C:
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// sendTo sends the entries peer lacks or a snapshot if they are compacted.
+// Returns whether there are more entries to send and false ok if
+// the member is not the leader of term anymore.
+func (r *Raft) sendTo(peer storage.ServiceAddr, term uint64) (more, ok bool) {
+	r.lock.Lock()
+	if r.role != leader || r.term != term {
+		r.lock.Unlock()
+		return false, false
+	}
+	next := r.nextIndex[peer]
+	if next <= r.log[0].Index {
+		req := SnapshotRequest{
+			Term:      term,
+			Leader:    r.cfg.ID,
+			LastIndex: r.log[0].Index,
+			LastTerm:  r.log[0].Term,
+			Data:      r.snapshot,
+		}
+		r.lock.Unlock()
+		return r.sendSnapshot(peer, term, req)
+	}
+
+	prev := next - 1
+	entries := r.log[next-r.log[0].Index:]
+	if len(entries) > maxAppendEntries {
+		entries = entries[:maxAppendEntries]
+	}
+	req := AppendRequest{
+		Term:         term,
+		Leader:       r.cfg.ID,
+		PrevLogIndex: prev,
+		PrevLogTerm:  r.termAtLocked(prev),
+		Entries:      append([]Entry(nil), entries...),
+		LeaderCommit: r.commitIndex,
+	}
+	r.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.cfg.ElectionTimeout/2)
+	reply, err := r.cfg.Transport.AppendEntries(ctx, peer, req)
+	cancel()
+	if err != nil {
+		return false, true
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if reply.Term > r.term {
+		r.stepDownLocked(reply.Term)
+		return false, false
+	}
+	if r.role != leader || r.term != term {
+		return false, false
+	}
+	r.lastContact[peer] = time.Now()
+	if !reply.Success {
+		next := reply.LastIndex + 1
+		if next >= r.nextIndex[peer] {
+			next = r.nextIndex[peer] - 1
+		}
+		if next < 1 {
+			next = 1
+		}
+		r.nextIndex[peer] = next
+		return true, true
+	}
+	if reply.LastIndex > r.matchIndex[peer] {
+		r.matchIndex[peer] = reply.LastIndex
+		r.advanceCommitLocked()
+	}
+	r.nextIndex[peer] = reply.LastIndex + 1
+	return r.nextIndex[peer] <= r.lastIndexLocked(), true
+}
+
+func (r *Raft) sendSnapshot(peer storage.ServiceAddr, term uint64, req SnapshotRequest) (more, ok bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.cfg.ElectionTimeout/2)
+	reply, err := r.cfg.Transport.InstallSnapshot(ctx, peer, req)
+	cancel()
+	if err != nil {
+		return false, true
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if reply.Term > r.term {
+		r.stepDownLocked(reply.Term)
+		return false, false
+	}
+	if r.role != leader || r.term != term {
+		return false, false
+	}
+	r.lastContact[peer] = time.Now()
+	if req.LastIndex > r.matchIndex[peer] {
+		r.matchIndex[peer] = req.LastIndex
+	}
+	r.nextIndex[peer] = req.LastIndex + 1
+	return r.nextIndex[peer] <= r.lastIndexLocked(), true
+}
+
+// advanceCommitLocked commits the last entry of the current term
+// replicated on the majority of the group.
+func (r *Raft) advanceCommitLocked() {
+	for i := r.lastIndexLocked(); i > r.commitIndex && r.termAtLocked(i) == r.term; i-- {
+		n := 0
+		for _, peer := range r.cfg.Peers {
+			if r.matchIndex[peer] >= i {
+				n++
+			}
+		}
+		if n >= r.majority() {
+			r.commitIndex = i
+			r.applyLocked()
+			return
+		}
+	}
+}
+
+// applyLocked applies committed entries to the state machine, replies
+// to their proposers and compacts the log if it has grown too long.
+func (r *Raft) applyLocked() {
+	for r.lastApplied < r.commitIndex {
+		r.lastApplied++
+		e := r.log[r.lastApplied-r.log[0].Index]
+		var v interface{}
+		if len(e.Cmd) != 0 {
+			v = r.cfg.StateMachine.Apply(e.Cmd)
+		}
+		if w, ok := r.waiters[e.Index]; ok {
+			delete(r.waiters, e.Index)
+			if w.term == e.Term {
+				w.ch <- result{v: v}
+			} else {
+				w.ch <- result{err: storage.ErrNotLeader}
+			}
+		}
+	}
+
+	if r.lastApplied-r.log[0].Index < uint64(r.cfg.SnapshotThreshold) {
+		return
+	}
+	data, err := r.cfg.StateMachine.Snapshot()
+	if err != nil {
+		return
+	}
+	i := r.lastApplied - r.log[0].Index
+	r.log = append([]Entry{{Index: r.lastApplied, Term: r.log[i].Term}}, r.log[i+1:]...)
+	r.snapshot = data
+	if err := r.saveSnapshotLocked(); err != nil {
+		log.Printf("Failed to save Raft snapshot: %v", err)
+	}
+}
+
+// truncateLocked removes entries starting with index i from the log.
+func (r *Raft) truncateLocked(i uint64) error {
+	if r.wal != nil {
+		var recs []wal.Record
+		for j := i; j <= r.lastIndexLocked(); j++ {
+			recs = append(recs, wal.Record{Op: wal.OpDel, Key: entryKey(j)})
+		}
+		if err := r.wal.Append(recs...); err != nil {
+			return err
+		}
+	}
+	r.log = r.log[:i-r.log[0].Index]
+	r.failWaitersLocked(i)
+	return nil
+}
+
+// failWaitersLocked fails proposals of entries starting with index i.
+func (r *Raft) failWaitersLocked(i uint64) {
+	for index, w := range r.waiters {
+		if index >= i {
+			delete(r.waiters, index)
+			w.ch <- result{err: storage.ErrNotLeader}
+		}
+	}
+}
+
+func contains(peers []storage.ServiceAddr, peer storage.ServiceAddr) bool {
+	for _, p := range peers {
+		if p == peer {
+			return true
+		}
+	}
+	return false
+}
+
+// Keys of the state kept in Config.Dir: the term and the vote, the last
+// entry compacted into the snapshot along with it, and entries of the log
+// by their indices.
+const (
+	stateKey    storage.Key = "state"
+	snapshotKey storage.Key = "snapshot"
+	entryPrefix             = "entry:"
+)
+
+func entryKey(i uint64) storage.Key {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], i)
+	return storage.Key(entryPrefix + string(b[:]))
+}
+
+// restore restores the state kept in Config.Dir and opens it for writing.
+func (r *Raft) restore() error {
+	var (
+		state, snapshot []byte
+		entries         = make(map[uint64]Entry)
+	)
+	l, err := wal.Open(wal.Options{Dir: r.cfg.Dir}, func(rec wal.Record) {
+		switch {
+		case rec.Key == stateKey:
+			state = rec.Data
+		case rec.Key == snapshotKey:
+			snapshot = rec.Data
+		case len(rec.Key) == len(entryPrefix)+8 && string(rec.Key[:len(entryPrefix)]) == entryPrefix:
+			i := binary.BigEndian.Uint64([]byte(rec.Key[len(entryPrefix):]))
+			if rec.Op == wal.OpDel || len(rec.Data) < 8 {
+				delete(entries, i)
+				return
+			}
+			e := Entry{Index: i, Term: binary.BigEndian.Uint64(rec.Data)}
+			if len(rec.Data) > 8 {
+				e.Cmd = rec.Data[8:]
+			}
+			entries[i] = e
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(state) >= 8 {
+		r.term = binary.BigEndian.Uint64(state)
+		r.votedFor = storage.ServiceAddr(state[8:])
+	}
+	if len(snapshot) >= 16 {
+		data := snapshot[16:]
+		if err := r.cfg.StateMachine.Restore(data); err != nil {
+			l.Close()
+			return err
+		}
+		base := Entry{Index: binary.BigEndian.Uint64(snapshot), Term: binary.BigEndian.Uint64(snapshot[8:])}
+		r.log = []Entry{base}
+		r.snapshot = data
+		r.commitIndex = base.Index
+		r.lastApplied = base.Index
+	}
+	for i := r.lastIndexLocked() + 1; ; i++ {
+		e, ok := entries[i]
+		if !ok {
+			break
+		}
+		r.log = append(r.log, e)
+	}
+	r.wal = l
+	r.savedTerm, r.savedVote = r.term, r.votedFor
+	return nil
+}
+
+func encodeState(term uint64, votedFor storage.ServiceAddr) []byte {
+	b := make([]byte, 8, 8+len(votedFor))
+	binary.BigEndian.PutUint64(b, term)
+	return append(b, votedFor...)
+}
+
+func encodeEntry(e Entry) []byte {
+	b := make([]byte, 8, 8+len(e.Cmd))
+	binary.BigEndian.PutUint64(b, e.Term)
+	return append(b, e.Cmd...)
+}
+
+// saveStateLocked writes the term and the vote if they have changed.
+func (r *Raft) saveStateLocked() error {
+	if r.wal == nil || (r.term == r.savedTerm && r.votedFor == r.savedVote) {
+		return nil
+	}
+	rec := wal.Record{Op: wal.OpPut, Key: stateKey, Data: encodeState(r.term, r.votedFor)}
+	if err := r.wal.Append(rec); err != nil {
+		return err
+	}
+	r.savedTerm, r.savedVote = r.term, r.votedFor
+	return nil
+}
+
+// saveEntriesLocked writes entries appended to the log.
+func (r *Raft) saveEntriesLocked(entries []Entry) error {
+	if r.wal == nil {
+		return nil
+	}
+	recs := make([]wal.Record, len(entries))
+	for i, e := range entries {
+		recs[i] = wal.Record{Op: wal.OpPut, Key: entryKey(e.Index), Data: encodeEntry(e)}
+	}
+	return r.wal.Append(recs...)
+}
+
+// saveSnapshotLocked replaces the kept state with the compacted log.
+func (r *Raft) saveSnapshotLocked() error {
+	if r.wal == nil {
+		return nil
+	}
+	err := r.wal.Snapshot(func(put func(k storage.Key, d []byte) error) error {
+		if err := put(stateKey, encodeState(r.term, r.votedFor)); err != nil {
+			return err
+		}
+		b := make([]byte, 16, 16+len(r.snapshot))
+		binary.BigEndian.PutUint64(b, r.log[0].Index)
+		binary.BigEndian.PutUint64(b[8:], r.log[0].Term)
+		if err := put(snapshotKey, append(b, r.snapshot...)); err != nil {
+			return err
+		}
+		for _, e := range r.log[1:] {
+			if err := put(entryKey(e.Index), encodeEntry(e)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.savedTerm, r.savedVote = r.term, r.votedFor
+	return nil
+}
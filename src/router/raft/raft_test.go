@@ -0,0 +1,399 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"storage"
+)
+
+const electionTimeout = 50 * time.Millisecond
+
+// network connects members of a group in memory, requests from and
+// to disconnected members fail.
+type network struct {
+	lock         sync.Mutex
+	members      map[storage.ServiceAddr]*Raft
+	disconnected map[storage.ServiceAddr]bool
+}
+
+func (n *network) member(peer storage.ServiceAddr) (*Raft, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.disconnected[peer] {
+		return nil, errors.New("disconnected")
+	}
+	return n.members[peer], nil
+}
+
+func (n *network) setConnected(peer storage.ServiceAddr, connected bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.disconnected[peer] = !connected
+}
+
+type transport struct {
+	net  *network
+	from storage.ServiceAddr
+}
+
+func (t transport) to(peer storage.ServiceAddr) (*Raft, error) {
+	if _, err := t.net.member(t.from); err != nil {
+		return nil, err
+	}
+	return t.net.member(peer)
+}
+
+func (t transport) RequestVote(ctx context.Context, peer storage.ServiceAddr, req VoteRequest) (VoteReply, error) {
+	r, err := t.to(peer)
+	if err != nil {
+		return VoteReply{}, err
+	}
+	return r.RequestVote(req), nil
+}
+
+func (t transport) AppendEntries(ctx context.Context, peer storage.ServiceAddr, req AppendRequest) (AppendReply, error) {
+	r, err := t.to(peer)
+	if err != nil {
+		return AppendReply{}, err
+	}
+	return r.AppendEntries(req), nil
+}
+
+func (t transport) InstallSnapshot(ctx context.Context, peer storage.ServiceAddr, req SnapshotRequest) (SnapshotReply, error) {
+	r, err := t.to(peer)
+	if err != nil {
+		return SnapshotReply{}, err
+	}
+	return r.InstallSnapshot(req), nil
+}
+
+// commands is a StateMachine keeping the applied commands.
+type commands struct {
+	lock     sync.Mutex
+	applied  []string
+	restored int
+}
+
+func (c *commands) Apply(cmd []byte) interface{} {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.applied = append(c.applied, string(cmd))
+	return len(c.applied)
+}
+
+func (c *commands) Snapshot() ([]byte, error) {
+	return []byte(strings.Join(c.applied, ",")), nil
+}
+
+func (c *commands) Restore(snapshot []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.applied = strings.Split(string(snapshot), ",")
+	c.restored++
+	return nil
+}
+
+func (c *commands) get() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]string(nil), c.applied...)
+}
+
+type group struct {
+	net    *network
+	peers  []storage.ServiceAddr
+	states map[storage.ServiceAddr]*commands
+	// dirs keep the state of members if they are set.
+	dirs              map[storage.ServiceAddr]string
+	snapshotThreshold int
+}
+
+func newGroup(t *testing.T, n int, snapshotThreshold int) *group {
+	g := makeGroup(n, snapshotThreshold)
+	g.start(t)
+	return g
+}
+
+// makeGroup makes a group of n members without starting them.
+func makeGroup(n int, snapshotThreshold int) *group {
+	g := &group{
+		net: &network{
+			members:      make(map[storage.ServiceAddr]*Raft),
+			disconnected: make(map[storage.ServiceAddr]bool),
+		},
+		states:            make(map[storage.ServiceAddr]*commands),
+		dirs:              make(map[storage.ServiceAddr]string),
+		snapshotThreshold: snapshotThreshold,
+	}
+	for i := 0; i < n; i++ {
+		g.peers = append(g.peers, storage.ServiceAddr(fmt.Sprintf("router%d", i)))
+	}
+	return g
+}
+
+// start creates the members restoring their state from dirs and starts them.
+func (g *group) start(t *testing.T) {
+	for _, peer := range g.peers {
+		g.states[peer] = new(commands)
+		r, err := New(Config{
+			ID:                peer,
+			Peers:             g.peers,
+			ElectionTimeout:   electionTimeout,
+			SnapshotThreshold: g.snapshotThreshold,
+			Dir:               g.dirs[peer],
+			Transport:         transport{net: g.net, from: peer},
+			StateMachine:      g.states[peer],
+		})
+		if err != nil {
+			t.Fatalf("New() error: %v", err)
+		}
+		g.net.lock.Lock()
+		g.net.members[peer] = r
+		g.net.lock.Unlock()
+	}
+	for _, r := range g.net.members {
+		r.Start()
+	}
+}
+
+func (g *group) stop() {
+	for _, r := range g.net.members {
+		r.Stop()
+	}
+}
+
+// leader waits for a single connected leader to be elected.
+func (g *group) leader(t *testing.T) storage.ServiceAddr {
+	deadline := time.Now().Add(20 * electionTimeout)
+	for time.Now().Before(deadline) {
+		var leaders []storage.ServiceAddr
+		for _, peer := range g.peers {
+			if r, err := g.net.member(peer); err == nil && r.IsLeader() {
+				leaders = append(leaders, peer)
+			}
+		}
+		if len(leaders) == 1 {
+			return leaders[0]
+		}
+		time.Sleep(electionTimeout / 5)
+	}
+	t.Fatalf("No single leader elected")
+	return ""
+}
+
+// propose proposes cmd to the leader retrying if it changes.
+func (g *group) propose(t *testing.T, cmd string) interface{} {
+	for i := 0; i < 10; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*electionTimeout)
+		v, err := g.net.members[g.leader(t)].Propose(ctx, []byte(cmd))
+		cancel()
+		if err == nil {
+			return v
+		}
+	}
+	t.Fatalf("Propose(%q) failed", cmd)
+	return nil
+}
+
+// waitApplied waits for the members to apply want.
+func (g *group) waitApplied(t *testing.T, peers []storage.ServiceAddr, want []string) {
+	deadline := time.Now().Add(20 * electionTimeout)
+	for _, peer := range peers {
+		for !reflect.DeepEqual(g.states[peer].get(), want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("Member %q applied %v, want %v", peer, g.states[peer].get(), want)
+			}
+			time.Sleep(electionTimeout / 5)
+		}
+	}
+}
+
+func TestRaft_Replication(t *testing.T) {
+	g := newGroup(t, 3, 0)
+	defer g.stop()
+
+	leader := g.leader(t)
+	for _, peer := range g.peers {
+		if peer == leader {
+			continue
+		}
+		if _, err := g.net.members[peer].Propose(context.Background(), []byte("a")); err != storage.ErrNotLeader {
+			t.Errorf("Propose() to a follower got error %v, want %v", err, storage.ErrNotLeader)
+		}
+	}
+
+	var want []string
+	for i := 0; i < 5; i++ {
+		cmd := fmt.Sprintf("cmd%d", i)
+		want = append(want, cmd)
+		if v := g.propose(t, cmd); v != len(want) {
+			t.Errorf("Propose(%q) got %v, want %v", cmd, v, len(want))
+		}
+	}
+	g.waitApplied(t, g.peers, want)
+	for _, peer := range g.peers {
+		if got := g.net.members[peer].Leader(); got != leader {
+			t.Errorf("Leader() of %q got %q, want %q", peer, got, leader)
+		}
+	}
+}
+
+func TestRaft_SingleMember(t *testing.T) {
+	g := newGroup(t, 1, 0)
+	defer g.stop()
+
+	if v := g.propose(t, "a"); v != 1 {
+		t.Errorf("Propose() got %v, want 1", v)
+	}
+	g.waitApplied(t, g.peers, []string{"a"})
+}
+
+func TestRaft_LeaderFailure(t *testing.T) {
+	g := newGroup(t, 3, 0)
+	defer g.stop()
+
+	g.propose(t, "a")
+	old := g.leader(t)
+	g.net.setConnected(old, false)
+
+	// The rest of the group elects a new leader keeping the committed command.
+	g.propose(t, "b")
+	leader := g.leader(t)
+	if leader == old {
+		t.Fatalf("Disconnected leader %q is still the leader", old)
+	}
+	var rest []storage.ServiceAddr
+	for _, peer := range g.peers {
+		if peer != old {
+			rest = append(rest, peer)
+		}
+	}
+	g.waitApplied(t, rest, []string{"a", "b"})
+
+	// A leader cut off from the majority steps down.
+	deadline := time.Now().Add(20 * electionTimeout)
+	for g.net.members[old].IsLeader() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Disconnected leader %q didn't step down", old)
+		}
+		time.Sleep(electionTimeout / 5)
+	}
+
+	g.net.setConnected(old, true)
+	g.waitApplied(t, g.peers, []string{"a", "b"})
+}
+
+func TestRaft_Snapshot(t *testing.T) {
+	g := newGroup(t, 3, 4)
+	defer g.stop()
+
+	leader := g.leader(t)
+	var lagging storage.ServiceAddr
+	for _, peer := range g.peers {
+		if peer != leader {
+			lagging = peer
+			break
+		}
+	}
+	g.net.setConnected(lagging, false)
+
+	var want []string
+	for i := 0; i < 10; i++ {
+		cmd := fmt.Sprintf("cmd%d", i)
+		want = append(want, cmd)
+		g.propose(t, cmd)
+	}
+
+	// The lagging member gets the compacted commands with a snapshot.
+	g.net.setConnected(lagging, true)
+	g.waitApplied(t, g.peers, want)
+	if g.states[lagging].restored == 0 {
+		t.Errorf("Member %q didn't restore a snapshot", lagging)
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "raft")
+	if err != nil {
+		t.Fatalf("TempDir() error: %v", err)
+	}
+	return dir
+}
+
+func TestRaft_Restart(t *testing.T) {
+	g := makeGroup(3, 4)
+	for _, peer := range g.peers {
+		g.dirs[peer] = tempDir(t)
+		defer os.RemoveAll(g.dirs[peer])
+	}
+	g.start(t)
+
+	var want []string
+	for i := 0; i < 10; i++ {
+		cmd := fmt.Sprintf("cmd%d", i)
+		want = append(want, cmd)
+		g.propose(t, cmd)
+	}
+	g.waitApplied(t, g.peers, want)
+
+	// The whole group restarts keeping the committed commands.
+	terms := make(map[storage.ServiceAddr]uint64)
+	for _, peer := range g.peers {
+		r := g.net.members[peer]
+		r.lock.Lock()
+		terms[peer] = r.term
+		r.lock.Unlock()
+	}
+	g.stop()
+	g.start(t)
+	defer g.stop()
+	for _, peer := range g.peers {
+		r := g.net.members[peer]
+		r.lock.Lock()
+		term := r.term
+		r.lock.Unlock()
+		if term < terms[peer] {
+			t.Errorf("Member %q restarted with term %d, want at least %d", peer, term, terms[peer])
+		}
+	}
+	want = append(want, "after")
+	g.propose(t, "after")
+	g.waitApplied(t, g.peers, want)
+}
+
+func TestRaft_RestartVote(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	cfg := Config{ID: "router0", Peers: []storage.ServiceAddr{"router0", "router1", "router2"}, Dir: dir, StateMachine: new(commands)}
+
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if reply := r.RequestVote(VoteRequest{Term: 5, Candidate: "router1"}); !reply.Granted {
+		t.Fatalf("RequestVote() got %+v, want the vote granted", reply)
+	}
+	r.Stop()
+
+	// A restarted member doesn't vote for another candidate in the same term.
+	r, err = New(cfg)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	defer r.Stop()
+	if reply := r.RequestVote(VoteRequest{Term: 5, Candidate: "router2"}); reply.Granted || reply.Term != 5 {
+		t.Errorf("RequestVote() of another candidate got %+v, want the vote refused in term 5", reply)
+	}
+	if reply := r.RequestVote(VoteRequest{Term: 5, Candidate: "router1"}); !reply.Granted {
+		t.Errorf("RequestVote() of the same candidate got %+v, want the vote granted", reply)
+	}
+}
@@ -3,6 +3,8 @@ package router
 import (
 	"crypto/md5"
 	"encoding/binary"
+	"hash/fnv"
+	"math"
 	"sort"
 
 	"storage"
@@ -15,6 +17,16 @@ type Hasher interface {
 	Hash(k storage.RecordID, node storage.ServiceAddr) uint64
 }
 
+// KeyHasher is a Hasher computing hash for byte keys. Its HashKey has to
+// return for k.Key() what Hash returns for a RecordID k.
+//
+// KeyHasher -- Hasher, вычисляющий hash для байтовых ключей. Его HashKey
+// должен возвращать для k.Key() то же, что Hash для RecordID k.
+type KeyHasher interface {
+	Hasher
+	HashKey(k storage.Key, node storage.ServiceAddr) uint64
+}
+
 const poolSize = 4096
 
 // MD5 implements Hasher interface computing hash base on md5 checksum.
@@ -32,6 +44,15 @@ func NewMD5Hasher() *MD5 {
 }
 
 func (h *MD5) Hash(k storage.RecordID, node storage.ServiceAddr) uint64 {
+	return h.HashKey(k.Key(), node)
+}
+
+// HashKey hashes the bytes of k followed by node. Keys of RecordIDs are their
+// little-endian bytes, so their hashes are the ones RecordIDs had.
+//
+// HashKey хеширует байты k, за которыми следует node. Ключи RecordID -- это их
+// байты в порядке little-endian, поэтому их хеши совпадают с хешами RecordID.
+func (h *MD5) HashKey(k storage.Key, node storage.ServiceAddr) uint64 {
 	keySize := k.BinSize()
 	size := keySize + node.BinSize()
 
@@ -46,7 +67,7 @@ func (h *MD5) Hash(k storage.RecordID, node storage.ServiceAddr) uint64 {
 	}
 
 	buf = buf[:size]
-	binary.LittleEndian.PutUint32(buf, uint32(k))
+	copy(buf, k)
 	copy(buf[keySize:], node)
 	hash := md5.Sum(buf)
 
@@ -65,25 +86,155 @@ func (h *MD5) Hash(k storage.RecordID, node storage.ServiceAddr) uint64 {
 // на которых должна храниться запись с данным ключом.
 type NodesFinder struct {
 	hasher Hasher
+	rf     int
+	// placement is nil unless records are placed according to
+	// capacity weights or labels of nodes.
+	placement *storage.Placement
 }
 
-// NewNodesFinder creates NodesFinder instance with given Hasher.
+// NewNodesFinder creates NodesFinder instance with given Hasher
+// placing records on storage.ReplicationFactor nodes.
 //
-// NewNodesFinder создает NodesFinder с данным Hasher.
+// NewNodesFinder создает NodesFinder с данным Hasher,
+// размещающий записи на storage.ReplicationFactor nodes.
 func NewNodesFinder(h Hasher) NodesFinder {
 	return NodesFinder{
 		hasher: h,
 	}
 }
 
+// WithReplicationFactor returns a copy of nf placing records on rf nodes.
+//
+// WithReplicationFactor возвращает копию nf, размещающую записи на rf nodes.
+func (nf NodesFinder) WithReplicationFactor(rf int) NodesFinder {
+	nf.rf = rf
+	return nf
+}
+
+// ReplicationFactor returns a number of nodes records are placed on.
+//
+// ReplicationFactor возвращает количество nodes, на которых размещаются записи.
+func (nf NodesFinder) ReplicationFactor() int {
+	if nf.rf == 0 {
+		return storage.ReplicationFactor
+	}
+	return nf.rf
+}
+
+// WithPlacement returns a copy of nf placing records according to p.
+// If p is weighted, nodes are ranked by weighted rendezvous hashing:
+// a node is ranked first with a probability proportional to its
+// capacity weight. If p spreads replicas, nodes in failure domains
+// which are not taken by nodes ranked above them come first.
+//
+// WithPlacement возвращает копию nf, размещающую записи согласно p.
+// Если у p заданы веса, nodes упорядочиваются взвешенным rendezvous
+// hashing: node оказывается первой с вероятностью, пропорциональной
+// ее весу емкости. Если p распределяет реплики, первыми идут nodes
+// в failure domains, не занятых nodes, стоящими выше них.
+func (nf NodesFinder) WithPlacement(p storage.Placement) NodesFinder {
+	nf.placement = nil
+	if p.Default() {
+		return nf
+	}
+	placement := storage.Placement{Spread: p.Spread}
+	if len(p.Capacity) != 0 {
+		placement.Capacity = make(map[storage.ServiceAddr]float64, len(p.Capacity))
+		for node, w := range p.Capacity {
+			placement.Capacity[node] = w
+		}
+	}
+	if len(p.Labels) != 0 {
+		placement.Labels = make(map[storage.ServiceAddr]storage.NodeLabels, len(p.Labels))
+		for node, l := range p.Labels {
+			placement.Labels[node] = l
+		}
+	}
+	nf.placement = &placement
+	return nf
+}
+
+// Placement returns the placement setting of nf.
+//
+// Placement возвращает настройки размещения nf.
+func (nf NodesFinder) Placement() storage.Placement {
+	if nf.placement == nil {
+		return storage.Placement{}
+	}
+	return *nf.placement
+}
+
 // NodesFind returns list of nodes where record with associated key k should be stored.
-// Not more than storage.ReplciationFactor nodes is returned.
+// Not more than nf.ReplicationFactor() nodes is returned.
 // Returned nodes are choosen from the provided slice of nodes.
 //
 // NodesFind возвращает список nodes, на которых должна храниться запись с ключом k.
-// Возвращается не больше чем storage.ReplicationFactor nodes.
+// Возвращается не больше чем nf.ReplicationFactor() nodes.
 // Возвращаемые nodes выбираются из передаваемых nodes.
 func (nf NodesFinder) NodesFind(k storage.RecordID, nodes []storage.ServiceAddr) []storage.ServiceAddr {
+	return nf.NodesFindKey(k.Key(), nodes)
+}
+
+// NodesFindKey is NodesFind for the record with byte key k.
+//
+// NodesFindKey -- NodesFind для записи с байтовым ключом k.
+func (nf NodesFinder) NodesFindKey(k storage.Key, nodes []storage.ServiceAddr) []storage.ServiceAddr {
+	ranked := nf.RankKey(k, nodes)
+	if rf := nf.ReplicationFactor(); len(ranked) > rf {
+		ranked = ranked[:rf]
+	}
+	return ranked
+}
+
+// Rank returns all the provided nodes ordered by their preference to store
+// the record with associated key k. The first nf.ReplicationFactor()
+// of them are returned by NodesFind.
+//
+// Rank возвращает все переданные nodes, упорядоченные по предпочтительности
+// хранения на них записи с ключом k. Первые nf.ReplicationFactor()
+// из них возвращаются NodesFind.
+func (nf NodesFinder) Rank(k storage.RecordID, nodes []storage.ServiceAddr) []storage.ServiceAddr {
+	return nf.RankKey(k.Key(), nodes)
+}
+
+// RankKey is Rank for the record with byte key k.
+//
+// RankKey -- Rank для записи с байтовым ключом k.
+func (nf NodesFinder) RankKey(k storage.Key, nodes []storage.ServiceAddr) []storage.ServiceAddr {
+	if nf.placement == nil {
+		return nf.rank(k, nodes)
+	}
+	var ranked []storage.ServiceAddr
+	if nf.placement.Weighted() {
+		ranked = nf.rankWeighted(k, nodes)
+	} else {
+		ranked = nf.rank(k, nodes)
+	}
+	if nf.placement.Spread != "" {
+		ranked = nf.spread(ranked)
+	}
+	return ranked
+}
+
+// hash hashes k and node with the Hasher of nf. A Hasher which is not
+// a KeyHasher hashes RecordIDs only, so a key which is not the key of
+// a RecordID is folded into one by FNV-1a for it.
+func (nf NodesFinder) hash(k storage.Key, node storage.ServiceAddr) uint64 {
+	if kh, ok := nf.hasher.(KeyHasher); ok {
+		return kh.HashKey(k, node)
+	}
+	id, ok := k.RecordID()
+	if !ok {
+		h := fnv.New32a()
+		h.Write([]byte(k))
+		id = storage.RecordID(h.Sum32())
+	}
+	return nf.hasher.Hash(id, node)
+}
+
+// rank is RankKey by rendezvous hashing: nodes are ordered by the hashes
+// of k and them.
+func (nf NodesFinder) rank(k storage.Key, nodes []storage.ServiceAddr) []storage.ServiceAddr {
 	nodeHashes := make([]struct {
 		hash uint64
 		node storage.ServiceAddr
@@ -94,7 +245,7 @@ func (nf NodesFinder) NodesFind(k storage.RecordID, nodes []storage.ServiceAddr)
 			hash uint64
 			node storage.ServiceAddr
 		}{
-			hash: nf.hasher.Hash(k, node),
+			hash: nf.hash(k, node),
 			node: node,
 		})
 	}
@@ -104,15 +255,176 @@ func (nf NodesFinder) NodesFind(k storage.RecordID, nodes []storage.ServiceAddr)
 			nodeHashes[i].hash == nodeHashes[j].hash && nodeHashes[i].node > nodeHashes[j].node
 	})
 
-	n := storage.ReplicationFactor
-	if len(nodes) < n {
-		n = len(nodes)
+	res := make([]storage.ServiceAddr, 0, len(nodes))
+	for _, nh := range nodeHashes {
+		res = append(res, nh.node)
+	}
+
+	return res
+}
+
+// rankWeighted is RankKey by the logarithmic method of weighted rendezvous
+// hashing: the hash of k and a node is mapped to u uniform in (0, 1) and
+// the node with weight w scores -w / ln(u). The score is the inverse of
+// an exponentially distributed variable with rate 1/w, so the highest
+// score belongs to a node with a probability proportional to its weight,
+// and changing the weight of a node only moves records to or from it.
+func (nf NodesFinder) rankWeighted(k storage.Key, nodes []storage.ServiceAddr) []storage.ServiceAddr {
+	type nodeScore struct {
+		score float64
+		node  storage.ServiceAddr
+	}
+	scores := make([]nodeScore, 0, len(nodes))
+	for _, node := range nodes {
+		// The top 53 bits of the hash are the mantissa of u, the half
+		// keeps u off zero and one.
+		u := (float64(nf.hash(k, node)>>11) + 0.5) / (1 << 53)
+		scores = append(scores, nodeScore{
+			score: -nf.placement.NodeCapacity(node) / math.Log(u),
+			node:  node,
+		})
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].score > scores[j].score ||
+			scores[i].score == scores[j].score && scores[i].node > scores[j].node
+	})
+
+	res := make([]storage.ServiceAddr, 0, len(nodes))
+	for _, ns := range scores {
+		res = append(res, ns.node)
 	}
+	return res
+}
+
+// domainKey identifies a failure domain.
+type domainKey struct {
+	// node is set for a node which is a failure domain of its own.
+	node storage.ServiceAddr
+	zone string
+	rack string
+}
+
+// domain returns the failure domain of kind d node is in. A node without
+// the label d is considered to be a failure domain of its own.
+func (nf NodesFinder) domain(node storage.ServiceAddr, d storage.FailureDomain) domainKey {
+	l := nf.placement.Labels[node]
+	switch {
+	case d == storage.DomainZone && l.Zone != "":
+		return domainKey{zone: l.Zone}
+	case d == storage.DomainRack && l.Rack != "":
+		return domainKey{zone: l.Zone, rack: l.Rack}
+	}
+	return domainKey{node: node}
+}
 
-	res := make([]storage.ServiceAddr, 0, n)
-	for i := 0; i < n; i++ {
-		res = append(res, nodeHashes[i].node)
+// spread reorders ranked nodes so that the first nodes are in distinct
+// failure domains of kind nf.placement.Spread: a node is moved below
+// the nodes which are in failure domains taken by fewer nodes ranked above
+// them. Racks not taken yet in zones not taken yet come first, so replicas
+// spread across racks span as many zones as possible. Nodes otherwise
+// keep their order, so that spreading is deterministic as ranking is.
+func (nf NodesFinder) spread(ranked []storage.ServiceAddr) []storage.ServiceAddr {
+	type spreadNode struct {
+		node storage.ServiceAddr
+		// round is the number of nodes ranked above in the same failure
+		// domain, zoneRound is the number of nodes of the same round
+		// ranked above in the same zone.
+		round     int
+		zoneRound int
+	}
+	nodes := make([]spreadNode, 0, len(ranked))
+	taken := make(map[domainKey]int)
+	for _, node := range ranked {
+		d := nf.domain(node, nf.placement.Spread)
+		nodes = append(nodes, spreadNode{node: node, round: taken[d]})
+		taken[d]++
+	}
+	if nf.placement.Spread == storage.DomainRack {
+		type roundZone struct {
+			round int
+			zone  domainKey
+		}
+		takenZones := make(map[roundZone]int)
+		for i := range nodes {
+			z := roundZone{round: nodes[i].round, zone: nf.domain(nodes[i].node, storage.DomainZone)}
+			nodes[i].zoneRound = takenZones[z]
+			takenZones[z]++
+		}
 	}
 
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].round < nodes[j].round ||
+			nodes[i].round == nodes[j].round && nodes[i].zoneRound < nodes[j].zoneRound
+	})
+
+	res := make([]storage.ServiceAddr, 0, len(nodes))
+	for _, n := range nodes {
+		res = append(res, n.node)
+	}
 	return res
 }
+
+// NodesFindAlive returns the nodes returned by NodesFindKey for which alive
+// reports true. Returns storage.ErrNotEnoughDaemons error if less then
+// quorum of them are alive.
+//
+// NodesFindAlive возвращает nodes, возвращаемые NodesFind, для которых alive
+// возвращает true. Возвращает ошибку storage.ErrNotEnoughDaemons, если живых
+// из них меньше, чем quorum.
+func (nf NodesFinder) NodesFindAlive(k storage.Key, nodes []storage.ServiceAddr, alive func(storage.ServiceAddr) bool, quorum int) ([]storage.ServiceAddr, error) {
+	neededNodes := nf.NodesFindKey(k, nodes)
+
+	availableNodes := make([]storage.ServiceAddr, 0, len(neededNodes))
+	for _, node := range neededNodes {
+		if alive(node) {
+			availableNodes = append(availableNodes, node)
+		}
+	}
+
+	if len(availableNodes) < quorum {
+		return nil, storage.ErrNotEnoughDaemons
+	}
+	return availableNodes, nil
+}
+
+// NodesFindHinted returns replicas the record with associated key k should be
+// written to. A node for which alive reports false is substituted with the next
+// alive node in the order of RankKey which is not an owner of the record, and the
+// write is hinted for the substituted node. Returns storage.ErrNotEnoughDaemons
+// error if less then quorum of replicas can be returned.
+//
+// NodesFindHinted возвращает реплики, в которые нужно записать запись с ключом k.
+// Node, для которой alive возвращает false, заменяется следующей в порядке RankKey
+// живой node, не являющейся владельцем записи, а запись помечается подсказкой
+// (hint) для замененной node. Возвращает ошибку storage.ErrNotEnoughDaemons,
+// если меньше, чем quorum реплик, найдено.
+func (nf NodesFinder) NodesFindHinted(k storage.Key, nodes []storage.ServiceAddr, alive func(storage.ServiceAddr) bool, quorum int) ([]storage.Replica, error) {
+	ranked := nf.RankKey(k, nodes)
+	owners := len(ranked)
+	if rf := nf.ReplicationFactor(); owners > rf {
+		owners = rf
+	}
+
+	replicas := make([]storage.Replica, 0, owners)
+	next := owners
+	for _, node := range ranked[:owners] {
+		if alive(node) {
+			replicas = append(replicas, storage.Replica{Node: node})
+			continue
+		}
+		for next < len(ranked) && !alive(ranked[next]) {
+			next++
+		}
+		if next == len(ranked) {
+			continue
+		}
+		replicas = append(replicas, storage.Replica{Node: ranked[next], Hint: node})
+		next++
+	}
+
+	if len(replicas) < quorum {
+		return nil, storage.ErrNotEnoughDaemons
+	}
+	return replicas, nil
+}
@@ -1,6 +1,9 @@
 package router
 
 import (
+	"fmt"
+	"math"
+	"reflect"
 	"testing"
 
 	"storage"
@@ -37,6 +40,14 @@ func TestNodesFind(t *testing.T) {
 	if !equalNodes(got, nodes[3:]) {
 		t.Errorf("NodesFind() wrong nodes, got %v, want %v", got, nodes[3:])
 	}
+
+	got = hrw.WithReplicationFactor(5).NodesFind(1, nodes)
+	if !equalNodes(got, nodes[1:]) {
+		t.Errorf("NodesFind() wrong nodes, got %v, want %v", got, nodes[1:])
+	}
+	if rf := hrw.ReplicationFactor(); rf != storage.ReplicationFactor {
+		t.Errorf("ReplicationFactor() = %d, want %d", rf, storage.ReplicationFactor)
+	}
 }
 
 func TestNodes_SameHashes(t *testing.T) {
@@ -57,3 +68,188 @@ func TestNodes_SameHashes(t *testing.T) {
 		t.Errorf("NodesFind() wrong nodes, got %v, want %v", got, nodes[3:])
 	}
 }
+
+// idHasher is a Hasher which is not a KeyHasher.
+type idHasher struct {
+	Hasher
+}
+
+func TestNodesFindKey(t *testing.T) {
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3", "node4", "node5", "node6"}
+	hrw := NewNodesFinder(NewMD5Hasher())
+	plain := NewNodesFinder(idHasher{NewMD5Hasher()})
+	for i := 0; i < 100; i++ {
+		k := storage.RecordID(i)
+		want := hrw.NodesFind(k, nodes)
+		if got := hrw.NodesFindKey(k.Key(), nodes); !reflect.DeepEqual(got, want) {
+			t.Fatalf("NodesFindKey(%d) got %v, want %v", i, got, want)
+		}
+		if got := plain.NodesFindKey(k.Key(), nodes); !reflect.DeepEqual(got, want) {
+			t.Fatalf("NodesFindKey(%d) with a Hasher got %v, want %v", i, got, want)
+		}
+	}
+
+	// Other keys are folded into RecordIDs for a Hasher.
+	k := storage.Key("key")
+	if got := plain.NodesFindKey(k, nodes); len(got) != storage.ReplicationFactor {
+		t.Errorf("NodesFindKey(%q) with a Hasher got %v", k, got)
+	}
+}
+
+func TestNodesFind_Weighted(t *testing.T) {
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3", "node4"}
+	placement := storage.Placement{Capacity: map[storage.ServiceAddr]float64{
+		"node1": 1,
+		"node2": 2,
+		"node3": 3,
+		"node4": 4,
+	}}
+	hrw := NewNodesFinder(NewMD5Hasher()).WithReplicationFactor(1).WithPlacement(placement)
+
+	const keys = 40000
+	counts := make(map[storage.ServiceAddr]int)
+	for i := 0; i < keys; i++ {
+		counts[hrw.NodesFindKey(storage.Key(fmt.Sprintf("key%d", i)), nodes)[0]]++
+	}
+	for _, node := range nodes {
+		// The standard deviation of the share is below 0.0025.
+		got, want := float64(counts[node])/keys, placement.NodeCapacity(node)/10
+		if math.Abs(got-want) > 0.01 {
+			t.Errorf("Node %q got %.3f of keys, want %.3f", node, got, want)
+		}
+	}
+}
+
+func TestNodesFind_WeightChange(t *testing.T) {
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3", "node4", "node5"}
+	hrw := NewNodesFinder(NewMD5Hasher())
+	before := hrw.WithPlacement(storage.Placement{Capacity: map[storage.ServiceAddr]float64{"node1": 1}})
+	after := hrw.WithPlacement(storage.Placement{Capacity: map[storage.ServiceAddr]float64{"node1": 2}})
+
+	const keys = 40000
+	moved := 0
+	for i := 0; i < keys; i++ {
+		k := storage.Key(fmt.Sprintf("key%d", i))
+		old, cur := before.RankKey(k, nodes), after.RankKey(k, nodes)
+		if old[0] != cur[0] {
+			moved++
+			if cur[0] != "node1" {
+				t.Fatalf("Key %q moved from %q to %q, not to the node with the increased weight", k, old[0], cur[0])
+			}
+		}
+		// Nodes other than node1 keep their relative order.
+		if !reflect.DeepEqual(without(old, "node1"), without(cur, "node1")) {
+			t.Fatalf("Rank(%q) changed from %v to %v", k, old, cur)
+		}
+	}
+	// node1 owned 1/5 of keys and owns 2/6 of them now.
+	if got, want := float64(moved)/keys, 2.0/6-1.0/5; math.Abs(got-want) > 0.01 {
+		t.Errorf("Moved %.3f of keys, want %.3f", got, want)
+	}
+}
+
+func TestNodesFind_EvenPlacement(t *testing.T) {
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3", "node4", "node5"}
+	hrw := NewNodesFinder(NewMD5Hasher())
+	even := hrw.WithPlacement(storage.Placement{})
+	for i := 0; i < 100; i++ {
+		k := storage.Key(fmt.Sprintf("key%d", i))
+		if got, want := even.RankKey(k, nodes), hrw.RankKey(k, nodes); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Rank(%q) with an even placement got %v, want %v", k, got, want)
+		}
+	}
+	if even.Placement().Weighted() {
+		t.Errorf("Placement() got %+v, want an even placement", even.Placement())
+	}
+}
+
+func without(nodes []storage.ServiceAddr, node storage.ServiceAddr) []storage.ServiceAddr {
+	res := make([]storage.ServiceAddr, 0, len(nodes))
+	for _, n := range nodes {
+		if n != node {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+func TestNodesFind_Spread(t *testing.T) {
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3", "node4", "node5", "node6", "node7"}
+	labels := map[storage.ServiceAddr]storage.NodeLabels{
+		"node1": {Zone: "a", Rack: "r1"},
+		"node2": {Zone: "a", Rack: "r1"},
+		"node3": {Zone: "a", Rack: "r2"},
+		"node4": {Zone: "a", Rack: "r2"},
+		"node5": {Zone: "b", Rack: "r1"},
+		"node6": {Zone: "b", Rack: "r1"},
+		// node7 is in a failure domain of its own.
+	}
+	domains := func(nodes []storage.ServiceAddr, d storage.FailureDomain) map[storage.NodeLabels]bool {
+		res := make(map[storage.NodeLabels]bool)
+		for _, node := range nodes {
+			l, ok := labels[node]
+			if !ok {
+				l = storage.NodeLabels{Zone: string(node), Rack: string(node)}
+			}
+			if d == storage.DomainZone {
+				l.Rack = ""
+			}
+			res[l] = true
+		}
+		return res
+	}
+	hrw := NewNodesFinder(NewMD5Hasher())
+	for _, weighted := range []bool{false, true} {
+		placement := storage.Placement{Labels: labels}
+		if weighted {
+			placement.Capacity = map[storage.ServiceAddr]float64{"node1": 3, "node5": 2}
+		}
+		unspread := hrw.WithPlacement(placement)
+		for _, tc := range []struct {
+			spread storage.FailureDomain
+			rf     int
+			want   int
+		}{
+			{spread: storage.DomainZone, rf: 3, want: 3},
+			{spread: storage.DomainZone, rf: 4, want: 3},
+			{spread: storage.DomainRack, rf: 3, want: 3},
+			{spread: storage.DomainRack, rf: 4, want: 4},
+		} {
+			placement.Spread = tc.spread
+			spread := hrw.WithReplicationFactor(tc.rf).WithPlacement(placement)
+			for i := 0; i < 1000; i++ {
+				k := storage.Key(fmt.Sprintf("key%d", i))
+				got := spread.NodesFindKey(k, nodes)
+				if len(got) != tc.rf {
+					t.Fatalf("NodesFind(%q) spread by %s got %v, want %d nodes", k, tc.spread, got, tc.rf)
+				}
+				if n := len(domains(got, tc.spread)); n != tc.want {
+					t.Fatalf("NodesFind(%q) spread by %s got %v in %d domains, want %d", k, tc.spread, got, n, tc.want)
+				}
+				// Racks of the replicas span all the zones.
+				if n := len(domains(got, storage.DomainZone)); tc.spread == storage.DomainRack && n != 3 {
+					t.Fatalf("NodesFind(%q) spread by %s got %v in %d zones, want 3", k, tc.spread, got, n)
+				}
+				// The first node is the one preferred regardless of labels.
+				if first := unspread.RankKey(k, nodes)[0]; got[0] != first {
+					t.Fatalf("NodesFind(%q) spread by %s got %v, want %q first", k, tc.spread, got, first)
+				}
+				if again := spread.NodesFindKey(k, nodes); !reflect.DeepEqual(again, got) {
+					t.Fatalf("NodesFind(%q) spread by %s got %v and then %v", k, tc.spread, got, again)
+				}
+			}
+		}
+	}
+}
+
+func TestNodesFind_SpreadWithoutLabels(t *testing.T) {
+	nodes := []storage.ServiceAddr{"node1", "node2", "node3", "node4", "node5"}
+	hrw := NewNodesFinder(NewMD5Hasher())
+	spread := hrw.WithPlacement(storage.Placement{Spread: storage.DomainRack})
+	for i := 0; i < 100; i++ {
+		k := storage.Key(fmt.Sprintf("key%d", i))
+		if got, want := spread.RankKey(k, nodes), hrw.RankKey(k, nodes); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Rank(%q) spread across unlabeled nodes got %v, want %v", k, got, want)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package router
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math/bits"
+
+	"storage"
+)
+
+// Names of Hashers in HasherOptions.
+const (
+	HasherMD5     = "md5"
+	HasherXXHash  = "xxhash"
+	HasherFNVMix  = "fnvmix"
+	HasherSipHash = "siphash"
+)
+
+// HasherOptions selects the Hasher of a NodesFinder. Routers, frontends
+// and nodes of a cluster have to use the same Hasher, otherwise they place
+// records on different nodes.
+//
+// HasherOptions выбирает Hasher для NodesFinder. Router, frontend и node
+// одного кластера должны использовать один и тот же Hasher, иначе они
+// размещают записи на разных узлах.
+type HasherOptions struct {
+	// Hasher is the name of the Hasher: md5, xxhash, fnvmix or siphash.
+	// MD5 is used if Hasher is empty.
+	// Hasher -- имя Hasher: md5, xxhash, fnvmix или siphash.
+	// Если Hasher пустой, используется MD5.
+	Hasher string `yaml:"hasher"`
+	// HashKey is the 128-bit key of the cluster in hex, siphash requires it.
+	// HashKey -- 128-битный ключ кластера в hex, требуется для siphash.
+	HashKey string `yaml:"hash_key"`
+}
+
+// NewHasher creates the Hasher selected by o. Returns ErrUnknownHasher
+// for an unknown name and ErrInvalidHashKey if siphash is selected
+// without a valid key.
+//
+// NewHasher создает Hasher, выбранный в o. Возвращает ErrUnknownHasher
+// для неизвестного имени и ErrInvalidHashKey, если выбран siphash
+// без корректного ключа.
+func (o HasherOptions) NewHasher() (Hasher, error) {
+	switch o.Hasher {
+	case "", HasherMD5:
+		return NewMD5Hasher(), nil
+	case HasherXXHash:
+		return XXHash{}, nil
+	case HasherFNVMix:
+		return FNVMix{}, nil
+	case HasherSipHash:
+		key, err := hex.DecodeString(o.HashKey)
+		if err != nil || len(key) != 16 {
+			return nil, storage.ErrInvalidHashKey
+		}
+		return NewSipHasher(key), nil
+	}
+	return nil, storage.ErrUnknownHasher
+}
+
+// hashBufSize is the size of buffers on the stack fitting most keys
+// followed by nodes, longer ones are allocated.
+const hashBufSize = 128
+
+// XXHash implements Hasher interface computing 64-bit xxHash with zero seed.
+//
+// XXHash реализует интерфейс Hasher, вычисляя 64-битный xxHash с нулевым seed.
+type XXHash struct{}
+
+func (h XXHash) Hash(k storage.RecordID, node storage.ServiceAddr) uint64 {
+	return h.HashKey(k.Key(), node)
+}
+
+// HashKey hashes the bytes of k followed by node.
+//
+// HashKey хеширует байты k, за которыми следует node.
+func (XXHash) HashKey(k storage.Key, node storage.ServiceAddr) uint64 {
+	var arr [hashBufSize]byte
+	buf := append(append(arr[:0], k...), node...)
+	return xxhash64(buf)
+}
+
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+func xxRound(acc, in uint64) uint64 {
+	acc += in * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMerge(acc, v uint64) uint64 {
+	acc ^= xxRound(0, v)
+	return acc*xxPrime1 + xxPrime4
+}
+
+func xxhash64(b []byte) uint64 {
+	n := len(b)
+	var h uint64
+	if n >= 32 {
+		var seed uint64
+		v1 := seed + xxPrime1 + xxPrime2
+		v2 := seed + xxPrime2
+		v3 := seed
+		v4 := seed - xxPrime1
+		for ; len(b) >= 32; b = b[32:] {
+			v1 = xxRound(v1, binary.LittleEndian.Uint64(b))
+			v2 = xxRound(v2, binary.LittleEndian.Uint64(b[8:]))
+			v3 = xxRound(v3, binary.LittleEndian.Uint64(b[16:]))
+			v4 = xxRound(v4, binary.LittleEndian.Uint64(b[24:]))
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) +
+			bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxMerge(h, v1)
+		h = xxMerge(h, v2)
+		h = xxMerge(h, v3)
+		h = xxMerge(h, v4)
+	} else {
+		h = xxPrime5
+	}
+	h += uint64(n)
+
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= xxRound(0, binary.LittleEndian.Uint64(b))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(b)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		b = b[4:]
+	}
+	for _, c := range b {
+		h ^= uint64(c) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+// FNVMix implements Hasher interface computing 64-bit FNV-1a finalized
+// with the mix of MurmurHash3, so it's not FNV-1a. Plain FNV-1a barely
+// mixes the last bytes, and nodes differing in the last digit of the port
+// would be ranked alike for many keys.
+//
+// FNVMix реализует интерфейс Hasher, вычисляя 64-битный FNV-1a, завершенный
+// перемешиванием из MurmurHash3, поэтому это не FNV-1a. Обычный FNV-1a слабо
+// перемешивает последние байты, и узлы, которые отличаются последней
+// цифрой порта, упорядочивались бы одинаково для многих ключей.
+type FNVMix struct{}
+
+const (
+	fnvOffset uint64 = 14695981039346656037
+	fnvPrime  uint64 = 1099511628211
+)
+
+func (h FNVMix) Hash(k storage.RecordID, node storage.ServiceAddr) uint64 {
+	return h.HashKey(k.Key(), node)
+}
+
+// HashKey hashes the bytes of k followed by node.
+//
+// HashKey хеширует байты k, за которыми следует node.
+func (FNVMix) HashKey(k storage.Key, node storage.ServiceAddr) uint64 {
+	h := fnvOffset
+	for i := 0; i < len(k); i++ {
+		h ^= uint64(k[i])
+		h *= fnvPrime
+	}
+	for i := 0; i < len(node); i++ {
+		h ^= uint64(node[i])
+		h *= fnvPrime
+	}
+	return fmix64(h)
+}
+
+// fmix64 is the finalization mix of MurmurHash3.
+func fmix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// SipHash implements Hasher interface computing SipHash-2-4 with a key
+// of the cluster. Unlike other Hashers, keys can't be chosen to be placed
+// on the same nodes without the key of the cluster.
+//
+// SipHash реализует интерфейс Hasher, вычисляя SipHash-2-4 с ключом
+// кластера. В отличие от других Hasher, без ключа кластера нельзя подобрать
+// ключи так, чтобы они размещались на одних и тех же узлах.
+type SipHash struct {
+	k0, k1 uint64
+}
+
+// NewSipHasher creates new SipHash with a given 16 byte key.
+//
+// NewSipHasher создает новый SipHash с данным 16-байтным ключом.
+func NewSipHasher(key []byte) SipHash {
+	return SipHash{
+		k0: binary.LittleEndian.Uint64(key),
+		k1: binary.LittleEndian.Uint64(key[8:]),
+	}
+}
+
+func (h SipHash) Hash(k storage.RecordID, node storage.ServiceAddr) uint64 {
+	return h.HashKey(k.Key(), node)
+}
+
+// HashKey hashes the bytes of k followed by node.
+//
+// HashKey хеширует байты k, за которыми следует node.
+func (h SipHash) HashKey(k storage.Key, node storage.ServiceAddr) uint64 {
+	var arr [hashBufSize]byte
+	buf := append(append(arr[:0], k...), node...)
+	return siphash24(h.k0, h.k1, buf)
+}
+
+func sipRound(v0, v1, v2, v3 uint64) (uint64, uint64, uint64, uint64) {
+	v0 += v1
+	v1 = bits.RotateLeft64(v1, 13)
+	v1 ^= v0
+	v0 = bits.RotateLeft64(v0, 32)
+	v2 += v3
+	v3 = bits.RotateLeft64(v3, 16)
+	v3 ^= v2
+	v0 += v3
+	v3 = bits.RotateLeft64(v3, 21)
+	v3 ^= v0
+	v2 += v1
+	v1 = bits.RotateLeft64(v1, 17)
+	v1 ^= v2
+	v2 = bits.RotateLeft64(v2, 32)
+	return v0, v1, v2, v3
+}
+
+func siphash24(k0, k1 uint64, b []byte) uint64 {
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+
+	last := uint64(len(b)) << 56
+	for ; len(b) >= 8; b = b[8:] {
+		m := binary.LittleEndian.Uint64(b)
+		v3 ^= m
+		v0, v1, v2, v3 = sipRound(v0, v1, v2, v3)
+		v0, v1, v2, v3 = sipRound(v0, v1, v2, v3)
+		v0 ^= m
+	}
+	for i, c := range b {
+		last |= uint64(c) << (8 * uint(i))
+	}
+	v3 ^= last
+	v0, v1, v2, v3 = sipRound(v0, v1, v2, v3)
+	v0, v1, v2, v3 = sipRound(v0, v1, v2, v3)
+	v0 ^= last
+
+	v2 ^= 0xff
+	for i := 0; i < 4; i++ {
+		v0, v1, v2, v3 = sipRound(v0, v1, v2, v3)
+	}
+	return v0 ^ v1 ^ v2 ^ v3
+}
@@ -0,0 +1,314 @@
+package router
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"testing"
+
+	"storage"
+)
+
+func TestXXHash(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"as", 0x1c330fb2d66be179},
+		{"asd", 0x631c37ce72a97393},
+		{"asdf", 0x415872f599cea71e},
+		{"abc", 0x44bc2cf5ad770999},
+		{"Call me Ishmael. Some years ago--never mind how long precisely-", 0x02a2e85470d6fd96},
+	} {
+		if got := xxhash64([]byte(tc.in)); got != tc.want {
+			t.Errorf("xxhash64(%q) = %#x, want %#x", tc.in, got, tc.want)
+		}
+	}
+	if got, want := (XXHash{}).HashKey("key", "node"), xxhash64([]byte("keynode")); got != want {
+		t.Errorf("HashKey() = %#x, want %#x", got, want)
+	}
+}
+
+func TestSipHash(t *testing.T) {
+	key := make([]byte, 16)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	h := NewSipHasher(key)
+	// Test vectors of the reference implementation hash bytes 0, 1, ... n-1.
+	for _, tc := range []struct {
+		n    int
+		want uint64
+	}{
+		{0, 0x726fdb47dd0e0e31},
+		{1, 0x74f839c593dc67fd},
+		{15, 0xa129ca6149be45e5},
+		{63, 0x958a324ceb064572},
+	} {
+		in := make([]byte, tc.n)
+		for i := range in {
+			in[i] = byte(i)
+		}
+		if got := siphash24(h.k0, h.k1, in); got != tc.want {
+			t.Errorf("siphash24(%d bytes) = %#x, want %#x", tc.n, got, tc.want)
+		}
+	}
+
+	other := NewSipHasher(make([]byte, 16))
+	if h.HashKey("key", "node") == other.HashKey("key", "node") {
+		t.Errorf("HashKey() doesn't depend on the key")
+	}
+}
+
+func TestFNVMix(t *testing.T) {
+	f := fnv.New64a()
+	f.Write([]byte("keynode"))
+	if got, want := (FNVMix{}).HashKey("key", "node"), fmix64(f.Sum64()); got != want {
+		t.Errorf("HashKey() = %#x, want %#x", got, want)
+	}
+}
+
+func TestHasherOptions(t *testing.T) {
+	for _, tc := range []struct {
+		opts HasherOptions
+		want Hasher
+		err  error
+	}{
+		{opts: HasherOptions{}, want: NewMD5Hasher()},
+		{opts: HasherOptions{Hasher: HasherMD5}, want: NewMD5Hasher()},
+		{opts: HasherOptions{Hasher: HasherXXHash}, want: XXHash{}},
+		{opts: HasherOptions{Hasher: HasherFNVMix}, want: FNVMix{}},
+		{opts: HasherOptions{Hasher: "fnv"}, err: storage.ErrUnknownHasher},
+		{
+			opts: HasherOptions{Hasher: HasherSipHash, HashKey: "000102030405060708090a0b0c0d0e0f"},
+			want: SipHash{k0: 0x0706050403020100, k1: 0x0f0e0d0c0b0a0908},
+		},
+		{opts: HasherOptions{Hasher: HasherSipHash}, err: storage.ErrInvalidHashKey},
+		{opts: HasherOptions{Hasher: HasherSipHash, HashKey: "0001"}, err: storage.ErrInvalidHashKey},
+		{opts: HasherOptions{Hasher: HasherSipHash, HashKey: "not a hex key"}, err: storage.ErrInvalidHashKey},
+		{opts: HasherOptions{Hasher: "sha1"}, err: storage.ErrUnknownHasher},
+	} {
+		h, err := tc.opts.NewHasher()
+		if err != tc.err {
+			t.Errorf("NewHasher(%+v) expected error %v, got %v", tc.opts, tc.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got, want := h.Hash(1, "node"), tc.want.Hash(1, "node"); got != want {
+			t.Errorf("NewHasher(%+v) got %T hashing to %#x, want %T hashing to %#x", tc.opts, h, got, tc.want, want)
+		}
+	}
+}
+
+type namedHasher struct {
+	name string
+	KeyHasher
+}
+
+// testHashers returns the Hashers distribution quality is tested for.
+func testHashers() []namedHasher {
+	key := make([]byte, 16)
+	for i := range key {
+		key[i] = byte(i * 17)
+	}
+	return []namedHasher{
+		{HasherMD5, NewMD5Hasher()},
+		{HasherXXHash, XXHash{}},
+		{HasherFNVMix, FNVMix{}},
+		{HasherSipHash, NewSipHasher(key)},
+	}
+}
+
+// testNodes returns n nodes with addresses differing in the last digits
+// of the port, which is the worst case for weak hashes.
+func testNodes(n int) []storage.ServiceAddr {
+	nodes := make([]storage.ServiceAddr, n)
+	for i := range nodes {
+		nodes[i] = storage.ServiceAddr(fmt.Sprintf("127.0.0.1:%d", 7320+i))
+	}
+	return nodes
+}
+
+// testKeys returns the keys of n sequential RecordIDs and n string keys.
+func testKeys(n int) []storage.Key {
+	keys := make([]storage.Key, 0, 2*n)
+	for i := 0; i < n; i++ {
+		keys = append(keys, storage.RecordID(i).Key())
+		keys = append(keys, storage.Key(fmt.Sprintf("user:%d", i)))
+	}
+	return keys
+}
+
+// chiSquare returns the chi-square statistic of counts expected
+// to be uniform.
+func chiSquare(counts []int) float64 {
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	expected := float64(total) / float64(len(counts))
+	var chi2 float64
+	for _, c := range counts {
+		d := float64(c) - expected
+		chi2 += d * d / expected
+	}
+	return chi2
+}
+
+// chiSquareCritical approximates the chi-square value exceeded with
+// probability 0.001 for df degrees of freedom by Wilson-Hilferty.
+func chiSquareCritical(df int) float64 {
+	const z = 3.09
+	k := float64(df)
+	v := 1 - 2/(9*k) + z*math.Sqrt(2/(9*k))
+	return k * v * v * v
+}
+
+func TestHashers_KeysDistribution(t *testing.T) {
+	nodes := testNodes(10)
+	keys := testKeys(50000)
+	for _, h := range testHashers() {
+		hrw := NewNodesFinder(h.KeyHasher)
+		index := make(map[storage.ServiceAddr]int, len(nodes))
+		for i, node := range nodes {
+			index[node] = i
+		}
+		// Every node is expected to be at every place of the replicas
+		// for the same share of keys.
+		counts := make([][]int, storage.ReplicationFactor)
+		for i := range counts {
+			counts[i] = make([]int, len(nodes))
+		}
+		for _, k := range keys {
+			for i, node := range hrw.NodesFindKey(k, nodes) {
+				counts[i][index[node]]++
+			}
+		}
+		for i, c := range counts {
+			if chi2, max := chiSquare(c), chiSquareCritical(len(nodes)-1); chi2 > max {
+				t.Errorf("Hasher %s places replicas %d unevenly: chi-square %.1f > %.1f, counts %v", h.name, i, chi2, max, c)
+			}
+		}
+	}
+}
+
+func TestHashers_NodesDistribution(t *testing.T) {
+	nodes := testNodes(10)
+	keys := testKeys(50000)
+	for _, h := range testHashers() {
+		hrw := NewNodesFinder(h.KeyHasher).WithReplicationFactor(2)
+		// Replicas of records stored on a node are expected to be spread
+		// evenly across the other nodes, so the pairs of nodes are uniform.
+		counts := make([]int, len(nodes)*len(nodes))
+		index := make(map[storage.ServiceAddr]int, len(nodes))
+		for i, node := range nodes {
+			index[node] = i
+		}
+		for _, k := range keys {
+			found := hrw.NodesFindKey(k, nodes)
+			counts[index[found[0]]*len(nodes)+index[found[1]]]++
+		}
+		pairs := make([]int, 0, len(nodes)*(len(nodes)-1))
+		for i := range nodes {
+			for j := range nodes {
+				if i != j {
+					pairs = append(pairs, counts[i*len(nodes)+j])
+				}
+			}
+		}
+		if chi2, max := chiSquare(pairs), chiSquareCritical(len(pairs)-1); chi2 > max {
+			t.Errorf("Hasher %s pairs nodes unevenly: chi-square %.1f > %.1f", h.name, chi2, max)
+		}
+	}
+}
+
+func TestHashers_BitsDistribution(t *testing.T) {
+	keys := testKeys(50000)
+	for _, h := range testHashers() {
+		// Every bit of the hash is expected to be set for half of keys.
+		counts := make([]int, 64)
+		for _, k := range keys {
+			hash := h.HashKey(k, "127.0.0.1:7320")
+			for i := range counts {
+				if hash&(1<<uint(i)) != 0 {
+					counts[i]++
+				}
+			}
+		}
+		// Bits are independent, so the sum of their statistics is
+		// a chi-square with a degree of freedom per bit.
+		var chi2 float64
+		for _, c := range counts {
+			chi2 += chiSquare([]int{c, len(keys) - c})
+		}
+		if max := chiSquareCritical(len(counts)); chi2 > max {
+			t.Errorf("Hasher %s sets bits unevenly: chi-square %.1f > %.1f, counts %v", h.name, chi2, max, counts)
+		}
+	}
+}
+
+func BenchmarkHash(b *testing.B) {
+	k := storage.RecordID(123456789).Key()
+	for _, h := range testHashers() {
+		b.Run(h.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				h.HashKey(k, "127.0.0.1:7320")
+			}
+		})
+	}
+}
+
+func BenchmarkNodesFind(b *testing.B) {
+	nodes := testNodes(10)
+	keys := make([]storage.Key, 1024)
+	for i := range keys {
+		keys[i] = storage.RecordID(i).Key()
+	}
+	for _, h := range testHashers() {
+		hrw := NewNodesFinder(h.KeyHasher)
+		b.Run(h.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				hrw.NodesFindKey(keys[i%len(keys)], nodes)
+			}
+		})
+	}
+}
+
+func BenchmarkNodesFind_Weighted(b *testing.B) {
+	nodes := testNodes(10)
+	placement := storage.Placement{Capacity: map[storage.ServiceAddr]float64{nodes[0]: 2, nodes[1]: 0.5}}
+	keys := make([]storage.Key, 1024)
+	for i := range keys {
+		keys[i] = storage.RecordID(i).Key()
+	}
+	for _, h := range testHashers() {
+		hrw := NewNodesFinder(h.KeyHasher).WithPlacement(placement)
+		b.Run(h.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				hrw.NodesFindKey(keys[i%len(keys)], nodes)
+			}
+		})
+	}
+}
+
+func BenchmarkNodesFindParallel(b *testing.B) {
+	nodes := testNodes(10)
+	k := storage.RecordID(123456789).Key()
+	for _, h := range testHashers() {
+		hrw := NewNodesFinder(h.KeyHasher)
+		b.Run(h.name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					hrw.NodesFindKey(k, nodes)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package router
+
+import (
+	"math"
+	"time"
+)
+
+// Defaults of the phi-accrual failure detector.
+//
+// Значения по умолчанию для phi-accrual failure detector.
+const (
+	// DefaultPhiWindow is a number of the last heartbeat intervals of a node
+	// the distribution of its intervals is estimated from.
+	// DefaultPhiWindow -- количество последних интервалов между heartbeats node,
+	// по которым оценивается распределение ее интервалов.
+	DefaultPhiWindow = 100
+	// DefaultPhiMinStdDev is a lower bound of the standard deviation of
+	// heartbeat intervals, so that regular heartbeats don't make
+	// a slight delay suspicious.
+	// DefaultPhiMinStdDev -- нижняя граница стандартного отклонения интервалов
+	// между heartbeats, чтобы при регулярных heartbeats небольшая задержка
+	// не становилась подозрительной.
+	DefaultPhiMinStdDev = 50 * time.Millisecond
+)
+
+// intervals is a window of the last heartbeat intervals of a node along with
+// their mean and standard deviation in seconds.
+type intervals struct {
+	samples []time.Duration
+	next    int
+	mean    float64
+	stdDev  float64
+}
+
+// add adds d to the window evicting the oldest interval
+// if there are size of them.
+func (iv *intervals) add(d time.Duration, size int) {
+	if len(iv.samples) < size {
+		iv.samples = append(iv.samples, d)
+	} else {
+		iv.samples[iv.next%len(iv.samples)] = d
+		iv.next = (iv.next + 1) % len(iv.samples)
+	}
+
+	iv.mean, iv.stdDev = 0, 0
+	for _, d := range iv.samples {
+		iv.mean += d.Seconds()
+	}
+	iv.mean /= float64(len(iv.samples))
+	for _, d := range iv.samples {
+		diff := d.Seconds() - iv.mean
+		iv.stdDev += diff * diff
+	}
+	iv.stdDev = math.Sqrt(iv.stdDev / float64(len(iv.samples)))
+}
+
+// ordered returns the intervals from the oldest to the latest.
+func (iv *intervals) ordered() []time.Duration {
+	n := len(iv.samples)
+	res := make([]time.Duration, 0, n)
+	for i := 0; i < n; i++ {
+		res = append(res, iv.samples[(iv.next+i)%n])
+	}
+	return res
+}
+
+// phi returns the suspicion level of the node whose last heartbeat was
+// elapsed ago, the standard deviation of the intervals is taken to be
+// at least minStdDev.
+func (iv *intervals) phi(elapsed, minStdDev time.Duration) float64 {
+	return phi(elapsed, iv.mean, math.Max(iv.stdDev, minStdDev.Seconds()))
+}
+
+// suspectedAfter returns the time since the last heartbeat after which
+// the phi of the node exceeds threshold.
+func (iv *intervals) suspectedAfter(threshold float64, minStdDev time.Duration) time.Duration {
+	return suspectedAfter(threshold, iv.mean, math.Max(iv.stdDev, minStdDev.Seconds()))
+}
+
+// phi returns the suspicion level of a node whose last heartbeat was
+// elapsed ago: -log10 of the probability that the next heartbeat arrives
+// even later, with intervals normally distributed with the given mean
+// and standard deviation. The normal distribution is approximated with
+// a logistic one as in Akka.
+func phi(elapsed time.Duration, mean, stdDev float64) float64 {
+	y := (elapsed.Seconds() - mean) / stdDev
+	e := math.Exp(-y * (1.5976 + 0.070566*y*y))
+	if elapsed.Seconds() > mean {
+		return -math.Log10(e / (1 + e))
+	}
+	return -math.Log10(1 - 1/(1+e))
+}
+
+// suspectedAfter returns the time since the last heartbeat after which phi
+// exceeds threshold.
+func suspectedAfter(threshold, mean, stdDev float64) time.Duration {
+	// phi grows with the elapsed time, so the bound is found by bisection.
+	lo, hi := time.Duration(0), time.Duration((mean+stdDev)*float64(time.Second))+time.Millisecond
+	for phi(hi, mean, stdDev) <= threshold {
+		lo, hi = hi, 2*hi
+	}
+	for hi-lo > time.Millisecond {
+		mid := lo + (hi-lo)/2
+		if phi(mid, mean, stdDev) <= threshold {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi
+}
@@ -0,0 +1,110 @@
+package router
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"storage"
+)
+
+func TestIntervals(t *testing.T) {
+	var iv intervals
+	for i := 1; i <= 5; i++ {
+		iv.add(time.Duration(i)*time.Second, 3)
+	}
+	want := []time.Duration{3 * time.Second, 4 * time.Second, 5 * time.Second}
+	if got := iv.ordered(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ordered() got %v, want %v", got, want)
+	}
+	if iv.mean != 4 || math.Abs(iv.stdDev-math.Sqrt(2.0/3)) > 1e-9 {
+		t.Errorf("Got mean %v and standard deviation %v, want 4 and %v", iv.mean, iv.stdDev, math.Sqrt(2.0/3))
+	}
+}
+
+func TestPhi(t *testing.T) {
+	mean, stdDev := 1.0, 0.1
+	if got := phi(time.Second, mean, stdDev); math.Abs(got-math.Log10(2)) > 1e-9 {
+		t.Errorf("phi() at the mean got %v, want %v", got, math.Log10(2))
+	}
+	prev := 0.0
+	for d := time.Duration(0); d < 3*time.Second; d += 10 * time.Millisecond {
+		p := phi(d, mean, stdDev)
+		if p < prev {
+			t.Fatalf("phi() decreased from %v to %v at %v", prev, p, d)
+		}
+		prev = p
+	}
+
+	for _, threshold := range []float64{1, 8, 12} {
+		d := suspectedAfter(threshold, mean, stdDev)
+		if phi(d, mean, stdDev) <= threshold || phi(d-time.Millisecond, mean, stdDev) > threshold {
+			t.Errorf("suspectedAfter(%v) got %v which is not where phi crosses it", threshold, d)
+		}
+	}
+}
+
+func TestPhiAccrual(t *testing.T) {
+	cfg := cfg
+	cfg.ForgetTimeout = time.Hour
+	cfg.PhiThreshold = 8
+	cfg.PhiMinStdDev = 5 * time.Millisecond
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	interval := 10 * time.Millisecond
+	for i := 0; i < 10; i++ {
+		if err := r.Heartbeat("node1"); err != nil {
+			t.Fatalf("Heartbeat() error: %v", err)
+		}
+		if i < 9 {
+			time.Sleep(interval)
+		}
+	}
+	if err := r.Heartbeat("node2"); err != nil {
+		t.Fatalf("Heartbeat() error: %v", err)
+	}
+	if alive, want := r.Alive(), []storage.ServiceAddr{"node1", "node2"}; !equalNodes(alive, want) {
+		t.Errorf("Alive() got %v, want %v", alive, want)
+	}
+
+	// node1 missed heartbeats it sent regularly, node2 is still within
+	// ForgetTimeout as it sent a single heartbeat.
+	time.Sleep(20 * interval)
+	if alive, want := r.Alive(), []storage.ServiceAddr{"node2"}; !equalNodes(alive, want) {
+		t.Errorf("Alive() got %v, want %v", alive, want)
+	}
+
+	s := r.Suspicion()
+	if len(s) != 3 {
+		t.Fatalf("Suspicion() got %v, want 3 nodes", s)
+	}
+	if s[0].Node != "node1" || s[0].Alive || s[0].Phi <= cfg.PhiThreshold || s[0].Intervals != 9 {
+		t.Errorf("Suspicion() got %+v for a failed node", s[0])
+	}
+	if s[1].Node != "node2" || !s[1].Alive || s[1].Phi != 0 || s[1].Intervals != 0 {
+		t.Errorf("Suspicion() got %+v for a node with a single heartbeat", s[1])
+	}
+	if s[2].Node != "node3" || s[2].Alive || !s[2].LastHeartbeat.IsZero() {
+		t.Errorf("Suspicion() got %+v for a node without heartbeats", s[2])
+	}
+
+	// The intervals are kept in snapshots of replicated routers.
+	b, err := stateMachine{r}.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() error: %v", err)
+	}
+	restored, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if err := (stateMachine{restored}).Restore(b); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	if got, want := restored.intervals["node1"].ordered(), r.intervals["node1"].ordered(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Restored intervals %v, want %v", got, want)
+	}
+}
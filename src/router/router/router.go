@@ -1,9 +1,11 @@
 package router
 
 import (
+	"sort"
 	"sync"
 	"time"
 
+	"router/raft"
 	"storage"
 )
 
@@ -25,6 +27,114 @@ type Config struct {
 	// node считается недоступной.
 	ForgetTimeout time.Duration `yaml:"forget_timeout"`
 
+	// PhiThreshold enables the phi-accrual failure detector replacing
+	// ForgetTimeout: a node is considered to be unavailable once its suspicion
+	// level phi, estimated from the intervals between its heartbeats, exceeds
+	// PhiThreshold. ForgetTimeout still applies to nodes which haven't sent
+	// two heartbeats in a row yet.
+	// PhiThreshold -- включает phi-accrual failure detector вместо ForgetTimeout:
+	// node считается недоступной, когда ее уровень подозрения phi, оцениваемый
+	// по интервалам между ее heartbeats, превышает PhiThreshold. ForgetTimeout
+	// по-прежнему применяется к node, еще не приславшим двух heartbeats подряд.
+	PhiThreshold float64 `yaml:"phi_threshold"`
+
+	// PhiWindow is a number of the last heartbeat intervals of a node
+	// phi is estimated from, DefaultPhiWindow if it's not set.
+	// PhiWindow -- количество последних интервалов между heartbeats node,
+	// по которым оценивается phi, DefaultPhiWindow, если не задано.
+	PhiWindow int `yaml:"phi_window"`
+
+	// PhiMinStdDev is a lower bound of the standard deviation of heartbeat
+	// intervals, DefaultPhiMinStdDev if it's not set.
+	// PhiMinStdDev -- нижняя граница стандартного отклонения интервалов между
+	// heartbeats, DefaultPhiMinStdDev, если не задано.
+	PhiMinStdDev time.Duration `yaml:"phi_min_std_dev"`
+
+	// ReplicationFactor is a number of nodes each record is stored on,
+	// storage.ReplicationFactor if it's not set.
+	// ReplicationFactor -- количество node, на которых хранится каждая запись,
+	// storage.ReplicationFactor, если не задано.
+	ReplicationFactor int `yaml:"replication_factor"`
+
+	// Quorum is a number of replicas which have to agree on an operation,
+	// a majority of ReplicationFactor if it's not set.
+	// Quorum -- количество реплик, которые должны согласиться с операцией,
+	// большинство из ReplicationFactor, если не задано.
+	Quorum int `yaml:"quorum"`
+
+	// Capacity holds capacity weights of nodes placing records on each node
+	// in proportion to its weight with weighted rendezvous hashing. Nodes
+	// which are not in Capacity have storage.DefaultCapacity. Records are
+	// placed on nodes evenly if Capacity is empty.
+	// Capacity -- веса емкости node, записи размещаются на каждой node
+	// пропорционально ее весу с помощью взвешенного rendezvous hashing.
+	// У node, отсутствующих в Capacity, вес storage.DefaultCapacity. Если
+	// Capacity пуст, записи размещаются на node равномерно.
+	Capacity map[storage.ServiceAddr]float64 `yaml:"capacity"`
+
+	// Labels holds topology labels of nodes: the zone and the rack they are in.
+	// Labels -- топологические метки node: зона и стойка, в которых они находятся.
+	Labels map[storage.ServiceAddr]storage.NodeLabels `yaml:"labels"`
+
+	// Spread is a failure domain, storage.DomainZone or storage.DomainRack,
+	// replicas of a record are placed in distinct ones of while there are
+	// enough of them. Nodes without the label are considered to be failure
+	// domains of their own. Replicas are placed regardless of Labels if
+	// Spread is empty.
+	// Spread -- failure domain, storage.DomainZone или storage.DomainRack,
+	// в различных экземплярах которого размещаются реплики записи, пока их
+	// достаточно. Node без соответствующей метки считаются отдельными failure
+	// domains. Если Spread пуст, реплики размещаются независимо от Labels.
+	Spread storage.FailureDomain `yaml:"spread"`
+
+	// Peers are addresses of all replicas of the router, Addr included.
+	// If there are several, the replicas replicate the set of nodes and their
+	// heartbeats with Raft: changes are accepted by the leader replica only,
+	// others reply with storage.ErrNotLeader. Any replica finds and lists nodes.
+	// The leader batches heartbeats, see HeartbeatBatch.
+	// Peers -- адреса всех реплик router, включая Addr.
+	// Если их несколько, реплики реплицируют множество node и их heartbeats
+	// с помощью Raft: изменения принимает только реплика-leader, остальные
+	// отвечают storage.ErrNotLeader. Находить и перечислять node может любая реплика.
+	// Leader объединяет heartbeats в пакеты, см. HeartbeatBatch.
+	Peers []storage.ServiceAddr `yaml:"peers"`
+
+	// ElectionTimeout is a time after which replicas elect a new leader
+	// in absence of requests from the current one, raft.DefaultElectionTimeout
+	// if it's not set.
+	// ElectionTimeout -- время, по истечении которого реплики выбирают нового
+	// leader в отсутствие запросов от текущего, raft.DefaultElectionTimeout,
+	// если не задано.
+	ElectionTimeout time.Duration `yaml:"election_timeout"`
+
+	// DataDir is a directory a replica keeps its Raft term, vote and log in,
+	// so that a restarted replica keeps the changes of the set of nodes.
+	// Replicas keep them in memory only if DataDir is not set.
+	// DataDir -- директория, в которой реплика хранит свои срок, голос и журнал
+	// Raft, чтобы перезапущенная реплика сохраняла изменения множества node.
+	// Если DataDir не задана, реплики хранят их только в памяти.
+	DataDir string `yaml:"data_dir"`
+
+	// HeartbeatBatch is a time the leader replica collects heartbeats for
+	// before it proposes them to Raft as a single entry,
+	// DefaultHeartbeatBatch if it's not set.
+	// HeartbeatBatch -- время, в течение которого реплика-leader собирает
+	// heartbeats, прежде чем предложить их Raft одной записью,
+	// DefaultHeartbeatBatch, если не задано.
+	HeartbeatBatch time.Duration `yaml:"heartbeat_batch"`
+
+	// HasherOptions select the Hasher main creates NodesFinder with,
+	// frontends and nodes of the cluster have to select the same one.
+	// HasherOptions выбирают Hasher, с которым main создает NodesFinder,
+	// frontend и node кластера должны выбрать такой же.
+	HasherOptions `yaml:",inline"`
+
+	// Transport specifies a Transport for Raft requests between Peers,
+	// it has to be set if there are several Peers.
+	// Transport -- Transport для запросов Raft между Peers,
+	// должен быть задан, если Peers несколько.
+	Transport raft.Transport `yaml:"-"`
+
 	// NodesFinder specifies a NodesFinder to use.
 	// NodesFinder -- NodesFinder, который нужно использовать в Router.
 	NodesFinder NodesFinder `yaml:"-"`
@@ -32,30 +142,121 @@ type Config struct {
 
 // Router is a router service.
 type Router struct {
-	cfg           Config
+	cfg Config
+	rep storage.Replication
+
+	lock          sync.RWMutex
+	nodes         []storage.ServiceAddr
 	nodesActivity map[storage.ServiceAddr]time.Time
-	activityLock  sync.RWMutex
+	draining      map[storage.ServiceAddr]bool
+	intervals     map[storage.ServiceAddr]*intervals
+	loads         map[storage.ServiceAddr]storage.NodeLoad
+	epoch         uint64
+	// changed is closed and replaced on every change of the topology.
+	changed chan struct{}
+
+	raft *raft.Raft
+	// batchLock guards batch, the heartbeats the leader replica collects
+	// to propose them at once.
+	batchLock sync.Mutex
+	batch     *heartbeatBatch
 }
 
 // New creates a new Router with a given cfg.
-// Returns storage.ErrNotEnoughDaemons error if less then storage.ReplicationFactor
-// nodes was provided in cfg.Nodes.
+// Returns storage.ErrInvalidReplication error if cfg.Quorum is not within
+// [1, cfg.ReplicationFactor], storage.ErrInvalidCapacity error if
+// a weight in cfg.Capacity is not positive, storage.ErrUnknownFailureDomain
+// error if cfg.Spread is unknown and storage.ErrNotEnoughDaemons
+// error if less then cfg.ReplicationFactor nodes was provided in cfg.Nodes.
 //
 // New создает новый Router с данным cfg.
-// Возвращает ошибку storage.ErrNotEnoughDaemons если в cfg.Nodes
-// меньше чем storage.ReplicationFactor nodes.
+// Возвращает ошибку storage.ErrInvalidReplication, если cfg.Quorum не лежит
+// в [1, cfg.ReplicationFactor], ошибку storage.ErrInvalidCapacity, если
+// вес в cfg.Capacity не положителен, ошибку storage.ErrUnknownFailureDomain,
+// если cfg.Spread неизвестен, и ошибку storage.ErrNotEnoughDaemons,
+// если в cfg.Nodes меньше чем cfg.ReplicationFactor nodes.
 func New(cfg Config) (*Router, error) {
-	if len(cfg.Nodes) < storage.ReplicationFactor {
+	rep := storage.Replication{Factor: cfg.ReplicationFactor, Quorum: cfg.Quorum}.Normalize()
+	if err := rep.Validate(); err != nil {
+		return nil, err
+	}
+	placement := storage.Placement{Capacity: cfg.Capacity, Labels: cfg.Labels, Spread: cfg.Spread}
+	if err := placement.Validate(); err != nil {
+		return nil, err
+	}
+	if len(cfg.Nodes) < rep.Factor {
 		return nil, storage.ErrNotEnoughDaemons
 	}
+	cfg.NodesFinder = cfg.NodesFinder.WithReplicationFactor(rep.Factor).WithPlacement(placement)
+	if cfg.PhiWindow <= 0 {
+		cfg.PhiWindow = DefaultPhiWindow
+	}
+	if cfg.PhiMinStdDev <= 0 {
+		cfg.PhiMinStdDev = DefaultPhiMinStdDev
+	}
+	if cfg.HeartbeatBatch <= 0 {
+		cfg.HeartbeatBatch = DefaultHeartbeatBatch
+	}
 	na := make(map[storage.ServiceAddr]time.Time, len(cfg.Nodes))
 	for _, node := range cfg.Nodes {
 		na[node] = time.Time{}
 	}
-	return &Router{
+	r := &Router{
 		cfg:           cfg,
+		rep:           rep,
+		nodes:         append([]storage.ServiceAddr(nil), cfg.Nodes...),
 		nodesActivity: na,
-	}, nil
+		draining:      make(map[storage.ServiceAddr]bool),
+		intervals:     make(map[storage.ServiceAddr]*intervals),
+		loads:         make(map[storage.ServiceAddr]storage.NodeLoad),
+		changed:       make(chan struct{}),
+	}
+	if len(cfg.Peers) > 1 {
+		rf, err := raft.New(raft.Config{
+			ID:              cfg.Addr,
+			Peers:           cfg.Peers,
+			ElectionTimeout: cfg.ElectionTimeout,
+			Dir:             cfg.DataDir,
+			Transport:       cfg.Transport,
+			StateMachine:    stateMachine{r},
+		})
+		if err != nil {
+			return nil, err
+		}
+		r.raft = rf
+		r.raft.Start()
+	}
+	return r, nil
+}
+
+// Stop stops the replica of a replicated Router.
+//
+// Stop останавливает реплику реплицируемого Router.
+func (r *Router) Stop() {
+	if r.raft != nil {
+		r.raft.Stop()
+	}
+}
+
+// Raft returns the Raft member replicating the Router, nil if
+// the Router is not replicated.
+//
+// Raft возвращает член группы Raft, реплицирующий Router, nil,
+// если Router не реплицируется.
+func (r *Router) Raft() *raft.Raft {
+	return r.raft
+}
+
+// Leader returns the address of the leader replica, an empty address if
+// it's unknown. A Router which is not replicated is its own leader.
+//
+// Leader возвращает адрес реплики-leader, пустой адрес, если он неизвестен.
+// Нереплицируемый Router является своим leader.
+func (r *Router) Leader() storage.ServiceAddr {
+	if r.raft == nil {
+		return r.cfg.Addr
+	}
+	return r.raft.Leader()
 }
 
 // Hearbeat registers node in the router.
@@ -65,11 +266,54 @@ func New(cfg Config) (*Router, error) {
 // Возвращает ошибку storage.ErrUnknownDaemon если node не
 // обслуживается Router.
 func (r *Router) Heartbeat(node storage.ServiceAddr) error {
-	r.activityLock.Lock()
-	defer r.activityLock.Unlock()
+	return r.heartbeat(node, nil)
+}
 
-	if _, ok := r.nodesActivity[node]; ok {
-		r.nodesActivity[node] = time.Now()
+// HeartbeatLoad registers node in the router as Heartbeat does and keeps
+// load as the last known load of node.
+// Returns storage.ErrUnknownDaemon error if node is not served by the Router.
+//
+// HeartbeatLoad регистрирует node в router, как и Heartbeat, и сохраняет
+// load как последнюю известную нагрузку node.
+// Возвращает ошибку storage.ErrUnknownDaemon, если node не
+// обслуживается Router.
+func (r *Router) HeartbeatLoad(node storage.ServiceAddr, load storage.NodeLoad) error {
+	return r.heartbeat(node, &load)
+}
+
+func (r *Router) heartbeat(node storage.ServiceAddr, load *storage.NodeLoad) error {
+	r.lock.RLock()
+	_, ok := r.nodesActivity[node]
+	r.lock.RUnlock()
+	if !ok {
+		return storage.ErrUnknownDaemon
+	}
+	hb := heartbeat{Node: node, Time: time.Now().UnixNano(), Load: load}
+	if r.raft == nil {
+		_, err := r.exec(command{Op: opHeartbeat, Node: hb.Node, Time: hb.Time, Load: hb.Load})
+		return err
+	}
+	return r.proposeHeartbeat(hb)
+}
+
+func (r *Router) heartbeatLocked(node storage.ServiceAddr, t time.Time, load *storage.NodeLoad) error {
+	if last, ok := r.nodesActivity[node]; ok {
+		if load != nil {
+			r.loads[node] = *load
+		}
+		revived := !r.aliveLocked(node)
+		// An interval ending a period of unavailability is not a sample
+		// of regular heartbeats.
+		if !revived && t.After(last) {
+			if r.intervals[node] == nil {
+				r.intervals[node] = new(intervals)
+			}
+			r.intervals[node].add(t.Sub(last), r.cfg.PhiWindow)
+		}
+		r.nodesActivity[node] = t
+		if revived && r.aliveLocked(node) {
+			r.notifyLocked()
+		}
 		return nil
 	}
 	return storage.ErrUnknownDaemon
@@ -77,33 +321,259 @@ func (r *Router) Heartbeat(node storage.ServiceAddr) error {
 
 // NodesFind returns a list of available nodes, where record with associated key k
 // should be stored. Returns storage.ErrNotEnoughDaemons error
-// if less then storage.MinRedundancy can be returned.
+// if less then the quorum can be returned.
 //
 // NodesFind возвращает cписок достпуных node, на которых должна храниться
 // запись с ключом k. Возвращает ошибку storage.ErrNotEnoughDaemons
-// если меньше, чем storage.MinRedundancy найдено.
+// если меньше, чем кворум, найдено.
 func (r *Router) NodesFind(k storage.RecordID) ([]storage.ServiceAddr, error) {
-	neededNodes := r.cfg.NodesFinder.NodesFind(k, r.cfg.Nodes)
-
-	availableNodes := make([]storage.ServiceAddr, 0, len(neededNodes))
-	r.activityLock.RLock()
-	defer r.activityLock.RUnlock()
-	for _, node := range neededNodes {
-		if !r.nodesActivity[node].Add(r.cfg.ForgetTimeout).Before(time.Now()) {
-			availableNodes = append(availableNodes, node)
+	return r.NodesFindKey(k.Key())
+}
+
+// NodesFindKey is NodesFind for the record with byte key k.
+//
+// NodesFindKey -- NodesFind для записи с байтовым ключом k.
+func (r *Router) NodesFindKey(k storage.Key) ([]storage.ServiceAddr, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.cfg.NodesFinder.NodesFindAlive(k, r.nodes, r.aliveLocked, r.rep.Quorum)
+}
+
+// NodesFindHinted returns replicas the record with associated key k should be
+// written to. An unavailable node is substituted with the next available node
+// in the order of preference which is not an owner of the record, and the
+// write is hinted for the unavailable node. Returns storage.ErrNotEnoughDaemons
+// error if less then the quorum of replicas can be returned.
+//
+// NodesFindHinted возвращает реплики, в которые нужно записать запись с ключом k.
+// Недоступная node заменяется следующей по предпочтительности доступной node,
+// не являющейся владельцем записи, а запись помечается подсказкой (hint) для
+// недоступной node. Возвращает ошибку storage.ErrNotEnoughDaemons,
+// если меньше, чем кворум реплик, найдено.
+func (r *Router) NodesFindHinted(k storage.RecordID) ([]storage.Replica, error) {
+	return r.NodesFindHintedKey(k.Key())
+}
+
+// NodesFindHintedKey is NodesFindHinted for the record with byte key k.
+//
+// NodesFindHintedKey -- NodesFindHinted для записи с байтовым ключом k.
+func (r *Router) NodesFindHintedKey(k storage.Key) ([]storage.Replica, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.cfg.NodesFinder.NodesFindHinted(k, r.nodes, r.aliveLocked, r.rep.Quorum)
+}
+
+// Alive returns a list of nodes whose heartbeats are received by Router,
+// draining nodes included.
+//
+// Alive возвращает cписок node, heartbeats которых получает Router,
+// включая выводимые из кластера node.
+func (r *Router) Alive() []storage.ServiceAddr {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	var nodes []storage.ServiceAddr
+	for node := range r.nodesActivity {
+		if r.aliveLocked(node) {
+			nodes = append(nodes, node)
 		}
 	}
+	return nodes
+}
+
+// aliveLocked reports whether node is considered to be available: its phi
+// doesn't exceed cfg.PhiThreshold if the failure detector is enabled and
+// knows intervals of node, its heartbeats were received within cfg.ForgetTimeout
+// otherwise. The caller must hold r.lock.
+func (r *Router) aliveLocked(node storage.ServiceAddr) bool {
+	last := r.nodesActivity[node]
+	if iv := r.intervals[node]; r.cfg.PhiThreshold > 0 && iv != nil {
+		return iv.phi(time.Since(last), r.cfg.PhiMinStdDev) <= r.cfg.PhiThreshold
+	}
+	return !last.Add(r.cfg.ForgetTimeout).Before(time.Now())
+}
 
-	if len(availableNodes) < storage.MinRedundancy {
-		return nil, storage.ErrNotEnoughDaemons
+// suspectedAtLocked returns the time node is considered to be unavailable at
+// in absence of heartbeats, the caller must hold r.lock.
+func (r *Router) suspectedAtLocked(node storage.ServiceAddr) time.Time {
+	last := r.nodesActivity[node]
+	if iv := r.intervals[node]; r.cfg.PhiThreshold > 0 && iv != nil {
+		return last.Add(iv.suspectedAfter(r.cfg.PhiThreshold, r.cfg.PhiMinStdDev))
 	}
+	// aliveLocked still holds exactly at the deadline.
+	return last.Add(r.cfg.ForgetTimeout + time.Nanosecond)
+}
+
+// Suspicion returns the state of the nodes served by Router in the failure
+// detector, draining nodes included, ordered by address. Phi is reported
+// even if cfg.PhiThreshold is not set.
+//
+// Suspicion возвращает состояние node, обслуживаемых Router, в failure
+// detector, включая выводимые из кластера node, в порядке адресов. Phi
+// возвращается, даже если cfg.PhiThreshold не задан.
+func (r *Router) Suspicion() []storage.NodeSuspicion {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.suspicionLocked()
+}
 
-	return availableNodes, nil
+// suspicionLocked returns the state of the nodes in the failure detector
+// ordered by address, the caller must hold r.lock.
+func (r *Router) suspicionLocked() []storage.NodeSuspicion {
+	res := make([]storage.NodeSuspicion, 0, len(r.nodesActivity))
+	for node, last := range r.nodesActivity {
+		s := storage.NodeSuspicion{
+			Node:          node,
+			Alive:         r.aliveLocked(node),
+			LastHeartbeat: last,
+		}
+		if iv := r.intervals[node]; iv != nil {
+			s.Phi = iv.phi(time.Since(last), r.cfg.PhiMinStdDev)
+			s.Intervals = len(iv.samples)
+		}
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Node < res[j].Node })
+	return res
 }
 
 // List returns a list of all nodes served by Router.
+// Draining nodes are not listed.
 //
 // List возвращает cписок всех node, обслуживаемых Router.
+// Выводимые из кластера node в список не входят.
 func (r *Router) List() []storage.ServiceAddr {
-	return r.cfg.Nodes
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return append([]storage.ServiceAddr(nil), r.nodes...)
+}
+
+// Replication returns the replication factor and quorum of the cluster.
+//
+// Replication возвращает фактор репликации и кворум кластера.
+func (r *Router) Replication() storage.Replication {
+	return r.rep
+}
+
+// Placement returns the placement setting of the cluster.
+//
+// Placement возвращает настройки размещения записей в кластере.
+func (r *Router) Placement() storage.Placement {
+	return r.cfg.NodesFinder.Placement()
+}
+
+// Epoch returns the topology epoch which is incremented on every
+// change of the set of nodes served by Router.
+//
+// Epoch возвращает номер эпохи топологии, который увеличивается
+// при каждом изменении множества node, обслуживаемых Router.
+func (r *Router) Epoch() uint64 {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.epoch
+}
+
+// AddNode starts serving node. A draining node is put back in service.
+// Returns storage.ErrDaemonExists error if node is already served.
+// The new topology epoch is returned.
+//
+// AddNode начинает обслуживание node. Выводимая из кластера node
+// возвращается в обслуживание. Возвращает ошибку storage.ErrDaemonExists,
+// если node уже обслуживается. Возвращается новый номер эпохи топологии.
+func (r *Router) AddNode(node storage.ServiceAddr) (uint64, error) {
+	return r.exec(command{Op: opAdd, Node: node})
+}
+
+func (r *Router) addNodeLocked(node storage.ServiceAddr) (uint64, error) {
+	if _, ok := r.nodesActivity[node]; ok {
+		if !r.draining[node] {
+			return r.epoch, storage.ErrDaemonExists
+		}
+		delete(r.draining, node)
+	} else {
+		r.nodesActivity[node] = time.Time{}
+	}
+	r.nodes = append(r.nodes, node)
+	r.epoch++
+	r.notifyLocked()
+	return r.epoch, nil
+}
+
+// RemoveNode stops serving node, its heartbeats are not accepted anymore.
+// Returns storage.ErrUnknownDaemon error if node is not served and
+// storage.ErrNotEnoughDaemons error if less than cfg.ReplicationFactor
+// nodes would be left. The new topology epoch is returned.
+//
+// RemoveNode прекращает обслуживание node, ее heartbeats больше не принимаются.
+// Возвращает ошибку storage.ErrUnknownDaemon, если node не обслуживается, и
+// storage.ErrNotEnoughDaemons, если останется меньше чем cfg.ReplicationFactor node.
+// Возвращается новый номер эпохи топологии.
+func (r *Router) RemoveNode(node storage.ServiceAddr) (uint64, error) {
+	return r.exec(command{Op: opRemove, Node: node})
+}
+
+func (r *Router) removeNodeLocked(node storage.ServiceAddr) (uint64, error) {
+	if _, ok := r.nodesActivity[node]; !ok {
+		return r.epoch, storage.ErrUnknownDaemon
+	}
+	if r.draining[node] {
+		delete(r.draining, node)
+		delete(r.nodesActivity, node)
+		delete(r.intervals, node)
+		delete(r.loads, node)
+		r.epoch++
+		r.notifyLocked()
+		return r.epoch, nil
+	}
+	if len(r.nodes) <= r.rep.Factor {
+		return r.epoch, storage.ErrNotEnoughDaemons
+	}
+	r.removeLocked(node)
+	delete(r.nodesActivity, node)
+	delete(r.intervals, node)
+	delete(r.loads, node)
+	r.epoch++
+	r.notifyLocked()
+	return r.epoch, nil
+}
+
+// DrainNode stops placing records on node while still accepting its heartbeats,
+// so that its records can be moved to other nodes before it is removed.
+// Returns storage.ErrUnknownDaemon error if node is not served and
+// storage.ErrNotEnoughDaemons error if less than cfg.ReplicationFactor
+// nodes would be left. The new topology epoch is returned.
+//
+// DrainNode прекращает размещение записей на node, продолжая принимать ее heartbeats,
+// чтобы записи можно было перенести на другие node до ее удаления.
+// Возвращает ошибку storage.ErrUnknownDaemon, если node не обслуживается, и
+// storage.ErrNotEnoughDaemons, если останется меньше чем cfg.ReplicationFactor node.
+// Возвращается новый номер эпохи топологии.
+func (r *Router) DrainNode(node storage.ServiceAddr) (uint64, error) {
+	return r.exec(command{Op: opDrain, Node: node})
+}
+
+func (r *Router) drainNodeLocked(node storage.ServiceAddr) (uint64, error) {
+	if _, ok := r.nodesActivity[node]; !ok {
+		return r.epoch, storage.ErrUnknownDaemon
+	}
+	if r.draining[node] {
+		return r.epoch, nil
+	}
+	if len(r.nodes) <= r.rep.Factor {
+		return r.epoch, storage.ErrNotEnoughDaemons
+	}
+	r.removeLocked(node)
+	r.draining[node] = true
+	r.epoch++
+	r.notifyLocked()
+	return r.epoch, nil
+}
+
+// removeLocked removes node from the placement list, the caller must hold r.lock.
+func (r *Router) removeLocked(node storage.ServiceAddr) {
+	for i, n := range r.nodes {
+		if n == node {
+			r.nodes = append(r.nodes[:i], r.nodes[i+1:]...)
+			return
+		}
+	}
 }
@@ -1,13 +1,17 @@
 package router
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"runtime"
 	"sort"
+	"sync"
 	"testing"
 	"time"
 
+	"router/raft"
 	"storage"
 )
 
@@ -54,6 +58,74 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNew_Replication(t *testing.T) {
+	c := cfg
+	c.Nodes = c.Nodes[:1]
+	c.ReplicationFactor = 1
+	r, err := New(c)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if rep := r.Replication(); rep != (storage.Replication{Factor: 1, Quorum: 1}) {
+		t.Errorf("Replication() = %+v, want factor and quorum 1", rep)
+	}
+	registerNodes(t, r, c.Nodes, 0)
+	if nodes, err := r.NodesFindKey("key"); err != nil || !equalNodes(nodes, c.Nodes) {
+		t.Errorf("NodesFind() got %v, %v, want %v", nodes, err, c.Nodes)
+	}
+
+	c = cfg
+	c.ReplicationFactor = 3
+	c.Quorum = 4
+	if _, err := New(c); err != storage.ErrInvalidReplication {
+		t.Errorf("New expected error %v, got %v", storage.ErrInvalidReplication, err)
+	}
+
+	c = cfg
+	c.Capacity = map[storage.ServiceAddr]float64{"node1": 2, "node2": 0}
+	if _, err := New(c); err != storage.ErrInvalidCapacity {
+		t.Errorf("New expected error %v, got %v", storage.ErrInvalidCapacity, err)
+	}
+
+	c = cfg
+	c.Spread = "row"
+	if _, err := New(c); err != storage.ErrUnknownFailureDomain {
+		t.Errorf("New expected error %v, got %v", storage.ErrUnknownFailureDomain, err)
+	}
+
+	c = cfg
+	c.ReplicationFactor = 5
+	if _, err := New(c); err != storage.ErrNotEnoughDaemons {
+		t.Errorf("New expected error %v, got %v", storage.ErrNotEnoughDaemons, err)
+	}
+}
+
+func TestRouterNodesFind_ReplicationFactor(t *testing.T) {
+	c := cfg
+	c.Nodes = []storage.ServiceAddr{"node1", "node2", "node3", "node4", "node5", "node6"}
+	c.NodesFinder = NewNodesFinder(NewMD5Hasher())
+	c.ReplicationFactor = 5
+	r, err := New(c)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if rep := r.Replication(); rep != (storage.Replication{Factor: 5, Quorum: 3}) {
+		t.Errorf("Replication() = %+v, want factor 5 and quorum 3", rep)
+	}
+
+	registerNodes(t, r, c.Nodes, 0)
+	nodes, err := r.NodesFindKey("key")
+	if err != nil || len(nodes) != 5 {
+		t.Fatalf("NodesFind() got %v, %v, want 5 nodes", nodes, err)
+	}
+
+	// Only 2 of the owners stay alive, less than the quorum.
+	registerNodes(t, r, nodes[:2], c.ForgetTimeout)
+	if _, err := r.NodesFindKey("key"); err != storage.ErrNotEnoughDaemons {
+		t.Errorf("NodesFind() got error %v, want %v", err, storage.ErrNotEnoughDaemons)
+	}
+}
+
 func TestList(t *testing.T) {
 	r, err := New(cfg)
 	if err != nil {
@@ -213,3 +285,498 @@ func TestRouterNodesFind_SameHashes(t *testing.T) {
 		}
 	}
 }
+
+func TestAddRemoveNode(t *testing.T) {
+	cfg := cfg
+	cfg.NodesFinder = NewNodesFinder(FakeHasher{
+		t: t,
+		hashes: map[storage.ServiceAddr]uint64{
+			"node1": 1,
+			"node2": 2,
+			"node3": 3,
+			"node4": 4,
+		}})
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if err := r.Heartbeat("node4"); err != storage.ErrUnknownDaemon {
+		t.Errorf("Heartbeat() got %v, expected error %v", err, storage.ErrUnknownDaemon)
+	}
+	epoch, err := r.AddNode("node4")
+	if err != nil {
+		t.Fatalf("AddNode() error: %v", err)
+	}
+	if epoch != 1 || r.Epoch() != 1 {
+		t.Errorf("Wrong epoch after AddNode(): got %v, %v, want 1", epoch, r.Epoch())
+	}
+	if _, err := r.AddNode("node4"); err != storage.ErrDaemonExists {
+		t.Errorf("AddNode() got %v, expected error %v", err, storage.ErrDaemonExists)
+	}
+
+	want := []storage.ServiceAddr{"node1", "node2", "node3", "node4"}
+	if nodes := r.List(); !equalNodes(nodes, want) {
+		t.Errorf("Wrong list of nodes got %v, want %v", nodes, want)
+	}
+	registerNodes(t, r, want, 0)
+	nodes, err := r.NodesFindKey("key")
+	if err != nil {
+		t.Fatalf("NodesFind() error: %v", err)
+	}
+	if !equalNodes(nodes, want[1:]) {
+		t.Errorf("NodesFind() wrong nodes, got %v, want %v", nodes, want[1:])
+	}
+
+	if _, err := r.RemoveNode("unknown"); err != storage.ErrUnknownDaemon {
+		t.Errorf("RemoveNode() got %v, expected error %v", err, storage.ErrUnknownDaemon)
+	}
+	if epoch, err := r.RemoveNode("node3"); err != nil || epoch != 2 {
+		t.Fatalf("RemoveNode() got epoch %v, error %v, want epoch 2", epoch, err)
+	}
+	if _, err := r.RemoveNode("node4"); err != storage.ErrNotEnoughDaemons {
+		t.Errorf("RemoveNode() got %v, expected error %v", err, storage.ErrNotEnoughDaemons)
+	}
+	if err := r.Heartbeat("node3"); err != storage.ErrUnknownDaemon {
+		t.Errorf("Heartbeat() got %v, expected error %v", err, storage.ErrUnknownDaemon)
+	}
+
+	want = []storage.ServiceAddr{"node1", "node2", "node4"}
+	if nodes := r.List(); !equalNodes(nodes, want) {
+		t.Errorf("Wrong list of nodes got %v, want %v", nodes, want)
+	}
+	nodes, err = r.NodesFindKey("key")
+	if err != nil {
+		t.Fatalf("NodesFind() error: %v", err)
+	}
+	if !equalNodes(nodes, want) {
+		t.Errorf("NodesFind() wrong nodes, got %v, want %v", nodes, want)
+	}
+}
+
+func TestDrainNode(t *testing.T) {
+	cfg := cfg
+	cfg.Nodes = []storage.ServiceAddr{"node1", "node2", "node3", "node4"}
+	cfg.NodesFinder = NewNodesFinder(FakeHasher{
+		t: t,
+		hashes: map[storage.ServiceAddr]uint64{
+			"node1": 1,
+			"node2": 2,
+			"node3": 3,
+			"node4": 4,
+		}})
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	registerNodes(t, r, cfg.Nodes, 0)
+
+	if epoch, err := r.DrainNode("node4"); err != nil || epoch != 1 {
+		t.Fatalf("DrainNode() got epoch %v, error %v, want epoch 1", epoch, err)
+	}
+	if _, err := r.DrainNode("node3"); err != storage.ErrNotEnoughDaemons {
+		t.Errorf("DrainNode() got %v, expected error %v", err, storage.ErrNotEnoughDaemons)
+	}
+	if err := r.Heartbeat("node4"); err != nil {
+		t.Errorf("Heartbeat() of a draining node error: %v", err)
+	}
+
+	want := cfg.Nodes[:3]
+	if nodes := r.List(); !equalNodes(nodes, want) {
+		t.Errorf("Wrong list of nodes got %v, want %v", nodes, want)
+	}
+	nodes, err := r.NodesFindKey("key")
+	if err != nil {
+		t.Fatalf("NodesFind() error: %v", err)
+	}
+	if !equalNodes(nodes, want) {
+		t.Errorf("NodesFind() wrong nodes, got %v, want %v", nodes, want)
+	}
+
+	if epoch, err := r.AddNode("node4"); err != nil || epoch != 2 {
+		t.Fatalf("AddNode() of a draining node got epoch %v, error %v, want epoch 2", epoch, err)
+	}
+	if _, err := r.DrainNode("node4"); err != nil {
+		t.Fatalf("DrainNode() error: %v", err)
+	}
+	if epoch, err := r.RemoveNode("node4"); err != nil || epoch != 4 {
+		t.Fatalf("RemoveNode() of a draining node got epoch %v, error %v, want epoch 4", epoch, err)
+	}
+	if err := r.Heartbeat("node4"); err != storage.ErrUnknownDaemon {
+		t.Errorf("Heartbeat() got %v, expected error %v", err, storage.ErrUnknownDaemon)
+	}
+}
+
+func TestNodesFindHinted(t *testing.T) {
+	cfg := cfg
+	cfg.Nodes = []storage.ServiceAddr{"node1", "node2", "node3", "node4", "node5"}
+	cfg.NodesFinder = NewNodesFinder(FakeHasher{
+		t: t,
+		hashes: map[storage.ServiceAddr]uint64{
+			"node1": 1,
+			"node2": 2,
+			"node3": 3,
+			"node4": 4,
+			"node5": 5,
+		}})
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	registerNodes(t, r, cfg.Nodes, 0)
+	replicas, err := r.NodesFindHintedKey("key")
+	if err != nil {
+		t.Fatalf("NodesFindHinted() error: %v", err)
+	}
+	want := []storage.Replica{{Node: "node5"}, {Node: "node4"}, {Node: "node3"}}
+	if !reflect.DeepEqual(replicas, want) {
+		t.Errorf("NodesFindHinted() got %v, want %v", replicas, want)
+	}
+
+	registerNodes(t, r, []storage.ServiceAddr{"node5", "node2", "node1"}, cfg.ForgetTimeout)
+	replicas, err = r.NodesFindHintedKey("key")
+	if err != nil {
+		t.Fatalf("NodesFindHinted() error: %v", err)
+	}
+	want = []storage.Replica{{Node: "node5"}, {Node: "node2", Hint: "node4"}, {Node: "node1", Hint: "node3"}}
+	if !reflect.DeepEqual(replicas, want) {
+		t.Errorf("NodesFindHinted() got %v, want %v", replicas, want)
+	}
+	if alive, want := r.Alive(), []storage.ServiceAddr{"node1", "node2", "node5"}; !equalNodes(alive, want) {
+		t.Errorf("Alive() got %v, want %v", alive, want)
+	}
+
+	registerNodes(t, r, []storage.ServiceAddr{"node5"}, cfg.ForgetTimeout)
+	if _, err := r.NodesFindHintedKey("key"); err != storage.ErrNotEnoughDaemons {
+		t.Errorf("NodesFindHinted() got error %v, want %v", err, storage.ErrNotEnoughDaemons)
+	}
+}
+
+func TestWatchTopology(t *testing.T) {
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	topologies := make(chan storage.Topology, 100)
+	done := make(chan error)
+	go func() {
+		done <- r.WatchTopology(ctx, func(t storage.Topology) error {
+			topologies <- t
+			return nil
+		})
+	}()
+
+	rep := storage.DefaultReplication
+	nodes := append([]storage.ServiceAddr(nil), cfg.Nodes...)
+	next := func(want storage.Topology) {
+		t.Helper()
+		select {
+		case got := <-topologies:
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("WatchTopology() sent %v, want %v", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("WatchTopology() didn't send %v", want)
+		}
+	}
+
+	next(storage.Topology{Nodes: nodes, Replication: rep})
+	if err := r.Heartbeat("node1"); err != nil {
+		t.Fatalf("Heartbeat() error: %v", err)
+	}
+	alive := []storage.ServiceAddr{"node1"}
+	next(storage.Topology{Nodes: nodes, Alive: alive, Replication: rep})
+	if _, err := r.AddNode("node4"); err != nil {
+		t.Fatalf("AddNode() error: %v", err)
+	}
+	nodes = append(nodes, "node4")
+	next(storage.Topology{Epoch: 1, Nodes: nodes, Alive: alive, Replication: rep})
+
+	// node1 expires in absence of heartbeats, an unchanged topology is resent.
+	next(storage.Topology{Epoch: 1, Nodes: nodes, Replication: rep})
+	next(storage.Topology{Epoch: 1, Nodes: nodes, Replication: rep})
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("WatchTopology() got error %v, want %v", err, context.Canceled)
+	}
+}
+
+// replicas connects replicas of a Router in memory,
+// requests from and to stopped replicas fail.
+type replicas struct {
+	lock    sync.Mutex
+	routers map[storage.ServiceAddr]*Router
+	stopped map[storage.ServiceAddr]bool
+}
+
+func (rs *replicas) get(from, to storage.ServiceAddr) (*raft.Raft, error) {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+	if rs.stopped[from] || rs.stopped[to] {
+		return nil, errors.New("replica stopped")
+	}
+	return rs.routers[to].Raft(), nil
+}
+
+func (rs *replicas) stop(replica storage.ServiceAddr) {
+	rs.lock.Lock()
+	rs.stopped[replica] = true
+	r := rs.routers[replica]
+	rs.lock.Unlock()
+	r.Stop()
+}
+
+// leader waits for a single running replica to become the leader.
+func (rs *replicas) leader(t *testing.T) *Router {
+	for i := 0; i < 100; i++ {
+		rs.lock.Lock()
+		var leaders []*Router
+		for addr, r := range rs.routers {
+			if !rs.stopped[addr] && r.Raft().IsLeader() {
+				leaders = append(leaders, r)
+			}
+		}
+		rs.lock.Unlock()
+		if len(leaders) == 1 {
+			return leaders[0]
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("No single leader elected")
+	return nil
+}
+
+type replicaTransport struct {
+	rs   *replicas
+	from storage.ServiceAddr
+}
+
+func (t replicaTransport) RequestVote(ctx context.Context, peer storage.ServiceAddr, req raft.VoteRequest) (raft.VoteReply, error) {
+	r, err := t.rs.get(t.from, peer)
+	if err != nil {
+		return raft.VoteReply{}, err
+	}
+	return r.RequestVote(req), nil
+}
+
+func (t replicaTransport) AppendEntries(ctx context.Context, peer storage.ServiceAddr, req raft.AppendRequest) (raft.AppendReply, error) {
+	r, err := t.rs.get(t.from, peer)
+	if err != nil {
+		return raft.AppendReply{}, err
+	}
+	return r.AppendEntries(req), nil
+}
+
+func (t replicaTransport) InstallSnapshot(ctx context.Context, peer storage.ServiceAddr, req raft.SnapshotRequest) (raft.SnapshotReply, error) {
+	r, err := t.rs.get(t.from, peer)
+	if err != nil {
+		return raft.SnapshotReply{}, err
+	}
+	return r.InstallSnapshot(req), nil
+}
+
+func TestReplicated(t *testing.T) {
+	peers := []storage.ServiceAddr{"router1", "router2", "router3"}
+	rs := &replicas{
+		routers: make(map[storage.ServiceAddr]*Router),
+		stopped: make(map[storage.ServiceAddr]bool),
+	}
+	rs.lock.Lock()
+	for _, addr := range peers {
+		c := cfg
+		c.Addr = addr
+		c.Peers = peers
+		c.ElectionTimeout = 50 * time.Millisecond
+		c.ForgetTimeout = time.Second
+		c.Transport = replicaTransport{rs: rs, from: addr}
+		r, err := New(c)
+		if err != nil {
+			t.Fatalf("New() error: %v", err)
+		}
+		rs.routers[addr] = r
+	}
+	rs.lock.Unlock()
+	defer func() {
+		for addr := range rs.routers {
+			if !rs.stopped[addr] {
+				rs.routers[addr].Stop()
+			}
+		}
+	}()
+
+	leader := rs.leader(t)
+	for _, r := range rs.routers {
+		if r == leader {
+			continue
+		}
+		if err := r.Heartbeat("node1"); err != storage.ErrNotLeader {
+			t.Errorf("Heartbeat() to a follower got %v, expected error %v", err, storage.ErrNotLeader)
+		}
+		if _, err := r.AddNode("node4"); err != storage.ErrNotLeader {
+			t.Errorf("AddNode() to a follower got %v, expected error %v", err, storage.ErrNotLeader)
+		}
+	}
+
+	registerNodes(t, leader, cfg.Nodes, 0)
+	if epoch, err := leader.AddNode("node4"); err != nil || epoch != 1 {
+		t.Fatalf("AddNode() got epoch %v, error %v, want epoch 1", epoch, err)
+	}
+
+	// Followers serve the replicated state.
+	want := []storage.ServiceAddr{"node1", "node2", "node3", "node4"}
+	alive := []storage.ServiceAddr{"node1", "node2", "node3"}
+	for addr, r := range rs.routers {
+		for i := 0; r.Epoch() != 1 && i < 100; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if nodes := r.List(); !equalNodes(nodes, want) {
+			t.Errorf("List() of %q got %v, want %v", addr, nodes, want)
+		}
+		if nodes := r.Alive(); !equalNodes(nodes, alive) {
+			t.Errorf("Alive() of %q got %v, want %v", addr, nodes, alive)
+		}
+	}
+
+	// The remaining replicas elect a new leader keeping the state.
+	rs.stop(leader.cfg.Addr)
+	next := rs.leader(t)
+	if next == leader {
+		t.Fatalf("Stopped leader is still the leader")
+	}
+	if err := next.Heartbeat("node4"); err != nil {
+		t.Errorf("Heartbeat() error: %v", err)
+	}
+	if epoch, err := next.DrainNode("node1"); err != nil || epoch != 2 {
+		t.Errorf("DrainNode() got epoch %v, error %v, want epoch 2", epoch, err)
+	}
+	want = []storage.ServiceAddr{"node2", "node3", "node4"}
+	if nodes := next.List(); !equalNodes(nodes, want) {
+		t.Errorf("List() got %v, want %v", nodes, want)
+	}
+	want = []storage.ServiceAddr{"node1", "node2", "node3", "node4"}
+	if nodes := next.Alive(); !equalNodes(nodes, want) {
+		t.Errorf("Alive() got %v, want %v", nodes, want)
+	}
+}
+
+// countingTransport is a replicaTransport recording the indexes of entries
+// with commands sent to replicas.
+type countingTransport struct {
+	replicaTransport
+	lock    *sync.Mutex
+	entries map[storage.ServiceAddr]map[uint64]bool
+}
+
+func (t countingTransport) AppendEntries(ctx context.Context, peer storage.ServiceAddr, req raft.AppendRequest) (raft.AppendReply, error) {
+	t.lock.Lock()
+	for _, e := range req.Entries {
+		if len(e.Cmd) != 0 {
+			if t.entries[peer] == nil {
+				t.entries[peer] = make(map[uint64]bool)
+			}
+			t.entries[peer][e.Index] = true
+		}
+	}
+	t.lock.Unlock()
+	return t.replicaTransport.AppendEntries(ctx, peer, req)
+}
+
+func TestReplicated_HeartbeatBatch(t *testing.T) {
+	peers := []storage.ServiceAddr{"router1", "router2", "router3"}
+	var nodes []storage.ServiceAddr
+	for i := 0; i < 50; i++ {
+		nodes = append(nodes, storage.ServiceAddr(fmt.Sprintf("node%d", i)))
+	}
+	rs := &replicas{
+		routers: make(map[storage.ServiceAddr]*Router),
+		stopped: make(map[storage.ServiceAddr]bool),
+	}
+	var lock sync.Mutex
+	entries := make(map[storage.ServiceAddr]map[uint64]bool)
+	rs.lock.Lock()
+	for _, addr := range peers {
+		c := cfg
+		c.Addr = addr
+		c.Nodes = nodes
+		c.Peers = peers
+		c.ElectionTimeout = 50 * time.Millisecond
+		c.ForgetTimeout = time.Second
+		c.HeartbeatBatch = 50 * time.Millisecond
+		c.Transport = countingTransport{
+			replicaTransport: replicaTransport{rs: rs, from: addr},
+			lock:             &lock,
+			entries:          entries,
+		}
+		r, err := New(c)
+		if err != nil {
+			t.Fatalf("New() error: %v", err)
+		}
+		rs.routers[addr] = r
+	}
+	rs.lock.Unlock()
+	defer func() {
+		for addr := range rs.routers {
+			if !rs.stopped[addr] {
+				rs.routers[addr].Stop()
+			}
+		}
+	}()
+
+	// Heartbeats of all nodes make a few entries on a follower.
+	leader := rs.leader(t)
+	var follower storage.ServiceAddr
+	for addr, r := range rs.routers {
+		if r != leader {
+			follower = addr
+		}
+	}
+	lock.Lock()
+	before := len(entries[follower])
+	lock.Unlock()
+	var wg sync.WaitGroup
+	for _, node := range nodes {
+		wg.Add(1)
+		go func(node storage.ServiceAddr) {
+			defer wg.Done()
+			if err := leader.Heartbeat(node); err != nil {
+				t.Errorf("Heartbeat(%q) error: %v", node, err)
+			}
+		}(node)
+	}
+	wg.Wait()
+	if err := leader.Heartbeat("unknown"); err != storage.ErrUnknownDaemon {
+		t.Errorf("Heartbeat() of an unknown node got %v, expected error %v", err, storage.ErrUnknownDaemon)
+	}
+	lock.Lock()
+	n := len(entries[follower]) - before
+	lock.Unlock()
+	if n == 0 || n > len(nodes)/10 {
+		t.Errorf("Heartbeats of %d nodes appended %d entries on a follower", len(nodes), n)
+	}
+	r := rs.routers[follower]
+	for i := 0; len(r.Alive()) != len(nodes) && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if alive := r.Alive(); !equalNodes(alive, nodes) {
+		t.Errorf("Alive() of a follower got %v, want %v", alive, nodes)
+	}
+
+	// Without a leader heartbeats are rejected without waiting for a batch.
+	rs.stop(leader.cfg.Addr)
+	for _, r := range rs.routers {
+		if r == leader || r.Raft().IsLeader() {
+			continue
+		}
+		start := time.Now()
+		if err := r.Heartbeat(nodes[0]); err != storage.ErrNotLeader {
+			t.Errorf("Heartbeat() without a leader got %v, expected error %v", err, storage.ErrNotLeader)
+		}
+		if d := time.Since(start); d >= 50*time.Millisecond {
+			t.Errorf("Heartbeat() without a leader took %v", d)
+		}
+	}
+}
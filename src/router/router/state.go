@@ -0,0 +1,253 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"storage"
+)
+
+// DefaultHeartbeatBatch is a time heartbeats are collected for if
+// Config.HeartbeatBatch is not set.
+//
+// DefaultHeartbeatBatch -- время, в течение которого собираются heartbeats,
+// если Config.HeartbeatBatch не задано.
+const DefaultHeartbeatBatch = 20 * time.Millisecond
+
+// Operations changing the state of a Router.
+const (
+	opHeartbeat  = "heartbeat"
+	opHeartbeats = "heartbeats"
+	opAdd        = "add"
+	opRemove     = "remove"
+	opDrain      = "drain"
+)
+
+// command is a change of the state of a Router. Replicas of a Router apply
+// the same commands in the same order, so heartbeats carry the time
+// they were received at.
+type command struct {
+	Op   string              `json:"op"`
+	Node storage.ServiceAddr `json:"node"`
+	Time int64               `json:"time,omitempty"`
+	// Load is the load reported in a heartbeat, if any.
+	Load *storage.NodeLoad `json:"load,omitempty"`
+	// Heartbeats are the heartbeats of an opHeartbeats command.
+	Heartbeats []heartbeat `json:"heartbeats,omitempty"`
+}
+
+// heartbeat is a heartbeat of node received at Time in nanoseconds.
+type heartbeat struct {
+	Node storage.ServiceAddr `json:"node"`
+	Time int64               `json:"time"`
+	Load *storage.NodeLoad   `json:"load,omitempty"`
+}
+
+type applyResult struct {
+	epoch uint64
+	err   error
+	// errs are the results of the heartbeats of an opHeartbeats command.
+	errs []error
+}
+
+// exec applies cmd to the state of the Router, through the Raft log
+// if the Router is replicated. Returns the topology epoch after cmd.
+func (r *Router) exec(cmd command) (uint64, error) {
+	res := r.execResult(cmd)
+	return res.epoch, res.err
+}
+
+// execResult is exec returning the whole result of cmd.
+func (r *Router) execResult(cmd command) applyResult {
+	if r.raft == nil {
+		return r.apply(cmd)
+	}
+	b, err := json.Marshal(cmd)
+	if err != nil {
+		return applyResult{epoch: r.Epoch(), err: err}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), storage.Timeout)
+	defer cancel()
+	v, err := r.raft.Propose(ctx, b)
+	if err != nil {
+		return applyResult{epoch: r.Epoch(), err: err}
+	}
+	return v.(applyResult)
+}
+
+// heartbeatBatch is a batch of heartbeats proposed as a single command.
+// done is closed once the command is applied, res is its result then.
+type heartbeatBatch struct {
+	heartbeats []heartbeat
+	done       chan struct{}
+	res        applyResult
+}
+
+// proposeHeartbeat replicates hb through the Raft log. Every heartbeat
+// proposed separately would be an entry every follower appends and the
+// leader waits a quorum for, so that the load on replicas grew with
+// the number of nodes. The leader collects heartbeats for
+// cfg.HeartbeatBatch instead and proposes them as a single entry,
+// followers and a replica which lost the leadership reject them at once.
+func (r *Router) proposeHeartbeat(hb heartbeat) error {
+	if !r.raft.IsLeader() {
+		return storage.ErrNotLeader
+	}
+
+	r.batchLock.Lock()
+	b := r.batch
+	if b == nil {
+		b = &heartbeatBatch{done: make(chan struct{})}
+		r.batch = b
+		time.AfterFunc(r.cfg.HeartbeatBatch, r.flushHeartbeats)
+	}
+	i := len(b.heartbeats)
+	b.heartbeats = append(b.heartbeats, hb)
+	r.batchLock.Unlock()
+
+	<-b.done
+	if b.res.err != nil {
+		return b.res.err
+	}
+	return b.res.errs[i]
+}
+
+// flushHeartbeats proposes the collected batch of heartbeats.
+func (r *Router) flushHeartbeats() {
+	r.batchLock.Lock()
+	b := r.batch
+	r.batch = nil
+	r.batchLock.Unlock()
+
+	b.res = r.execResult(command{Op: opHeartbeats, Heartbeats: b.heartbeats})
+	if b.res.err == nil && len(b.res.errs) != len(b.heartbeats) {
+		b.res.err = storage.ErrUnknownStatus
+	}
+	close(b.done)
+}
+
+func (r *Router) apply(cmd command) applyResult {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var res applyResult
+	switch cmd.Op {
+	case opHeartbeat:
+		res.err = r.heartbeatLocked(cmd.Node, time.Unix(0, cmd.Time), cmd.Load)
+		res.epoch = r.epoch
+	case opHeartbeats:
+		res.errs = make([]error, len(cmd.Heartbeats))
+		for i, hb := range cmd.Heartbeats {
+			res.errs[i] = r.heartbeatLocked(hb.Node, time.Unix(0, hb.Time), hb.Load)
+		}
+		res.epoch = r.epoch
+	case opAdd:
+		res.epoch, res.err = r.addNodeLocked(cmd.Node)
+	case opRemove:
+		res.epoch, res.err = r.removeNodeLocked(cmd.Node)
+	case opDrain:
+		res.epoch, res.err = r.drainNodeLocked(cmd.Node)
+	default:
+		res.epoch, res.err = r.epoch, storage.ErrUnknownStatus
+	}
+	return res
+}
+
+// snapshot is the state of a Router compacting its Raft log.
+// Activity holds the times of the last heartbeats in nanoseconds,
+// zero for nodes which haven't sent any. Intervals holds the heartbeat
+// intervals of the failure detector from the oldest to the latest
+// and Loads the loads reported in the last heartbeats.
+type snapshot struct {
+	Nodes     []storage.ServiceAddr                    `json:"nodes"`
+	Activity  map[storage.ServiceAddr]int64            `json:"activity"`
+	Draining  []storage.ServiceAddr                    `json:"draining"`
+	Intervals map[storage.ServiceAddr][]time.Duration  `json:"intervals,omitempty"`
+	Loads     map[storage.ServiceAddr]storage.NodeLoad `json:"loads,omitempty"`
+	Epoch     uint64                                   `json:"epoch"`
+}
+
+// stateMachine is the raft.StateMachine of a replicated Router.
+type stateMachine struct {
+	r *Router
+}
+
+func (m stateMachine) Apply(cmd []byte) interface{} {
+	var c command
+	if err := json.Unmarshal(cmd, &c); err != nil {
+		return applyResult{epoch: m.r.Epoch(), err: err}
+	}
+	return m.r.apply(c)
+}
+
+func (m stateMachine) Snapshot() ([]byte, error) {
+	r := m.r
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	s := snapshot{
+		Nodes:    r.nodes,
+		Activity: make(map[storage.ServiceAddr]int64, len(r.nodesActivity)),
+		Epoch:    r.epoch,
+	}
+	for node, t := range r.nodesActivity {
+		var ns int64
+		if !t.IsZero() {
+			ns = t.UnixNano()
+		}
+		s.Activity[node] = ns
+	}
+	for node := range r.draining {
+		s.Draining = append(s.Draining, node)
+	}
+	if len(r.intervals) != 0 {
+		s.Intervals = make(map[storage.ServiceAddr][]time.Duration, len(r.intervals))
+		for node, iv := range r.intervals {
+			s.Intervals[node] = iv.ordered()
+		}
+	}
+	if len(r.loads) != 0 {
+		s.Loads = r.loads
+	}
+	return json.Marshal(s)
+}
+
+func (m stateMachine) Restore(b []byte) error {
+	var s snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	r := m.r
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.nodes = s.Nodes
+	r.nodesActivity = make(map[storage.ServiceAddr]time.Time, len(s.Activity))
+	for node, ns := range s.Activity {
+		var t time.Time
+		if ns != 0 {
+			t = time.Unix(0, ns)
+		}
+		r.nodesActivity[node] = t
+	}
+	r.draining = make(map[storage.ServiceAddr]bool, len(s.Draining))
+	for _, node := range s.Draining {
+		r.draining[node] = true
+	}
+	r.intervals = make(map[storage.ServiceAddr]*intervals, len(s.Intervals))
+	for node, samples := range s.Intervals {
+		iv := new(intervals)
+		for _, d := range samples {
+			iv.add(d, r.cfg.PhiWindow)
+		}
+		r.intervals[node] = iv
+	}
+	r.loads = make(map[storage.ServiceAddr]storage.NodeLoad, len(s.Loads))
+	for node, load := range s.Loads {
+		r.loads[node] = load
+	}
+	r.epoch = s.Epoch
+	r.notifyLocked()
+	return nil
+}
@@ -0,0 +1,36 @@
+package router
+
+import (
+	"storage"
+)
+
+// ClusterStatus returns the state of the cluster served by the Router:
+// the topology epoch, the replication setting, the leader of the Router
+// replicas and the state of the nodes ordered by address along with
+// the loads reported in their last heartbeats.
+//
+// ClusterStatus возвращает состояние кластера, обслуживаемого Router:
+// номер эпохи топологии, настройки репликации, leader реплик Router
+// и состояние node в порядке адресов вместе с нагрузкой, переданной
+// в их последних heartbeats.
+func (r *Router) ClusterStatus() storage.ClusterStatus {
+	leader := r.Leader()
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	suspicion := r.suspicionLocked()
+	status := storage.ClusterStatus{
+		Epoch:       r.epoch,
+		Replication: r.rep,
+		Leader:      leader,
+		Nodes:       make([]storage.NodeStatus, 0, len(suspicion)),
+	}
+	for _, s := range suspicion {
+		status.Nodes = append(status.Nodes, storage.NodeStatus{
+			NodeSuspicion: s,
+			Draining:      r.draining[s.Node],
+			Load:          r.loads[s.Node],
+		})
+	}
+	return status
+}
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"encoding/json"
+	"testing"
+
+	"storage"
+)
+
+func TestClusterStatus(t *testing.T) {
+	c := cfg
+	c.Nodes = []storage.ServiceAddr{"node1", "node2", "node3", "node4"}
+	r, err := New(c)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	load := storage.NodeLoad{Records: 10, Bytes: 100, Memory: 1 << 20, ReadRate: 2.5, WriteRate: 1}
+	if err := r.HeartbeatLoad("unknown", load); err != storage.ErrUnknownDaemon {
+		t.Errorf("HeartbeatLoad() got %v, expected error %v", err, storage.ErrUnknownDaemon)
+	}
+	if err := r.HeartbeatLoad("node1", load); err != nil {
+		t.Fatalf("HeartbeatLoad() error: %v", err)
+	}
+	// Heartbeats without a load keep the last reported one.
+	if err := r.Heartbeat("node1"); err != nil {
+		t.Fatalf("Heartbeat() error: %v", err)
+	}
+	if err := r.Heartbeat("node2"); err != nil {
+		t.Fatalf("Heartbeat() error: %v", err)
+	}
+	if _, err := r.DrainNode("node3"); err != nil {
+		t.Fatalf("DrainNode() error: %v", err)
+	}
+
+	s := r.ClusterStatus()
+	if s.Epoch != 1 || s.Replication != r.Replication() || s.Leader != c.Addr {
+		t.Errorf("ClusterStatus() got epoch %v, replication %+v, leader %q, want 1, %+v, %q",
+			s.Epoch, s.Replication, s.Leader, r.Replication(), c.Addr)
+	}
+	if len(s.Nodes) != 4 {
+		t.Fatalf("ClusterStatus() got %+v, want 4 nodes", s.Nodes)
+	}
+	if n := s.Nodes[0]; n.Node != "node1" || !n.Alive || n.Draining || n.Load != load {
+		t.Errorf("ClusterStatus() got %+v for a node reporting its load", n)
+	}
+	if n := s.Nodes[1]; n.Node != "node2" || !n.Alive || n.Load != (storage.NodeLoad{}) {
+		t.Errorf("ClusterStatus() got %+v for a node not reporting its load", n)
+	}
+	if n := s.Nodes[2]; n.Node != "node3" || n.Alive || !n.Draining {
+		t.Errorf("ClusterStatus() got %+v for a draining node", n)
+	}
+
+	// Loads are replicated with heartbeats and kept in snapshots.
+	restored, err := New(c)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	load.EngineError = "disk is full"
+	b, err := json.Marshal(command{Op: opHeartbeat, Node: "node4", Time: 1, Load: &load})
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	if res := (stateMachine{r}).Apply(b).(applyResult); res.err != nil {
+		t.Fatalf("Apply() error: %v", res.err)
+	}
+	b, err = stateMachine{r}.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() error: %v", err)
+	}
+	if err := (stateMachine{restored}).Restore(b); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	if got := restored.ClusterStatus().Nodes; got[0].Load != s.Nodes[0].Load || got[3].Load != load {
+		t.Errorf("Restored loads %+v and %+v, want %+v and %+v", got[0].Load, got[3].Load, s.Nodes[0].Load, load)
+	}
+
+	if err := r.HeartbeatLoad("node3", load); err != nil {
+		t.Fatalf("HeartbeatLoad() error: %v", err)
+	}
+	if _, err := r.RemoveNode("node3"); err != nil {
+		t.Fatalf("RemoveNode() error: %v", err)
+	}
+	if _, ok := r.loads["node3"]; ok {
+		t.Errorf("Load of a removed node is kept")
+	}
+}
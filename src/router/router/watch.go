@@ -0,0 +1,108 @@
+package router
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"time"
+
+	"storage"
+)
+
+// Topology returns the nodes served by the Router along with their liveness,
+// the topology epoch and the replication and placement settings of the cluster.
+//
+// Topology возвращает node, обслуживаемые Router, вместе с их доступностью,
+// номером эпохи топологии и настройками репликации и размещения кластера.
+func (r *Router) Topology() storage.Topology {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.topologyLocked()
+}
+
+// topologyLocked returns the topology with alive nodes sorted,
+// the caller must hold r.lock.
+func (r *Router) topologyLocked() storage.Topology {
+	t := storage.Topology{
+		Epoch:       r.epoch,
+		Nodes:       append([]storage.ServiceAddr(nil), r.nodes...),
+		Replication: r.rep,
+		Placement:   r.cfg.NodesFinder.Placement(),
+	}
+	for node := range r.nodesActivity {
+		if r.aliveLocked(node) {
+			t.Alive = append(t.Alive, node)
+		}
+	}
+	sort.Slice(t.Alive, func(i, j int) bool { return t.Alive[i] < t.Alive[j] })
+	return t
+}
+
+// notifyLocked wakes up watchers of the topology, the caller must hold r.lock.
+func (r *Router) notifyLocked() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// expiryLocked returns the time the first of the alive nodes is considered
+// to be unavailable at in absence of heartbeats, the zero time if no nodes
+// are alive. The caller must hold r.lock.
+func (r *Router) expiryLocked() time.Time {
+	var expiry time.Time
+	for node := range r.nodesActivity {
+		if !r.aliveLocked(node) {
+			continue
+		}
+		if t := r.suspectedAtLocked(node); expiry.IsZero() || t.Before(expiry) {
+			expiry = t
+		}
+	}
+	return expiry
+}
+
+// WatchTopology calls send with the topology of the Router and then every time
+// nodes are added, removed or drained, become available or unavailable.
+// The topology is also sent every cfg.ForgetTimeout while it doesn't change
+// so that watchers can tell an unchanged topology from a lost Router.
+// Returns when ctx is done or send fails.
+//
+// WatchTopology вызывает send с топологией Router, а затем каждый раз, когда
+// node добавляются, удаляются или выводятся из кластера, становятся доступны
+// или недоступны. Пока топология не меняется, она также отправляется каждые
+// cfg.ForgetTimeout, чтобы наблюдатели могли отличить неизменную топологию
+// от потерянного Router. Возвращается, когда ctx завершен или send неуспешен.
+func (r *Router) WatchTopology(ctx context.Context, send func(storage.Topology) error) error {
+	var (
+		last storage.Topology
+		sent time.Time
+	)
+	for {
+		r.lock.RLock()
+		changed := r.changed
+		t := r.topologyLocked()
+		expiry := r.expiryLocked()
+		r.lock.RUnlock()
+
+		now := time.Now()
+		if sent.IsZero() || !reflect.DeepEqual(t, last) || !now.Before(sent.Add(r.cfg.ForgetTimeout)) {
+			if err := send(t); err != nil {
+				return err
+			}
+			last, sent = t, now
+		}
+
+		wait := sent.Add(r.cfg.ForgetTimeout).Sub(now)
+		if !expiry.IsZero() && expiry.Sub(now) < wait {
+			wait = expiry.Sub(now)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-changed:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"router/pb"
+	"router/raft"
+	"storage"
+)
+
+var errNotReplicated = errors.New("Router is not replicated")
+
+func (s *Server) RequestVote(ctx context.Context, req *pb.VoteRequest) (*pb.VoteReply, error) {
+	r := s.rtr.Raft()
+	if r == nil {
+		return nil, errNotReplicated
+	}
+	reply := r.RequestVote(raft.VoteRequest{
+		Term:         req.Term,
+		Candidate:    storage.ServiceAddr(req.Candidate),
+		LastLogIndex: req.LastLogIndex,
+		LastLogTerm:  req.LastLogTerm,
+	})
+	return &pb.VoteReply{Term: reply.Term, Granted: reply.Granted}, nil
+}
+
+func (s *Server) AppendEntries(ctx context.Context, req *pb.AppendRequest) (*pb.AppendReply, error) {
+	r := s.rtr.Raft()
+	if r == nil {
+		return nil, errNotReplicated
+	}
+	entries := make([]raft.Entry, 0, len(req.Entries))
+	for _, e := range req.Entries {
+		entries = append(entries, raft.Entry{Index: e.Index, Term: e.Term, Cmd: e.Cmd})
+	}
+	reply := r.AppendEntries(raft.AppendRequest{
+		Term:         req.Term,
+		Leader:       storage.ServiceAddr(req.Leader),
+		PrevLogIndex: req.PrevLogIndex,
+		PrevLogTerm:  req.PrevLogTerm,
+		Entries:      entries,
+		LeaderCommit: req.LeaderCommit,
+	})
+	return &pb.AppendReply{Term: reply.Term, Success: reply.Success, LastIndex: reply.LastIndex}, nil
+}
+
+func (s *Server) InstallSnapshot(ctx context.Context, req *pb.SnapshotRequest) (*pb.SnapshotReply, error) {
+	r := s.rtr.Raft()
+	if r == nil {
+		return nil, errNotReplicated
+	}
+	reply := r.InstallSnapshot(raft.SnapshotRequest{
+		Term:      req.Term,
+		Leader:    storage.ServiceAddr(req.Leader),
+		LastIndex: req.LastIndex,
+		LastTerm:  req.LastTerm,
+		Data:      req.Data,
+	})
+	return &pb.SnapshotReply{Term: reply.Term}, nil
+}
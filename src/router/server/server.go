@@ -46,7 +46,12 @@ func (s *Server) Heartbeat(ctx context.Context, req *pb.HBRequest) (*pb.HBReply,
 	node := storage.ServiceAddr(req.Node)
 	log.Printf("Hearbeat request: node = %q", node)
 
-	err := s.rtr.Heartbeat(node)
+	var err error
+	if req.Load != nil {
+		err = s.rtr.HeartbeatLoad(node, loadFromPB(req.Load))
+	} else {
+		err = s.rtr.Heartbeat(node)
+	}
 	status := storage.ErrToStatus(err)
 
 	reply := pb.HBReply{
@@ -55,18 +60,22 @@ func (s *Server) Heartbeat(ctx context.Context, req *pb.HBRequest) (*pb.HBReply,
 	if status == storage.StatusUnknown {
 		reply.Error = err.Error()
 	}
+	if err == storage.ErrNotLeader {
+		reply.Leader = string(s.rtr.Leader())
+	}
 	return &reply, nil
 }
 
 func (s *Server) NodesFind(ctx context.Context, req *pb.NFRequest) (*pb.NFReply, error) {
-	key := storage.RecordID(req.Key)
-	log.Printf("NodesFind request: key = %v", key)
+	key := nfKey(req)
+	log.Printf("NodesFind request: key = %s", storage.FormatKey(key))
 
-	nodes, err := s.rtr.NodesFind(key)
+	nodes, err := s.rtr.NodesFindKey(key)
 	status := storage.ErrToStatus(err)
 
 	reply := pb.NFReply{
 		Status: int32(status),
+		Epoch:  s.rtr.Epoch(),
 	}
 	if status == storage.StatusUnknown {
 		reply.Error = err.Error()
@@ -84,8 +93,95 @@ func (s *Server) List(ctx context.Context, req *pb.Empty) (*pb.ListReply, error)
 	log.Printf("List request")
 
 	nodes := s.rtr.List()
+	rep := s.rtr.Replication()
+	reply := pb.ListReply{
+		Status:            int32(storage.StatusOk),
+		Epoch:             s.rtr.Epoch(),
+		ReplicationFactor: uint32(rep.Factor),
+		Quorum:            uint32(rep.Quorum),
+	}
+	reply.Capacity, reply.Labels, reply.Spread = placementToPB(s.rtr.Placement())
+	reply.Nodes = make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		reply.Nodes = append(reply.Nodes, string(node))
+	}
+	return &reply, nil
+}
+
+func (s *Server) AddNode(ctx context.Context, req *pb.NodeRequest) (*pb.NodeReply, error) {
+	node := storage.ServiceAddr(req.Node)
+	log.Printf("AddNode request: node = %q", node)
+	return s.nodeReply(s.rtr.AddNode(node)), nil
+}
+
+func (s *Server) RemoveNode(ctx context.Context, req *pb.NodeRequest) (*pb.NodeReply, error) {
+	node := storage.ServiceAddr(req.Node)
+	log.Printf("RemoveNode request: node = %q", node)
+	return s.nodeReply(s.rtr.RemoveNode(node)), nil
+}
+
+func (s *Server) DrainNode(ctx context.Context, req *pb.NodeRequest) (*pb.NodeReply, error) {
+	node := storage.ServiceAddr(req.Node)
+	log.Printf("DrainNode request: node = %q", node)
+	return s.nodeReply(s.rtr.DrainNode(node)), nil
+}
+
+// nfKey returns the key nodes are requested for: the byte key if it's set,
+// the key of the RecordID otherwise.
+func nfKey(req *pb.NFRequest) storage.Key {
+	if len(req.KeyBytes) != 0 {
+		return storage.Key(req.KeyBytes)
+	}
+	return storage.RecordID(req.Key).Key()
+}
+
+func (s *Server) nodeReply(epoch uint64, err error) *pb.NodeReply {
+	status := storage.ErrToStatus(err)
+	reply := pb.NodeReply{
+		Status: int32(status),
+		Epoch:  epoch,
+	}
+	if status == storage.StatusUnknown {
+		reply.Error = err.Error()
+	}
+	if err == storage.ErrNotLeader {
+		reply.Leader = string(s.rtr.Leader())
+	}
+	return &reply
+}
+
+func (s *Server) NodesFindHinted(ctx context.Context, req *pb.NFRequest) (*pb.NFReply, error) {
+	key := nfKey(req)
+	log.Printf("NodesFindHinted request: key = %s", storage.FormatKey(key))
+
+	replicas, err := s.rtr.NodesFindHintedKey(key)
+	status := storage.ErrToStatus(err)
+
+	reply := pb.NFReply{
+		Status: int32(status),
+		Epoch:  s.rtr.Epoch(),
+	}
+	if status == storage.StatusUnknown {
+		reply.Error = err.Error()
+		return &reply, nil
+	}
+
+	reply.Nodes = make([]string, 0, len(replicas))
+	reply.Hints = make([]string, 0, len(replicas))
+	for _, r := range replicas {
+		reply.Nodes = append(reply.Nodes, string(r.Node))
+		reply.Hints = append(reply.Hints, string(r.Hint))
+	}
+	return &reply, nil
+}
+
+func (s *Server) Alive(ctx context.Context, req *pb.Empty) (*pb.ListReply, error) {
+	log.Printf("Alive request")
+
+	nodes := s.rtr.Alive()
 	reply := pb.ListReply{
 		Status: int32(storage.StatusOk),
+		Epoch:  s.rtr.Epoch(),
 	}
 	reply.Nodes = make([]string, 0, len(nodes))
 	for _, node := range nodes {
@@ -93,3 +189,110 @@ func (s *Server) List(ctx context.Context, req *pb.Empty) (*pb.ListReply, error)
 	}
 	return &reply, nil
 }
+
+func (s *Server) Suspicion(ctx context.Context, req *pb.Empty) (*pb.SuspicionReply, error) {
+	log.Printf("Suspicion request")
+
+	reply := pb.SuspicionReply{
+		Status: int32(storage.StatusOk),
+	}
+	for _, n := range s.rtr.Suspicion() {
+		reply.Nodes = append(reply.Nodes, suspicionToPB(n))
+	}
+	return &reply, nil
+}
+
+func suspicionToPB(n storage.NodeSuspicion) *pb.NodeSuspicion {
+	var last int64
+	if !n.LastHeartbeat.IsZero() {
+		last = n.LastHeartbeat.UnixNano()
+	}
+	return &pb.NodeSuspicion{
+		Node:          string(n.Node),
+		Phi:           n.Phi,
+		Alive:         n.Alive,
+		Intervals:     uint32(n.Intervals),
+		LastHeartbeat: last,
+	}
+}
+
+func (s *Server) ClusterStatus(ctx context.Context, req *pb.Empty) (*pb.ClusterStatusReply, error) {
+	log.Printf("ClusterStatus request")
+
+	cs := s.rtr.ClusterStatus()
+	reply := pb.ClusterStatusReply{
+		Status:            int32(storage.StatusOk),
+		Epoch:             cs.Epoch,
+		ReplicationFactor: uint32(cs.Replication.Factor),
+		Quorum:            uint32(cs.Replication.Quorum),
+		Leader:            string(cs.Leader),
+	}
+	for _, n := range cs.Nodes {
+		reply.Nodes = append(reply.Nodes, &pb.NodeStatus{
+			Suspicion: suspicionToPB(n.NodeSuspicion),
+			Draining:  n.Draining,
+			Load: &pb.NodeLoad{
+				Records:     n.Load.Records,
+				Bytes:       n.Load.Bytes,
+				Memory:      n.Load.Memory,
+				ReadRate:    n.Load.ReadRate,
+				WriteRate:   n.Load.WriteRate,
+				EngineError: n.Load.EngineError,
+			},
+		})
+	}
+	return &reply, nil
+}
+
+func loadFromPB(l *pb.NodeLoad) storage.NodeLoad {
+	return storage.NodeLoad{
+		Records:     l.Records,
+		Bytes:       l.Bytes,
+		Memory:      l.Memory,
+		ReadRate:    l.ReadRate,
+		WriteRate:   l.WriteRate,
+		EngineError: l.EngineError,
+	}
+}
+
+func placementToPB(p storage.Placement) (map[string]float64, map[string]*pb.NodeLabels, string) {
+	var (
+		capacity map[string]float64
+		labels   map[string]*pb.NodeLabels
+	)
+	if len(p.Capacity) != 0 {
+		capacity = make(map[string]float64, len(p.Capacity))
+		for node, w := range p.Capacity {
+			capacity[string(node)] = w
+		}
+	}
+	if len(p.Labels) != 0 {
+		labels = make(map[string]*pb.NodeLabels, len(p.Labels))
+		for node, l := range p.Labels {
+			labels[string(node)] = &pb.NodeLabels{Zone: l.Zone, Rack: l.Rack}
+		}
+	}
+	return capacity, labels, string(p.Spread)
+}
+
+func (s *Server) WatchTopology(req *pb.Empty, stream pb.Router_WatchTopologyServer) error {
+	log.Printf("WatchTopology request")
+
+	return s.rtr.WatchTopology(stream.Context(), func(t storage.Topology) error {
+		reply := pb.TopologyReply{
+			Epoch:             t.Epoch,
+			ReplicationFactor: uint32(t.Replication.Factor),
+			Quorum:            uint32(t.Replication.Quorum),
+		}
+		reply.Capacity, reply.Labels, reply.Spread = placementToPB(t.Placement)
+		reply.Nodes = make([]string, 0, len(t.Nodes))
+		for _, node := range t.Nodes {
+			reply.Nodes = append(reply.Nodes, string(node))
+		}
+		reply.Alive = make([]string, 0, len(t.Alive))
+		for _, node := range t.Alive {
+			reply.Alive = append(reply.Alive, string(node))
+		}
+		return stream.Send(&reply)
+	})
+}
@@ -0,0 +1,66 @@
+package storage
+
+import "context"
+
+// Result is the result of a batch operation for a single record.
+// Data is set by reads only.
+type Result struct {
+	Data []byte
+	Err  error
+}
+
+// BatchStorage is a Storage which serves batches of operations itself.
+// Results are returned in the order of keys or records, the error is
+// returned if the batch failed as a whole. Server serves batches
+// for other storages record by record.
+type BatchStorage interface {
+	MultiGetContext(ctx context.Context, keys []Key) ([]Result, error)
+	MultiPutContext(ctx context.Context, records []Record) ([]Result, error)
+	MultiDelContext(ctx context.Context, keys []Key) ([]Result, error)
+}
+
+// BatchClient is a Client which is able to send a batch of operations
+// to a node in a single request.
+type BatchClient interface {
+	MultiGetContext(ctx context.Context, node ServiceAddr, keys []Key) ([]Result, error)
+	MultiPutContext(ctx context.Context, node ServiceAddr, records []Record) ([]Result, error)
+	MultiDelContext(ctx context.Context, node ServiceAddr, keys []Key) ([]Result, error)
+}
+
+// batchStorage serves batches for a storage which doesn't implement BatchStorage.
+type batchStorage struct {
+	st KeyStorage
+}
+
+func (b batchStorage) MultiGetContext(ctx context.Context, keys []Key) ([]Result, error) {
+	res := make([]Result, len(keys))
+	for i, k := range keys {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		res[i].Data, res[i].Err = b.st.GetKeyContext(ctx, k)
+	}
+	return res, nil
+}
+
+func (b batchStorage) MultiPutContext(ctx context.Context, records []Record) ([]Result, error) {
+	res := make([]Result, len(records))
+	for i, r := range records {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		res[i].Err = b.st.PutKeyContext(ctx, r.Key, r.Data)
+	}
+	return res, nil
+}
+
+func (b batchStorage) MultiDelContext(ctx context.Context, keys []Key) ([]Result, error) {
+	res := make([]Result, len(keys))
+	for i, k := range keys {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		res[i].Err = b.st.DelKeyContext(ctx, k)
+	}
+	return res, nil
+}
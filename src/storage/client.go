@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"time"
 
 	"google.golang.org/grpc"
 
@@ -17,34 +19,132 @@ type Client interface {
 	Del(node ServiceAddr, k RecordID) error
 }
 
-type StorageClient struct{}
+// ContextClient is a Client whose requests are bound to a context:
+// the request is cancelled with ctx and doesn't outlive its deadline.
+type ContextClient interface {
+	PutContext(ctx context.Context, node ServiceAddr, k RecordID, d []byte) error
+	GetContext(ctx context.Context, node ServiceAddr, k RecordID) ([]byte, error)
+	DelContext(ctx context.Context, node ServiceAddr, k RecordID) error
+}
+
+// WithContext returns c as a ContextClient. If c doesn't implement ContextClient,
+// ctx is only checked before a request is sent.
+func WithContext(c Client) ContextClient {
+	if cc, ok := c.(ContextClient); ok {
+		return cc
+	}
+	return contextClient{c}
+}
+
+type contextClient struct {
+	c Client
+}
+
+func (c contextClient) PutContext(ctx context.Context, node ServiceAddr, k RecordID, d []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.c.Put(node, k, d)
+}
 
-var defaultClient Client = StorageClient{}
+func (c contextClient) GetContext(ctx context.Context, node ServiceAddr, k RecordID) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.c.Get(node, k)
+}
+
+func (c contextClient) DelContext(ctx context.Context, node ServiceAddr, k RecordID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.c.Del(node, k)
+}
 
+// StorageClient is a Client keeping connections to nodes in a ConnPool.
+// The zero StorageClient dials a new connection for every request.
+type StorageClient struct {
+	pool *ConnPool
+}
+
+// NewClient creates a StorageClient with its own connection pool.
+// Close should be called to release the pooled connections.
 func NewClient() Client {
-	return defaultClient
+	return NewPooledClient(DefaultIdleTimeout)
 }
 
-func (c StorageClient) do(addr ServiceAddr, cb func(client pb.StorageClient) ([]byte, error)) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
-	defer cancel()
-	conn, err := grpc.DialContext(ctx, string(addr), grpc.WithInsecure())
+// NewPooledClient creates a StorageClient closing connections idle for longer than idleTimeout.
+func NewPooledClient(idleTimeout time.Duration) StorageClient {
+	return StorageClient{
+		pool: NewConnPool(idleTimeout),
+	}
+}
+
+// Close closes pooled connections.
+func (c StorageClient) Close() error {
+	if c.pool == nil {
+		return nil
+	}
+	return c.pool.Close()
+}
+
+func (c StorageClient) do(ctx context.Context, addr ServiceAddr, cb func(client pb.StorageClient) ([]byte, error)) ([]byte, error) {
+	if c.pool == nil {
+		ctx, cancel := context.WithTimeout(ctx, Timeout)
+		defer cancel()
+		conn, err := grpc.DialContext(ctx, string(addr), grpc.WithInsecure())
+		if err != nil {
+			return nil, fmt.Errorf("Error dialing %q: %v", addr, err)
+		}
+		defer conn.Close()
+		return cb(pb.NewStorageClient(conn))
+	}
+
+	conn, release, err := c.pool.Get(addr)
 	if err != nil {
-		return nil, fmt.Errorf("Error dialing %q: %v", addr, err)
+		return nil, err
 	}
-	defer conn.Close()
-	client := pb.NewStorageClient(conn)
-	return cb(client)
+	defer release()
+	d, err := cb(pb.NewStorageClient(conn))
+	if err != nil {
+		c.pool.Failed(addr, conn, err)
+	}
+	return d, err
 }
 
 func (c StorageClient) Put(node ServiceAddr, k RecordID, d []byte) error {
-	log.Printf("Putting record to %q, key = %v", node, k)
-	_, err := c.do(node, func(client pb.StorageClient) ([]byte, error) {
-		ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	return c.PutContext(context.Background(), node, k, d)
+}
+
+func (c StorageClient) PutContext(ctx context.Context, node ServiceAddr, k RecordID, d []byte) error {
+	return c.PutKeyContext(ctx, node, k.Key(), d)
+}
+
+func (c StorageClient) PutKeyContext(ctx context.Context, node ServiceAddr, k Key, d []byte) error {
+	log.Printf("Putting record to %q, key = %s", node, FormatKey(k))
+	return c.put(ctx, node, "", k, d)
+}
+
+func (c StorageClient) PutHintContext(ctx context.Context, node, hint ServiceAddr, k Key, d []byte) error {
+	log.Printf("Putting record to %q hinted for %q, key = %s", node, hint, FormatKey(k))
+	return c.put(ctx, node, hint, k, d)
+}
+
+func (c StorageClient) put(ctx context.Context, node, hint ServiceAddr, k Key, d []byte) error {
+	if k == "" {
+		return ErrInvalidKey
+	}
+	_, err := c.do(ctx, node, func(client pb.StorageClient) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(ctx, Timeout)
 		defer cancel()
+		key, keyBytes := keyToPB(k)
 		req := pb.PutRequest{
-			Key:  uint32(k),
-			Data: d,
+			Key:         key,
+			KeyBytes:    keyBytes,
+			Data:        d,
+			Hint:        string(hint),
+			Consistency: consistencyToPB(ConsistencyFromContext(ctx)),
+			Expires:     ExpiryFromContext(ctx),
 		}
 		reply, err := client.Put(ctx, &req)
 		if err != nil {
@@ -63,12 +163,26 @@ func (c StorageClient) Put(node ServiceAddr, k RecordID, d []byte) error {
 }
 
 func (c StorageClient) Get(node ServiceAddr, k RecordID) ([]byte, error) {
-	log.Printf("Getting record from %q, key = %v", node, k)
-	return c.do(node, func(client pb.StorageClient) ([]byte, error) {
-		ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	return c.GetContext(context.Background(), node, k)
+}
+
+func (c StorageClient) GetContext(ctx context.Context, node ServiceAddr, k RecordID) ([]byte, error) {
+	return c.GetKeyContext(ctx, node, k.Key())
+}
+
+func (c StorageClient) GetKeyContext(ctx context.Context, node ServiceAddr, k Key) ([]byte, error) {
+	log.Printf("Getting record from %q, key = %s", node, FormatKey(k))
+	if k == "" {
+		return nil, ErrInvalidKey
+	}
+	return c.do(ctx, node, func(client pb.StorageClient) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(ctx, Timeout)
 		defer cancel()
+		key, keyBytes := keyToPB(k)
 		req := pb.GetRequest{
-			Key: uint32(k),
+			Key:         key,
+			KeyBytes:    keyBytes,
+			Consistency: consistencyToPB(ConsistencyFromContext(ctx)),
 		}
 		reply, err := client.Get(ctx, &req)
 		if err != nil {
@@ -86,12 +200,36 @@ func (c StorageClient) Get(node ServiceAddr, k RecordID) ([]byte, error) {
 }
 
 func (c StorageClient) Del(node ServiceAddr, k RecordID) error {
-	log.Printf("Deleting record from %q, key = %v", node, k)
-	_, err := c.do(node, func(client pb.StorageClient) ([]byte, error) {
-		ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	return c.DelContext(context.Background(), node, k)
+}
+
+func (c StorageClient) DelContext(ctx context.Context, node ServiceAddr, k RecordID) error {
+	return c.DelKeyContext(ctx, node, k.Key())
+}
+
+func (c StorageClient) DelKeyContext(ctx context.Context, node ServiceAddr, k Key) error {
+	log.Printf("Deleting record from %q, key = %s", node, FormatKey(k))
+	return c.del(ctx, node, "", k)
+}
+
+func (c StorageClient) DelHintContext(ctx context.Context, node, hint ServiceAddr, k Key) error {
+	log.Printf("Deleting record from %q hinted for %q, key = %s", node, hint, FormatKey(k))
+	return c.del(ctx, node, hint, k)
+}
+
+func (c StorageClient) del(ctx context.Context, node, hint ServiceAddr, k Key) error {
+	if k == "" {
+		return ErrInvalidKey
+	}
+	_, err := c.do(ctx, node, func(client pb.StorageClient) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(ctx, Timeout)
 		defer cancel()
+		key, keyBytes := keyToPB(k)
 		req := pb.DelRequest{
-			Key: uint32(k),
+			Key:         key,
+			KeyBytes:    keyBytes,
+			Hint:        string(hint),
+			Consistency: consistencyToPB(ConsistencyFromContext(ctx)),
 		}
 		reply, err := client.Del(ctx, &req)
 		if err != nil {
@@ -108,3 +246,348 @@ func (c StorageClient) Del(node ServiceAddr, k RecordID) error {
 	})
 	return err
 }
+
+func (c StorageClient) Scan(node ServiceAddr, opts ScanOptions, f func(k Key, d []byte) error) (string, error) {
+	return c.ScanContext(context.Background(), node, opts, f)
+}
+
+func (c StorageClient) ScanContext(ctx context.Context, node ServiceAddr, opts ScanOptions, f func(k Key, d []byte) error) (string, error) {
+	log.Printf("Scanning records from %q, start = %s, end = %s", node, FormatKey(opts.Start), FormatKey(opts.End))
+	var token string
+	_, err := c.do(ctx, node, func(client pb.StorageClient) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(ctx, Timeout)
+		defer cancel()
+		req := pb.ScanRequest{
+			StartKey: []byte(opts.Start),
+			EndKey:   []byte(opts.End),
+			Limit:    uint32(opts.Limit),
+			Token:    opts.Token,
+		}
+		stream, err := client.Scan(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+		// Records are streamed in batches, the last reply carries
+		// the status of the scan and its resume token.
+		var last *pb.ScanReply
+		for {
+			reply, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range reply.Records {
+				if err := f(keyFromPB(r.Key, r.KeyBytes), r.Data); err != nil {
+					return nil, err
+				}
+			}
+			last = reply
+		}
+		if last == nil {
+			return nil, errors.New("Scan stream ended without status")
+		}
+		status := StatusCode(last.Status)
+		if status == StatusOk {
+			token = last.Token
+			return nil, nil
+		}
+		if err := status.ToError(); err != ErrUnknownStatus {
+			return nil, err
+		}
+		return nil, errors.New(last.Error)
+	})
+	return token, err
+}
+
+func (c StorageClient) Tree(node ServiceAddr, opts TreeOptions) (*MerkleTree, error) {
+	return c.TreeContext(context.Background(), node, opts)
+}
+
+func (c StorageClient) TreeContext(ctx context.Context, node ServiceAddr, opts TreeOptions) (*MerkleTree, error) {
+	log.Printf("Requesting Merkle tree from %q, peer = %q", node, opts.Peer)
+	var tree *MerkleTree
+	_, err := c.do(ctx, node, func(client pb.StorageClient) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(ctx, Timeout)
+		defer cancel()
+		req := pb.TreeRequest{
+			Peer:  string(opts.Peer),
+			Depth: uint32(opts.Depth),
+		}
+		for _, n := range opts.Nodes {
+			req.Nodes = append(req.Nodes, string(n))
+		}
+		reply, err := client.Tree(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+		status := StatusCode(reply.Status)
+		if status == StatusOk {
+			tree, err = MerkleTreeFromHashes(reply.Hashes)
+			return nil, err
+		}
+		if err := status.ToError(); err != ErrUnknownStatus {
+			return nil, err
+		}
+		return nil, errors.New(reply.Error)
+	})
+	return tree, err
+}
+
+func (c StorageClient) GetVersionsContext(ctx context.Context, node ServiceAddr, k Key) ([]Version, error) {
+	log.Printf("Getting record versions from %q, key = %s", node, FormatKey(k))
+	if k == "" {
+		return nil, ErrInvalidKey
+	}
+	var versions []Version
+	_, err := c.do(ctx, node, func(client pb.StorageClient) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(ctx, Timeout)
+		defer cancel()
+		key, keyBytes := keyToPB(k)
+		req := pb.GetRequest{
+			Key:         key,
+			KeyBytes:    keyBytes,
+			Consistency: consistencyToPB(ConsistencyFromContext(ctx)),
+		}
+		reply, err := client.GetVersions(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+		status := StatusCode(reply.Status)
+		if status == StatusOk {
+			for _, v := range reply.Versions {
+				versions = append(versions, versionFromPB(v))
+			}
+			return nil, nil
+		}
+		if err := status.ToError(); err != ErrUnknownStatus {
+			return nil, err
+		}
+		return nil, errors.New(reply.Error)
+	})
+	return versions, err
+}
+
+func (c StorageClient) UpdateContext(ctx context.Context, node ServiceAddr, k Key, v Version) error {
+	log.Printf("Updating record on %q, key = %s", node, FormatKey(k))
+	if k == "" {
+		return ErrInvalidKey
+	}
+	_, err := c.do(ctx, node, func(client pb.StorageClient) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(ctx, Timeout)
+		defer cancel()
+		key, keyBytes := keyToPB(k)
+		req := pb.UpdateRequest{
+			Key:         key,
+			KeyBytes:    keyBytes,
+			Version:     versionToPB(v),
+			Consistency: consistencyToPB(ConsistencyFromContext(ctx)),
+		}
+		reply, err := client.Update(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+		status := StatusCode(reply.Status)
+		if status == StatusOk {
+			return nil, nil
+		}
+		if err := status.ToError(); err != ErrUnknownStatus {
+			return nil, err
+		}
+		return nil, errors.New(reply.Error)
+	})
+	return err
+}
+
+func (c StorageClient) PutIfVersionContext(ctx context.Context, node ServiceAddr, k Key, cond Condition, v Version) error {
+	log.Printf("Conditionally putting record to %q, key = %s", node, FormatKey(k))
+	if k == "" {
+		return ErrInvalidKey
+	}
+	_, err := c.do(ctx, node, func(client pb.StorageClient) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(ctx, Timeout)
+		defer cancel()
+		key, keyBytes := keyToPB(k)
+		req := pb.PutIfVersionRequest{
+			Key:         key,
+			KeyBytes:    keyBytes,
+			Condition:   conditionToPB(cond),
+			Version:     versionToPB(v),
+			Consistency: consistencyToPB(ConsistencyFromContext(ctx)),
+		}
+		reply, err := client.PutIfVersion(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+		status := StatusCode(reply.Status)
+		if status == StatusOk {
+			return nil, nil
+		}
+		if err := status.ToError(); err != ErrUnknownStatus {
+			return nil, err
+		}
+		return nil, errors.New(reply.Error)
+	})
+	return err
+}
+
+func (c StorageClient) DeleteIfVersionContext(ctx context.Context, node ServiceAddr, k Key, cond Condition) error {
+	log.Printf("Conditionally deleting record from %q, key = %s", node, FormatKey(k))
+	if k == "" {
+		return ErrInvalidKey
+	}
+	_, err := c.do(ctx, node, func(client pb.StorageClient) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(ctx, Timeout)
+		defer cancel()
+		key, keyBytes := keyToPB(k)
+		req := pb.DeleteIfVersionRequest{
+			Key:         key,
+			KeyBytes:    keyBytes,
+			Condition:   conditionToPB(cond),
+			Consistency: consistencyToPB(ConsistencyFromContext(ctx)),
+		}
+		reply, err := client.DeleteIfVersion(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+		status := StatusCode(reply.Status)
+		if status == StatusOk {
+			return nil, nil
+		}
+		if err := status.ToError(); err != ErrUnknownStatus {
+			return nil, err
+		}
+		return nil, errors.New(reply.Error)
+	})
+	return err
+}
+
+func (c StorageClient) UpsertContext(ctx context.Context, node ServiceAddr, k Key, v Version) error {
+	log.Printf("Upserting record to %q, key = %s", node, FormatKey(k))
+	if k == "" {
+		return ErrInvalidKey
+	}
+	_, err := c.do(ctx, node, func(client pb.StorageClient) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(ctx, Timeout)
+		defer cancel()
+		key, keyBytes := keyToPB(k)
+		req := pb.UpdateRequest{
+			Key:         key,
+			KeyBytes:    keyBytes,
+			Version:     versionToPB(v),
+			Consistency: consistencyToPB(ConsistencyFromContext(ctx)),
+		}
+		reply, err := client.Upsert(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+		status := StatusCode(reply.Status)
+		if status == StatusOk {
+			return nil, nil
+		}
+		if err := status.ToError(); err != ErrUnknownStatus {
+			return nil, err
+		}
+		return nil, errors.New(reply.Error)
+	})
+	return err
+}
+
+func (c StorageClient) MultiGetContext(ctx context.Context, node ServiceAddr, keys []Key) ([]Result, error) {
+	log.Printf("Getting %d records from %q", len(keys), node)
+	req, err := multiKeysRequest(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	return c.multi(ctx, node, len(keys), func(ctx context.Context, client pb.StorageClient) (*pb.MultiReply, error) {
+		return client.MultiGet(ctx, req)
+	})
+}
+
+func (c StorageClient) MultiPutContext(ctx context.Context, node ServiceAddr, records []Record) ([]Result, error) {
+	log.Printf("Putting %d records to %q", len(records), node)
+	req := pb.MultiPutRequest{
+		Consistency: consistencyToPB(ConsistencyFromContext(ctx)),
+		Expires:     ExpiryFromContext(ctx),
+	}
+	for _, r := range records {
+		if r.Key == "" {
+			return nil, ErrInvalidKey
+		}
+		key, keyBytes := keyToPB(r.Key)
+		req.Records = append(req.Records, &pb.Record{Key: key, KeyBytes: keyBytes, Data: r.Data})
+	}
+	return c.multi(ctx, node, len(records), func(ctx context.Context, client pb.StorageClient) (*pb.MultiReply, error) {
+		return client.MultiPut(ctx, &req)
+	})
+}
+
+func (c StorageClient) MultiDelContext(ctx context.Context, node ServiceAddr, keys []Key) ([]Result, error) {
+	log.Printf("Deleting %d records from %q", len(keys), node)
+	req, err := multiKeysRequest(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	return c.multi(ctx, node, len(keys), func(ctx context.Context, client pb.StorageClient) (*pb.MultiReply, error) {
+		return client.MultiDel(ctx, req)
+	})
+}
+
+// multi sends a batch request of n records and converts its reply.
+func (c StorageClient) multi(ctx context.Context, node ServiceAddr, n int, call func(ctx context.Context, client pb.StorageClient) (*pb.MultiReply, error)) ([]Result, error) {
+	var results []Result
+	_, err := c.do(ctx, node, func(client pb.StorageClient) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(ctx, Timeout)
+		defer cancel()
+		reply, err := call(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		status := StatusCode(reply.Status)
+		if status != StatusOk {
+			if err := status.ToError(); err != ErrUnknownStatus {
+				return nil, err
+			}
+			return nil, errors.New(reply.Error)
+		}
+		if len(reply.Results) != n {
+			return nil, fmt.Errorf("Got %d results for a batch of %d records", len(reply.Results), n)
+		}
+		results = make([]Result, n)
+		for i, r := range reply.Results {
+			results[i].Data = r.Data
+			status := StatusCode(r.Status)
+			if status == StatusOk {
+				continue
+			}
+			if results[i].Err = status.ToError(); results[i].Err == ErrUnknownStatus {
+				results[i].Err = errors.New(r.Error)
+			}
+		}
+		return nil, nil
+	})
+	return results, err
+}
+
+func multiKeysRequest(ctx context.Context, keys []Key) (*pb.MultiKeysRequest, error) {
+	req := pb.MultiKeysRequest{
+		Consistency: consistencyToPB(ConsistencyFromContext(ctx)),
+	}
+	for _, k := range keys {
+		if k == "" {
+			return nil, ErrInvalidKey
+		}
+		key, keyBytes := keyToPB(k)
+		req.Keys = append(req.Keys, key)
+		req.KeyBytes = append(req.KeyBytes, keyBytes)
+	}
+	return &req, nil
+}
+
+// keyToPB returns k as the fields of a request: the byte key along with
+// the RecordID of k for servers which don't know byte keys, or zero.
+func keyToPB(k Key) (uint32, []byte) {
+	id, _ := k.RecordID()
+	return uint32(id), []byte(k)
+}
@@ -0,0 +1,516 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testAddr ServiceAddr = "127.0.0.1:7400"
+
+type memStorage struct {
+	sync.Mutex
+	records map[RecordID][]byte
+}
+
+func (s *memStorage) Put(k RecordID, d []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.records[k]; ok {
+		return ErrRecordExists
+	}
+	s.records[k] = d
+	return nil
+}
+
+func (s *memStorage) Get(k RecordID) ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+	d, ok := s.records[k]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return d, nil
+}
+
+func (s *memStorage) Del(k RecordID) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.records[k]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(s.records, k)
+	return nil
+}
+
+type scanStorage struct {
+	memStorage
+}
+
+func (s *scanStorage) ScanContext(ctx context.Context, opts ScanOptions, f func(k Key, d []byte) error) (string, error) {
+	from, err := opts.From()
+	if err != nil {
+		return "", err
+	}
+	s.Lock()
+	defer s.Unlock()
+	records := make(map[Key][]byte)
+	var keys []Key
+	for id, d := range s.records {
+		if k := id.Key(); k >= from && opts.Contains(k) {
+			records[k] = d
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	for i, k := range keys {
+		if opts.Limit > 0 && i == opts.Limit {
+			return ScanToken(k), nil
+		}
+		if err := f(k, records[k]); err != nil {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// consistencyStorage records the consistency requests are made with
+// and the expiry of put records.
+type consistencyStorage struct {
+	memStorage
+	got     []Consistency
+	expires []int64
+}
+
+func (s *consistencyStorage) PutContext(ctx context.Context, k RecordID, d []byte) error {
+	s.record(ctx)
+	s.Lock()
+	s.expires = append(s.expires, ExpiryFromContext(ctx))
+	s.Unlock()
+	return s.Put(k, d)
+}
+
+func (s *consistencyStorage) GetContext(ctx context.Context, k RecordID) ([]byte, error) {
+	s.record(ctx)
+	return s.Get(k)
+}
+
+func (s *consistencyStorage) DelContext(ctx context.Context, k RecordID) error {
+	s.record(ctx)
+	return s.Del(k)
+}
+
+func (s *consistencyStorage) record(ctx context.Context) {
+	s.Lock()
+	defer s.Unlock()
+	s.got = append(s.got, ConsistencyFromContext(ctx))
+}
+
+// byteKeyStorage stores records with byte keys.
+type byteKeyStorage struct {
+	sync.Mutex
+	records map[Key][]byte
+}
+
+func (s *byteKeyStorage) Put(k RecordID, d []byte) error {
+	return s.PutKeyContext(context.Background(), k.Key(), d)
+}
+
+func (s *byteKeyStorage) Get(k RecordID) ([]byte, error) {
+	return s.GetKeyContext(context.Background(), k.Key())
+}
+
+func (s *byteKeyStorage) Del(k RecordID) error {
+	return s.DelKeyContext(context.Background(), k.Key())
+}
+
+func (s *byteKeyStorage) PutKeyContext(ctx context.Context, k Key, d []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.records[k]; ok {
+		return ErrRecordExists
+	}
+	s.records[k] = d
+	return nil
+}
+
+func (s *byteKeyStorage) GetKeyContext(ctx context.Context, k Key) ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+	d, ok := s.records[k]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return d, nil
+}
+
+func (s *byteKeyStorage) DelKeyContext(ctx context.Context, k Key) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.records[k]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(s.records, k)
+	return nil
+}
+
+func startServer(t testing.TB) *Server {
+	srv := NewServer(&memStorage{records: map[RecordID][]byte{1: []byte("data")}}, string(testAddr))
+	go srv.ListenAndServe()
+	time.Sleep(100 * time.Millisecond)
+	return srv
+}
+
+func TestConnPool(t *testing.T) {
+	srv := startServer(t)
+	defer srv.Stop()
+
+	p := NewConnPool(100 * time.Millisecond)
+	defer p.Close()
+
+	conn, release, err := p.Get(testAddr)
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	same, releaseSame, err := p.Get(testAddr)
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	if same != conn {
+		t.Errorf("Get() dialed a new connection while one is pooled")
+	}
+	releaseSame()
+	release()
+
+	time.Sleep(300 * time.Millisecond)
+	idle, release, err := p.Get(testAddr)
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	release()
+	if idle == conn {
+		t.Errorf("Get() returned a connection which should have been closed as idle")
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if _, _, err := p.Get(testAddr); err != ErrPoolClosed {
+		t.Errorf("Get() got error %v, want %v", err, ErrPoolClosed)
+	}
+}
+
+func TestClient_Reconnect(t *testing.T) {
+	srv := startServer(t)
+	c := NewPooledClient(DefaultIdleTimeout)
+	defer c.Close()
+
+	if _, err := c.Get(testAddr, 1); err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+
+	srv.Stop()
+	if _, err := c.Get(testAddr, 1); err == nil {
+		t.Fatalf("Get() expected error from a stopped server")
+	}
+
+	srv = startServer(t)
+	defer srv.Stop()
+	if _, err := c.Get(testAddr, 1); err != nil {
+		t.Fatalf("Get() error after server restart: %v", err)
+	}
+}
+
+func TestClient_Scan(t *testing.T) {
+	const n = 2*ScanBatch + 50
+	st := &scanStorage{memStorage{records: make(map[RecordID][]byte)}}
+	for i := 0; i < n; i++ {
+		st.records[RecordID(i)] = []byte(fmt.Sprintf("data%d", i))
+	}
+	srv := NewServer(st, string(testAddr))
+	go srv.ListenAndServe()
+	defer srv.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	c := NewPooledClient(DefaultIdleTimeout)
+	defer c.Close()
+
+	scan := func(opts ScanOptions) ([]RecordID, string) {
+		var keys []RecordID
+		token, err := c.Scan(testAddr, opts, func(key Key, d []byte) error {
+			k, ok := key.RecordID()
+			if !ok {
+				t.Fatalf("Scan() got key %q of no RecordID", key)
+			}
+			if want := fmt.Sprintf("data%d", k); string(d) != want {
+				t.Errorf("Wrong data for %v: got %q, want %q", k, d, want)
+			}
+			keys = append(keys, k)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Scan() error: %v", err)
+		}
+		return keys, token
+	}
+	checkKeys := func(keys []RecordID, from, to int) {
+		if len(keys) != to-from {
+			t.Fatalf("Scan() got %d records, want %d", len(keys), to-from)
+		}
+		for i, k := range keys {
+			if k != RecordID(from+i) {
+				t.Fatalf("Scan() got key %v at %d, want %v", k, i, from+i)
+			}
+		}
+	}
+
+	keys, token := scan(ScanOptions{})
+	checkKeys(keys, 0, n)
+	if token != "" {
+		t.Errorf("Scan() returned token %q for a complete scan", token)
+	}
+
+	keys, token = scan(ScanOptions{Start: RecordID(10).Key(), End: RecordID(20).Key()})
+	checkKeys(keys, 10, 20)
+
+	opts := ScanOptions{Start: RecordID(5).Key(), Limit: ScanBatch + 10}
+	keys, token = scan(opts)
+	checkKeys(keys, 5, 5+opts.Limit)
+	if token == "" {
+		t.Fatalf("Scan() returned no token when stopped at the limit")
+	}
+	next := 5 + opts.Limit
+	opts.Token, opts.Limit = token, 0
+	keys, token = scan(opts)
+	checkKeys(keys, next, n)
+	if token != "" {
+		t.Errorf("Scan() returned token %q for a complete scan", token)
+	}
+
+	_, err := c.Scan(testAddr, ScanOptions{Token: "bad token"}, func(Key, []byte) error { return nil })
+	if err != ErrInvalidScanToken {
+		t.Errorf("Scan() got error %v, want %v", err, ErrInvalidScanToken)
+	}
+}
+
+func TestClient_ScanNotSupported(t *testing.T) {
+	srv := startServer(t)
+	defer srv.Stop()
+
+	c := NewPooledClient(DefaultIdleTimeout)
+	defer c.Close()
+	_, err := c.Scan(testAddr, ScanOptions{}, func(Key, []byte) error { return nil })
+	if err != ErrScanNotSupported {
+		t.Errorf("Scan() got error %v, want %v", err, ErrScanNotSupported)
+	}
+}
+
+func TestClient_VersionsNotSupported(t *testing.T) {
+	srv := startServer(t)
+	defer srv.Stop()
+
+	c := NewPooledClient(DefaultIdleTimeout)
+	defer c.Close()
+	ctx := context.Background()
+	if _, err := c.GetVersionsContext(ctx, testAddr, "key"); err != ErrVersionsNotSupported {
+		t.Errorf("GetVersionsContext() got error %v, want %v", err, ErrVersionsNotSupported)
+	}
+	if err := c.UpdateContext(ctx, testAddr, "key", Version{}); err != ErrVersionsNotSupported {
+		t.Errorf("UpdateContext() got error %v, want %v", err, ErrVersionsNotSupported)
+	}
+}
+
+func TestClient_ConditionalNotSupported(t *testing.T) {
+	srv := startServer(t)
+	defer srv.Stop()
+
+	c := NewPooledClient(DefaultIdleTimeout)
+	defer c.Close()
+	ctx := context.Background()
+	if err := c.PutIfVersionContext(ctx, testAddr, "key", Condition{Absent: true}, Version{}); err != ErrConditionalNotSupported {
+		t.Errorf("PutIfVersionContext() got error %v, want %v", err, ErrConditionalNotSupported)
+	}
+	if err := c.DeleteIfVersionContext(ctx, testAddr, "key", Condition{}); err != ErrConditionalNotSupported {
+		t.Errorf("DeleteIfVersionContext() got error %v, want %v", err, ErrConditionalNotSupported)
+	}
+	if err := c.UpsertContext(ctx, testAddr, "key", Version{}); err != ErrConditionalNotSupported {
+		t.Errorf("UpsertContext() got error %v, want %v", err, ErrConditionalNotSupported)
+	}
+}
+
+func TestClient_Consistency(t *testing.T) {
+	st := &consistencyStorage{memStorage: memStorage{records: make(map[RecordID][]byte)}}
+	srv := NewServer(st, string(testAddr))
+	go srv.ListenAndServe()
+	defer srv.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	c := NewPooledClient(DefaultIdleTimeout)
+	defer c.Close()
+	want := []Consistency{{Level: LevelAll}, {Level: LevelOne, R: 2}, {}}
+	if err := c.PutContext(WithConsistency(context.Background(), want[0]), testAddr, 1, []byte("data")); err != nil {
+		t.Fatalf("PutContext() error: %v", err)
+	}
+	if _, err := c.GetContext(WithConsistency(context.Background(), want[1]), testAddr, 1); err != nil {
+		t.Fatalf("GetContext() error: %v", err)
+	}
+	if err := c.Del(testAddr, 1); err != nil {
+		t.Fatalf("Del() error: %v", err)
+	}
+	if !reflect.DeepEqual(st.got, want) {
+		t.Errorf("Storage got consistency %v, want %v", st.got, want)
+	}
+}
+
+func TestClient_Expiry(t *testing.T) {
+	st := &consistencyStorage{memStorage: memStorage{records: make(map[RecordID][]byte)}}
+	srv := NewServer(st, string(testAddr))
+	go srv.ListenAndServe()
+	defer srv.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	c := NewPooledClient(DefaultIdleTimeout)
+	defer c.Close()
+	expiry := time.Now().Add(time.Minute)
+	if err := c.PutContext(WithExpiry(context.Background(), expiry), testAddr, 1, []byte("data")); err != nil {
+		t.Fatalf("PutContext() error: %v", err)
+	}
+	if err := c.Put(testAddr, 2, []byte("data")); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	if want := []int64{expiry.UnixNano(), 0}; !reflect.DeepEqual(st.expires, want) {
+		t.Errorf("Storage got expiry %v, want %v", st.expires, want)
+	}
+}
+
+func TestClient_Multi(t *testing.T) {
+	srv := startServer(t)
+	defer srv.Stop()
+
+	c := NewPooledClient(DefaultIdleTimeout)
+	defer c.Close()
+	ctx := context.Background()
+	results, err := c.MultiPutContext(ctx, testAddr, []Record{{Key: RecordID(1).Key(), Data: []byte("one")}, {Key: RecordID(2).Key(), Data: []byte("two")}})
+	if err != nil {
+		t.Fatalf("MultiPutContext() error: %v", err)
+	}
+	if want := []Result{{Err: ErrRecordExists}, {}}; !reflect.DeepEqual(results, want) {
+		t.Errorf("MultiPutContext() got %v, want %v", results, want)
+	}
+
+	results, err = c.MultiGetContext(ctx, testAddr, []Key{RecordID(2).Key(), RecordID(3).Key(), RecordID(1).Key()})
+	if err != nil {
+		t.Fatalf("MultiGetContext() error: %v", err)
+	}
+	want := []Result{{Data: []byte("two")}, {Err: ErrRecordNotFound}, {Data: []byte("data")}}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("MultiGetContext() got %v, want %v", results, want)
+	}
+
+	results, err = c.MultiDelContext(ctx, testAddr, []Key{RecordID(1).Key(), RecordID(3).Key()})
+	if err != nil {
+		t.Fatalf("MultiDelContext() error: %v", err)
+	}
+	if want := []Result{{}, {Err: ErrRecordNotFound}}; !reflect.DeepEqual(results, want) {
+		t.Errorf("MultiDelContext() got %v, want %v", results, want)
+	}
+}
+
+func TestClient_Keys(t *testing.T) {
+	st := &byteKeyStorage{records: map[Key][]byte{"user:1": []byte("data")}}
+	srv := NewServer(st, string(testAddr))
+	go srv.ListenAndServe()
+	time.Sleep(100 * time.Millisecond)
+
+	c := NewPooledClient(DefaultIdleTimeout)
+	defer c.Close()
+	ctx := context.Background()
+	if d, err := c.GetKeyContext(ctx, testAddr, "user:1"); err != nil || string(d) != "data" {
+		t.Errorf("GetKeyContext() got %q, %v, want %q", d, err, "data")
+	}
+	if err := c.PutKeyContext(ctx, testAddr, "user:2", []byte("two")); err != nil {
+		t.Fatalf("PutKeyContext() error: %v", err)
+	}
+	if err := c.Put(testAddr, 2, []byte("id")); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	want := map[Key][]byte{"user:1": []byte("data"), "user:2": []byte("two"), RecordID(2).Key(): []byte("id")}
+	if !reflect.DeepEqual(st.records, want) {
+		t.Errorf("Storage got records %q, want %q", st.records, want)
+	}
+	if err := c.DelKeyContext(ctx, testAddr, "user:2"); err != nil {
+		t.Errorf("DelKeyContext() error: %v", err)
+	}
+	if err := c.PutKeyContext(ctx, testAddr, "", []byte("data")); err != ErrInvalidKey {
+		t.Errorf("PutKeyContext() with an empty key got error %v, want %v", err, ErrInvalidKey)
+	}
+	srv.Stop()
+
+	// A storage keyed by RecordIDs serves byte keys of RecordIDs only.
+	srv = startServer(t)
+	defer srv.Stop()
+	if d, err := c.GetKeyContext(ctx, testAddr, RecordID(1).Key()); err != nil || string(d) != "data" {
+		t.Errorf("GetKeyContext() got %q, %v, want %q", d, err, "data")
+	}
+	if _, err := c.GetKeyContext(ctx, testAddr, "user:1"); err != ErrKeyNotSupported {
+		t.Errorf("GetKeyContext() got error %v, want %v", err, ErrKeyNotSupported)
+	}
+}
+
+func benchmarkGet(b *testing.B, c Client) {
+	srv := startServer(b)
+	defer srv.Stop()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Get(testAddr, 1); err != nil {
+			b.Fatalf("Get() error: %v", err)
+		}
+	}
+}
+
+func BenchmarkGet_Dial(b *testing.B) {
+	benchmarkGet(b, StorageClient{})
+}
+
+func BenchmarkGet_Pool(b *testing.B) {
+	c := NewPooledClient(DefaultIdleTimeout)
+	defer c.Close()
+	benchmarkGet(b, c)
+}
+
+func benchmarkGetParallel(b *testing.B, c Client) {
+	srv := startServer(b)
+	defer srv.Stop()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := c.Get(testAddr, 1); err != nil {
+				b.Fatalf("Get() error: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetParallel_Dial(b *testing.B) {
+	benchmarkGetParallel(b, StorageClient{})
+}
+
+func BenchmarkGetParallel_Pool(b *testing.B) {
+	c := NewPooledClient(DefaultIdleTimeout)
+	defer c.Close()
+	benchmarkGetParallel(b, c)
+}
+
+func TestMain(m *testing.M) {
+	log.SetOutput(ioutil.Discard)
+	os.Exit(m.Run())
+}
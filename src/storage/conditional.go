@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Condition is a precondition of a conditional write: the record has to be
+// absent if Absent is set, otherwise it has to have a single version
+// with Clock.
+type Condition struct {
+	Absent bool
+	Clock  VectorClock
+}
+
+// Holds reports whether a record with versions satisfies the condition.
+// A missing record has no versions.
+func (c Condition) Holds(versions []Version) bool {
+	if c.Absent {
+		return len(versions) == 0
+	}
+	return len(versions) == 1 && versions[0].Clock.Compare(c.Clock) == Equal
+}
+
+// ConditionalStorage is a Storage supporting conditional writes.
+// PutIfVersionContext replaces the record with v if cond holds,
+// DeleteIfVersionContext deletes the record if cond holds, both return
+// ErrConditionFailed otherwise. UpsertContext unconditionally replaces
+// all versions of the record with v.
+type ConditionalStorage interface {
+	PutIfVersionContext(ctx context.Context, k Key, cond Condition, v Version) error
+	DeleteIfVersionContext(ctx context.Context, k Key, cond Condition) error
+	UpsertContext(ctx context.Context, k Key, v Version) error
+}
+
+// ConditionalClient is a Client which is able to make conditional writes.
+type ConditionalClient interface {
+	PutIfVersionContext(ctx context.Context, node ServiceAddr, k Key, cond Condition, v Version) error
+	DeleteIfVersionContext(ctx context.Context, node ServiceAddr, k Key, cond Condition) error
+	UpsertContext(ctx context.Context, node ServiceAddr, k Key, v Version) error
+}
+
+// String formats c as comma separated writer:counter pairs sorted by writer.
+func (c VectorClock) String() string {
+	ids := make([]string, 0, len(c))
+	for id := range c {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, fmt.Sprintf("%s:%d", id, c[id]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseVectorClock parses a clock formatted by VectorClock.String.
+func ParseVectorClock(s string) (VectorClock, error) {
+	c := VectorClock{}
+	if s == "" {
+		return c, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		i := strings.LastIndex(part, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("Invalid vector clock entry %q", part)
+		}
+		n, err := strconv.ParseUint(part[i+1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid vector clock entry %q: %v", part, err)
+		}
+		c[part[:i]] = n
+	}
+	return c, nil
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCondition_Holds(t *testing.T) {
+	clock := VectorClock{"a": 1}
+	tests := []struct {
+		cond     Condition
+		versions []Version
+		want     bool
+	}{
+		{Condition{Absent: true}, nil, true},
+		{Condition{Absent: true}, []Version{{Clock: clock}}, false},
+		{Condition{Clock: clock}, nil, false},
+		{Condition{Clock: clock}, []Version{{Clock: VectorClock{"a": 1}}}, true},
+		{Condition{Clock: clock}, []Version{{Clock: VectorClock{"a": 2}}}, false},
+		{Condition{Clock: clock}, []Version{{Clock: clock}, {Clock: VectorClock{"b": 1}}}, false},
+		{Condition{Clock: VectorClock{}}, []Version{{Clock: nil}}, true},
+	}
+	for i, tt := range tests {
+		if got := tt.cond.Holds(tt.versions); got != tt.want {
+			t.Errorf("#%d: Holds() = %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestParseVectorClock(t *testing.T) {
+	for _, c := range []VectorClock{{}, {"a": 1}, {"127.0.0.1:7300": 3, "b": 2}} {
+		got, err := ParseVectorClock(c.String())
+		if err != nil {
+			t.Fatalf("ParseVectorClock(%q) error: %v", c.String(), err)
+		}
+		if !reflect.DeepEqual(got, c) {
+			t.Errorf("ParseVectorClock(%q) = %v, want %v", c.String(), got, c)
+		}
+	}
+	for _, s := range []string{"a", ":1", "a:x", "a:1,"} {
+		if _, err := ParseVectorClock(s); err == nil {
+			t.Errorf("ParseVectorClock(%q) succeeded, want error", s)
+		}
+	}
+}
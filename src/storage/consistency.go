@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Level is a consistency level: a number of replicas which have to
+// acknowledge an operation for it to succeed.
+type Level int32
+
+const (
+	// LevelDefault is LevelQuorum.
+	LevelDefault Level = iota
+	// LevelOne requires a single replica.
+	LevelOne
+	// LevelQuorum requires Replication.Quorum replicas.
+	LevelQuorum
+	// LevelAll requires all Replication.Factor replicas.
+	LevelAll
+)
+
+var levelNames = map[string]Level{
+	"":       LevelDefault,
+	"one":    LevelOne,
+	"quorum": LevelQuorum,
+	"all":    LevelAll,
+}
+
+// ParseLevel parses a level name: one, quorum or all. An empty name is LevelDefault.
+func ParseLevel(s string) (Level, error) {
+	l, ok := levelNames[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("Unknown consistency level %q", s)
+	}
+	return l, nil
+}
+
+func (l Level) String() string {
+	for name, level := range levelNames {
+		if level == l && name != "" {
+			return name
+		}
+	}
+	if l == LevelDefault {
+		return "default"
+	}
+	return fmt.Sprintf("Level(%d)", int32(l))
+}
+
+// Consistency selects a number of replicas an operation waits for.
+// R and W override Level for reads and writes if they are positive.
+type Consistency struct {
+	Level Level
+	R     int
+	W     int
+}
+
+// Reads returns a number of replicas a read has to get the same reply from
+// in a cluster with replication rep.
+func (c Consistency) Reads(rep Replication) (int, error) {
+	return c.required(rep, c.R)
+}
+
+// Writes returns a number of replicas which have to acknowledge a write
+// in a cluster with replication rep.
+func (c Consistency) Writes(rep Replication) (int, error) {
+	return c.required(rep, c.W)
+}
+
+func (c Consistency) required(rep Replication, override int) (int, error) {
+	if override > 0 {
+		if override > rep.Factor {
+			return 0, ErrInvalidConsistency
+		}
+		return override, nil
+	}
+	switch c.Level {
+	case LevelOne:
+		return 1, nil
+	case LevelDefault, LevelQuorum:
+		return rep.Quorum, nil
+	case LevelAll:
+		return rep.Factor, nil
+	default:
+		return 0, ErrInvalidConsistency
+	}
+}
+
+type consistencyKey struct{}
+
+// WithConsistency returns a copy of ctx carrying c. Client sends it along with
+// requests made with the context, and Server passes it to a ContextStorage.
+func WithConsistency(ctx context.Context, c Consistency) context.Context {
+	return context.WithValue(ctx, consistencyKey{}, c)
+}
+
+// ConsistencyFromContext returns the consistency carried by ctx, the zero
+// value selecting LevelDefault if there is none.
+func ConsistencyFromContext(ctx context.Context) Consistency {
+	c, _ := ctx.Value(consistencyKey{}).(Consistency)
+	return c
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConsistency_Required(t *testing.T) {
+	for _, test := range []struct {
+		c             Consistency
+		reads, writes int
+		err           error
+	}{
+		{c: Consistency{}, reads: MinRedundancy, writes: MinRedundancy},
+		{c: Consistency{Level: LevelOne}, reads: 1, writes: 1},
+		{c: Consistency{Level: LevelAll, R: 1}, reads: 1, writes: ReplicationFactor},
+		{c: Consistency{Level: LevelQuorum, W: ReplicationFactor + 1}, err: ErrInvalidConsistency},
+		{c: Consistency{Level: -1}, err: ErrInvalidConsistency},
+	} {
+		r, rerr := test.c.Reads(DefaultReplication)
+		w, werr := test.c.Writes(DefaultReplication)
+		if test.err != nil {
+			if rerr != test.err && werr != test.err {
+				t.Errorf("%+v: got errors %v and %v, want %v", test.c, rerr, werr, test.err)
+			}
+			continue
+		}
+		if r != test.reads || w != test.writes || rerr != nil || werr != nil {
+			t.Errorf("%+v: got %d, %v reads and %d, %v writes, want %d and %d", test.c, r, rerr, w, werr, test.reads, test.writes)
+		}
+	}
+
+	rep := Replication{Factor: 5, Quorum: 3}
+	if r, err := (Consistency{Level: LevelAll, R: 5}).Reads(rep); r != 5 || err != nil {
+		t.Errorf("Reads() got %d, %v, want 5", r, err)
+	}
+	if w, err := (Consistency{}).Writes(rep); w != 3 || err != nil {
+		t.Errorf("Writes() got %d, %v, want 3", w, err)
+	}
+
+	for _, name := range []string{"one", "QUORUM", "all"} {
+		l, err := ParseLevel(name)
+		if err != nil || l.String() != strings.ToLower(name) {
+			t.Errorf("ParseLevel(%q) got %v, %v", name, l, err)
+		}
+	}
+	if _, err := ParseLevel("most"); err == nil {
+		t.Errorf("ParseLevel(%q) succeeded", "most")
+	}
+}
+
+func TestReplication_Normalize(t *testing.T) {
+	for _, test := range []struct {
+		rep, want Replication
+		valid     bool
+	}{
+		{rep: Replication{}, want: DefaultReplication, valid: true},
+		{rep: Replication{Factor: 1}, want: Replication{Factor: 1, Quorum: 1}, valid: true},
+		{rep: Replication{Factor: 5}, want: Replication{Factor: 5, Quorum: 3}, valid: true},
+		{rep: Replication{Factor: 5, Quorum: 5}, want: Replication{Factor: 5, Quorum: 5}, valid: true},
+		{rep: Replication{Factor: 2, Quorum: 3}, want: Replication{Factor: 2, Quorum: 3}},
+		{rep: Replication{Factor: -1}, want: Replication{Factor: -1, Quorum: 1}},
+	} {
+		got := test.rep.Normalize()
+		if got != test.want {
+			t.Errorf("%+v.Normalize() = %+v, want %+v", test.rep, got, test.want)
+		}
+		if err := got.Validate(); (err == nil) != test.valid {
+			t.Errorf("%+v.Validate() = %v", got, err)
+		}
+	}
+}
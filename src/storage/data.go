@@ -1,10 +1,120 @@
 package storage
 
+import (
+	"math"
+	"time"
+)
+
+// ReplicationFactor and MinRedundancy are the replication factor and quorum
+// of a cluster whose router doesn't configure them.
 const (
 	ReplicationFactor = 3
 	MinRedundancy     = 2
 )
 
+// Replication is a cluster-wide replication setting owned by the router:
+// each record is stored on Factor nodes, and Quorum of them have to agree
+// on an operation made with the default consistency.
+type Replication struct {
+	Factor int
+	Quorum int
+}
+
+// DefaultReplication is a Replication of a cluster whose router doesn't configure it.
+var DefaultReplication = Replication{Factor: ReplicationFactor, Quorum: MinRedundancy}
+
+// Normalize returns r with unset fields defaulted: Factor to ReplicationFactor
+// and Quorum to a majority of Factor.
+func (r Replication) Normalize() Replication {
+	if r.Factor == 0 {
+		r.Factor = ReplicationFactor
+	}
+	if r.Quorum == 0 {
+		r.Quorum = r.Factor/2 + 1
+	}
+	return r
+}
+
+// Validate returns ErrInvalidReplication unless Factor is positive and
+// Quorum is within [1, Factor].
+func (r Replication) Validate() error {
+	if r.Factor < 1 || r.Quorum < 1 || r.Quorum > r.Factor {
+		return ErrInvalidReplication
+	}
+	return nil
+}
+
+// DefaultCapacity is the capacity weight of a node whose capacity is not set.
+const DefaultCapacity = 1.0
+
+// NodeLabels are topology labels of a node: the zone and the rack it is in.
+// Racks are named within their zones.
+type NodeLabels struct {
+	Zone string
+	Rack string
+}
+
+// FailureDomain is a kind of group of nodes which may fail together.
+type FailureDomain string
+
+const (
+	// DomainZone groups nodes by their zones.
+	DomainZone FailureDomain = "zone"
+	// DomainRack groups nodes by their racks.
+	DomainRack FailureDomain = "rack"
+)
+
+// Placement is the setting of a cluster placing records on some nodes
+// more than on others and spreading replicas of records across failure domains.
+type Placement struct {
+	// Capacity holds capacity weights of nodes, a node is chosen to store
+	// a record with a probability proportional to its weight. Nodes which
+	// are not in Capacity have DefaultCapacity.
+	Capacity map[ServiceAddr]float64
+	// Labels holds topology labels of nodes.
+	Labels map[ServiceAddr]NodeLabels
+	// Spread is the failure domain replicas of a record are placed in
+	// distinct ones of, empty if replicas are placed regardless of labels.
+	Spread FailureDomain
+}
+
+// NodeCapacity returns the capacity weight of node.
+func (p Placement) NodeCapacity(node ServiceAddr) float64 {
+	if w, ok := p.Capacity[node]; ok {
+		return w
+	}
+	return DefaultCapacity
+}
+
+// Weighted reports whether records are placed on nodes according to
+// their capacity weights rather than evenly.
+func (p Placement) Weighted() bool {
+	return len(p.Capacity) != 0
+}
+
+// Default reports whether records are placed on nodes evenly
+// regardless of their labels.
+func (p Placement) Default() bool {
+	return !p.Weighted() && p.Spread == ""
+}
+
+// Validate returns ErrInvalidCapacity unless all capacity weights are
+// positive and finite, and ErrUnknownFailureDomain if Spread is neither
+// empty, DomainZone nor DomainRack.
+func (p Placement) Validate() error {
+	for _, w := range p.Capacity {
+		if !(w > 0) || math.IsInf(w, 1) {
+			return ErrInvalidCapacity
+		}
+	}
+	switch p.Spread {
+	case "", DomainZone, DomainRack:
+	default:
+		return ErrUnknownFailureDomain
+	}
+	return nil
+}
+
 type ServiceAddr string
 type RecordID uint32
 
@@ -15,3 +125,68 @@ func (RecordID) BinSize() int {
 func (addr ServiceAddr) BinSize() int {
 	return len(addr)
 }
+
+// Topology is a state of the nodes served by a router.
+type Topology struct {
+	// Epoch is incremented on every change of the set of served nodes.
+	Epoch uint64
+	// Nodes are the nodes records are placed on, draining nodes excluded.
+	Nodes []ServiceAddr
+	// Alive are the nodes whose heartbeats are received, draining nodes included.
+	Alive []ServiceAddr
+	// Replication is the replication setting of the cluster.
+	Replication Replication
+	// Placement is the placement setting of the cluster.
+	Placement Placement
+}
+
+// NodeSuspicion is the state of a node in the failure detector of a router.
+type NodeSuspicion struct {
+	Node ServiceAddr
+	// Phi is the suspicion level of the node, zero if no heartbeat
+	// intervals of the node are known.
+	Phi float64
+	// Alive reports whether the node is considered to be available.
+	Alive bool
+	// Intervals is the number of heartbeat intervals Phi is estimated from.
+	Intervals int
+	// LastHeartbeat is the time of the last heartbeat, zero if there were none.
+	LastHeartbeat time.Time
+}
+
+// NodeLoad is the load of a node reported in its heartbeats.
+type NodeLoad struct {
+	// Records is the number of records stored by the node.
+	Records int64
+	// Bytes is the total size of keys and data of the records.
+	Bytes int64
+	// Memory is the heap memory allocated by the node in bytes.
+	Memory uint64
+	// ReadRate and WriteRate are the reads and writes served per second
+	// since the previous heartbeat.
+	ReadRate  float64
+	WriteRate float64
+	// EngineError is the last error of the storage engine of the node,
+	// empty if the engine is healthy.
+	EngineError string
+}
+
+// NodeStatus is the state of a node served by a router.
+type NodeStatus struct {
+	NodeSuspicion
+	// Draining reports whether the node is being drained.
+	Draining bool
+	// Load is the load of the node from its last heartbeat,
+	// zero if the node doesn't report it.
+	Load NodeLoad
+}
+
+// ClusterStatus is the state of the cluster served by a router.
+type ClusterStatus struct {
+	Epoch       uint64
+	Replication Replication
+	// Leader is the leader of the router replicas, empty if the router
+	// is not replicated.
+	Leader ServiceAddr
+	Nodes  []NodeStatus
+}
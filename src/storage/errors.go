@@ -5,11 +5,31 @@ import (
 )
 
 var (
-	ErrQuorumNotReached = errors.New("Quorum not reached")
-	ErrNotEnoughDaemons = errors.New("Not Enough Daemons Available")
-	ErrUnknownDaemon    = errors.New("Unknown Daemon")
-	ErrRecordNotFound   = errors.New("Record Not Found")
-	ErrRecordExists     = errors.New("Already have record")
+	ErrQuorumNotReached        = errors.New("Quorum not reached")
+	ErrNotEnoughDaemons        = errors.New("Not Enough Daemons Available")
+	ErrUnknownDaemon           = errors.New("Unknown Daemon")
+	ErrRecordNotFound          = errors.New("Record Not Found")
+	ErrRecordExists            = errors.New("Already have record")
+	ErrDaemonExists            = errors.New("Daemon already exists")
+	ErrInvalidScanToken        = errors.New("Invalid scan token")
+	ErrScanNotSupported        = errors.New("Scan is not supported")
+	ErrTreeNotSupported        = errors.New("Merkle tree is not supported")
+	ErrInvalidMerkleTree       = errors.New("Invalid Merkle tree")
+	ErrHintNotSupported        = errors.New("Hinted handoff is not supported")
+	ErrObsoleteVersion         = errors.New("Version is obsolete")
+	ErrConflict                = errors.New("Record has concurrent versions")
+	ErrVersionsNotSupported    = errors.New("Versions are not supported")
+	ErrInvalidConsistency      = errors.New("Invalid consistency level")
+	ErrInvalidReplication      = errors.New("Invalid replication factor or quorum")
+	ErrConditionFailed         = errors.New("Condition failed")
+	ErrConditionalNotSupported = errors.New("Conditional writes are not supported")
+	ErrInvalidKey              = errors.New("Invalid key")
+	ErrKeyNotSupported         = errors.New("Byte keys are not supported")
+	ErrNotLeader               = errors.New("Router is not a leader")
+	ErrInvalidCapacity         = errors.New("Invalid node capacity")
+	ErrUnknownFailureDomain    = errors.New("Unknown failure domain")
+	ErrUnknownHasher           = errors.New("Unknown hasher")
+	ErrInvalidHashKey          = errors.New("Invalid hash key")
 
 	ErrUnknownStatus = errors.New("Error Unknown")
 )
@@ -25,6 +45,28 @@ const (
 	StatusRecordExists
 
 	StatusUnknown
+
+	// Codes added after StatusUnknown keep the values above stable on the wire.
+	StatusDaemonExists
+	StatusInvalidScanToken
+	StatusScanNotSupported
+	StatusTreeNotSupported
+	StatusInvalidMerkleTree
+	StatusHintNotSupported
+	StatusObsoleteVersion
+	StatusConflict
+	StatusVersionsNotSupported
+	StatusInvalidConsistency
+	StatusInvalidReplication
+	StatusConditionFailed
+	StatusConditionalNotSupported
+	StatusInvalidKey
+	StatusKeyNotSupported
+	StatusNotLeader
+	StatusInvalidCapacity
+	StatusUnknownFailureDomain
+	StatusUnknownHasher
+	StatusInvalidHashKey
 )
 
 func (s StatusCode) ToError() error {
@@ -41,6 +83,46 @@ func (s StatusCode) ToError() error {
 		return ErrRecordNotFound
 	case StatusRecordExists:
 		return ErrRecordExists
+	case StatusDaemonExists:
+		return ErrDaemonExists
+	case StatusInvalidScanToken:
+		return ErrInvalidScanToken
+	case StatusScanNotSupported:
+		return ErrScanNotSupported
+	case StatusTreeNotSupported:
+		return ErrTreeNotSupported
+	case StatusInvalidMerkleTree:
+		return ErrInvalidMerkleTree
+	case StatusHintNotSupported:
+		return ErrHintNotSupported
+	case StatusObsoleteVersion:
+		return ErrObsoleteVersion
+	case StatusConflict:
+		return ErrConflict
+	case StatusVersionsNotSupported:
+		return ErrVersionsNotSupported
+	case StatusInvalidConsistency:
+		return ErrInvalidConsistency
+	case StatusInvalidReplication:
+		return ErrInvalidReplication
+	case StatusConditionFailed:
+		return ErrConditionFailed
+	case StatusConditionalNotSupported:
+		return ErrConditionalNotSupported
+	case StatusInvalidKey:
+		return ErrInvalidKey
+	case StatusKeyNotSupported:
+		return ErrKeyNotSupported
+	case StatusNotLeader:
+		return ErrNotLeader
+	case StatusInvalidCapacity:
+		return ErrInvalidCapacity
+	case StatusUnknownFailureDomain:
+		return ErrUnknownFailureDomain
+	case StatusUnknownHasher:
+		return ErrUnknownHasher
+	case StatusInvalidHashKey:
+		return ErrInvalidHashKey
 	default:
 		return ErrUnknownStatus
 	}
@@ -62,6 +144,46 @@ func ErrToStatus(err error) StatusCode {
 		return StatusRecordNotFound
 	case ErrRecordExists:
 		return StatusRecordExists
+	case ErrDaemonExists:
+		return StatusDaemonExists
+	case ErrInvalidScanToken:
+		return StatusInvalidScanToken
+	case ErrScanNotSupported:
+		return StatusScanNotSupported
+	case ErrTreeNotSupported:
+		return StatusTreeNotSupported
+	case ErrInvalidMerkleTree:
+		return StatusInvalidMerkleTree
+	case ErrHintNotSupported:
+		return StatusHintNotSupported
+	case ErrObsoleteVersion:
+		return StatusObsoleteVersion
+	case ErrConflict:
+		return StatusConflict
+	case ErrVersionsNotSupported:
+		return StatusVersionsNotSupported
+	case ErrInvalidConsistency:
+		return StatusInvalidConsistency
+	case ErrInvalidReplication:
+		return StatusInvalidReplication
+	case ErrConditionFailed:
+		return StatusConditionFailed
+	case ErrConditionalNotSupported:
+		return StatusConditionalNotSupported
+	case ErrInvalidKey:
+		return StatusInvalidKey
+	case ErrKeyNotSupported:
+		return StatusKeyNotSupported
+	case ErrNotLeader:
+		return StatusNotLeader
+	case ErrInvalidCapacity:
+		return StatusInvalidCapacity
+	case ErrUnknownFailureDomain:
+		return StatusUnknownFailureDomain
+	case ErrUnknownHasher:
+		return StatusUnknownHasher
+	case ErrInvalidHashKey:
+		return StatusInvalidHashKey
 	default:
 		return StatusUnknown
 	}
@@ -0,0 +1,24 @@
+package storage
+
+import "context"
+
+// Replica is a node a record is written to. If Node substitutes an unavailable
+// owner of the record, Hint is the owner the write is to be handed off to.
+type Replica struct {
+	Node ServiceAddr
+	Hint ServiceAddr
+}
+
+// HintStorage is a Storage which is able to keep writes intended
+// for another node and replay them once it's available.
+type HintStorage interface {
+	PutHintContext(ctx context.Context, hint ServiceAddr, k Key, d []byte) error
+	DelHintContext(ctx context.Context, hint ServiceAddr, k Key) error
+}
+
+// HintClient is a Client which is able to hand writes intended for the node hint
+// off to a substitute node.
+type HintClient interface {
+	PutHintContext(ctx context.Context, node, hint ServiceAddr, k Key, d []byte) error
+	DelHintContext(ctx context.Context, node, hint ServiceAddr, k Key) error
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"strconv"
+)
+
+// Key is a record key: a non-empty byte string. Keys are ordered bytewise.
+// RecordID is a compatibility path over keys, see RecordID.Key.
+type Key string
+
+// Key returns the key the record k is stored with: the four little-endian
+// bytes of k. Hasher hashed the same bytes before keys were byte strings,
+// so records written with a RecordID keep their placement.
+func (k RecordID) Key() Key {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(k))
+	return Key(b[:])
+}
+
+// RecordID returns the RecordID k is the key of, false if k is not
+// four bytes long.
+func (k Key) RecordID() (RecordID, bool) {
+	if len(k) != 4 {
+		return 0, false
+	}
+	return RecordID(binary.LittleEndian.Uint32([]byte(k))), true
+}
+
+// FormatKey formats k for logs: a key of a RecordID as the number,
+// the way RecordIDs were logged before keys were byte strings,
+// and other keys quoted.
+func FormatKey(k Key) string {
+	if id, ok := k.RecordID(); ok {
+		return strconv.FormatUint(uint64(id), 10)
+	}
+	return strconv.Quote(string(k))
+}
+
+func (k Key) BinSize() int {
+	return len(k)
+}
+
+// prefix returns the first four bytes of k as a big-endian number, a shorter
+// key is padded with zeros. Keys' prefixes are ordered as the keys are.
+func (k Key) prefix() uint64 {
+	var b [4]byte
+	copy(b[:], k)
+	return uint64(binary.BigEndian.Uint32(b[:]))
+}
+
+// keyWithPrefix returns the least key whose prefix is p,
+// or an empty key if p doesn't fit into four bytes.
+func keyWithPrefix(p uint64) Key {
+	if p > 0xffffffff {
+		return ""
+	}
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(p))
+	n := len(b)
+	for n > 0 && b[n-1] == 0 {
+		n--
+	}
+	return Key(b[:n])
+}
+
+// KeyStorage is a Storage of records with byte keys. Storage and
+// ContextStorage are its compatibility path for RecordID keys.
+type KeyStorage interface {
+	PutKeyContext(ctx context.Context, k Key, d []byte) error
+	GetKeyContext(ctx context.Context, k Key) ([]byte, error)
+	DelKeyContext(ctx context.Context, k Key) error
+}
+
+// KeyClient is a Client whose requests are made with byte keys.
+type KeyClient interface {
+	PutKeyContext(ctx context.Context, node ServiceAddr, k Key, d []byte) error
+	GetKeyContext(ctx context.Context, node ServiceAddr, k Key) ([]byte, error)
+	DelKeyContext(ctx context.Context, node ServiceAddr, k Key) error
+}
+
+// WithKeys returns c as a KeyClient. If c doesn't implement KeyClient,
+// only keys of RecordIDs are supported, others fail with ErrKeyNotSupported.
+func WithKeys(c Client) KeyClient {
+	if kc, ok := c.(KeyClient); ok {
+		return kc
+	}
+	return keyClient{WithContext(c)}
+}
+
+type keyClient struct {
+	c ContextClient
+}
+
+func (c keyClient) PutKeyContext(ctx context.Context, node ServiceAddr, k Key, d []byte) error {
+	id, ok := k.RecordID()
+	if !ok {
+		return ErrKeyNotSupported
+	}
+	return c.c.PutContext(ctx, node, id, d)
+}
+
+func (c keyClient) GetKeyContext(ctx context.Context, node ServiceAddr, k Key) ([]byte, error) {
+	id, ok := k.RecordID()
+	if !ok {
+		return nil, ErrKeyNotSupported
+	}
+	return c.c.GetContext(ctx, node, id)
+}
+
+func (c keyClient) DelKeyContext(ctx context.Context, node ServiceAddr, k Key) error {
+	id, ok := k.RecordID()
+	if !ok {
+		return ErrKeyNotSupported
+	}
+	return c.c.DelContext(ctx, node, id)
+}
+
+// recordStorage serves byte keys of RecordIDs for a storage which doesn't
+// implement KeyStorage.
+type recordStorage struct {
+	st Storage
+}
+
+// keyStorage returns st as a KeyStorage.
+func keyStorage(st Storage) KeyStorage {
+	if ks, ok := st.(KeyStorage); ok {
+		return ks
+	}
+	return recordStorage{st}
+}
+
+func (s recordStorage) PutKeyContext(ctx context.Context, k Key, d []byte) error {
+	id, ok := k.RecordID()
+	if !ok {
+		return ErrKeyNotSupported
+	}
+	if cs, ok := s.st.(ContextStorage); ok {
+		return cs.PutContext(ctx, id, d)
+	}
+	return s.st.Put(id, d)
+}
+
+func (s recordStorage) GetKeyContext(ctx context.Context, k Key) ([]byte, error) {
+	id, ok := k.RecordID()
+	if !ok {
+		return nil, ErrKeyNotSupported
+	}
+	if cs, ok := s.st.(ContextStorage); ok {
+		return cs.GetContext(ctx, id)
+	}
+	return s.st.Get(id)
+}
+
+func (s recordStorage) DelKeyContext(ctx context.Context, k Key) error {
+	id, ok := k.RecordID()
+	if !ok {
+		return ErrKeyNotSupported
+	}
+	if cs, ok := s.st.(ContextStorage); ok {
+		return cs.DelContext(ctx, id)
+	}
+	return s.st.Del(id)
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestKey_RecordID(t *testing.T) {
+	for _, id := range []RecordID{0, 1, 0x12345678, 0xffffffff} {
+		got, ok := id.Key().RecordID()
+		if !ok || got != id {
+			t.Errorf("Key of %v converted back to %v, %v", id, got, ok)
+		}
+	}
+	if got := RecordID(0x04030201).Key(); got != "\x01\x02\x03\x04" {
+		t.Errorf("Key() got %q, want little-endian bytes", got)
+	}
+	if _, ok := Key("user:1").RecordID(); ok {
+		t.Errorf("RecordID() of a long key succeeded")
+	}
+}
+
+func TestFormatKey(t *testing.T) {
+	for _, tc := range []struct {
+		k    Key
+		want string
+	}{
+		{RecordID(1).Key(), "1"},
+		{RecordID(0xffffffff).Key(), "4294967295"},
+		{"user:1", `"user:1"`},
+		{"\x00\xff", `"\x00\xff"`},
+		{"", `""`},
+	} {
+		if got := FormatKey(tc.k); got != tc.want {
+			t.Errorf("FormatKey(%q) = %s, want %s", tc.k, got, tc.want)
+		}
+	}
+}
+
+func TestKey_Prefix(t *testing.T) {
+	keys := []Key{"", "\x00", "\x00\x01", "a", "ab", "abcd", "abcde", "b", "\xff\xff\xff\xff\xff"}
+	for i := 1; i < len(keys); i++ {
+		if keys[i-1].prefix() > keys[i].prefix() {
+			t.Errorf("Prefix of %q is greater than of %q", keys[i-1], keys[i])
+		}
+	}
+	for _, p := range []uint64{0, 1, 0x61000000, 0x61620000, 0xffffffff} {
+		if k := keyWithPrefix(p); k.prefix() != p {
+			t.Errorf("keyWithPrefix(%x) got %q with prefix %x", p, k, k.prefix())
+		}
+	}
+	if k := keyWithPrefix(1 << 32); k != "" {
+		t.Errorf("keyWithPrefix() past the key space got %q", k)
+	}
+}
+
+// recordClient is a Client keyed by RecordIDs only.
+type recordClient struct {
+	got []RecordID
+}
+
+func (c *recordClient) Put(node ServiceAddr, k RecordID, d []byte) error {
+	c.got = append(c.got, k)
+	return nil
+}
+
+func (c *recordClient) Get(node ServiceAddr, k RecordID) ([]byte, error) {
+	c.got = append(c.got, k)
+	return nil, nil
+}
+
+func (c *recordClient) Del(node ServiceAddr, k RecordID) error {
+	c.got = append(c.got, k)
+	return nil
+}
+
+func TestWithKeys(t *testing.T) {
+	rc := &recordClient{}
+	c := WithKeys(rc)
+	ctx := context.Background()
+	if err := c.PutKeyContext(ctx, testAddr, RecordID(7).Key(), nil); err != nil {
+		t.Errorf("PutKeyContext() error: %v", err)
+	}
+	if err := c.PutKeyContext(ctx, testAddr, "user:7", nil); err != ErrKeyNotSupported {
+		t.Errorf("PutKeyContext() got error %v, want %v", err, ErrKeyNotSupported)
+	}
+	if len(rc.got) != 1 || rc.got[0] != 7 {
+		t.Errorf("Client got keys %v, want [7]", rc.got)
+	}
+	if _, ok := WithKeys(StorageClient{}).(StorageClient); !ok {
+		t.Errorf("WithKeys() wrapped a KeyClient")
+	}
+}
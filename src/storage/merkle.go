@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// MerkleDepth is a default depth of Merkle trees exchanged by nodes,
+// the key space is split into 1 << MerkleDepth leaf ranges.
+const MerkleDepth = 10
+
+// MaxMerkleDepth is a maximum supported depth of a Merkle tree.
+const MaxMerkleDepth = 16
+
+// MerkleTree is a hash tree over the key space. Each leaf covers an equal range
+// of keys and hashes records within it regardless of their order, each inner
+// node hashes its children. Trees of equal depth holding the same records are equal.
+type MerkleTree struct {
+	depth uint
+	// hashes is a heap: the root is at 1, children of i are at 2i and 2i+1.
+	hashes []uint64
+	// sealed is set once inner nodes are computed over the added records.
+	sealed bool
+}
+
+// NewMerkleTree creates an empty MerkleTree of a given depth.
+func NewMerkleTree(depth int) *MerkleTree {
+	if depth < 0 || depth > MaxMerkleDepth {
+		depth = MerkleDepth
+	}
+	return &MerkleTree{
+		depth:  uint(depth),
+		hashes: make([]uint64, 2<<uint(depth)),
+	}
+}
+
+// Depth returns the depth of the tree.
+func (t *MerkleTree) Depth() int {
+	return int(t.depth)
+}
+
+// Add adds a record to the tree.
+func (t *MerkleTree) Add(k Key, d []byte) {
+	h := fnv.New64a()
+	var b [binary.MaxVarintLen64]byte
+	h.Write(b[:binary.PutUvarint(b[:], uint64(len(k)))])
+	h.Write([]byte(k))
+	h.Write(d)
+	t.hashes[t.leaf(k)] += h.Sum64()
+	t.sealed = false
+}
+
+// seal computes inner nodes of the tree.
+func (t *MerkleTree) seal() {
+	var b [16]byte
+	for i := len(t.hashes)/2 - 1; i > 0; i-- {
+		binary.BigEndian.PutUint64(b[:8], t.hashes[2*i])
+		binary.BigEndian.PutUint64(b[8:], t.hashes[2*i+1])
+		h := fnv.New64a()
+		h.Write(b[:])
+		t.hashes[i] = h.Sum64()
+	}
+	t.sealed = true
+}
+
+// Hashes returns hashes of the tree in a heap order sealing it if needed.
+func (t *MerkleTree) Hashes() []uint64 {
+	if !t.sealed {
+		t.seal()
+	}
+	return t.hashes
+}
+
+// MerkleTreeFromHashes restores a sealed tree from its Hashes.
+func MerkleTreeFromHashes(hashes []uint64) (*MerkleTree, error) {
+	for depth := 0; depth <= MaxMerkleDepth; depth++ {
+		if len(hashes) == 2<<uint(depth) {
+			return &MerkleTree{depth: uint(depth), hashes: hashes, sealed: true}, nil
+		}
+	}
+	return nil, ErrInvalidMerkleTree
+}
+
+// Diff returns key ranges of leaves which differ between t and other.
+// Only subtrees whose hashes differ are descended into.
+func (t *MerkleTree) Diff(other *MerkleTree) ([]ScanOptions, error) {
+	if t.depth != other.depth {
+		return nil, ErrInvalidMerkleTree
+	}
+	a, b := t.Hashes(), other.Hashes()
+
+	var ranges []ScanOptions
+	var walk func(i int)
+	walk = func(i int) {
+		if a[i] == b[i] {
+			return
+		}
+		if i >= len(a)/2 {
+			ranges = append(ranges, t.leafRange(i))
+			return
+		}
+		walk(2 * i)
+		walk(2*i + 1)
+	}
+	walk(1)
+	return ranges, nil
+}
+
+// leaf returns the leaf of k: leaves split the key space by the first
+// bytes of keys.
+func (t *MerkleTree) leaf(k Key) int {
+	return 1<<t.depth + int(k.prefix()>>(32-t.depth))
+}
+
+// leafRange returns the key range covered by the leaf i, End of the last leaf
+// is empty as it's unbounded.
+func (t *MerkleTree) leafRange(i int) ScanOptions {
+	n := uint64(i - 1<<t.depth)
+	return ScanOptions{
+		Start: keyWithPrefix(n << (32 - t.depth)),
+		End:   keyWithPrefix((n + 1) << (32 - t.depth)),
+	}
+}
+
+// TreeOptions selects records a Merkle tree is built over.
+type TreeOptions struct {
+	// Peer is the node the tree is compared with.
+	Peer ServiceAddr
+	// Nodes is the topology the records are placed by: only records
+	// replicated to both Peer and the node building the tree are added.
+	Nodes []ServiceAddr
+	// Depth is the depth of the tree.
+	Depth int
+}
+
+// TreeStorage is a Storage which is able to build a Merkle tree over its records.
+type TreeStorage interface {
+	TreeContext(ctx context.Context, opts TreeOptions) (*MerkleTree, error)
+}
+
+// TreeClient is a Client which is able to request a Merkle tree from a node.
+type TreeClient interface {
+	Tree(node ServiceAddr, opts TreeOptions) (*MerkleTree, error)
+	TreeContext(ctx context.Context, node ServiceAddr, opts TreeOptions) (*MerkleTree, error)
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMerkleTree_Diff(t *testing.T) {
+	const depth = 4
+	a, b := NewMerkleTree(depth), NewMerkleTree(depth)
+	for i := 0; i < 100; i++ {
+		k := bigEndianKey(uint32(i) << 26)
+		d := []byte(fmt.Sprintf("data%d", i))
+		a.Add(k, d)
+		b.Add(k, d)
+	}
+	ranges, err := a.Diff(b)
+	if err != nil {
+		t.Fatalf("Diff() error: %v", err)
+	}
+	if len(ranges) != 0 {
+		t.Fatalf("Diff() of equal trees got %v", ranges)
+	}
+
+	// A record missing in the first leaf, a diverged one in the last.
+	a.Add(bigEndianKey(1), []byte("missing"))
+	a.Add(bigEndianKey(0xfffffff0), []byte("one"))
+	b.Add(bigEndianKey(0xfffffff0), []byte("other"))
+	want := []ScanOptions{
+		{Start: "", End: "\x10"},
+		{Start: "\xf0", End: ""},
+	}
+	ranges, err = a.Diff(b)
+	if err != nil {
+		t.Fatalf("Diff() error: %v", err)
+	}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("Diff() got %v, want %v", ranges, want)
+	}
+}
+
+func TestMerkleTree_Hashes(t *testing.T) {
+	tree := NewMerkleTree(3)
+	tree.Add("key", []byte("data"))
+	got, err := MerkleTreeFromHashes(tree.Hashes())
+	if err != nil {
+		t.Fatalf("MerkleTreeFromHashes() error: %v", err)
+	}
+	if got.Depth() != tree.Depth() {
+		t.Errorf("Got depth %d, want %d", got.Depth(), tree.Depth())
+	}
+	if ranges, _ := got.Diff(tree); len(ranges) != 0 {
+		t.Errorf("Restored tree differs in %v", ranges)
+	}
+	if _, err := MerkleTreeFromHashes(make([]uint64, 5)); err != ErrInvalidMerkleTree {
+		t.Errorf("MerkleTreeFromHashes() got error %v, want %v", err, ErrInvalidMerkleTree)
+	}
+	if _, err := tree.Diff(NewMerkleTree(4)); err != ErrInvalidMerkleTree {
+		t.Errorf("Diff() got error %v, want %v", err, ErrInvalidMerkleTree)
+	}
+}
+
+func TestMerkleTree_ByteKeys(t *testing.T) {
+	const depth = 8
+	a, b := NewMerkleTree(depth), NewMerkleTree(depth)
+	a.Add("apple", []byte("data"))
+	b.Add("apple", []byte("data"))
+	a.Add("a", []byte("short"))
+	b.Add("banana", []byte("data"))
+	ranges, err := a.Diff(b)
+	if err != nil {
+		t.Fatalf("Diff() error: %v", err)
+	}
+	want := []ScanOptions{
+		{Start: "a", End: "b"},
+		{Start: "b", End: "c"},
+	}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Fatalf("Diff() got %v, want %v", ranges, want)
+	}
+	for _, r := range ranges {
+		for _, k := range []Key{"a", "banana"} {
+			if r.Contains(k) != (a.leaf(k) == a.leaf(r.Start)) {
+				t.Errorf("Range [%q, %q) containing %q disagrees with its leaf", r.Start, r.End, k)
+			}
+		}
+	}
+}
+
+func bigEndianKey(n uint32) Key {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], n)
+	return Key(b[:])
+}
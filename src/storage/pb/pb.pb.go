@@ -23,18 +23,74 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
-type GetRequest struct {
-	Key                  uint32   `protobuf:"varint,1,opt,name=key,proto3" json:"key,omitempty"`
+type Consistency struct {
+	Level                int32    `protobuf:"varint,1,opt,name=level,proto3" json:"level,omitempty"`
+	R                    uint32   `protobuf:"varint,2,opt,name=r,proto3" json:"r,omitempty"`
+	W                    uint32   `protobuf:"varint,3,opt,name=w,proto3" json:"w,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
+func (m *Consistency) Reset()         { *m = Consistency{} }
+func (m *Consistency) String() string { return proto.CompactTextString(m) }
+func (*Consistency) ProtoMessage()    {}
+func (*Consistency) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_848324b33804a705, []int{0}
+}
+func (m *Consistency) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Consistency.Unmarshal(m, b)
+}
+func (m *Consistency) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Consistency.Marshal(b, m, deterministic)
+}
+func (dst *Consistency) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Consistency.Merge(dst, src)
+}
+func (m *Consistency) XXX_Size() int {
+	return xxx_messageInfo_Consistency.Size(m)
+}
+func (m *Consistency) XXX_DiscardUnknown() {
+	xxx_messageInfo_Consistency.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Consistency proto.InternalMessageInfo
+
+func (m *Consistency) GetLevel() int32 {
+	if m != nil {
+		return m.Level
+	}
+	return 0
+}
+
+func (m *Consistency) GetR() uint32 {
+	if m != nil {
+		return m.R
+	}
+	return 0
+}
+
+func (m *Consistency) GetW() uint32 {
+	if m != nil {
+		return m.W
+	}
+	return 0
+}
+
+type GetRequest struct {
+	Key                  uint32       `protobuf:"varint,1,opt,name=key,proto3" json:"key,omitempty"`
+	Consistency          *Consistency `protobuf:"bytes,2,opt,name=consistency,proto3" json:"consistency,omitempty"`
+	KeyBytes             []byte       `protobuf:"bytes,3,opt,name=key_bytes,json=keyBytes,proto3" json:"key_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
 func (m *GetRequest) Reset()         { *m = GetRequest{} }
 func (m *GetRequest) String() string { return proto.CompactTextString(m) }
 func (*GetRequest) ProtoMessage()    {}
 func (*GetRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_fd9dd68e056fbc00, []int{0}
+	return fileDescriptor_pb_848324b33804a705, []int{1}
 }
 func (m *GetRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetRequest.Unmarshal(m, b)
@@ -61,6 +117,20 @@ func (m *GetRequest) GetKey() uint32 {
 	return 0
 }
 
+func (m *GetRequest) GetConsistency() *Consistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return nil
+}
+
+func (m *GetRequest) GetKeyBytes() []byte {
+	if m != nil {
+		return m.KeyBytes
+	}
+	return nil
+}
+
 type GetReply struct {
 	Status               int32    `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
 	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
@@ -74,7 +144,7 @@ func (m *GetReply) Reset()         { *m = GetReply{} }
 func (m *GetReply) String() string { return proto.CompactTextString(m) }
 func (*GetReply) ProtoMessage()    {}
 func (*GetReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_fd9dd68e056fbc00, []int{1}
+	return fileDescriptor_pb_848324b33804a705, []int{2}
 }
 func (m *GetReply) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetReply.Unmarshal(m, b)
@@ -116,18 +186,22 @@ func (m *GetReply) GetData() []byte {
 }
 
 type PutRequest struct {
-	Key                  uint32   `protobuf:"varint,1,opt,name=key,proto3" json:"key,omitempty"`
-	Data                 []byte   `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Key                  uint32       `protobuf:"varint,1,opt,name=key,proto3" json:"key,omitempty"`
+	Data                 []byte       `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Hint                 string       `protobuf:"bytes,3,opt,name=hint,proto3" json:"hint,omitempty"`
+	Consistency          *Consistency `protobuf:"bytes,4,opt,name=consistency,proto3" json:"consistency,omitempty"`
+	Expires              int64        `protobuf:"varint,5,opt,name=expires,proto3" json:"expires,omitempty"`
+	KeyBytes             []byte       `protobuf:"bytes,6,opt,name=key_bytes,json=keyBytes,proto3" json:"key_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
 }
 
 func (m *PutRequest) Reset()         { *m = PutRequest{} }
 func (m *PutRequest) String() string { return proto.CompactTextString(m) }
 func (*PutRequest) ProtoMessage()    {}
 func (*PutRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_fd9dd68e056fbc00, []int{2}
+	return fileDescriptor_pb_848324b33804a705, []int{3}
 }
 func (m *PutRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_PutRequest.Unmarshal(m, b)
@@ -161,6 +235,34 @@ func (m *PutRequest) GetData() []byte {
 	return nil
 }
 
+func (m *PutRequest) GetHint() string {
+	if m != nil {
+		return m.Hint
+	}
+	return ""
+}
+
+func (m *PutRequest) GetConsistency() *Consistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return nil
+}
+
+func (m *PutRequest) GetExpires() int64 {
+	if m != nil {
+		return m.Expires
+	}
+	return 0
+}
+
+func (m *PutRequest) GetKeyBytes() []byte {
+	if m != nil {
+		return m.KeyBytes
+	}
+	return nil
+}
+
 type PutReply struct {
 	Status               int32    `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
 	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
@@ -173,7 +275,7 @@ func (m *PutReply) Reset()         { *m = PutReply{} }
 func (m *PutReply) String() string { return proto.CompactTextString(m) }
 func (*PutReply) ProtoMessage()    {}
 func (*PutReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_fd9dd68e056fbc00, []int{3}
+	return fileDescriptor_pb_848324b33804a705, []int{4}
 }
 func (m *PutReply) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_PutReply.Unmarshal(m, b)
@@ -208,17 +310,20 @@ func (m *PutReply) GetError() string {
 }
 
 type DelRequest struct {
-	Key                  uint32   `protobuf:"varint,1,opt,name=key,proto3" json:"key,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Key                  uint32       `protobuf:"varint,1,opt,name=key,proto3" json:"key,omitempty"`
+	Hint                 string       `protobuf:"bytes,2,opt,name=hint,proto3" json:"hint,omitempty"`
+	Consistency          *Consistency `protobuf:"bytes,3,opt,name=consistency,proto3" json:"consistency,omitempty"`
+	KeyBytes             []byte       `protobuf:"bytes,4,opt,name=key_bytes,json=keyBytes,proto3" json:"key_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
 }
 
 func (m *DelRequest) Reset()         { *m = DelRequest{} }
 func (m *DelRequest) String() string { return proto.CompactTextString(m) }
 func (*DelRequest) ProtoMessage()    {}
 func (*DelRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_fd9dd68e056fbc00, []int{4}
+	return fileDescriptor_pb_848324b33804a705, []int{5}
 }
 func (m *DelRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DelRequest.Unmarshal(m, b)
@@ -245,6 +350,27 @@ func (m *DelRequest) GetKey() uint32 {
 	return 0
 }
 
+func (m *DelRequest) GetHint() string {
+	if m != nil {
+		return m.Hint
+	}
+	return ""
+}
+
+func (m *DelRequest) GetConsistency() *Consistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return nil
+}
+
+func (m *DelRequest) GetKeyBytes() []byte {
+	if m != nil {
+		return m.KeyBytes
+	}
+	return nil
+}
+
 type DelReply struct {
 	Status               int32    `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
 	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
@@ -257,7 +383,7 @@ func (m *DelReply) Reset()         { *m = DelReply{} }
 func (m *DelReply) String() string { return proto.CompactTextString(m) }
 func (*DelReply) ProtoMessage()    {}
 func (*DelReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_pb_fd9dd68e056fbc00, []int{5}
+	return fileDescriptor_pb_848324b33804a705, []int{6}
 }
 func (m *DelReply) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DelReply.Unmarshal(m, b)
@@ -291,121 +417,1138 @@ func (m *DelReply) GetError() string {
 	return ""
 }
 
-func init() {
-	proto.RegisterType((*GetRequest)(nil), "GetRequest")
-	proto.RegisterType((*GetReply)(nil), "GetReply")
-	proto.RegisterType((*PutRequest)(nil), "PutRequest")
-	proto.RegisterType((*PutReply)(nil), "PutReply")
-	proto.RegisterType((*DelRequest)(nil), "DelRequest")
-	proto.RegisterType((*DelReply)(nil), "DelReply")
+type ScanRequest struct {
+	Start                uint32   `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End                  uint32   `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	Limit                uint32   `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	Token                string   `protobuf:"bytes,4,opt,name=token,proto3" json:"token,omitempty"`
+	StartKey             []byte   `protobuf:"bytes,5,opt,name=start_key,json=startKey,proto3" json:"start_key,omitempty"`
+	EndKey               []byte   `protobuf:"bytes,6,opt,name=end_key,json=endKey,proto3" json:"end_key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
+func (m *ScanRequest) Reset()         { *m = ScanRequest{} }
+func (m *ScanRequest) String() string { return proto.CompactTextString(m) }
+func (*ScanRequest) ProtoMessage()    {}
+func (*ScanRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_848324b33804a705, []int{7}
+}
+func (m *ScanRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ScanRequest.Unmarshal(m, b)
+}
+func (m *ScanRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ScanRequest.Marshal(b, m, deterministic)
+}
+func (dst *ScanRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ScanRequest.Merge(dst, src)
+}
+func (m *ScanRequest) XXX_Size() int {
+	return xxx_messageInfo_ScanRequest.Size(m)
+}
+func (m *ScanRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ScanRequest.DiscardUnknown(m)
+}
 
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
+var xxx_messageInfo_ScanRequest proto.InternalMessageInfo
 
-// StorageClient is the client API for Storage service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type StorageClient interface {
-	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetReply, error)
-	Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutReply, error)
-	Del(ctx context.Context, in *DelRequest, opts ...grpc.CallOption) (*DelReply, error)
+func (m *ScanRequest) GetStart() uint32 {
+	if m != nil {
+		return m.Start
+	}
+	return 0
 }
 
-type storageClient struct {
-	cc *grpc.ClientConn
+func (m *ScanRequest) GetEnd() uint32 {
+	if m != nil {
+		return m.End
+	}
+	return 0
 }
 
-func NewStorageClient(cc *grpc.ClientConn) StorageClient {
-	return &storageClient{cc}
+func (m *ScanRequest) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
 }
 
-func (c *storageClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetReply, error) {
-	out := new(GetReply)
-	err := c.cc.Invoke(ctx, "/Storage/Get", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *ScanRequest) GetToken() string {
+	if m != nil {
+		return m.Token
 	}
-	return out, nil
+	return ""
 }
 
-func (c *storageClient) Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutReply, error) {
-	out := new(PutReply)
-	err := c.cc.Invoke(ctx, "/Storage/Put", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *ScanRequest) GetStartKey() []byte {
+	if m != nil {
+		return m.StartKey
 	}
-	return out, nil
+	return nil
 }
 
-func (c *storageClient) Del(ctx context.Context, in *DelRequest, opts ...grpc.CallOption) (*DelReply, error) {
-	out := new(DelReply)
-	err := c.cc.Invoke(ctx, "/Storage/Del", in, out, opts...)
-	if err != nil {
-		return nil, err
+func (m *ScanRequest) GetEndKey() []byte {
+	if m != nil {
+		return m.EndKey
 	}
-	return out, nil
+	return nil
 }
 
-// StorageServer is the server API for Storage service.
-type StorageServer interface {
-	Get(context.Context, *GetRequest) (*GetReply, error)
-	Put(context.Context, *PutRequest) (*PutReply, error)
-	Del(context.Context, *DelRequest) (*DelReply, error)
+type Record struct {
+	Key                  uint32   `protobuf:"varint,1,opt,name=key,proto3" json:"key,omitempty"`
+	Data                 []byte   `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	KeyBytes             []byte   `protobuf:"bytes,3,opt,name=key_bytes,json=keyBytes,proto3" json:"key_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func RegisterStorageServer(s *grpc.Server, srv StorageServer) {
-	s.RegisterService(&_Storage_serviceDesc, srv)
+func (m *Record) Reset()         { *m = Record{} }
+func (m *Record) String() string { return proto.CompactTextString(m) }
+func (*Record) ProtoMessage()    {}
+func (*Record) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_848324b33804a705, []int{8}
+}
+func (m *Record) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Record.Unmarshal(m, b)
+}
+func (m *Record) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Record.Marshal(b, m, deterministic)
+}
+func (dst *Record) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Record.Merge(dst, src)
+}
+func (m *Record) XXX_Size() int {
+	return xxx_messageInfo_Record.Size(m)
+}
+func (m *Record) XXX_DiscardUnknown() {
+	xxx_messageInfo_Record.DiscardUnknown(m)
 }
 
-func _Storage_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetRequest)
-	if err := dec(in); err != nil {
-		return nil, err
+var xxx_messageInfo_Record proto.InternalMessageInfo
+
+func (m *Record) GetKey() uint32 {
+	if m != nil {
+		return m.Key
 	}
-	if interceptor == nil {
-		return srv.(StorageServer).Get(ctx, in)
+	return 0
+}
+
+func (m *Record) GetData() []byte {
+	if m != nil {
+		return m.Data
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/Storage/Get",
+	return nil
+}
+
+func (m *Record) GetKeyBytes() []byte {
+	if m != nil {
+		return m.KeyBytes
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(StorageServer).Get(ctx, req.(*GetRequest))
+	return nil
+}
+
+type ScanReply struct {
+	Status               int32     `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	Error                string    `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Records              []*Record `protobuf:"bytes,3,rep,name=records,proto3" json:"records,omitempty"`
+	Token                string    `protobuf:"bytes,4,opt,name=token,proto3" json:"token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *ScanReply) Reset()         { *m = ScanReply{} }
+func (m *ScanReply) String() string { return proto.CompactTextString(m) }
+func (*ScanReply) ProtoMessage()    {}
+func (*ScanReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_848324b33804a705, []int{9}
+}
+func (m *ScanReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ScanReply.Unmarshal(m, b)
+}
+func (m *ScanReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ScanReply.Marshal(b, m, deterministic)
+}
+func (dst *ScanReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ScanReply.Merge(dst, src)
+}
+func (m *ScanReply) XXX_Size() int {
+	return xxx_messageInfo_ScanReply.Size(m)
+}
+func (m *ScanReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ScanReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ScanReply proto.InternalMessageInfo
+
+func (m *ScanReply) GetStatus() int32 {
+	if m != nil {
+		return m.Status
 	}
-	return interceptor(ctx, in, info, handler)
+	return 0
 }
 
-func _Storage_Put_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(PutRequest)
-	if err := dec(in); err != nil {
-		return nil, err
+func (m *ScanReply) GetError() string {
+	if m != nil {
+		return m.Error
 	}
-	if interceptor == nil {
-		return srv.(StorageServer).Put(ctx, in)
+	return ""
+}
+
+func (m *ScanReply) GetRecords() []*Record {
+	if m != nil {
+		return m.Records
 	}
-	info := &grpc.UnaryServerInfo{
-		Server:     srv,
-		FullMethod: "/Storage/Put",
+	return nil
+}
+
+func (m *ScanReply) GetToken() string {
+	if m != nil {
+		return m.Token
 	}
-	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(StorageServer).Put(ctx, req.(*PutRequest))
+	return ""
+}
+
+type TreeRequest struct {
+	Peer                 string   `protobuf:"bytes,1,opt,name=peer,proto3" json:"peer,omitempty"`
+	Nodes                []string `protobuf:"bytes,2,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	Depth                uint32   `protobuf:"varint,3,opt,name=depth,proto3" json:"depth,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TreeRequest) Reset()         { *m = TreeRequest{} }
+func (m *TreeRequest) String() string { return proto.CompactTextString(m) }
+func (*TreeRequest) ProtoMessage()    {}
+func (*TreeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_848324b33804a705, []int{10}
+}
+func (m *TreeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TreeRequest.Unmarshal(m, b)
+}
+func (m *TreeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TreeRequest.Marshal(b, m, deterministic)
+}
+func (dst *TreeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TreeRequest.Merge(dst, src)
+}
+func (m *TreeRequest) XXX_Size() int {
+	return xxx_messageInfo_TreeRequest.Size(m)
+}
+func (m *TreeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TreeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TreeRequest proto.InternalMessageInfo
+
+func (m *TreeRequest) GetPeer() string {
+	if m != nil {
+		return m.Peer
 	}
-	return interceptor(ctx, in, info, handler)
+	return ""
 }
 
-func _Storage_Del_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DelRequest)
-	if err := dec(in); err != nil {
-		return nil, err
+func (m *TreeRequest) GetNodes() []string {
+	if m != nil {
+		return m.Nodes
 	}
-	if interceptor == nil {
-		return srv.(StorageServer).Del(ctx, in)
+	return nil
+}
+
+func (m *TreeRequest) GetDepth() uint32 {
+	if m != nil {
+		return m.Depth
+	}
+	return 0
+}
+
+type TreeReply struct {
+	Status               int32    `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Hashes               []uint64 `protobuf:"varint,3,rep,packed,name=hashes,proto3" json:"hashes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TreeReply) Reset()         { *m = TreeReply{} }
+func (m *TreeReply) String() string { return proto.CompactTextString(m) }
+func (*TreeReply) ProtoMessage()    {}
+func (*TreeReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_848324b33804a705, []int{11}
+}
+func (m *TreeReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TreeReply.Unmarshal(m, b)
+}
+func (m *TreeReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TreeReply.Marshal(b, m, deterministic)
+}
+func (dst *TreeReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TreeReply.Merge(dst, src)
+}
+func (m *TreeReply) XXX_Size() int {
+	return xxx_messageInfo_TreeReply.Size(m)
+}
+func (m *TreeReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_TreeReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TreeReply proto.InternalMessageInfo
+
+func (m *TreeReply) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *TreeReply) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *TreeReply) GetHashes() []uint64 {
+	if m != nil {
+		return m.Hashes
+	}
+	return nil
+}
+
+type Version struct {
+	Clock                map[string]uint64 `protobuf:"bytes,1,rep,name=clock,proto3" json:"clock,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Data                 []byte            `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Expires              int64             `protobuf:"varint,3,opt,name=expires,proto3" json:"expires,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Version) Reset()         { *m = Version{} }
+func (m *Version) String() string { return proto.CompactTextString(m) }
+func (*Version) ProtoMessage()    {}
+func (*Version) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_848324b33804a705, []int{12}
+}
+func (m *Version) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Version.Unmarshal(m, b)
+}
+func (m *Version) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Version.Marshal(b, m, deterministic)
+}
+func (dst *Version) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Version.Merge(dst, src)
+}
+func (m *Version) XXX_Size() int {
+	return xxx_messageInfo_Version.Size(m)
+}
+func (m *Version) XXX_DiscardUnknown() {
+	xxx_messageInfo_Version.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Version proto.InternalMessageInfo
+
+func (m *Version) GetClock() map[string]uint64 {
+	if m != nil {
+		return m.Clock
+	}
+	return nil
+}
+
+func (m *Version) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *Version) GetExpires() int64 {
+	if m != nil {
+		return m.Expires
+	}
+	return 0
+}
+
+type VersionsReply struct {
+	Status               int32      `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	Error                string     `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Versions             []*Version `protobuf:"bytes,3,rep,name=versions,proto3" json:"versions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *VersionsReply) Reset()         { *m = VersionsReply{} }
+func (m *VersionsReply) String() string { return proto.CompactTextString(m) }
+func (*VersionsReply) ProtoMessage()    {}
+func (*VersionsReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_848324b33804a705, []int{13}
+}
+func (m *VersionsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VersionsReply.Unmarshal(m, b)
+}
+func (m *VersionsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VersionsReply.Marshal(b, m, deterministic)
+}
+func (dst *VersionsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VersionsReply.Merge(dst, src)
+}
+func (m *VersionsReply) XXX_Size() int {
+	return xxx_messageInfo_VersionsReply.Size(m)
+}
+func (m *VersionsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_VersionsReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VersionsReply proto.InternalMessageInfo
+
+func (m *VersionsReply) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *VersionsReply) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *VersionsReply) GetVersions() []*Version {
+	if m != nil {
+		return m.Versions
+	}
+	return nil
+}
+
+type UpdateRequest struct {
+	Key                  uint32       `protobuf:"varint,1,opt,name=key,proto3" json:"key,omitempty"`
+	Version              *Version     `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Consistency          *Consistency `protobuf:"bytes,3,opt,name=consistency,proto3" json:"consistency,omitempty"`
+	KeyBytes             []byte       `protobuf:"bytes,4,opt,name=key_bytes,json=keyBytes,proto3" json:"key_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *UpdateRequest) Reset()         { *m = UpdateRequest{} }
+func (m *UpdateRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateRequest) ProtoMessage()    {}
+func (*UpdateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_848324b33804a705, []int{14}
+}
+func (m *UpdateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateRequest.Unmarshal(m, b)
+}
+func (m *UpdateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateRequest.Marshal(b, m, deterministic)
+}
+func (dst *UpdateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateRequest.Merge(dst, src)
+}
+func (m *UpdateRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateRequest.Size(m)
+}
+func (m *UpdateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateRequest proto.InternalMessageInfo
+
+func (m *UpdateRequest) GetKey() uint32 {
+	if m != nil {
+		return m.Key
+	}
+	return 0
+}
+
+func (m *UpdateRequest) GetVersion() *Version {
+	if m != nil {
+		return m.Version
+	}
+	return nil
+}
+
+func (m *UpdateRequest) GetConsistency() *Consistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return nil
+}
+
+func (m *UpdateRequest) GetKeyBytes() []byte {
+	if m != nil {
+		return m.KeyBytes
+	}
+	return nil
+}
+
+type Condition struct {
+	Absent               bool              `protobuf:"varint,1,opt,name=absent,proto3" json:"absent,omitempty"`
+	Clock                map[string]uint64 `protobuf:"bytes,2,rep,name=clock,proto3" json:"clock,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *Condition) Reset()         { *m = Condition{} }
+func (m *Condition) String() string { return proto.CompactTextString(m) }
+func (*Condition) ProtoMessage()    {}
+func (*Condition) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_848324b33804a705, []int{15}
+}
+func (m *Condition) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Condition.Unmarshal(m, b)
+}
+func (m *Condition) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Condition.Marshal(b, m, deterministic)
+}
+func (dst *Condition) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Condition.Merge(dst, src)
+}
+func (m *Condition) XXX_Size() int {
+	return xxx_messageInfo_Condition.Size(m)
+}
+func (m *Condition) XXX_DiscardUnknown() {
+	xxx_messageInfo_Condition.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Condition proto.InternalMessageInfo
+
+func (m *Condition) GetAbsent() bool {
+	if m != nil {
+		return m.Absent
+	}
+	return false
+}
+
+func (m *Condition) GetClock() map[string]uint64 {
+	if m != nil {
+		return m.Clock
+	}
+	return nil
+}
+
+type PutIfVersionRequest struct {
+	Key                  uint32       `protobuf:"varint,1,opt,name=key,proto3" json:"key,omitempty"`
+	Condition            *Condition   `protobuf:"bytes,2,opt,name=condition,proto3" json:"condition,omitempty"`
+	Version              *Version     `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	Consistency          *Consistency `protobuf:"bytes,4,opt,name=consistency,proto3" json:"consistency,omitempty"`
+	KeyBytes             []byte       `protobuf:"bytes,5,opt,name=key_bytes,json=keyBytes,proto3" json:"key_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *PutIfVersionRequest) Reset()         { *m = PutIfVersionRequest{} }
+func (m *PutIfVersionRequest) String() string { return proto.CompactTextString(m) }
+func (*PutIfVersionRequest) ProtoMessage()    {}
+func (*PutIfVersionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_848324b33804a705, []int{16}
+}
+func (m *PutIfVersionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PutIfVersionRequest.Unmarshal(m, b)
+}
+func (m *PutIfVersionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PutIfVersionRequest.Marshal(b, m, deterministic)
+}
+func (dst *PutIfVersionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PutIfVersionRequest.Merge(dst, src)
+}
+func (m *PutIfVersionRequest) XXX_Size() int {
+	return xxx_messageInfo_PutIfVersionRequest.Size(m)
+}
+func (m *PutIfVersionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PutIfVersionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PutIfVersionRequest proto.InternalMessageInfo
+
+func (m *PutIfVersionRequest) GetKey() uint32 {
+	if m != nil {
+		return m.Key
+	}
+	return 0
+}
+
+func (m *PutIfVersionRequest) GetCondition() *Condition {
+	if m != nil {
+		return m.Condition
+	}
+	return nil
+}
+
+func (m *PutIfVersionRequest) GetVersion() *Version {
+	if m != nil {
+		return m.Version
+	}
+	return nil
+}
+
+func (m *PutIfVersionRequest) GetConsistency() *Consistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return nil
+}
+
+func (m *PutIfVersionRequest) GetKeyBytes() []byte {
+	if m != nil {
+		return m.KeyBytes
+	}
+	return nil
+}
+
+type DeleteIfVersionRequest struct {
+	Key                  uint32       `protobuf:"varint,1,opt,name=key,proto3" json:"key,omitempty"`
+	Condition            *Condition   `protobuf:"bytes,2,opt,name=condition,proto3" json:"condition,omitempty"`
+	Consistency          *Consistency `protobuf:"bytes,3,opt,name=consistency,proto3" json:"consistency,omitempty"`
+	KeyBytes             []byte       `protobuf:"bytes,4,opt,name=key_bytes,json=keyBytes,proto3" json:"key_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *DeleteIfVersionRequest) Reset()         { *m = DeleteIfVersionRequest{} }
+func (m *DeleteIfVersionRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteIfVersionRequest) ProtoMessage()    {}
+func (*DeleteIfVersionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_848324b33804a705, []int{17}
+}
+func (m *DeleteIfVersionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteIfVersionRequest.Unmarshal(m, b)
+}
+func (m *DeleteIfVersionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteIfVersionRequest.Marshal(b, m, deterministic)
+}
+func (dst *DeleteIfVersionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteIfVersionRequest.Merge(dst, src)
+}
+func (m *DeleteIfVersionRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteIfVersionRequest.Size(m)
+}
+func (m *DeleteIfVersionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteIfVersionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteIfVersionRequest proto.InternalMessageInfo
+
+func (m *DeleteIfVersionRequest) GetKey() uint32 {
+	if m != nil {
+		return m.Key
+	}
+	return 0
+}
+
+func (m *DeleteIfVersionRequest) GetCondition() *Condition {
+	if m != nil {
+		return m.Condition
+	}
+	return nil
+}
+
+func (m *DeleteIfVersionRequest) GetConsistency() *Consistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return nil
+}
+
+func (m *DeleteIfVersionRequest) GetKeyBytes() []byte {
+	if m != nil {
+		return m.KeyBytes
+	}
+	return nil
+}
+
+type MultiKeysRequest struct {
+	Keys                 []uint32     `protobuf:"varint,1,rep,packed,name=keys,proto3" json:"keys,omitempty"`
+	Consistency          *Consistency `protobuf:"bytes,2,opt,name=consistency,proto3" json:"consistency,omitempty"`
+	KeyBytes             [][]byte     `protobuf:"bytes,3,rep,name=key_bytes,json=keyBytes,proto3" json:"key_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *MultiKeysRequest) Reset()         { *m = MultiKeysRequest{} }
+func (m *MultiKeysRequest) String() string { return proto.CompactTextString(m) }
+func (*MultiKeysRequest) ProtoMessage()    {}
+func (*MultiKeysRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_848324b33804a705, []int{18}
+}
+func (m *MultiKeysRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MultiKeysRequest.Unmarshal(m, b)
+}
+func (m *MultiKeysRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MultiKeysRequest.Marshal(b, m, deterministic)
+}
+func (dst *MultiKeysRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MultiKeysRequest.Merge(dst, src)
+}
+func (m *MultiKeysRequest) XXX_Size() int {
+	return xxx_messageInfo_MultiKeysRequest.Size(m)
+}
+func (m *MultiKeysRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MultiKeysRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MultiKeysRequest proto.InternalMessageInfo
+
+func (m *MultiKeysRequest) GetKeys() []uint32 {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+func (m *MultiKeysRequest) GetConsistency() *Consistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return nil
+}
+
+func (m *MultiKeysRequest) GetKeyBytes() [][]byte {
+	if m != nil {
+		return m.KeyBytes
+	}
+	return nil
+}
+
+type MultiPutRequest struct {
+	Records              []*Record    `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+	Consistency          *Consistency `protobuf:"bytes,2,opt,name=consistency,proto3" json:"consistency,omitempty"`
+	Expires              int64        `protobuf:"varint,3,opt,name=expires,proto3" json:"expires,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *MultiPutRequest) Reset()         { *m = MultiPutRequest{} }
+func (m *MultiPutRequest) String() string { return proto.CompactTextString(m) }
+func (*MultiPutRequest) ProtoMessage()    {}
+func (*MultiPutRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_848324b33804a705, []int{19}
+}
+func (m *MultiPutRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MultiPutRequest.Unmarshal(m, b)
+}
+func (m *MultiPutRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MultiPutRequest.Marshal(b, m, deterministic)
+}
+func (dst *MultiPutRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MultiPutRequest.Merge(dst, src)
+}
+func (m *MultiPutRequest) XXX_Size() int {
+	return xxx_messageInfo_MultiPutRequest.Size(m)
+}
+func (m *MultiPutRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MultiPutRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MultiPutRequest proto.InternalMessageInfo
+
+func (m *MultiPutRequest) GetRecords() []*Record {
+	if m != nil {
+		return m.Records
+	}
+	return nil
+}
+
+func (m *MultiPutRequest) GetConsistency() *Consistency {
+	if m != nil {
+		return m.Consistency
+	}
+	return nil
+}
+
+func (m *MultiPutRequest) GetExpires() int64 {
+	if m != nil {
+		return m.Expires
+	}
+	return 0
+}
+
+type Result struct {
+	Status               int32    `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Data                 []byte   `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Result) Reset()         { *m = Result{} }
+func (m *Result) String() string { return proto.CompactTextString(m) }
+func (*Result) ProtoMessage()    {}
+func (*Result) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_848324b33804a705, []int{20}
+}
+func (m *Result) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Result.Unmarshal(m, b)
+}
+func (m *Result) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Result.Marshal(b, m, deterministic)
+}
+func (dst *Result) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Result.Merge(dst, src)
+}
+func (m *Result) XXX_Size() int {
+	return xxx_messageInfo_Result.Size(m)
+}
+func (m *Result) XXX_DiscardUnknown() {
+	xxx_messageInfo_Result.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Result proto.InternalMessageInfo
+
+func (m *Result) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *Result) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *Result) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+type MultiReply struct {
+	Status               int32     `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	Error                string    `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Results              []*Result `protobuf:"bytes,3,rep,name=results,proto3" json:"results,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *MultiReply) Reset()         { *m = MultiReply{} }
+func (m *MultiReply) String() string { return proto.CompactTextString(m) }
+func (*MultiReply) ProtoMessage()    {}
+func (*MultiReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_pb_848324b33804a705, []int{21}
+}
+func (m *MultiReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MultiReply.Unmarshal(m, b)
+}
+func (m *MultiReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MultiReply.Marshal(b, m, deterministic)
+}
+func (dst *MultiReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MultiReply.Merge(dst, src)
+}
+func (m *MultiReply) XXX_Size() int {
+	return xxx_messageInfo_MultiReply.Size(m)
+}
+func (m *MultiReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_MultiReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MultiReply proto.InternalMessageInfo
+
+func (m *MultiReply) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *MultiReply) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *MultiReply) GetResults() []*Result {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Consistency)(nil), "Consistency")
+	proto.RegisterType((*GetRequest)(nil), "GetRequest")
+	proto.RegisterType((*GetReply)(nil), "GetReply")
+	proto.RegisterType((*PutRequest)(nil), "PutRequest")
+	proto.RegisterType((*PutReply)(nil), "PutReply")
+	proto.RegisterType((*DelRequest)(nil), "DelRequest")
+	proto.RegisterType((*DelReply)(nil), "DelReply")
+	proto.RegisterType((*ScanRequest)(nil), "ScanRequest")
+	proto.RegisterType((*Record)(nil), "Record")
+	proto.RegisterType((*ScanReply)(nil), "ScanReply")
+	proto.RegisterType((*TreeRequest)(nil), "TreeRequest")
+	proto.RegisterType((*TreeReply)(nil), "TreeReply")
+	proto.RegisterType((*Version)(nil), "Version")
+	proto.RegisterMapType((map[string]uint64)(nil), "Version.ClockEntry")
+	proto.RegisterType((*VersionsReply)(nil), "VersionsReply")
+	proto.RegisterType((*UpdateRequest)(nil), "UpdateRequest")
+	proto.RegisterType((*Condition)(nil), "Condition")
+	proto.RegisterMapType((map[string]uint64)(nil), "Condition.ClockEntry")
+	proto.RegisterType((*PutIfVersionRequest)(nil), "PutIfVersionRequest")
+	proto.RegisterType((*DeleteIfVersionRequest)(nil), "DeleteIfVersionRequest")
+	proto.RegisterType((*MultiKeysRequest)(nil), "MultiKeysRequest")
+	proto.RegisterType((*MultiPutRequest)(nil), "MultiPutRequest")
+	proto.RegisterType((*Result)(nil), "Result")
+	proto.RegisterType((*MultiReply)(nil), "MultiReply")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// StorageClient is the client API for Storage service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type StorageClient interface {
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetReply, error)
+	Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutReply, error)
+	Del(ctx context.Context, in *DelRequest, opts ...grpc.CallOption) (*DelReply, error)
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (Storage_ScanClient, error)
+	Tree(ctx context.Context, in *TreeRequest, opts ...grpc.CallOption) (*TreeReply, error)
+	GetVersions(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*VersionsReply, error)
+	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*PutReply, error)
+	PutIfVersion(ctx context.Context, in *PutIfVersionRequest, opts ...grpc.CallOption) (*PutReply, error)
+	DeleteIfVersion(ctx context.Context, in *DeleteIfVersionRequest, opts ...grpc.CallOption) (*DelReply, error)
+	Upsert(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*PutReply, error)
+	MultiGet(ctx context.Context, in *MultiKeysRequest, opts ...grpc.CallOption) (*MultiReply, error)
+	MultiPut(ctx context.Context, in *MultiPutRequest, opts ...grpc.CallOption) (*MultiReply, error)
+	MultiDel(ctx context.Context, in *MultiKeysRequest, opts ...grpc.CallOption) (*MultiReply, error)
+}
+
+type storageClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewStorageClient(cc *grpc.ClientConn) StorageClient {
+	return &storageClient{cc}
+}
+
+func (c *storageClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetReply, error) {
+	out := new(GetReply)
+	err := c.cc.Invoke(ctx, "/Storage/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageClient) Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutReply, error) {
+	out := new(PutReply)
+	err := c.cc.Invoke(ctx, "/Storage/Put", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageClient) Del(ctx context.Context, in *DelRequest, opts ...grpc.CallOption) (*DelReply, error) {
+	out := new(DelReply)
+	err := c.cc.Invoke(ctx, "/Storage/Del", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (Storage_ScanClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Storage_serviceDesc.Streams[0], "/Storage/Scan", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &storageScanClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Storage_ScanClient interface {
+	Recv() (*ScanReply, error)
+	grpc.ClientStream
+}
+
+type storageScanClient struct {
+	grpc.ClientStream
+}
+
+func (x *storageScanClient) Recv() (*ScanReply, error) {
+	m := new(ScanReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *storageClient) Tree(ctx context.Context, in *TreeRequest, opts ...grpc.CallOption) (*TreeReply, error) {
+	out := new(TreeReply)
+	err := c.cc.Invoke(ctx, "/Storage/Tree", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageClient) GetVersions(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*VersionsReply, error) {
+	out := new(VersionsReply)
+	err := c.cc.Invoke(ctx, "/Storage/GetVersions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageClient) Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*PutReply, error) {
+	out := new(PutReply)
+	err := c.cc.Invoke(ctx, "/Storage/Update", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageClient) PutIfVersion(ctx context.Context, in *PutIfVersionRequest, opts ...grpc.CallOption) (*PutReply, error) {
+	out := new(PutReply)
+	err := c.cc.Invoke(ctx, "/Storage/PutIfVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageClient) DeleteIfVersion(ctx context.Context, in *DeleteIfVersionRequest, opts ...grpc.CallOption) (*DelReply, error) {
+	out := new(DelReply)
+	err := c.cc.Invoke(ctx, "/Storage/DeleteIfVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageClient) Upsert(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*PutReply, error) {
+	out := new(PutReply)
+	err := c.cc.Invoke(ctx, "/Storage/Upsert", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageClient) MultiGet(ctx context.Context, in *MultiKeysRequest, opts ...grpc.CallOption) (*MultiReply, error) {
+	out := new(MultiReply)
+	err := c.cc.Invoke(ctx, "/Storage/MultiGet", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageClient) MultiPut(ctx context.Context, in *MultiPutRequest, opts ...grpc.CallOption) (*MultiReply, error) {
+	out := new(MultiReply)
+	err := c.cc.Invoke(ctx, "/Storage/MultiPut", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageClient) MultiDel(ctx context.Context, in *MultiKeysRequest, opts ...grpc.CallOption) (*MultiReply, error) {
+	out := new(MultiReply)
+	err := c.cc.Invoke(ctx, "/Storage/MultiDel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StorageServer is the server API for Storage service.
+type StorageServer interface {
+	Get(context.Context, *GetRequest) (*GetReply, error)
+	Put(context.Context, *PutRequest) (*PutReply, error)
+	Del(context.Context, *DelRequest) (*DelReply, error)
+	Scan(*ScanRequest, Storage_ScanServer) error
+	Tree(context.Context, *TreeRequest) (*TreeReply, error)
+	GetVersions(context.Context, *GetRequest) (*VersionsReply, error)
+	Update(context.Context, *UpdateRequest) (*PutReply, error)
+	PutIfVersion(context.Context, *PutIfVersionRequest) (*PutReply, error)
+	DeleteIfVersion(context.Context, *DeleteIfVersionRequest) (*DelReply, error)
+	Upsert(context.Context, *UpdateRequest) (*PutReply, error)
+	MultiGet(context.Context, *MultiKeysRequest) (*MultiReply, error)
+	MultiPut(context.Context, *MultiPutRequest) (*MultiReply, error)
+	MultiDel(context.Context, *MultiKeysRequest) (*MultiReply, error)
+}
+
+func RegisterStorageServer(s *grpc.Server, srv StorageServer) {
+	s.RegisterService(&_Storage_serviceDesc, srv)
+}
+
+func _Storage_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Storage/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storage_Put_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).Put(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Storage/Put",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).Put(ctx, req.(*PutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storage_Del_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).Del(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
@@ -417,6 +1560,189 @@ func _Storage_Del_Handler(srv interface{}, ctx context.Context, dec func(interfa
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Storage_Scan_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ScanRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StorageServer).Scan(m, &storageScanServer{stream})
+}
+
+type Storage_ScanServer interface {
+	Send(*ScanReply) error
+	grpc.ServerStream
+}
+
+type storageScanServer struct {
+	grpc.ServerStream
+}
+
+func (x *storageScanServer) Send(m *ScanReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Storage_Tree_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TreeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).Tree(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Storage/Tree",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).Tree(ctx, req.(*TreeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storage_GetVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).GetVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Storage/GetVersions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).GetVersions(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storage_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Storage/Update",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).Update(ctx, req.(*UpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storage_PutIfVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutIfVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).PutIfVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Storage/PutIfVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).PutIfVersion(ctx, req.(*PutIfVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storage_DeleteIfVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteIfVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).DeleteIfVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Storage/DeleteIfVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).DeleteIfVersion(ctx, req.(*DeleteIfVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storage_Upsert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).Upsert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Storage/Upsert",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).Upsert(ctx, req.(*UpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storage_MultiGet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MultiKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).MultiGet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Storage/MultiGet",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).MultiGet(ctx, req.(*MultiKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storage_MultiPut_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MultiPutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).MultiPut(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Storage/MultiPut",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).MultiPut(ctx, req.(*MultiPutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storage_MultiDel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MultiKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).MultiDel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Storage/MultiDel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).MultiDel(ctx, req.(*MultiKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Storage_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "Storage",
 	HandlerType: (*StorageServer)(nil),
@@ -433,28 +1759,114 @@ var _Storage_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Del",
 			Handler:    _Storage_Del_Handler,
 		},
+		{
+			MethodName: "Tree",
+			Handler:    _Storage_Tree_Handler,
+		},
+		{
+			MethodName: "GetVersions",
+			Handler:    _Storage_GetVersions_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _Storage_Update_Handler,
+		},
+		{
+			MethodName: "PutIfVersion",
+			Handler:    _Storage_PutIfVersion_Handler,
+		},
+		{
+			MethodName: "DeleteIfVersion",
+			Handler:    _Storage_DeleteIfVersion_Handler,
+		},
+		{
+			MethodName: "Upsert",
+			Handler:    _Storage_Upsert_Handler,
+		},
+		{
+			MethodName: "MultiGet",
+			Handler:    _Storage_MultiGet_Handler,
+		},
+		{
+			MethodName: "MultiPut",
+			Handler:    _Storage_MultiPut_Handler,
+		},
+		{
+			MethodName: "MultiDel",
+			Handler:    _Storage_MultiDel_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Scan",
+			Handler:       _Storage_Scan_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "pb.proto",
 }
 
-func init() { proto.RegisterFile("pb.proto", fileDescriptor_pb_fd9dd68e056fbc00) }
-
-var fileDescriptor_pb_fd9dd68e056fbc00 = []byte{
-	// 226 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x28, 0x48, 0xd2, 0x2b,
-	0x28, 0xca, 0x2f, 0xc9, 0x57, 0x92, 0xe3, 0xe2, 0x72, 0x4f, 0x2d, 0x09, 0x4a, 0x2d, 0x2c, 0x4d,
-	0x2d, 0x2e, 0x11, 0x12, 0xe0, 0x62, 0xce, 0x4e, 0xad, 0x94, 0x60, 0x54, 0x60, 0xd4, 0xe0, 0x0d,
-	0x02, 0x31, 0x95, 0x7c, 0xb8, 0x38, 0xc0, 0xf2, 0x05, 0x39, 0x95, 0x42, 0x62, 0x5c, 0x6c, 0xc5,
-	0x25, 0x89, 0x25, 0xa5, 0xc5, 0x60, 0x05, 0xac, 0x41, 0x50, 0x9e, 0x90, 0x08, 0x17, 0x6b, 0x6a,
-	0x51, 0x51, 0x7e, 0x91, 0x04, 0x93, 0x02, 0xa3, 0x06, 0x67, 0x10, 0x84, 0x23, 0x24, 0xc4, 0xc5,
-	0x92, 0x92, 0x58, 0x92, 0x28, 0xc1, 0xac, 0xc0, 0xa8, 0xc1, 0x13, 0x04, 0x66, 0x2b, 0x19, 0x71,
-	0x71, 0x05, 0x94, 0xe2, 0xb6, 0x0d, 0xae, 0x87, 0x09, 0x49, 0x8f, 0x05, 0x17, 0x07, 0x58, 0x0f,
-	0xc9, 0x2e, 0x00, 0xf9, 0xcd, 0x25, 0x35, 0x07, 0xb7, 0xdf, 0x2c, 0xb8, 0x38, 0xc0, 0xf2, 0x24,
-	0x9b, 0x6c, 0x94, 0xc3, 0xc5, 0x1e, 0x5c, 0x92, 0x5f, 0x94, 0x98, 0x9e, 0x2a, 0x24, 0xcf, 0xc5,
-	0xec, 0x9e, 0x5a, 0x22, 0xc4, 0xad, 0x87, 0x08, 0x46, 0x29, 0x4e, 0x3d, 0x58, 0x98, 0x29, 0x31,
-	0x80, 0x14, 0x04, 0x94, 0x82, 0x14, 0x20, 0x7c, 0x2e, 0xc5, 0xa9, 0x07, 0xf3, 0x12, 0x44, 0x81,
-	0x4b, 0x6a, 0x8e, 0x10, 0xb7, 0x1e, 0xc2, 0xb1, 0x52, 0x9c, 0x7a, 0x30, 0x97, 0x29, 0x31, 0x24,
-	0xb1, 0x81, 0xa3, 0xca, 0x18, 0x10, 0x00, 0x00, 0xff, 0xff, 0x38, 0xe5, 0xcc, 0x66, 0xb6, 0x01,
-	0x00, 0x00,
+func init() { proto.RegisterFile("pb.proto", fileDescriptor_pb_848324b33804a705) }
+
+var fileDescriptor_pb_848324b33804a705 = []byte{
+	// 932 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0xcf, 0x6f, 0xdc, 0x44,
+	0x14, 0xde, 0x59, 0x7b, 0x7f, 0xf8, 0x79, 0xb7, 0x0d, 0xd3, 0x90, 0xae, 0x96, 0x03, 0x61, 0x54,
+	0xa4, 0x45, 0xad, 0x46, 0x10, 0x0e, 0x44, 0x5c, 0x90, 0x48, 0x50, 0x04, 0xa1, 0xd2, 0x32, 0x05,
+	0x6e, 0xa8, 0x72, 0xd6, 0x0f, 0x62, 0xad, 0xb1, 0x17, 0xcf, 0x38, 0xc5, 0x17, 0xb8, 0x73, 0xe4,
+	0xc2, 0x85, 0x0b, 0x27, 0x4e, 0xfc, 0x13, 0xfc, 0x65, 0xd5, 0xcc, 0xd8, 0xb1, 0xbd, 0xcd, 0xa6,
+	0xdd, 0x24, 0xb7, 0xf9, 0xe6, 0xc7, 0x9b, 0xef, 0x7b, 0x6f, 0xde, 0x7b, 0x03, 0xc3, 0xd5, 0x19,
+	0x5f, 0x65, 0xa9, 0x4a, 0xd9, 0x67, 0xe0, 0x1f, 0xa5, 0x89, 0x8c, 0xa4, 0xc2, 0x64, 0x51, 0xd0,
+	0x5d, 0xe8, 0xc5, 0x78, 0x81, 0xf1, 0x84, 0xec, 0x93, 0x59, 0x4f, 0x58, 0x40, 0x47, 0x40, 0xb2,
+	0x49, 0x77, 0x9f, 0xcc, 0xc6, 0x82, 0x64, 0x1a, 0xbd, 0x98, 0x38, 0x16, 0xbd, 0x60, 0x4b, 0x80,
+	0x13, 0x54, 0x02, 0x7f, 0xc9, 0x51, 0x2a, 0xba, 0x03, 0xce, 0x12, 0x0b, 0x73, 0x7a, 0x2c, 0xf4,
+	0x90, 0x72, 0xf0, 0x17, 0xf5, 0x05, 0xc6, 0x8a, 0x7f, 0x30, 0xe2, 0x8d, 0x4b, 0x45, 0x73, 0x03,
+	0x7d, 0x07, 0xbc, 0x25, 0x16, 0xcf, 0xcf, 0x0a, 0x85, 0xd2, 0xdc, 0x32, 0x12, 0xc3, 0x25, 0x16,
+	0x9f, 0x6b, 0xcc, 0xbe, 0x86, 0xa1, 0xb9, 0x6c, 0x15, 0x17, 0x74, 0x0f, 0xfa, 0x52, 0x05, 0x2a,
+	0x97, 0x25, 0xd7, 0x12, 0x69, 0x09, 0x98, 0x65, 0xa9, 0x25, 0xec, 0x09, 0x0b, 0x28, 0x05, 0x37,
+	0x0c, 0x54, 0x50, 0x5a, 0x34, 0x63, 0xf6, 0x1f, 0x01, 0x98, 0xe7, 0xd7, 0x70, 0xaf, 0x0e, 0x75,
+	0xeb, 0x43, 0x7a, 0xee, 0x3c, 0x4a, 0x94, 0x31, 0xe4, 0x09, 0x33, 0x5e, 0xd7, 0xe8, 0xbe, 0x4e,
+	0xe3, 0x04, 0x06, 0xf8, 0xeb, 0x2a, 0xca, 0x50, 0x4e, 0x7a, 0xfb, 0x64, 0xe6, 0x88, 0x0a, 0xb6,
+	0xd5, 0xf7, 0xd7, 0xd4, 0x1f, 0xc2, 0x70, 0x9e, 0xdf, 0x44, 0x3d, 0xfb, 0x1d, 0xe0, 0x18, 0xe3,
+	0x6b, 0x85, 0x1a, 0x51, 0xdd, 0xcd, 0xa2, 0x9c, 0xad, 0x02, 0xe7, 0xbe, 0x4a, 0xdd, 0x10, 0xd8,
+	0x9e, 0xfa, 0x5f, 0x04, 0xfc, 0x67, 0x8b, 0x20, 0xa9, 0xc8, 0xef, 0x42, 0x4f, 0xaa, 0x20, 0x53,
+	0x25, 0x7d, 0x0b, 0xb4, 0x24, 0x4c, 0xc2, 0xf2, 0x8d, 0xea, 0xa1, 0x79, 0xc9, 0xd1, 0xcf, 0x91,
+	0x2a, 0x5f, 0xaa, 0x05, 0x7a, 0x56, 0xa5, 0x4b, 0x4c, 0x0c, 0x41, 0x4f, 0x58, 0xa0, 0xa9, 0x1b,
+	0x33, 0xcf, 0xb5, 0x5b, 0x7a, 0x96, 0xba, 0x99, 0x38, 0xc5, 0x82, 0x3e, 0x84, 0x01, 0x26, 0xa1,
+	0x59, 0xb2, 0x01, 0xe9, 0x63, 0x12, 0x9e, 0x62, 0xc1, 0x4e, 0xa1, 0x2f, 0x70, 0x91, 0x66, 0xe1,
+	0x1b, 0xbe, 0x9c, 0x6b, 0x5f, 0x76, 0x06, 0x9e, 0x55, 0xb9, 0xfd, 0xd3, 0x7e, 0x0f, 0x06, 0x99,
+	0xe1, 0xa1, 0xad, 0x3a, 0x33, 0xff, 0x60, 0xc0, 0x2d, 0x2f, 0x51, 0xcd, 0x5f, 0x2d, 0x9b, 0x3d,
+	0x05, 0xff, 0xdb, 0x0c, 0xb1, 0xf2, 0x2c, 0x05, 0x77, 0x85, 0x98, 0x99, 0x3b, 0x3d, 0x61, 0xc6,
+	0xfa, 0x60, 0x92, 0x86, 0x28, 0x27, 0xdd, 0x7d, 0x47, 0x1f, 0x34, 0x40, 0xcf, 0x86, 0xb8, 0x52,
+	0xe7, 0x95, 0x6f, 0x0d, 0x60, 0xdf, 0x80, 0x67, 0xcd, 0x6d, 0x2f, 0x61, 0x0f, 0xfa, 0xe7, 0x81,
+	0x3c, 0x47, 0xab, 0xc0, 0x15, 0x25, 0x62, 0x7f, 0x13, 0x18, 0x7c, 0x8f, 0x99, 0x8c, 0xd2, 0x84,
+	0x7e, 0x00, 0xbd, 0x45, 0x9c, 0x2e, 0x96, 0x13, 0x62, 0x44, 0x3e, 0xe0, 0xe5, 0x02, 0x3f, 0xd2,
+	0xb3, 0x5f, 0x24, 0x2a, 0x2b, 0x84, 0xdd, 0x71, 0xa5, 0xf7, 0x1b, 0x39, 0xe7, 0xb4, 0x72, 0x6e,
+	0x7a, 0x08, 0x50, 0x9b, 0x68, 0xc6, 0xd2, 0xb3, 0xb1, 0xdc, 0x85, 0xde, 0x45, 0x10, 0xe7, 0x68,
+	0xcc, 0xb9, 0xc2, 0x82, 0x4f, 0xbb, 0x87, 0x84, 0x2d, 0x60, 0x5c, 0x92, 0x90, 0x37, 0x51, 0xfd,
+	0x08, 0x86, 0x17, 0xe5, 0xf1, 0x32, 0x72, 0xc3, 0x4a, 0x94, 0xb8, 0x5c, 0x61, 0x7f, 0x12, 0x18,
+	0x7f, 0xb7, 0x0a, 0x03, 0x85, 0x9b, 0xf3, 0x97, 0xc1, 0xa0, 0xdc, 0x5f, 0x16, 0xd8, 0xda, 0x50,
+	0xb5, 0x70, 0xb7, 0xf9, 0xfc, 0x07, 0x01, 0xef, 0x28, 0x4d, 0xc2, 0x48, 0x69, 0xd3, 0x7b, 0xd0,
+	0x0f, 0xce, 0x24, 0x26, 0x36, 0x29, 0x87, 0xa2, 0x44, 0xf4, 0x71, 0x15, 0xb2, 0xae, 0x51, 0xf7,
+	0x36, 0xbf, 0x3c, 0xf2, 0x6a, 0xd0, 0x6e, 0x11, 0x86, 0xff, 0x09, 0x3c, 0x98, 0xe7, 0xea, 0xcb,
+	0x1f, 0x2b, 0xcd, 0x1b, 0xfd, 0x34, 0x03, 0x6f, 0x51, 0x51, 0x28, 0x3d, 0x05, 0x35, 0x29, 0x51,
+	0x2f, 0x36, 0x3d, 0xea, 0xbc, 0xa1, 0x47, 0xdd, 0xad, 0x3c, 0xda, 0x5b, 0xf3, 0xe8, 0x3f, 0x04,
+	0xf6, 0x8e, 0x31, 0x46, 0x85, 0x77, 0xaa, 0xe3, 0x4e, 0xa3, 0x2e, 0x61, 0xe7, 0x69, 0x1e, 0xab,
+	0xe8, 0x14, 0x0b, 0xd9, 0xa8, 0x1a, 0x4b, 0x2c, 0xa4, 0xc9, 0xca, 0xb1, 0x30, 0xe3, 0xdb, 0xf6,
+	0x7c, 0xa7, 0x75, 0xe9, 0x6f, 0x70, 0xdf, 0x5c, 0xda, 0xe8, 0xd4, 0x8d, 0x8a, 0x47, 0x36, 0x54,
+	0xbc, 0x6d, 0x29, 0x6c, 0x2c, 0x0f, 0xec, 0x2b, 0x5d, 0xe6, 0x65, 0x1e, 0xab, 0x3b, 0xf8, 0x71,
+	0xfc, 0x00, 0x60, 0xb4, 0xdc, 0xb8, 0xcc, 0x6b, 0x1e, 0xcd, 0x32, 0xaf, 0xb1, 0xa8, 0xe6, 0x0f,
+	0xfe, 0x75, 0x61, 0xf0, 0x4c, 0xa5, 0x59, 0xf0, 0x13, 0xd2, 0x77, 0xc1, 0x39, 0x41, 0x45, 0x7d,
+	0x5e, 0xff, 0xce, 0xa6, 0x1e, 0xaf, 0x7e, 0x4f, 0xac, 0xa3, 0x37, 0xcc, 0x73, 0xbd, 0xa1, 0x76,
+	0xec, 0xd4, 0xe3, 0xf3, 0xbc, 0xb9, 0xe1, 0x18, 0x63, 0xea, 0xf3, 0xfa, 0xeb, 0x30, 0xf5, 0x78,
+	0xd5, 0xc6, 0x59, 0x87, 0x3e, 0x02, 0x57, 0xf7, 0x2c, 0x3a, 0xe2, 0x8d, 0x06, 0x3d, 0x05, 0x7e,
+	0xd9, 0xc8, 0x58, 0xe7, 0x43, 0x42, 0x19, 0xb8, 0xba, 0x2d, 0xd0, 0x11, 0x6f, 0x34, 0x9b, 0x29,
+	0xf0, 0xcb, 0x5e, 0xc1, 0x3a, 0xf4, 0x09, 0xf8, 0x27, 0xa8, 0xaa, 0x5a, 0xda, 0x26, 0x7d, 0x8f,
+	0xb7, 0x6a, 0x2c, 0xeb, 0xd0, 0xf7, 0xa1, 0x6f, 0x0b, 0x22, 0xbd, 0xc7, 0x5b, 0x95, 0xb1, 0xcd,
+	0xff, 0x23, 0x18, 0x35, 0xab, 0x02, 0xdd, 0xe5, 0x57, 0x14, 0x89, 0xf6, 0x91, 0x4f, 0xe0, 0xfe,
+	0x5a, 0x0e, 0xd2, 0x87, 0xfc, 0xea, 0xac, 0x6c, 0xbb, 0xc2, 0x50, 0x92, 0x98, 0xa9, 0xeb, 0x29,
+	0x3d, 0x81, 0xa1, 0x89, 0xbf, 0x8e, 0xcc, 0x5b, 0x7c, 0x3d, 0x97, 0xa6, 0x3e, 0xaf, 0x5f, 0x07,
+	0xeb, 0xd0, 0xc7, 0xe5, 0x6e, 0x1d, 0xa6, 0x1d, 0xbe, 0x96, 0x04, 0xeb, 0x9b, 0x2b, 0xd3, 0x3a,
+	0x64, 0xaf, 0x35, 0x7d, 0xd6, 0x37, 0xbf, 0xff, 0x8f, 0x5f, 0x0e, 0x00, 0xc7, 0xd4, 0x2f, 0x44,
+	0x09, 0x0c, 0x00, 0x00,
 }
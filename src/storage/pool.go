@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultIdleTimeout is a time after which an unused pooled connection is closed.
+const DefaultIdleTimeout = time.Minute
+
+var ErrPoolClosed = errors.New("Connection pool is closed")
+
+type pooledConn struct {
+	conn     *grpc.ClientConn
+	refs     int
+	lastUsed time.Time
+	evicted  bool
+}
+
+// ConnPool is a concurrency-safe cache of gRPC connections keyed by ServiceAddr.
+// Connections unused for longer than the idle timeout are closed, connections
+// which failed with a transient error are redialed on the next request.
+type ConnPool struct {
+	idleTimeout time.Duration
+
+	lock   sync.Mutex
+	conns  map[ServiceAddr]*pooledConn
+	closed bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewConnPool creates a ConnPool closing connections idle for longer than idleTimeout.
+// DefaultIdleTimeout is used if idleTimeout is not positive.
+func NewConnPool(idleTimeout time.Duration) *ConnPool {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+	p := &ConnPool{
+		idleTimeout: idleTimeout,
+		conns:       make(map[ServiceAddr]*pooledConn),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go p.evictIdle()
+	return p
+}
+
+// Get returns a connection to addr dialing it if needed.
+// release must be called once the connection is not used anymore.
+func (p *ConnPool) Get(addr ServiceAddr) (conn *grpc.ClientConn, release func(), err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		return nil, nil, ErrPoolClosed
+	}
+
+	pc, ok := p.conns[addr]
+	if ok && pc.conn.GetState() == connectivity.Shutdown {
+		p.evictLocked(addr, pc)
+		ok = false
+	}
+	if !ok {
+		conn, err := grpc.Dial(string(addr), grpc.WithInsecure())
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error dialing %q: %v", addr, err)
+		}
+		pc = &pooledConn{conn: conn}
+		p.conns[addr] = pc
+	}
+
+	pc.refs++
+	pc.lastUsed = time.Now()
+	return pc.conn, func() { p.release(pc) }, nil
+}
+
+// Failed reports that a request over conn to addr finished with err.
+// The connection is dropped from the pool if err is a transient failure,
+// so that the next request dials a new one.
+func (p *ConnPool) Failed(addr ServiceAddr, conn *grpc.ClientConn, err error) {
+	if status.Code(err) != codes.Unavailable {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if pc, ok := p.conns[addr]; ok && pc.conn == conn {
+		p.evictLocked(addr, pc)
+	}
+}
+
+// Close closes all pooled connections.
+func (p *ConnPool) Close() error {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return nil
+	}
+	p.closed = true
+	for addr, pc := range p.conns {
+		delete(p.conns, addr)
+		pc.conn.Close()
+	}
+	p.lock.Unlock()
+
+	close(p.stop)
+	<-p.done
+	return nil
+}
+
+func (p *ConnPool) release(pc *pooledConn) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	pc.refs--
+	pc.lastUsed = time.Now()
+	if pc.evicted && pc.refs == 0 {
+		pc.conn.Close()
+	}
+}
+
+// evictLocked removes pc from the pool closing it once it's not in use,
+// the caller must hold p.lock.
+func (p *ConnPool) evictLocked(addr ServiceAddr, pc *pooledConn) {
+	delete(p.conns, addr)
+	pc.evicted = true
+	if pc.refs == 0 {
+		pc.conn.Close()
+	}
+}
+
+func (p *ConnPool) evictIdle() {
+	defer close(p.done)
+	t := time.NewTicker(p.idleTimeout / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			p.lock.Lock()
+			for addr, pc := range p.conns {
+				if pc.refs == 0 && time.Since(pc.lastUsed) > p.idleTimeout {
+					p.evictLocked(addr, pc)
+				}
+			}
+			p.lock.Unlock()
+		case <-p.stop:
+			return
+		}
+	}
+}
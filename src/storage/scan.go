@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+)
+
+// ScanBatch is a maximum number of records sent in a single Scan reply.
+const ScanBatch = 100
+
+// ScanOptions selects records enumerated by Scan.
+type ScanOptions struct {
+	// Start is the first key of the range.
+	Start Key
+	// End is the key following the last one of the range, empty means no upper bound.
+	End Key
+	// Limit is a maximum number of records to enumerate, zero means no limit.
+	Limit int
+	// Token resumes a scan stopped at Limit.
+	Token string
+}
+
+// From returns the key the scan starts from taking Token into account.
+func (o ScanOptions) From() (Key, error) {
+	if o.Token == "" {
+		return o.Start, nil
+	}
+	next, err := hex.DecodeString(o.Token)
+	if err != nil {
+		return "", ErrInvalidScanToken
+	}
+	if k := Key(next); k > o.Start {
+		return k, nil
+	}
+	return o.Start, nil
+}
+
+// Contains reports whether k is within the range.
+func (o ScanOptions) Contains(k Key) bool {
+	return k >= o.Start && (o.End == "" || k < o.End)
+}
+
+// ScanToken returns a token resuming a scan from the key next.
+func ScanToken(next Key) string {
+	return hex.EncodeToString([]byte(next))
+}
+
+// Record is a record enumerated by Scan or written by a batch.
+type Record struct {
+	Key  Key
+	Data []byte
+}
+
+// ScanStorage is a Storage which is able to enumerate its records.
+// ScanContext calls f for each record selected by opts in key order
+// and returns a token resuming the scan if it was stopped at opts.Limit.
+type ScanStorage interface {
+	ScanContext(ctx context.Context, opts ScanOptions, f func(k Key, d []byte) error) (string, error)
+}
+
+// ScanClient is a Client which is able to enumerate records held by a node.
+type ScanClient interface {
+	Scan(node ServiceAddr, opts ScanOptions, f func(k Key, d []byte) error) (string, error)
+	ScanContext(ctx context.Context, node ServiceAddr, opts ScanOptions, f func(k Key, d []byte) error) (string, error)
+}
@@ -20,6 +20,15 @@ type Storage interface {
 	Del(k RecordID) error
 }
 
+// ContextStorage is a Storage whose operations are bound to a context.
+// Server passes the request context to storages implementing it, the context
+// carries the Consistency the request was made with.
+type ContextStorage interface {
+	PutContext(ctx context.Context, k RecordID, d []byte) error
+	GetContext(ctx context.Context, k RecordID) ([]byte, error)
+	DelContext(ctx context.Context, k RecordID) error
+}
+
 type Server struct {
 	addr string
 	st   Storage
@@ -51,10 +60,11 @@ func (s *Server) Stop() {
 }
 
 func (s *Server) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetReply, error) {
-	key := RecordID(req.Key)
-	log.Printf("GET request: key = %v", key)
+	key := keyFromPB(req.Key, req.KeyBytes)
+	log.Printf("GET request: key = %s", FormatKey(key))
+	ctx = withConsistencyPB(ctx, req.Consistency)
 
-	data, err := s.st.Get(key)
+	data, err := keyStorage(s.st).GetKeyContext(ctx, key)
 	status := ErrToStatus(err)
 
 	reply := pb.GetReply{
@@ -70,10 +80,24 @@ func (s *Server) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetReply, err
 }
 
 func (s *Server) Put(ctx context.Context, req *pb.PutRequest) (*pb.PutReply, error) {
-	key := RecordID(req.Key)
-	log.Printf("PUT request: key = %v", key)
+	key := keyFromPB(req.Key, req.KeyBytes)
+	log.Printf("PUT request: key = %s", FormatKey(key))
+	ctx = withConsistencyPB(ctx, req.Consistency)
+	if req.Expires != 0 {
+		ctx = WithExpiry(ctx, time.Unix(0, req.Expires))
+	}
 
-	err := s.st.Put(key, req.Data)
+	var err error
+	if req.Hint != "" {
+		log.Printf("PUT request is hinted for %q", req.Hint)
+		if hs, ok := s.st.(HintStorage); ok {
+			err = hs.PutHintContext(ctx, ServiceAddr(req.Hint), key, req.Data)
+		} else {
+			err = ErrHintNotSupported
+		}
+	} else {
+		err = keyStorage(s.st).PutKeyContext(ctx, key, req.Data)
+	}
 	status := ErrToStatus(err)
 	reply := pb.PutReply{
 		Status: int32(status),
@@ -85,10 +109,21 @@ func (s *Server) Put(ctx context.Context, req *pb.PutRequest) (*pb.PutReply, err
 }
 
 func (s *Server) Del(ctx context.Context, req *pb.DelRequest) (*pb.DelReply, error) {
-	key := RecordID(req.Key)
-	log.Printf("DEL request: key = %v", key)
+	key := keyFromPB(req.Key, req.KeyBytes)
+	log.Printf("DEL request: key = %s", FormatKey(key))
+	ctx = withConsistencyPB(ctx, req.Consistency)
 
-	err := s.st.Del(key)
+	var err error
+	if req.Hint != "" {
+		log.Printf("DEL request is hinted for %q", req.Hint)
+		if hs, ok := s.st.(HintStorage); ok {
+			err = hs.DelHintContext(ctx, ServiceAddr(req.Hint), key)
+		} else {
+			err = ErrHintNotSupported
+		}
+	} else {
+		err = keyStorage(s.st).DelKeyContext(ctx, key)
+	}
 	status := ErrToStatus(err)
 	reply := pb.DelReply{
 		Status: int32(status),
@@ -98,3 +133,334 @@ func (s *Server) Del(ctx context.Context, req *pb.DelRequest) (*pb.DelReply, err
 	}
 	return &reply, nil
 }
+
+func (s *Server) Scan(req *pb.ScanRequest, stream pb.Storage_ScanServer) error {
+	opts := ScanOptions{
+		Start: Key(req.StartKey),
+		End:   Key(req.EndKey),
+		Limit: int(req.Limit),
+		Token: req.Token,
+	}
+	if opts.Start == "" && req.Start != 0 {
+		opts.Start = RecordID(req.Start).Key()
+	}
+	if opts.End == "" && req.End != 0 {
+		opts.End = RecordID(req.End).Key()
+	}
+	log.Printf("SCAN request: start = %s, end = %s, limit = %v", FormatKey(opts.Start), FormatKey(opts.End), opts.Limit)
+
+	reply := pb.ScanReply{}
+	var (
+		token string
+		err   error
+	)
+	if ss, ok := s.st.(ScanStorage); ok {
+		token, err = ss.ScanContext(stream.Context(), opts, func(k Key, d []byte) error {
+			key, keyBytes := keyToPB(k)
+			reply.Records = append(reply.Records, &pb.Record{Key: key, KeyBytes: keyBytes, Data: d})
+			if len(reply.Records) < ScanBatch {
+				return nil
+			}
+			if err := stream.Send(&reply); err != nil {
+				return err
+			}
+			reply.Records = nil
+			return nil
+		})
+	} else {
+		err = ErrScanNotSupported
+	}
+
+	status := ErrToStatus(err)
+	reply.Status = int32(status)
+	reply.Token = token
+	if status == StatusUnknown {
+		reply.Error = err.Error()
+	}
+	return stream.Send(&reply)
+}
+
+func (s *Server) Tree(ctx context.Context, req *pb.TreeRequest) (*pb.TreeReply, error) {
+	opts := TreeOptions{
+		Peer:  ServiceAddr(req.Peer),
+		Depth: int(req.Depth),
+	}
+	for _, node := range req.Nodes {
+		opts.Nodes = append(opts.Nodes, ServiceAddr(node))
+	}
+	log.Printf("TREE request: peer = %q, depth = %v", opts.Peer, opts.Depth)
+
+	var (
+		tree *MerkleTree
+		err  error
+	)
+	if ts, ok := s.st.(TreeStorage); ok {
+		tree, err = ts.TreeContext(ctx, opts)
+	} else {
+		err = ErrTreeNotSupported
+	}
+	status := ErrToStatus(err)
+	reply := pb.TreeReply{
+		Status: int32(status),
+	}
+	if err == nil {
+		reply.Hashes = tree.Hashes()
+	}
+	if status == StatusUnknown {
+		reply.Error = err.Error()
+	}
+	return &reply, nil
+}
+
+func (s *Server) GetVersions(ctx context.Context, req *pb.GetRequest) (*pb.VersionsReply, error) {
+	key := keyFromPB(req.Key, req.KeyBytes)
+	log.Printf("GETVERSIONS request: key = %s", FormatKey(key))
+	ctx = withConsistencyPB(ctx, req.Consistency)
+
+	var (
+		versions []Version
+		err      error
+	)
+	if vs, ok := s.st.(VersionedStorage); ok {
+		versions, err = vs.GetVersionsContext(ctx, key)
+	} else {
+		err = ErrVersionsNotSupported
+	}
+	status := ErrToStatus(err)
+	reply := pb.VersionsReply{
+		Status: int32(status),
+	}
+	for _, v := range versions {
+		reply.Versions = append(reply.Versions, versionToPB(v))
+	}
+	if status == StatusUnknown {
+		reply.Error = err.Error()
+	}
+	return &reply, nil
+}
+
+func (s *Server) Update(ctx context.Context, req *pb.UpdateRequest) (*pb.PutReply, error) {
+	key := keyFromPB(req.Key, req.KeyBytes)
+	log.Printf("UPDATE request: key = %s", FormatKey(key))
+	ctx = withConsistencyPB(ctx, req.Consistency)
+
+	var err error
+	if vs, ok := s.st.(VersionedStorage); ok {
+		err = vs.UpdateContext(ctx, key, versionFromPB(req.Version))
+	} else {
+		err = ErrVersionsNotSupported
+	}
+	status := ErrToStatus(err)
+	reply := pb.PutReply{
+		Status: int32(status),
+	}
+	if status == StatusUnknown {
+		reply.Error = err.Error()
+	}
+	return &reply, nil
+}
+
+func (s *Server) PutIfVersion(ctx context.Context, req *pb.PutIfVersionRequest) (*pb.PutReply, error) {
+	key := keyFromPB(req.Key, req.KeyBytes)
+	log.Printf("PUT IF VERSION request: key = %s", FormatKey(key))
+	ctx = withConsistencyPB(ctx, req.Consistency)
+
+	var err error
+	if cs, ok := s.st.(ConditionalStorage); ok {
+		err = cs.PutIfVersionContext(ctx, key, conditionFromPB(req.Condition), versionFromPB(req.Version))
+	} else {
+		err = ErrConditionalNotSupported
+	}
+	status := ErrToStatus(err)
+	reply := pb.PutReply{
+		Status: int32(status),
+	}
+	if status == StatusUnknown {
+		reply.Error = err.Error()
+	}
+	return &reply, nil
+}
+
+func (s *Server) DeleteIfVersion(ctx context.Context, req *pb.DeleteIfVersionRequest) (*pb.DelReply, error) {
+	key := keyFromPB(req.Key, req.KeyBytes)
+	log.Printf("DEL IF VERSION request: key = %s", FormatKey(key))
+	ctx = withConsistencyPB(ctx, req.Consistency)
+
+	var err error
+	if cs, ok := s.st.(ConditionalStorage); ok {
+		err = cs.DeleteIfVersionContext(ctx, key, conditionFromPB(req.Condition))
+	} else {
+		err = ErrConditionalNotSupported
+	}
+	status := ErrToStatus(err)
+	reply := pb.DelReply{
+		Status: int32(status),
+	}
+	if status == StatusUnknown {
+		reply.Error = err.Error()
+	}
+	return &reply, nil
+}
+
+func (s *Server) Upsert(ctx context.Context, req *pb.UpdateRequest) (*pb.PutReply, error) {
+	key := keyFromPB(req.Key, req.KeyBytes)
+	log.Printf("UPSERT request: key = %s", FormatKey(key))
+	ctx = withConsistencyPB(ctx, req.Consistency)
+
+	var err error
+	if cs, ok := s.st.(ConditionalStorage); ok {
+		err = cs.UpsertContext(ctx, key, versionFromPB(req.Version))
+	} else {
+		err = ErrConditionalNotSupported
+	}
+	status := ErrToStatus(err)
+	reply := pb.PutReply{
+		Status: int32(status),
+	}
+	if status == StatusUnknown {
+		reply.Error = err.Error()
+	}
+	return &reply, nil
+}
+
+func (s *Server) MultiGet(ctx context.Context, req *pb.MultiKeysRequest) (*pb.MultiReply, error) {
+	log.Printf("MULTI GET request: %d keys", len(req.Keys))
+	ctx = withConsistencyPB(ctx, req.Consistency)
+	results, err := s.batch().MultiGetContext(ctx, keysFromPB(req))
+	return multiReply(results, err), nil
+}
+
+func (s *Server) MultiPut(ctx context.Context, req *pb.MultiPutRequest) (*pb.MultiReply, error) {
+	log.Printf("MULTI PUT request: %d records", len(req.Records))
+	ctx = withConsistencyPB(ctx, req.Consistency)
+	if req.Expires != 0 {
+		ctx = WithExpiry(ctx, time.Unix(0, req.Expires))
+	}
+	records := make([]Record, len(req.Records))
+	for i, r := range req.Records {
+		records[i] = Record{Key: keyFromPB(r.Key, r.KeyBytes), Data: r.Data}
+	}
+	results, err := s.batch().MultiPutContext(ctx, records)
+	return multiReply(results, err), nil
+}
+
+func (s *Server) MultiDel(ctx context.Context, req *pb.MultiKeysRequest) (*pb.MultiReply, error) {
+	log.Printf("MULTI DEL request: %d keys", len(req.Keys))
+	ctx = withConsistencyPB(ctx, req.Consistency)
+	results, err := s.batch().MultiDelContext(ctx, keysFromPB(req))
+	return multiReply(results, err), nil
+}
+
+// batch returns the storage as a BatchStorage.
+func (s *Server) batch() BatchStorage {
+	if bs, ok := s.st.(BatchStorage); ok {
+		return bs
+	}
+	return batchStorage{keyStorage(s.st)}
+}
+
+func keysFromPB(req *pb.MultiKeysRequest) []Key {
+	if len(req.KeyBytes) != 0 {
+		res := make([]Key, len(req.KeyBytes))
+		for i, k := range req.KeyBytes {
+			res[i] = Key(k)
+		}
+		return res
+	}
+	res := make([]Key, len(req.Keys))
+	for i, k := range req.Keys {
+		res[i] = RecordID(k).Key()
+	}
+	return res
+}
+
+// keyFromPB returns the key a request is made with: the byte key if it's
+// set, the key of the RecordID otherwise.
+func keyFromPB(key uint32, keyBytes []byte) Key {
+	if len(keyBytes) != 0 {
+		return Key(keyBytes)
+	}
+	return RecordID(key).Key()
+}
+
+func multiReply(results []Result, err error) *pb.MultiReply {
+	status := ErrToStatus(err)
+	reply := pb.MultiReply{
+		Status: int32(status),
+	}
+	if status == StatusUnknown {
+		reply.Error = err.Error()
+	}
+	for _, r := range results {
+		status := ErrToStatus(r.Err)
+		res := &pb.Result{
+			Status: int32(status),
+			Data:   r.Data,
+		}
+		if status == StatusUnknown {
+			res.Error = r.Err.Error()
+		}
+		reply.Results = append(reply.Results, res)
+	}
+	return &reply
+}
+
+func versionToPB(v Version) *pb.Version {
+	return &pb.Version{
+		Clock:   v.Clock,
+		Data:    v.Data,
+		Expires: v.Expires,
+	}
+}
+
+func versionFromPB(v *pb.Version) Version {
+	if v == nil {
+		return Version{Clock: VectorClock{}}
+	}
+	clock := VectorClock(v.Clock)
+	if clock == nil {
+		clock = VectorClock{}
+	}
+	return Version{Clock: clock, Data: v.Data, Expires: v.Expires}
+}
+
+func conditionToPB(c Condition) *pb.Condition {
+	return &pb.Condition{
+		Absent: c.Absent,
+		Clock:  c.Clock,
+	}
+}
+
+func conditionFromPB(c *pb.Condition) Condition {
+	if c == nil {
+		return Condition{Clock: VectorClock{}}
+	}
+	clock := VectorClock(c.Clock)
+	if clock == nil {
+		clock = VectorClock{}
+	}
+	return Condition{Absent: c.Absent, Clock: clock}
+}
+
+func consistencyToPB(c Consistency) *pb.Consistency {
+	if c == (Consistency{}) {
+		return nil
+	}
+	return &pb.Consistency{
+		Level: int32(c.Level),
+		R:     uint32(c.R),
+		W:     uint32(c.W),
+	}
+}
+
+// withConsistencyPB returns ctx carrying the consistency sent with a request.
+func withConsistencyPB(ctx context.Context, c *pb.Consistency) context.Context {
+	if c == nil {
+		return ctx
+	}
+	return WithConsistency(ctx, Consistency{
+		Level: Level(c.Level),
+		R:     int(c.R),
+		W:     int(c.W),
+	})
+}
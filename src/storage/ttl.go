@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+type expiryKey struct{}
+
+// WithTTL returns a copy of ctx making records put with the context expire
+// after ttl. The expiry time is computed once, so all replicas of a record
+// expire at the same time.
+func WithTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return WithExpiry(ctx, time.Now().Add(ttl))
+}
+
+// WithExpiry returns a copy of ctx making records put with the context expire
+// at t. Client sends it along with Put requests, and Server passes it
+// to a ContextStorage.
+func WithExpiry(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, expiryKey{}, t.UnixNano())
+}
+
+// ExpiryFromContext returns the expiry time carried by ctx in Unix nanoseconds,
+// zero if records put with ctx never expire.
+func ExpiryFromContext(ctx context.Context) int64 {
+	t, _ := ctx.Value(expiryKey{}).(int64)
+	return t
+}
+
+// Expired reports whether v has expired by now.
+func (v Version) Expired(now time.Time) bool {
+	return v.Expires != 0 && v.Expires <= now.UnixNano()
+}
+
+// Unexpired returns versions which haven't expired by now.
+func Unexpired(versions []Version, now time.Time) []Version {
+	var res []Version
+	for _, v := range versions {
+		if !v.Expired(now) {
+			res = append(res, v)
+		}
+	}
+	return res
+}
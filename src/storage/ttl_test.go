@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUnexpired(t *testing.T) {
+	now := time.Now()
+	versions := []Version{
+		{Data: []byte("forever")},
+		{Data: []byte("expired"), Expires: now.Add(-time.Second).UnixNano()},
+		{Data: []byte("expiring"), Expires: now.Add(time.Second).UnixNano()},
+		{Data: []byte("just expired"), Expires: now.UnixNano()},
+	}
+	want := []Version{versions[0], versions[2]}
+	if got := Unexpired(versions, now); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpired() = %v, want %v", got, want)
+	}
+	if got := Unexpired(versions[1:2], now); got != nil {
+		t.Errorf("Unexpired() = %v, want nil", got)
+	}
+}
+
+func TestWithTTL(t *testing.T) {
+	if got := ExpiryFromContext(context.Background()); got != 0 {
+		t.Errorf("ExpiryFromContext() = %v, want 0", got)
+	}
+	before := time.Now()
+	got := ExpiryFromContext(WithTTL(context.Background(), time.Minute))
+	if got < before.Add(time.Minute).UnixNano() || got > time.Now().Add(time.Minute).UnixNano() {
+		t.Errorf("ExpiryFromContext() = %v, want a minute after %v", time.Unix(0, got), before)
+	}
+}
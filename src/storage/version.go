@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"sort"
+)
+
+// VectorClock is a version of a record: a counter of updates per writer
+// (a frontend or a node) which made them.
+type VectorClock map[string]uint64
+
+// Ordering is a causal relation between two VectorClocks.
+type Ordering int
+
+const (
+	Equal Ordering = iota
+	Before
+	After
+	Concurrent
+)
+
+// Compare returns the causal relation of c to o: Before if c happened before o,
+// After if c happened after o.
+func (c VectorClock) Compare(o VectorClock) Ordering {
+	less, greater := false, false
+	for id, n := range c {
+		if m := o[id]; n < m {
+			less = true
+		} else if n > m {
+			greater = true
+		}
+	}
+	for id, m := range o {
+		if _, ok := c[id]; !ok && m > 0 {
+			less = true
+		}
+	}
+	switch {
+	case less && greater:
+		return Concurrent
+	case less:
+		return Before
+	case greater:
+		return After
+	default:
+		return Equal
+	}
+}
+
+// Merge returns a clock descending both c and o.
+func (c VectorClock) Merge(o VectorClock) VectorClock {
+	res := make(VectorClock, len(c))
+	for id, n := range c {
+		res[id] = n
+	}
+	for id, m := range o {
+		if m > res[id] {
+			res[id] = m
+		}
+	}
+	return res
+}
+
+// Increment returns a copy of c with the counter of id incremented.
+func (c VectorClock) Increment(id string) VectorClock {
+	res := c.Merge(nil)
+	res[id]++
+	return res
+}
+
+// Version is a record data along with its clock. Expires is the time
+// the version expires at in Unix nanoseconds, zero if it never expires.
+type Version struct {
+	Clock   VectorClock
+	Data    []byte
+	Expires int64
+}
+
+// MergedClock returns a clock descending clocks of all versions. An update
+// with it supersedes all of them.
+func MergedClock(versions []Version) VectorClock {
+	res := VectorClock{}
+	for _, v := range versions {
+		res = res.Merge(v.Clock)
+	}
+	return res
+}
+
+// Reconcile merges sets of versions leaving only the ones which are
+// not superseded by others. Equal versions are left once. Versions with
+// equal clocks but different data are kept as siblings, so the result
+// doesn't depend on the order of sets.
+func Reconcile(sets ...[]Version) []Version {
+	var res []Version
+	for _, set := range sets {
+		for _, v := range set {
+			res = addVersion(res, v)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return versionLess(res[i], res[j]) })
+	return res
+}
+
+func addVersion(versions []Version, v Version) []Version {
+	var res []Version
+	for _, s := range versions {
+		switch v.Clock.Compare(s.Clock) {
+		case Before:
+			return versions
+		case Equal:
+			if string(v.Data) == string(s.Data) {
+				return versions
+			}
+			res = append(res, s)
+		case Concurrent:
+			res = append(res, s)
+		}
+	}
+	return append(res, v)
+}
+
+// versionLess orders versions canonically by their clocks.
+func versionLess(a, b Version) bool {
+	ids := make([]string, 0, len(a.Clock)+len(b.Clock))
+	for id := range a.Clock {
+		ids = append(ids, id)
+	}
+	for id := range b.Clock {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if a.Clock[id] != b.Clock[id] {
+			return a.Clock[id] > b.Clock[id]
+		}
+	}
+	return string(a.Data) < string(b.Data)
+}
+
+// VersionedStorage is a Storage keeping versions of records.
+// UpdateContext stores v superseding the versions it descends, a version
+// concurrent with the stored ones is kept along with them. Returns
+// ErrObsoleteVersion if a stored version descends v.
+type VersionedStorage interface {
+	GetVersionsContext(ctx context.Context, k Key) ([]Version, error)
+	UpdateContext(ctx context.Context, k Key, v Version) error
+}
+
+// VersionedClient is a Client which is able to read and update versions of records.
+type VersionedClient interface {
+	GetVersionsContext(ctx context.Context, node ServiceAddr, k Key) ([]Version, error)
+	UpdateContext(ctx context.Context, node ServiceAddr, k Key, v Version) error
+}
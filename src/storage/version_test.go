@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVectorClock_Compare(t *testing.T) {
+	tests := []struct {
+		a, b VectorClock
+		want Ordering
+	}{
+		{VectorClock{}, VectorClock{}, Equal},
+		{VectorClock{"a": 0}, VectorClock{}, Equal},
+		{VectorClock{"a": 1}, VectorClock{"a": 1}, Equal},
+		{VectorClock{}, VectorClock{"a": 1}, Before},
+		{VectorClock{"a": 1}, VectorClock{"a": 2, "b": 1}, Before},
+		{VectorClock{"a": 2, "b": 1}, VectorClock{"a": 1}, After},
+		{VectorClock{"a": 2}, VectorClock{"a": 1, "b": 1}, Concurrent},
+	}
+	for _, test := range tests {
+		if got := test.a.Compare(test.b); got != test.want {
+			t.Errorf("%v.Compare(%v) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	v1 := Version{Clock: VectorClock{"a": 1}, Data: []byte("v1")}
+	v2 := Version{Clock: VectorClock{"a": 2}, Data: []byte("v2")}
+	v3 := Version{Clock: VectorClock{"a": 1, "b": 1}, Data: []byte("v3")}
+
+	got := Reconcile([]Version{v1, v2}, []Version{v3, v1})
+	want := Reconcile([]Version{v3}, []Version{v2})
+	if !reflect.DeepEqual(got, want) || len(got) != 2 {
+		t.Errorf("Reconcile() got %v, want %v and %v", got, v2, v3)
+	}
+
+	merged := Version{Clock: MergedClock(got).Increment("a"), Data: []byte("v4")}
+	if c := merged.Clock; c.Compare(v2.Clock) != After || c.Compare(v3.Clock) != After {
+		t.Errorf("Clock %v doesn't descend %v and %v", c, v2.Clock, v3.Clock)
+	}
+	if got := Reconcile(got, []Version{merged}); !reflect.DeepEqual(got, []Version{merged}) {
+		t.Errorf("Reconcile() got %v, want %v", got, merged)
+	}
+
+	// Versions written with the same clock are kept regardless of the order.
+	v5 := Version{Clock: v2.Clock, Data: []byte("v5")}
+	got = Reconcile([]Version{v2}, []Version{v5, v1})
+	if want := Reconcile([]Version{v5}, []Version{v2}); !reflect.DeepEqual(got, want) || len(got) != 2 {
+		t.Errorf("Reconcile() got %v, want %v and %v", got, v2, v5)
+	}
+}
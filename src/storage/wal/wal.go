@@ -0,0 +1,462 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"storage"
+)
+
+// SyncMode is a policy of flushing the log to a stable storage.
+//
+// SyncMode -- политика сброса лога на диск.
+type SyncMode string
+
+const (
+	// SyncAlways fsyncs the log after every appended record.
+	// SyncAlways -- fsync после каждой записи.
+	SyncAlways SyncMode = "always"
+	// SyncInterval fsyncs the log every Options.SyncInterval.
+	// SyncInterval -- fsync через каждый Options.SyncInterval.
+	SyncInterval SyncMode = "interval"
+	// SyncNone leaves flushing to the operating system.
+	// SyncNone -- сброс на диск остается на усмотрение операционной системы.
+	SyncNone SyncMode = "none"
+)
+
+// DefaultSyncInterval is used with SyncInterval if Options.SyncInterval is not set.
+//
+// DefaultSyncInterval используется с SyncInterval, если Options.SyncInterval не задан.
+const DefaultSyncInterval = time.Second
+
+const (
+	logName      = "wal.log"
+	snapshotName = "snapshot"
+	snapshotTmp  = "snapshot.tmp"
+
+	headerSize = 8
+	// maxRecordSize protects recovery from allocating huge buffers
+	// because of a garbage length in a torn record.
+	maxRecordSize = 64 << 20
+)
+
+var (
+	ErrUnknownSyncMode   = errors.New("Unknown sync mode")
+	ErrCorruptedRecord   = errors.New("Corrupted record")
+	ErrCorruptedSnapshot = errors.New("Corrupted snapshot")
+	ErrLogFailed         = errors.New("Log failed")
+)
+
+// Op is a kind of change recorded in the log.
+//
+// Op -- тип изменения, записанного в лог.
+type Op byte
+
+const (
+	OpPut Op = iota + 1
+	OpDel
+)
+
+// opKeyed marks an encoded op followed by a length-prefixed key. Ops of
+// four-byte keys are encoded without it, as they were when keys were RecordIDs.
+const opKeyed = 0x80
+
+// Record is a single change of a node storage.
+//
+// Record -- одно изменение хранилища node.
+type Record struct {
+	Op   Op
+	Key  storage.Key
+	Data []byte
+}
+
+// Options stores configuration for a Log.
+//
+// Options -- содержит конфигурацию Log.
+type Options struct {
+	// Dir is a directory to keep the log and snapshots in.
+	// Dir -- директория, в которой хранятся лог и snapshots.
+	Dir string
+	// Sync is a fsync policy, SyncAlways is used if empty.
+	// Sync -- политика fsync, если не задана, используется SyncAlways.
+	Sync SyncMode
+	// SyncInterval is a time interval between fsyncs for SyncInterval.
+	// SyncInterval -- интервал между fsync для SyncInterval.
+	SyncInterval time.Duration
+}
+
+// file is the part of *os.File a Log is written with.
+type file interface {
+	io.Writer
+	io.Seeker
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+// Log is an append-only write-ahead log with snapshots.
+//
+// Log -- write-ahead лог, в который можно только дописывать, со snapshots.
+type Log struct {
+	opts Options
+
+	lock  sync.Mutex
+	f     file
+	n     int
+	dirty bool
+	// failed is set if a torn record couldn't be cut off the log,
+	// records appended after it would be lost on recovery.
+	failed bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Open recovers the state stored in opts.Dir calling apply for every record
+// of the latest snapshot and of the log written after it, and opens the log for appending.
+// A torn or corrupted tail of the log left by a crash is truncated.
+//
+// Open восстанавливает состояние, сохраненное в opts.Dir, вызывая apply для каждой
+// записи последнего snapshot и лога, записанного после него, и открывает лог для записи.
+// Поврежденный при падении конец лога обрезается.
+func Open(opts Options, apply func(Record)) (*Log, error) {
+	switch opts.Sync {
+	case "":
+		opts.Sync = SyncAlways
+	case SyncAlways, SyncNone:
+	case SyncInterval:
+		if opts.SyncInterval <= 0 {
+			opts.SyncInterval = DefaultSyncInterval
+		}
+	default:
+		return nil, fmt.Errorf("%v: %q", ErrUnknownSyncMode, opts.Sync)
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("Failed to create data dir %q: %v", opts.Dir, err)
+	}
+	if err := loadSnapshot(filepath.Join(opts.Dir, snapshotName), apply); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(opts.Dir, logName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open log: %v", err)
+	}
+	n, valid, err := readRecords(f, apply)
+	if err != nil && err != ErrCorruptedRecord {
+		f.Close()
+		return nil, fmt.Errorf("Failed to read log: %v", err)
+	}
+	if err == ErrCorruptedRecord {
+		log.Printf("Truncating corrupted log tail at offset %d", valid)
+		if err := f.Truncate(valid); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("Failed to truncate log: %v", err)
+		}
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("Failed to sync log: %v", err)
+		}
+	}
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Failed to seek log: %v", err)
+	}
+
+	l := &Log{
+		opts: opts,
+		f:    f,
+		n:    n,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if opts.Sync == SyncInterval {
+		go l.syncLoop()
+	} else {
+		close(l.done)
+	}
+	return l, nil
+}
+
+// Append writes records to the end of the log. With SyncAlways the log
+// is synced once for all of them. A partially written record is cut off,
+// and if it can't be, the log fails further appends with ErrLogFailed.
+//
+// Append дописывает records в конец лога. С SyncAlways лог сбрасывается
+// на диск один раз для всех них. Частично записанная запись обрезается,
+// а если это не удается, дальнейшие добавления завершаются ошибкой ErrLogFailed.
+func (l *Log) Append(records ...Record) error {
+	var buf []byte
+	for _, r := range records {
+		buf = append(buf, encode(r)...)
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.failed {
+		return ErrLogFailed
+	}
+	off, err := l.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("Failed to seek log: %v", err)
+	}
+	if _, err := l.f.Write(buf); err != nil {
+		// Recovery stops at a torn record, so records appended after it
+		// would be lost.
+		if terr := l.f.Truncate(off); terr != nil {
+			l.failed = true
+		} else if _, serr := l.f.Seek(off, io.SeekStart); serr != nil {
+			l.failed = true
+		}
+		return fmt.Errorf("Failed to append to log: %v", err)
+	}
+	l.n += len(records)
+	if l.opts.Sync == SyncAlways {
+		return l.f.Sync()
+	}
+	l.dirty = true
+	return nil
+}
+
+// Len returns a number of records appended since the last snapshot.
+//
+// Len возвращает количество записей, добавленных с момента последнего snapshot.
+func (l *Log) Len() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.n
+}
+
+// Snapshot atomically replaces the snapshot with records produced by each
+// and truncates the log. each should produce the full state of the storage
+// and no records should be appended until Snapshot returns.
+//
+// Snapshot атомарно заменяет snapshot записями, которые выдает each,
+// и обрезает лог. each должна выдавать полное состояние хранилища,
+// и до завершения Snapshot нельзя добавлять записи в лог.
+func (l *Log) Snapshot(each func(put func(k storage.Key, d []byte) error) error) error {
+	tmp := filepath.Join(l.opts.Dir, snapshotTmp)
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("Failed to create snapshot: %v", err)
+	}
+	w := bufio.NewWriter(f)
+	err = each(func(k storage.Key, d []byte) error {
+		_, err := w.Write(encode(Record{Op: OpPut, Key: k, Data: d}))
+		return err
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("Failed to write snapshot: %v", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(l.opts.Dir, snapshotName)); err != nil {
+		return fmt.Errorf("Failed to install snapshot: %v", err)
+	}
+	if err := syncDir(l.opts.Dir); err != nil {
+		return err
+	}
+
+	// Replaying the log on top of the new snapshot is idempotent,
+	// so a crash before truncation below loses nothing.
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if err := l.f.Truncate(0); err != nil {
+		return fmt.Errorf("Failed to truncate log: %v", err)
+	}
+	if _, err := l.f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("Failed to seek log: %v", err)
+	}
+	l.n = 0
+	l.dirty = false
+	l.failed = false
+	return l.f.Sync()
+}
+
+// Sync flushes the log to a stable storage.
+//
+// Sync сбрасывает лог на диск.
+func (l *Log) Sync() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if !l.dirty {
+		return nil
+	}
+	l.dirty = false
+	return l.f.Sync()
+}
+
+// Close flushes and closes the log.
+//
+// Close сбрасывает на диск и закрывает лог.
+func (l *Log) Close() error {
+	if l.opts.Sync == SyncInterval {
+		close(l.stop)
+	}
+	<-l.done
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	err := l.f.Sync()
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (l *Log) syncLoop() {
+	defer close(l.done)
+	t := time.NewTicker(l.opts.SyncInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := l.Sync(); err != nil {
+				log.Printf("Failed to sync log: %v", err)
+			}
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// encode frames r as crc32 | length | op | key | data,
+// where crc32 covers everything after the header. A key which is not
+// four bytes long is prefixed with its uvarint length and the op with opKeyed.
+func encode(r Record) []byte {
+	op, key := byte(r.Op), []byte(r.Key)
+	if len(key) != 4 {
+		var b [binary.MaxVarintLen64]byte
+		op |= opKeyed
+		key = append(b[:binary.PutUvarint(b[:], uint64(len(key)))], key...)
+	}
+	size := 1 + len(key) + len(r.Data)
+	buf := make([]byte, headerSize+size)
+	buf[headerSize] = op
+	copy(buf[headerSize+1:], key)
+	copy(buf[headerSize+1+len(key):], r.Data)
+	binary.LittleEndian.PutUint32(buf[4:], uint32(size))
+	binary.LittleEndian.PutUint32(buf, crc32.ChecksumIEEE(buf[headerSize:]))
+	return buf
+}
+
+// readRecords calls apply for every valid record in r.
+// Returns a number of records read, an offset right after the last valid record
+// and ErrCorruptedRecord if r doesn't end with a valid record.
+func readRecords(r io.Reader, apply func(Record)) (int, int64, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, headerSize)
+	var (
+		n     int
+		valid int64
+	)
+	for {
+		if _, err := io.ReadFull(br, header); err != nil {
+			if err == io.EOF {
+				return n, valid, nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				return n, valid, ErrCorruptedRecord
+			}
+			return n, valid, err
+		}
+		sum := binary.LittleEndian.Uint32(header)
+		size := binary.LittleEndian.Uint32(header[4:])
+		if size < 2 || size > maxRecordSize {
+			return n, valid, ErrCorruptedRecord
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return n, valid, ErrCorruptedRecord
+			}
+			return n, valid, err
+		}
+		if crc32.ChecksumIEEE(payload) != sum {
+			return n, valid, ErrCorruptedRecord
+		}
+		rec, ok := decode(payload)
+		if !ok {
+			return n, valid, ErrCorruptedRecord
+		}
+		apply(rec)
+		n++
+		valid += int64(headerSize) + int64(size)
+	}
+}
+
+// decode parses a payload framed by encode.
+func decode(payload []byte) (Record, bool) {
+	rec := Record{Op: Op(payload[0] &^ opKeyed)}
+	if rec.Op != OpPut && rec.Op != OpDel {
+		return rec, false
+	}
+	rest := payload[1:]
+	keySize := uint64(4)
+	if payload[0]&opKeyed != 0 {
+		var n int
+		keySize, n = binary.Uvarint(rest)
+		if n <= 0 {
+			return rec, false
+		}
+		rest = rest[n:]
+	}
+	if uint64(len(rest)) < keySize {
+		return rec, false
+	}
+	rec.Key = storage.Key(rest[:keySize])
+	if len(rest) > int(keySize) {
+		rec.Data = rest[keySize:]
+	} else if rec.Op == OpPut {
+		rec.Data = []byte{}
+	}
+	return rec, true
+}
+
+func loadSnapshot(fname string, apply func(Record)) error {
+	f, err := os.Open(fname)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to open snapshot: %v", err)
+	}
+	defer f.Close()
+	if _, _, err := readRecords(f, apply); err != nil {
+		if err == ErrCorruptedRecord {
+			return ErrCorruptedSnapshot
+		}
+		return fmt.Errorf("Failed to read snapshot: %v", err)
+	}
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("Failed to open data dir %q: %v", dir, err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("Failed to sync data dir %q: %v", dir, err)
+	}
+	return nil
+}
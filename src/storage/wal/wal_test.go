@@ -0,0 +1,66 @@
+package wal
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"storage"
+)
+
+// shortFile writes only a half of the next write and fails it.
+type shortFile struct {
+	file
+	short bool
+}
+
+func (f *shortFile) Write(b []byte) (int, error) {
+	if f.short {
+		f.short = false
+		n, _ := f.file.Write(b[:len(b)/2])
+		return n, errors.New("short write")
+	}
+	return f.file.Write(b)
+}
+
+func TestAppend_ShortWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	if err != nil {
+		t.Fatalf("TempDir() error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := Open(Options{Dir: dir}, func(Record) {})
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	first := Record{Op: OpPut, Key: "first", Data: []byte("a")}
+	last := Record{Op: OpDel, Key: storage.RecordID(1).Key()}
+	if err := l.Append(first); err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+	f := &shortFile{file: l.f, short: true}
+	l.f = f
+	if err := l.Append(Record{Op: OpPut, Key: "torn", Data: []byte("data")}); err == nil {
+		t.Fatalf("Append() with a short write succeeded")
+	}
+	if err := l.Append(last); err != nil {
+		t.Fatalf("Append() after a short write error: %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	// Records appended after the torn one survive a restart.
+	var got []Record
+	l, err = Open(Options{Dir: dir}, func(r Record) { got = append(got, r) })
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer l.Close()
+	if want := []Record{first, last}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Recovered %v, want %v", got, want)
+	}
+}